@@ -0,0 +1,48 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/image"
+)
+
+var BundleCmd *cobra.Command
+
+func init() {
+	BundleCmd = NewBundleCmd()
+	BundleCmd.AddCommand(bundleExportCmd(image.ValidateImage))
+}
+
+func NewBundleCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "bundle",
+		Short: "Manage offline bundles for validating images without network access",
+
+		Long: hd.Doc(`
+			Manage offline bundles for validating images without network access
+
+			An offline bundle holds everything fetched when validating images: the image
+			manifests and configurations, the image signatures and attestations, the
+			policy and data sources, the Rekor transparency log entries and the sigstore
+			TUF root. Use the bundle with the --offline-bundle flag of the
+			"ec validate image" command.
+		`),
+	}
+}
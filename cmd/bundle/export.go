@@ -0,0 +1,240 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+type imageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
+
+var newConftestEvaluator = evaluator.NewConftestEvaluator
+
+func bundleExportCmd(validate imageValidationFunc) *cobra.Command {
+	data := struct {
+		certificateIdentity         string
+		certificateIdentityRegExp   string
+		certificateOIDCIssuer       string
+		certificateOIDCIssuerRegExp string
+		effectiveTime               string
+		ignoreRekor                 bool
+		imageRef                    string
+		images                      string
+		output                      string
+		policy                      policy.Policy
+		policyConfiguration         string
+		publicKey                   string
+		recorder                    *offline.Recorder
+		rekorURL                    string
+		spec                        *app.SnapshotSpec
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export everything needed to validate images into an offline bundle",
+
+		Long: hd.Doc(`
+			Export everything needed to validate images into an offline bundle
+
+			The images are validated just as with the "ec validate image" command, and
+			everything fetched while validating is recorded: the image manifests and
+			configurations, the image signatures and attestations, the policy and data
+			sources and the Rekor transparency log entries. The recorded content, the
+			sigstore TUF root and the policy configuration are written to a gzipped
+			tarball.
+
+			The images can then be validated without network access by providing the
+			bundle to the "ec validate image" command via the --offline-bundle flag.
+			Registry credentials are never included in the bundle.
+
+			The export fails if any of the images can not be validated, policy
+			violations do not cause the export to fail.
+		`),
+
+		Example: hd.Doc(`
+			Export the offline bundle for an image using a policy configuration file:
+
+			  ec bundle export --image registry/name:tag --policy policy.yaml --output bundle.tar.gz
+
+			Validate the image without network access:
+
+			  ec validate image --offline-bundle bundle.tar.gz
+		`),
+
+		Args: cobra.NoArgs,
+
+		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
+			recorder, err := offline.NewRecorder()
+			if err != nil {
+				return err
+			}
+			data.recorder = recorder
+			defer func() {
+				if allErrors != nil {
+					_ = recorder.Close()
+				}
+			}()
+
+			ctx := offline.WithRecorder(cmd.Context(), recorder)
+			cmd.SetContext(ctx)
+
+			if s, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
+				Image:  data.imageRef,
+				Images: data.images,
+			}); err != nil {
+				allErrors = errors.Join(allErrors, err)
+			} else {
+				data.spec = s
+			}
+
+			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+			if err != nil {
+				allErrors = errors.Join(allErrors, err)
+				return
+			}
+
+			policyOptions := policy.Options{
+				EffectiveTime: data.effectiveTime,
+				Identity: cosign.Identity{
+					Issuer:        data.certificateOIDCIssuer,
+					IssuerRegExp:  data.certificateOIDCIssuerRegExp,
+					Subject:       data.certificateIdentity,
+					SubjectRegExp: data.certificateIdentityRegExp,
+				},
+				IgnoreRekor: data.ignoreRekor,
+				PolicyRef:   policyConfiguration,
+				PublicKey:   data.publicKey,
+				RekorURL:    data.rekorURL,
+			}
+
+			if p, _, err := policy.PreProcessPolicy(ctx, policyOptions); err != nil {
+				allErrors = errors.Join(allErrors, err)
+			} else {
+				data.policy = p
+			}
+
+			return
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer data.recorder.Close()
+
+			ctx := cmd.Context()
+			evaluators := []evaluator.Evaluator{}
+			for _, sourceGroup := range data.policy.Spec().Sources {
+				log.Debugf("Fetching policy source group '%s'", sourceGroup.Name)
+				c, err := newConftestEvaluator(ctx, source.PolicySourcesFrom(sourceGroup), data.policy, sourceGroup)
+				if err != nil {
+					return err
+				}
+
+				evaluators = append(evaluators, c)
+				defer c.Destroy()
+			}
+
+			var allErrors error
+			for _, comp := range data.spec.Components {
+				if _, err := validate(ctx, comp, data.spec, data.policy, evaluators, false); err != nil {
+					allErrors = errors.Join(allErrors, fmt.Errorf("error validating image %s of component %s: %w", comp.ContainerImage, comp.Name, err))
+				}
+			}
+			if allErrors != nil {
+				return allErrors
+			}
+
+			policySpec, err := json.Marshal(data.policy.Spec())
+			if err != nil {
+				return err
+			}
+
+			if err := data.recorder.Write(data.output, offline.Manifest{
+				Policy:   string(policySpec),
+				Snapshot: data.spec,
+			}); err != nil {
+				return fmt.Errorf("writing offline bundle: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported offline bundle to %s\n", data.output)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&data.policyConfiguration, "policy", "p", data.policyConfiguration, hd.Doc(`
+		Policy configuration as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}, identity: {...}}')")`))
+
+	cmd.Flags().StringVarP(&data.imageRef, "image", "i", data.imageRef, "OCI image reference")
+
+	cmd.Flags().StringVar(&data.images, "images", data.images,
+		"path to ApplicationSnapshot Spec JSON file or JSON representation of an ApplicationSnapshot Spec")
+
+	cmd.Flags().StringVarP(&data.publicKey, "public-key", "k", data.publicKey,
+		"path to the public key. Overrides publicKey from EnterpriseContractPolicy")
+
+	cmd.Flags().StringVarP(&data.rekorURL, "rekor-url", "r", data.rekorURL,
+		"Rekor URL. Overrides rekorURL from EnterpriseContractPolicy")
+
+	cmd.Flags().BoolVar(&data.ignoreRekor, "ignore-rekor", data.ignoreRekor,
+		"Skip Rekor transparency log checks during validation.")
+
+	cmd.Flags().StringVar(&data.certificateIdentity, "certificate-identity", data.certificateIdentity,
+		"URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateIdentityRegExp, "certificate-identity-regexp", data.certificateIdentityRegExp,
+		"Regular expression for the URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuer, "certificate-oidc-issuer", data.certificateOIDCIssuer,
+		"URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuerRegExp, "certificate-oidc-issuer-regexp", data.certificateOIDCIssuerRegExp,
+		"Regular expresssion for the URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVar(&data.effectiveTime, "effective-time", policy.Now, hd.Doc(`
+		Run policy checks with the provided time. The value can be "now" (default) -
+		for current time, "attestation" - for time from the youngest attestation, or
+		a RFC3339 formatted value, e.g. 2022-11-18T00:00:00Z.
+	`))
+
+	cmd.Flags().StringVarP(&data.output, "output", "o", data.output, "path of the offline bundle to write")
+
+	if err := cmd.MarkFlagRequired("output"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
@@ -0,0 +1,103 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func run(t *testing.T, validate imageValidationFunc, args ...string) (string, error) {
+	bundleCmd := NewBundleCmd()
+	bundleCmd.AddCommand(bundleExportCmd(validate))
+
+	rootCmd := root.NewRootCmd()
+	rootCmd.AddCommand(bundleCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	rootCmd.SetArgs(append([]string{"bundle", "export"}, args...))
+
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestBundleExport(t *testing.T) {
+	t.Setenv("TUF_ROOT", t.TempDir())
+	utils.SetTestRekorPublicKey(t)
+
+	var validated []string
+	validate := func(ctx context.Context, comp app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		// validation is recorded
+		assert.NotNil(t, offline.RecorderFromContext(ctx))
+		validated = append(validated, comp.ContainerImage)
+		return &output.Output{}, nil
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	out, err := run(t, validate,
+		"--image", "registry/image:tag",
+		"--policy", fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--output", path)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("Exported offline bundle to %s\n", path), out)
+	assert.Equal(t, []string{"registry/image:tag"}, validated)
+
+	b, err := offline.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	m := b.Manifest()
+	assert.JSONEq(t, fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON), m.Policy)
+	assert.Equal(t, &app.SnapshotSpec{Components: []app.SnapshotComponent{{Name: "Unnamed", ContainerImage: "registry/image:tag"}}}, m.Snapshot)
+	assert.True(t, m.TUFRoot)
+}
+
+func TestBundleExportValidationError(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+
+	validate := func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error) {
+		return nil, errors.New("expected")
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	_, err := run(t, validate,
+		"--image", "registry/image:tag",
+		"--policy", fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--output", path)
+	assert.EqualError(t, err, "error validating image registry/image:tag of component Unnamed: expected")
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,64 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"errors"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+// diskCacheFunc provides the persistent cache of policy and data sources, nil
+// when the cache is disabled or unavailable
+type diskCacheFunc func() *cache.DiskCache
+
+var errCacheDisabled = errors.New("the policy cache is disabled or unavailable, check the EC_CACHE environment variable")
+
+var CacheCmd *cobra.Command
+
+func init() {
+	CacheCmd = NewCacheCmd()
+	CacheCmd.AddCommand(cacheListCmd(cache.DefaultDiskCache))
+	CacheCmd.AddCommand(cachePruneCmd(cache.DefaultDiskCache))
+	CacheCmd.AddCommand(cacheClearCmd(cache.DefaultDiskCache))
+}
+
+func NewCacheCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the persistent cache of policy and data sources",
+
+		Long: hd.Doc(`
+			Manage the persistent cache of policy and data sources
+
+			Policy and data sources downloaded from git repositories and OCI registries
+			are stored in the "ec/policies" directory within the user's cache directory,
+			and reused by subsequent executions. Downloaded content is stored once for
+			each resolved git commit or OCI digest.
+
+			Sources pinned to a git commit or an OCI digest are always reused. Sources
+			referencing mutable content, like git branches or OCI tags, are reused for
+			one hour. The EC_POLICY_CACHE_TTL environment variable sets a different
+			duration, e.g. "15m", setting it to "0" downloads those again on each use.
+
+			Setting the EC_CACHE environment variable to "false" disables the cache.
+		`),
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+func cacheListCmd(diskCache diskCacheFunc) *cobra.Command {
+	var outputFormat string
+
+	validFormats := []string{"text", "json"}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the cached policy and data sources",
+
+		Long: hd.Doc(`
+			List the cached policy and data sources
+
+			For each cached source the URL, the type of the source, the resolved git
+			commit or OCI digest and the time the source was downloaded and last used
+			are listed.
+		`),
+
+		Example: hd.Doc(`
+			List the cached sources:
+
+			  ec cache list
+
+			List the cached sources in JSON format:
+
+			  ec cache list --output json
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !slices.Contains(validFormats, outputFormat) {
+				return fmt.Errorf("invalid value for --output '%s'. accepted values: %s", outputFormat, strings.Join(validFormats, ", "))
+			}
+
+			c := diskCache()
+			if c == nil {
+				return errCacheDisabled
+			}
+
+			entries, err := c.List()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if outputFormat == "json" {
+				return json.NewEncoder(out).Encode(entries)
+			}
+
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "URL\tTYPE\tRESOLVED\tFETCHED\tUSED")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.URL, e.Type, e.Resolved, e.Fetched.Format(time.RFC3339), e.Used.Format(time.RFC3339))
+			}
+
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", fmt.Sprintf("output format. one of: %s", strings.Join(validFormats, ", ")))
+
+	return cmd
+}
@@ -0,0 +1,73 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+)
+
+func cachePruneCmd(diskCache diskCacheFunc) *cobra.Command {
+	unusedFor := 7 * 24 * time.Hour
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove unused and expired policy and data sources from the cache",
+
+		Long: hd.Doc(`
+			Remove unused and expired policy and data sources from the cache
+
+			Sources not used within the duration set by the --unused-for flag, and
+			sources referencing mutable content not downloaded within the duration set
+			by the EC_POLICY_CACHE_TTL environment variable, one hour by default, are
+			removed. Downloaded content no longer referenced by any source is removed
+			as well. The URLs of the removed sources are printed.
+		`),
+
+		Example: hd.Doc(`
+			Remove sources not used in the last week:
+
+			  ec cache prune
+
+			Remove sources not used in the last day:
+
+			  ec cache prune --unused-for 24h
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			c := diskCache()
+			if c == nil {
+				return errCacheDisabled
+			}
+
+			pruned, err := c.Prune(unusedFor)
+			for _, e := range pruned {
+				fmt.Fprintln(cmd.OutOrStdout(), e.URL)
+			}
+
+			return err
+		},
+	}
+
+	cmd.Flags().DurationVar(&unusedFor, "unused-for", unusedFor, "remove sources not used within this duration")
+
+	return cmd
+}
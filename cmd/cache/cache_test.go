@@ -0,0 +1,117 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gitMetadata "github.com/enterprise-contract/go-gather/metadata/git"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+const commit = "6c3a1b4a1b7e8a3f0c9c2f5d6e8a9b0c1d2e3f4a"
+
+func run(t *testing.T, sub *cobra.Command, args ...string) (string, error) {
+	cacheCmd := NewCacheCmd()
+	cacheCmd.AddCommand(sub)
+
+	rootCmd := root.NewRootCmd()
+	rootCmd.AddCommand(cacheCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetContext(context.Background())
+	rootCmd.SetArgs(append([]string{"cache", sub.Name()}, args...))
+
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func populatedCache(t *testing.T) *cache.DiskCache {
+	c := cache.NewDiskCache(t.TempDir(), time.Hour)
+
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "policy.rego"), []byte("package policy"), 0600))
+	require.NoError(t, c.Put("git::https://github.com/org/repo?ref="+commit, src, &gitMetadata.GitMetadata{LatestCommit: commit}))
+
+	return c
+}
+
+func TestCacheList(t *testing.T) {
+	c := populatedCache(t)
+	diskCache := func() *cache.DiskCache { return c }
+
+	out, err := run(t, cacheListCmd(diskCache))
+	require.NoError(t, err)
+	assert.Regexp(t, `^URL\s+TYPE\s+RESOLVED\s+FETCHED\s+USED\ngit::https://github.com/org/repo\?ref=`+commit+`\s+git\s+`+commit+`\s+\S+\s+\S+\n$`, out)
+
+	out, err = run(t, cacheListCmd(diskCache), "--output", "json")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"pinnedUrl":"git::github.com/org/repo?ref=`+commit+`"`)
+
+	_, err = run(t, cacheListCmd(diskCache), "--output", "yaml")
+	assert.EqualError(t, err, "invalid value for --output 'yaml'. accepted values: text, json")
+}
+
+func TestCachePrune(t *testing.T) {
+	c := populatedCache(t)
+	diskCache := func() *cache.DiskCache { return c }
+
+	out, err := run(t, cachePruneCmd(diskCache))
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	out, err = run(t, cachePruneCmd(diskCache), "--unused-for", "0s")
+	require.NoError(t, err)
+	assert.Equal(t, "git::https://github.com/org/repo?ref="+commit+"\n", out)
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCacheClear(t *testing.T) {
+	c := populatedCache(t)
+
+	_, err := run(t, cacheClearCmd(func() *cache.DiskCache { return c }))
+	require.NoError(t, err)
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	disabled := func() *cache.DiskCache { return nil }
+
+	for _, cmd := range []*cobra.Command{cacheListCmd(disabled), cachePruneCmd(disabled), cacheClearCmd(disabled)} {
+		_, err := run(t, cmd)
+		assert.ErrorIs(t, err, errCacheDisabled)
+	}
+}
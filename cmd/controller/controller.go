@@ -0,0 +1,162 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/controller"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	_ "github.com/enterprise-contract/ec-cli/internal/rego"
+	"github.com/enterprise-contract/ec-cli/internal/server"
+)
+
+var ControllerCmd *cobra.Command
+
+func init() {
+	ControllerCmd = NewControllerCmd()
+}
+
+func NewControllerCmd() *cobra.Command {
+	opts := controller.Options{
+		ResyncPeriod: 10 * time.Hour,
+		Workers:      2,
+	}
+	serverOpts := server.Options{
+		PolicyTTL: 10 * time.Minute,
+		Workers:   5,
+	}
+	evaluatorName := "conftest"
+
+	cmd := &cobra.Command{
+		Use:   "controller",
+		Short: "Run a Kubernetes controller validating Snapshot resources",
+
+		Long: hd.Doc(`
+			Run a Kubernetes controller validating Snapshot resources
+
+			The controller watches the Snapshot resources and validates the images of
+			each Snapshot, the same way the "ec validate image" command does, against
+			the EnterpriseContractPolicy referenced by the "enterprisecontract.dev/policy"
+			annotation of the Snapshot, as [<namespace>/]<name>. The namespace defaults
+			to the namespace of the Snapshot. The default policy, if provided, is used
+			for Snapshots without the annotation. Snapshots with neither are not
+			validated.
+
+			The result of the validation is written to the "enterprisecontract.dev/result"
+			annotation of the Snapshot, in the same format as the "appstudio" output
+			format. Snapshots are validated again when they change or when the
+			referenced EnterpriseContractPolicy changes.
+
+			The service account running the controller needs permission to get, list,
+			watch and patch snapshots, and to get, list and watch
+			enterprisecontractpolicies.
+		`),
+
+		Example: hd.Doc(`
+			Validate the Snapshots from all namespaces:
+
+			  ec controller
+
+			Validate the Snapshots from the "team" namespace, using the "default"
+			policy for Snapshots without the policy annotation:
+
+			  ec controller --namespace team --policy team/default
+		`),
+
+		Args: cobra.NoArgs,
+
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			if evaluatorName != "conftest" && evaluatorName != "opa" {
+				return fmt.Errorf("unsupported evaluator %q, use one of: conftest, opa", evaluatorName)
+			}
+			if opts.Policy != "" && !strings.Contains(opts.Policy, "/") {
+				return fmt.Errorf("the default policy %q needs to be provided as <namespace>/<name>", opts.Policy)
+			}
+			return nil
+		},
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// Same as for "ec server", the global timeout doesn't apply to
+			// long running services.
+			ctx := context.WithoutCancel(cmd.Context())
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			client, err := kubernetes.NewDynamicClient()
+			if err != nil {
+				return err
+			}
+
+			// Share the downloaded policies between all validations
+			policyCache, err := cache.NewPolicyCache(ctx)
+			if err != nil {
+				return err
+			}
+			ctx = cache.WithPolicyCache(ctx, policyCache)
+
+			newEvaluator := evaluator.NewConftestEvaluator
+			if evaluatorName == "opa" {
+				newEvaluator = evaluator.NewOPAEvaluator
+			}
+
+			validator := server.New(serverOpts, image.ValidateImage, newEvaluator)
+			defer validator.Close()
+
+			return controller.New(client, validator, opts).Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", opts.Namespace,
+		"Namespace to watch the Snapshots in, all namespaces if not provided")
+
+	cmd.Flags().StringVarP(&opts.Policy, "policy", "p", opts.Policy, hd.Doc(`
+		Default EnterpriseContractPolicy, as <namespace>/<name>, used for Snapshots
+		without the policy annotation`))
+
+	cmd.Flags().IntVar(&opts.Workers, "workers", opts.Workers,
+		"Number of Snapshots to validate concurrently.")
+
+	cmd.Flags().IntVar(&serverOpts.Workers, "component-workers", serverOpts.Workers,
+		"Number of workers to use for validation of components within a single Snapshot.")
+
+	cmd.Flags().DurationVar(&opts.ResyncPeriod, "resync-period", opts.ResyncPeriod, hd.Doc(`
+		Interval at which all Snapshots are reconciled, Snapshots already validated
+		against the current policy are not validated again. Use 0 to disable.`))
+
+	cmd.Flags().DurationVar(&serverOpts.PolicyTTL, "policy-ttl", serverOpts.PolicyTTL, hd.Doc(`
+		Duration for which a prepared policy is reused. Once expired the policy
+		sources are downloaded again. Use 0 to never expire prepared policies.`))
+
+	cmd.Flags().StringVar(&evaluatorName, "evaluator", evaluatorName, hd.Doc(`
+		Policy evaluator to use, one of "conftest" or "opa". The "opa" evaluator
+		compiles the policies only once and reuses them for subsequent validations.`))
+
+	return cmd
+}
@@ -0,0 +1,151 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func diffReportCmd() *cobra.Command {
+	var (
+		output []string
+		strict bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "report <baseline> <current>",
+		Short: "Compare two reports of the validation of the same images",
+
+		Long: hd.Doc(`
+			Compare two reports of the validation of the same images
+
+			Reads two reports, as written by "ec validate image" in the JSON or YAML
+			format, and prints the rules that are newly failing, newly passing or that
+			changed severity, from a violation to a warning or the other way around, in
+			the current report compared to the baseline report. The changes are listed
+			per component, components are matched by name or, for unnamed components,
+			by image reference.
+
+			Successes are included in a report only with the --show-successes flag, a
+			rule passing in one report and not included in the other is not considered
+			a change.
+		`),
+
+		Example: hd.Doc(`
+			Compare the results of the validation against two versions of the policy:
+
+			  ec validate image --images snapshot.json --policy v1.yaml --output json=v1.json
+			  ec validate image --images snapshot.json --policy v2.yaml --output json=v2.json
+			  ec diff report v1.json v2.json
+
+			Write the differences in JSON format to a file and fail if any rule is newly
+			failing:
+
+			  ec diff report v1.json v2.json --output json=diff.json --strict
+		`),
+
+		Args: cobra.ExactArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fs := utils.FS(cmd.Context())
+
+			baseline, err := readReport(fs, args[0])
+			if err != nil {
+				return err
+			}
+
+			current, err := readReport(fs, args[1])
+			if err != nil {
+				return err
+			}
+
+			diff := applicationsnapshot.DiffReports(baseline, current)
+
+			if len(output) == 0 {
+				output = []string{applicationsnapshot.Text}
+			}
+
+			p := format.NewTargetParser(applicationsnapshot.Text, format.Options{}, cmd.OutOrStdout(), fs)
+			var allErrors error
+			for _, o := range output {
+				target, err := p.Parse(o)
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					continue
+				}
+
+				data, err := diff.Format(target.Format)
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					continue
+				}
+
+				if !bytes.HasSuffix(data, []byte{'\n'}) {
+					data = append(data, '\n')
+				}
+
+				if _, err := target.Write(data); err != nil {
+					allErrors = errors.Join(allErrors, err)
+				}
+			}
+			if allErrors != nil {
+				return allErrors
+			}
+
+			if strict && diff.NewlyFailing > 0 {
+				return fmt.Errorf("%d rules newly failing", diff.NewlyFailing)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&output, "output", output, hd.Doc(`
+		write output to a file in a specific format, "text" (default), "json" or "yaml". Use
+		empty string path for stdout. May be used multiple times.`))
+
+	cmd.Flags().BoolVarP(&strict, "strict", "s", strict,
+		"Return non-zero status if any rule is newly failing")
+
+	return cmd
+}
+
+func readReport(fs afero.Fs, path string) (applicationsnapshot.Report, error) {
+	var report applicationsnapshot.Report
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return report, fmt.Errorf("unable to read the report %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("unable to parse the report %s: %w", path, err)
+	}
+
+	return report, nil
+}
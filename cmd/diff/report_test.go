@@ -0,0 +1,125 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package diff
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+const baseline = `{
+  "success": false,
+  "components": [
+    {
+      "name": "a",
+      "containerImage": "registry.io/a:1",
+      "violations": [{"msg": "Fails", "metadata": {"code": "rule.fails"}}],
+      "success": false
+    }
+  ]
+}`
+
+// in YAML, as written by the "yaml" output format
+const current = `
+success: false
+components:
+- name: a
+  containerImage: registry.io/a:1
+  violations:
+  - msg: Fails too
+    metadata:
+      code: rule.new
+  success: false
+`
+
+func run(t *testing.T, args ...string) (string, afero.Fs, error) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "baseline.json", []byte(baseline), 0644))
+	require.NoError(t, afero.WriteFile(fs, "current.yaml", []byte(current), 0644))
+
+	diffCmd := NewDiffCmd()
+	diffCmd.AddCommand(diffReportCmd())
+
+	rootCmd := root.NewRootCmd()
+	rootCmd.AddCommand(diffCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetContext(utils.WithFS(context.Background(), fs))
+	rootCmd.SetArgs(append([]string{"diff", "report"}, args...))
+
+	err := rootCmd.Execute()
+	return out.String(), fs, err
+}
+
+func TestDiffReport(t *testing.T) {
+	out, _, err := run(t, "baseline.json", "current.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, hd.Doc(`
+		Component: a
+		ImageRef: registry.io/a:1
+		  Newly failing:
+		    rule.new (not reported -> violation)
+		      Fails too
+		  Newly passing:
+		    rule.fails (violation -> not reported)
+
+		Changes: 1 newly failing, 1 newly passing, 0 changed severity, 0 removed
+	`), out)
+}
+
+func TestDiffReportJSON(t *testing.T) {
+	out, fs, err := run(t, "baseline.json", "current.yaml", "--output", "json=diff.json", "--strict")
+	assert.EqualError(t, err, "1 rules newly failing")
+	assert.Empty(t, out)
+
+	data, err := afero.ReadFile(fs, "diff.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"components": [{
+			"name": "a",
+			"containerImage": "registry.io/a:1",
+			"changes": [
+				{"rule": "rule.fails", "change": "newly-passing", "baseline": "violation"},
+				{"rule": "rule.new", "change": "newly-failing", "current": "violation", "messages": ["Fails too"]}
+			]
+		}],
+		"newly-failing": 1,
+		"newly-passing": 1,
+		"severity-changed": 0,
+		"removed": 0
+	}`, string(data))
+}
+
+func TestDiffReportErrors(t *testing.T) {
+	_, _, err := run(t, "baseline.json", "missing.json")
+	assert.ErrorContains(t, err, "unable to read the report missing.json")
+
+	_, _, err = run(t, "baseline.json", "current.yaml", "--output", "junit")
+	assert.EqualError(t, err, `"junit" is not a valid diff format`)
+}
@@ -0,0 +1,60 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Define the `ec inspect input-schema` command
+package inspect
+
+import (
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/pkg/schema"
+)
+
+func inspectInputSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "input-schema",
+		Short: "Print the JSON schema of the policy input",
+
+		Long: hd.Doc(`
+			Print the JSON schema of the policy input.
+
+			The schema describes the input provided to the policy rules by the
+			'ec validate image' command. Policy authors can use it to check the
+			references to the input in the policy rules. Use the --strict-input flag
+			of the 'ec validate image' command to validate the input against the
+			schema before evaluating the policies.
+
+			The version in the schema ID is changed on breaking changes of the input.
+		`),
+
+		Example: hd.Doc(`
+			Save the policy input schema to a file:
+
+			ec inspect input-schema > policy_input.json
+		`),
+
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := fmt.Fprint(cmd.OutOrStdout(), schema.Policy_Input_v1_JSON)
+			return err
+		},
+	}
+
+	return cmd
+}
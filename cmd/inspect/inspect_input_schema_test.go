@@ -0,0 +1,46 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/pkg/schema"
+)
+
+func TestInspectInputSchema(t *testing.T) {
+	cmd := setUpCobra(inspectInputSchemaCmd())
+	cmd.SetContext(context.Background())
+	buffy := bytes.Buffer{}
+	cmd.SetOut(&buffy)
+
+	cmd.SetArgs([]string{
+		"inspect",
+		"input-schema",
+	})
+
+	require.NoError(t, cmd.Execute())
+	assert.JSONEq(t, schema.Policy_Input_v1_JSON, buffy.String())
+	assert.Contains(t, buffy.String(), schema.Policy_Input_v1_URI)
+}
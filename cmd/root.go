@@ -21,16 +21,23 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/enterprise-contract/ec-cli/cmd/bundle"
+	"github.com/enterprise-contract/ec-cli/cmd/cache"
+	"github.com/enterprise-contract/ec-cli/cmd/controller"
+	"github.com/enterprise-contract/ec-cli/cmd/diff"
 	"github.com/enterprise-contract/ec-cli/cmd/fetch"
 	"github.com/enterprise-contract/ec-cli/cmd/initialize"
 	"github.com/enterprise-contract/ec-cli/cmd/inspect"
 	"github.com/enterprise-contract/ec-cli/cmd/opa"
 	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/cmd/server"
 	"github.com/enterprise-contract/ec-cli/cmd/sigstore"
 	"github.com/enterprise-contract/ec-cli/cmd/test"
 	"github.com/enterprise-contract/ec-cli/cmd/track"
 	"github.com/enterprise-contract/ec-cli/cmd/validate"
+	"github.com/enterprise-contract/ec-cli/cmd/verify"
 	"github.com/enterprise-contract/ec-cli/cmd/version"
+	"github.com/enterprise-contract/ec-cli/cmd/webhook"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
@@ -59,6 +66,13 @@ func init() {
 	RootCmd.AddCommand(version.VersionCmd)
 	RootCmd.AddCommand(opa.OPACmd)
 	RootCmd.AddCommand(sigstore.SigstoreCmd)
+	RootCmd.AddCommand(server.ServerCmd)
+	RootCmd.AddCommand(webhook.WebhookCmd)
+	RootCmd.AddCommand(controller.ControllerCmd)
+	RootCmd.AddCommand(cache.CacheCmd)
+	RootCmd.AddCommand(bundle.BundleCmd)
+	RootCmd.AddCommand(verify.VerifyCmd)
+	RootCmd.AddCommand(diff.DiffCmd)
 	if utils.Experimental() {
 		RootCmd.AddCommand(test.TestCmd)
 	}
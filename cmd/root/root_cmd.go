@@ -31,9 +31,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/enterprise-contract/ec-cli/internal/auth"
 	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
 	"github.com/enterprise-contract/ec-cli/internal/logging"
 	"github.com/enterprise-contract/ec-cli/internal/tracing"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 )
 
 var (
@@ -67,7 +69,7 @@ func NewRootCmd() *cobra.Command {
 
 		SilenceUsage: true,
 
-		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 			logging.InitLogging(verbose, quiet, debug, enabledTraces.Enabled(tracing.Log, tracing.Opa), logfile)
 
 			// set a custom message for context.DeadlineExceeded error
@@ -79,6 +81,14 @@ func NewRootCmd() *cobra.Command {
 			cmd.SetContext(ctx)
 			log.Debugf("globalTimeout is %d", globalTimeout)
 
+			keychain, err := auth.NewKeychain(ctx)
+			if err != nil {
+				cancel()
+				return err
+			}
+			ctx = auth.WithKeychain(ctx, keychain)
+			cmd.SetContext(ctx)
+
 			var cpuprofile *os.File
 			var tracefile *os.File
 			if enabledTraces.Enabled(tracing.CPU) {
@@ -133,6 +143,8 @@ func NewRootCmd() *cobra.Command {
 					}
 				}
 
+				oci.LogRequestCounts()
+
 				// perform resource cleanup
 				if f, ok := log.StandardLogger().Out.(io.Closer); ok {
 					f.Close()
@@ -141,6 +153,8 @@ func NewRootCmd() *cobra.Command {
 					cancel()
 				}
 			})
+
+			return nil
 		},
 	}
 
@@ -165,4 +179,5 @@ func setFlags(rootCmd *cobra.Command) {
 	rootCmd.PersistentFlags().DurationVar(&globalTimeout, "timeout", globalTimeout, "max overall execution duration")
 	rootCmd.PersistentFlags().StringVar(&logfile, "logfile", "", "file to write the logging output. If not specified logging output will be written to stderr")
 	kubernetes.AddKubeconfigFlag(rootCmd)
+	auth.AddFlags(rootCmd)
 }
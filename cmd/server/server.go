@@ -0,0 +1,150 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	_ "github.com/enterprise-contract/ec-cli/internal/rego"
+	"github.com/enterprise-contract/ec-cli/internal/server"
+)
+
+var ServerCmd *cobra.Command
+
+func init() {
+	ServerCmd = NewServerCmd()
+}
+
+func NewServerCmd() *cobra.Command {
+	opts := server.Options{
+		Address:        ":8080",
+		PolicyTTL:      10 * time.Minute,
+		RequestTimeout: 5 * time.Minute,
+		Workers:        5,
+	}
+	evaluatorName := "conftest"
+
+	cmd := &cobra.Command{
+		Use:   "server",
+		Short: "Run a long running service validating container images",
+
+		Long: hd.Doc(`
+			Run a long running service validating container images
+
+			The service exposes the same validation performed by the "ec validate image"
+			command over HTTP. Policies are downloaded and prepared once, and reused for
+			subsequent requests until they expire.
+
+			The following endpoints are available:
+
+			  POST /v1/validate/image - validates the images from the provided Snapshot
+			  spec, responds with the same report as the JSON output of the "ec validate
+			  image" command
+
+			  GET /healthz - responds with 200 OK while the service is running
+
+			  GET /readyz - responds with 200 OK once the default policy, if configured,
+			  has been prepared
+
+			The request to validate images is a JSON object with the following
+			attributes, only "snapshot" is required:
+
+			  snapshot - the ApplicationSnapshot spec with the images to validate
+			  policy - the policy configuration, same as the --policy flag of the "ec
+			  validate image" command
+			  publicKey, rekorURL, ignoreRekor, certificateIdentity,
+			  certificateIdentityRegExp, certificateOIDCIssuer,
+			  certificateOIDCIssuerRegExp, effectiveTime - same as the flags of the same
+			  name of the "ec validate image" command
+			  info - include additional information on the failures
+			  showSuccesses - include the successes in the report
+		`),
+
+		Example: hd.Doc(`
+			Run the service with a default policy:
+
+			  ec server --policy github.com/user/repo
+
+			Validate an image using the service:
+
+			  curl -X POST http://localhost:8080/v1/validate/image \
+			    -d '{"snapshot":{"components":[{"containerImage":"registry/name:tag"}]}, "publicKey":"<key>"}'
+		`),
+
+		Args: cobra.NoArgs,
+
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			if evaluatorName != "conftest" && evaluatorName != "opa" {
+				return fmt.Errorf("unsupported evaluator %q, use one of: conftest, opa", evaluatorName)
+			}
+			return nil
+		},
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// The command's context is limited by the global timeout, this
+			// doesn't apply to long running services, the time limit is
+			// imposed for each request instead.
+			ctx := context.WithoutCancel(cmd.Context())
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			newEvaluator := evaluator.NewConftestEvaluator
+			if evaluatorName == "opa" {
+				newEvaluator = evaluator.NewOPAEvaluator
+			}
+
+			s := server.New(opts, image.ValidateImage, newEvaluator)
+
+			return s.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Address, "address", opts.Address, "address to listen on")
+
+	cmd.Flags().StringVarP(&opts.Policy, "policy", "p", opts.Policy, hd.Doc(`
+		Default policy configuration used when the request doesn't specify one, as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}, identity: {...}}')`))
+
+	cmd.Flags().DurationVar(&opts.PolicyTTL, "policy-ttl", opts.PolicyTTL, hd.Doc(`
+		Duration for which a prepared policy is reused. Once expired the policy
+		sources are downloaded again. Use 0 to never expire prepared policies.`))
+
+	cmd.Flags().DurationVar(&opts.RequestTimeout, "request-timeout", opts.RequestTimeout,
+		"max duration of a single validation request")
+
+	cmd.Flags().IntVar(&opts.Workers, "workers", opts.Workers,
+		"Number of workers to use for validation of components within a single request.")
+
+	cmd.Flags().StringVar(&evaluatorName, "evaluator", evaluatorName, hd.Doc(`
+		Policy evaluator to use, one of "conftest" or "opa". The "opa" evaluator
+		compiles the policies only once and reuses them for subsequent requests.`))
+
+	return cmd
+}
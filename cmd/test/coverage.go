@@ -0,0 +1,68 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/open-policy-agent/conftest/output"
+	conftest "github.com/open-policy-agent/conftest/policy"
+	"github.com/open-policy-agent/conftest/runner"
+	"github.com/spf13/afero"
+
+	"github.com/enterprise-contract/ec-cli/internal/coverage"
+	"github.com/enterprise-contract/ec-cli/internal/format"
+)
+
+var coverageUsage = "Report which policy rules were evaluated, as <format>[=<path>]. The policy queries are evaluated a second time after the test run to collect the report. Valid formats: " + strings.Join(coverage.Formats, ", ")
+
+var profileUsage = "Report the time spent evaluating each policy rule, as <format>[=<path>]. The policy queries are evaluated a second time after the test run, the reported times are those of that evaluation and add to the duration of the command. Valid formats: " + strings.Join(coverage.Formats, ", ")
+
+// reportCoverage evaluates again the queries of the results with the coverage
+// and profiling tracers, and writes the requested reports. The Conftest test
+// runner does not accept query tracers so its queries need to be replayed, the
+// profile therefore times the replay and not the test run itself.
+func reportCoverage(ctx context.Context, r runner.TestRunner, results []output.CheckResult, coverageTarget, profileTarget string, out io.Writer, fs afero.Fs) error {
+	if coverageTarget == "" && profileTarget == "" {
+		return nil
+	}
+
+	if err := errors.Join(coverage.CheckTarget(coverageTarget), coverage.CheckTarget(profileTarget)); err != nil {
+		return err
+	}
+
+	engine, err := conftest.LoadWithData(r.Policy, r.Data, r.Capabilities, r.Strict)
+	if err != nil {
+		return err
+	}
+
+	policyDir := ""
+	if len(r.Policy) == 1 {
+		policyDir = r.Policy[0]
+	}
+
+	collector := coverage.NewCollector(coverageTarget != "", profileTarget != "")
+	if err := collector.Replay(ctx, engine, results, policyDir); err != nil {
+		return err
+	}
+
+	p := format.NewTargetParser(coverage.Text, format.Options{}, out, fs)
+	return collector.WriteReports(p, coverageTarget, profileTarget)
+}
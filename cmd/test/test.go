@@ -33,6 +33,9 @@ import (
 	"github.com/open-policy-agent/conftest/runner"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/enterprise-contract/ec-cli/internal/coverage"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
 const testDesc = `
@@ -160,6 +163,14 @@ func newTestCommand() *cobra.Command {
 			}
 
 			results, resultsErr := runner.Run(ctx, fileList)
+
+			if resultsErr == nil {
+				coverageTarget, _ := cmd.Flags().GetString("coverage")
+				profileTarget, _ := cmd.Flags().GetString("profile")
+				if err := reportCoverage(ctx, runner, results, coverageTarget, profileTarget, cmd.OutOrStdout(), utils.FS(ctx)); err != nil {
+					return fmt.Errorf("reporting coverage: %w", err)
+				}
+			}
 			var exitCode int
 			if runner.FailOnWarn {
 				exitCode = output.ExitCodeFailOnWarn(results)
@@ -268,6 +279,10 @@ func newTestCommand() *cobra.Command {
 	cmd.Flags().StringSliceP("output", "o", []string{}, fmt.Sprintf("Output format for conftest results - valid options are: %s. You can optionally specify a file for the output, e.g. -o json=out.json", append(output.Outputs(), OutputAppstudio)))
 
 	cmd.Flags().StringSlice("proto-file-dirs", []string{}, "A list of directories containing Protocol Buffer definitions")
+	cmd.Flags().String("coverage", "", coverageUsage)
+	cmd.Flags().Lookup("coverage").NoOptDefVal = coverage.Text
+	cmd.Flags().String("profile", "", profileUsage)
+	cmd.Flags().Lookup("profile").NoOptDefVal = coverage.Text
 
 	return &cmd
 }
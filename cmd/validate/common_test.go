@@ -20,6 +20,8 @@ package validate
 
 import (
 	"context"
+	"os"
+	"testing"
 
 	"github.com/enterprise-contract/go-gather/metadata"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -32,6 +34,13 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
 )
 
+// TestMain keeps the tests from using the persistent cache in the user's
+// cache directory
+func TestMain(m *testing.M) {
+	os.Setenv("EC_CACHE", "false")
+	os.Exit(m.Run())
+}
+
 func commonMockClient(client *fake.FakeClient) {
 	// TODO: Replace mock.Anything calls with specific values
 	client.On("Head", mock.Anything).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
@@ -0,0 +1,73 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package validate
+
+import (
+	"errors"
+	"strings"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/coverage"
+	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+// addCoverageFlags adds the --coverage and --profile flags to the command
+func addCoverageFlags(cmd *cobra.Command, coverageTarget, profileTarget *string) {
+	cmd.Flags().StringVar(coverageTarget, "coverage", *coverageTarget, hd.Doc(`
+		Report which policy rules were evaluated. Rules are identified by their code
+		and are hit if any of their lines were evaluated, for any of the inputs. The
+		report is written to stdout or to a file, as <format>[=<path>], e.g.
+		--coverage json=coverage.json. Possible formats are: `+strings.Join(coverage.Formats, ", ")+`.`))
+	cmd.Flags().Lookup("coverage").NoOptDefVal = coverage.Text
+
+	cmd.Flags().StringVar(profileTarget, "profile", *profileTarget, hd.Doc(`
+		Report the time spent evaluating each policy rule, for all of the inputs. The
+		report is written to stdout or to a file, as <format>[=<path>], e.g.
+		--profile json=profile.json. Possible formats are: `+strings.Join(coverage.Formats, ", ")+`.`))
+	cmd.Flags().Lookup("profile").NoOptDefVal = coverage.Text
+}
+
+// enableCoverage sets up the collection of the coverage and profiling
+// information in the command's context if requested
+func enableCoverage(cmd *cobra.Command, coverageTarget, profileTarget string) error {
+	if coverageTarget == "" && profileTarget == "" {
+		return nil
+	}
+
+	if err := errors.Join(coverage.CheckTarget(coverageTarget), coverage.CheckTarget(profileTarget)); err != nil {
+		return err
+	}
+
+	collector := coverage.NewCollector(coverageTarget != "", profileTarget != "")
+	cmd.SetContext(coverage.WithCollector(cmd.Context(), collector))
+
+	return nil
+}
+
+// writeCoverage writes the coverage and profiling reports if requested
+func writeCoverage(cmd *cobra.Command, coverageTarget, profileTarget string) error {
+	collector := coverage.FromContext(cmd.Context())
+	if collector == nil {
+		return nil
+	}
+
+	p := format.NewTargetParser(coverage.Text, format.Options{}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
+	return collector.WriteReports(p, coverageTarget, profileTarget)
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime/trace"
+	"slices"
 	"sort"
 	"strings"
 
@@ -33,18 +34,161 @@ import (
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 
 	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/coverage"
+	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/application_snapshot_image"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
 	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
 )
 
 type imageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
 
-var newConftestEvaluator = evaluator.NewConftestEvaluator
+var (
+	newConftestEvaluator = evaluator.NewConftestEvaluator
+	newOPAEvaluator      = evaluator.NewOPAEvaluator
+	attachVSA            = vsa.Attach
+)
+
+// pinnedPolicySources returns the URLs of the policy sources, once fetched
+// these are pinned to the digest of the fetched content. Inline data is not
+// included as it is part of the policy configuration.
+func pinnedPolicySources(sources []source.PolicySource) []string {
+	var urls []string
+	for _, s := range sources {
+		if u := s.PolicyUrl(); !strings.HasPrefix(u, "data:") {
+			urls = append(urls, u)
+		}
+	}
+
+	return urls
+}
+
+// signVSA signs the VSA of the report, which is then output instead of the
+// unsigned VSA, and if requested attaches the signed VSA to each of the
+// validated images.
+func signVSA(ctx context.Context, report *applicationsnapshot.Report, opts vsa.Options, attach bool) error {
+	statement, err := applicationsnapshot.NewVSA(*report)
+	if err != nil {
+		return err
+	}
+
+	signer, err := vsa.NewSigner(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	signed, err := signer.Sign(ctx, applicationsnapshot.PredicateVSAProvenance, statement)
+	if err != nil {
+		return err
+	}
+	report.SignedVSA = signed.Envelope
+
+	if !attach {
+		return nil
+	}
+
+	var allErrors error
+	for _, c := range report.Components {
+		if oci.IsLocal(c.ContainerImage) {
+			allErrors = errors.Join(allErrors, fmt.Errorf("unable to attach the VSA to the local image %s", c.ContainerImage))
+			continue
+		}
+		if err := attachVSA(ctx, c.ContainerImage, signed); err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("unable to attach the VSA to image %s: %w", c.ContainerImage, err))
+		}
+	}
+
+	return allErrors
+}
+
+// isDiffOutput returns true if the output target, as given to the --output
+// flag, is of the diff format.
+func isDiffOutput(target string) bool {
+	f, _, _ := strings.Cut(target, "=")
+	f, _, _ = strings.Cut(f, "?")
+	return f == applicationsnapshot.Diff
+}
+
+// isPolicyInputOutput returns true if the output target, as given to the
+// --output flag, is of the policy input.
+func isPolicyInputOutput(target string) bool {
+	f, _, _ := strings.Cut(target, "=")
+	f, _, _ = strings.Cut(f, "?")
+	return f == applicationsnapshot.PolicyInput
+}
+
+// isStdoutOutput returns true if the output target, as given to the --output
+// flag, is written to stdout.
+func isStdoutOutput(target string) bool {
+	formatAndPath, _, _ := strings.Cut(target, "?")
+	_, path, _ := strings.Cut(formatAndPath, "=")
+	return path == ""
+}
+
+// withExtraRuleData returns the policy with the extra rule data, provided as
+// key=value pairs, injected into the rule data of each of its sources.
+func withExtraRuleData(ctx context.Context, p policy.Policy, extraRuleData []string) policy.Policy {
+	if len(extraRuleData) == 0 {
+		return p
+	}
+
+	var err error
+	policySpec := p.Spec()
+	sources := policySpec.Sources
+	for i := range sources {
+		src := sources[i]
+		var rule_data_raw []byte
+		unmarshaled := make(map[string]interface{})
+
+		if src.RuleData != nil {
+			rule_data_raw, err = src.RuleData.MarshalJSON()
+			if err != nil {
+				log.Errorf("Unable to parse ruledata to raw data")
+			}
+			err = json.Unmarshal(rule_data_raw, &unmarshaled)
+			if err != nil {
+				log.Errorf("Unable to parse ruledata into standard JSON object")
+			}
+		} else {
+			sources[i].RuleData = new(extv1.JSON)
+		}
+
+		for j := range extraRuleData {
+			parts := strings.SplitN(extraRuleData[j], "=", 2)
+			if len(parts) < 2 {
+				log.Errorf("Incorrect syntax for --extra-rule-data")
+			}
+			extraRuleDataPolicyConfig, err := validate_utils.GetPolicyConfig(ctx, parts[1])
+			if err != nil {
+				log.Errorf("Unable to load data from extraRuleData: %s", err.Error())
+			}
+			unmarshaled[parts[0]] = extraRuleDataPolicyConfig
+		}
+		rule_data_raw, err = json.Marshal(unmarshaled)
+		if err != nil {
+			log.Errorf("Unable to parse updated ruledata: %s", err.Error())
+		}
+
+		if rule_data_raw == nil {
+			log.Errorf("Invalid rule data JSON")
+		}
+
+		err = sources[i].RuleData.UnmarshalJSON(rule_data_raw)
+		if err != nil {
+			log.Errorf("Unable to marshal updated JSON: %s", err.Error())
+		}
+	}
+	policySpec.Sources = sources
+	return p.WithSpec(policySpec)
+}
 
 func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	data := struct {
@@ -52,7 +196,10 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		certificateIdentityRegExp   string
 		certificateOIDCIssuer       string
 		certificateOIDCIssuerRegExp string
+		coverage                    string
 		effectiveTime               string
+		evaluator                   string
+		explain                     bool
 		extraRuleData               []string
 		filePath                    string // Deprecated: images replaced this
 		imageRef                    string
@@ -62,22 +209,35 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		output                      []string
 		outputFile                  string
 		policy                      policy.Policy
+		baselinePolicy              policy.Policy
+		baselinePolicyConfiguration string
+		profile                     string
 		policyConfiguration         string
 		publicKey                   string
 		rekorURL                    string
 		snapshot                    string
 		spec                        *app.SnapshotSpec
 		strict                      bool
+		strictInput                 bool
 		images                      string
 		noColor                     bool
 		forceColor                  bool
 		workers                     int
+		offlineBundle               string
+		bundle                      *offline.Bundle
+		vsa                         vsa.Options
+		vsaAttach                   bool
+		vsaUpload                   bool
+		vsaRekorURL                 string
 	}{
-		strict:  true,
-		workers: 5,
+		evaluator:   "conftest",
+		strict:      true,
+		workers:     5,
+		vsaRekorURL: vsa.DefaultRekorURL,
 	}
 
-	validOutputFormats := applicationsnapshot.OutputFormats
+	// the diff format is available only with a baseline policy
+	validOutputFormats := slices.Concat(applicationsnapshot.OutputFormats, []string{applicationsnapshot.Diff})
 
 	cmd := &cobra.Command{
 		Use:   "image",
@@ -116,6 +276,13 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 
 			  ec validate image --images my-app.yaml
 
+			Validate an image, with its signatures and attestations, from a local OCI
+			layout directory, e.g. created by "cosign save", or from an archive created
+			by "docker save":
+
+			  ec validate image --image oci:./my-image@sha256:<digest>
+			  ec validate image --image docker-archive:./my-image.tar:latest
+
 			Validate attestation of images from an inline ApplicationSnapshot Spec:
 
 			  ec validate image --images '{"components":[{"containerImage":"<image url>"}]}'
@@ -186,6 +353,28 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			    --certificate-identity-regexp '^https://github\.com' \
 			    --certificate-oidc-issuer-regexp 'githubusercontent' \
 			    --rekor-url 'https://rekor.sigstore.dev'
+
+			Report the rules newly failing, newly passing or with changed severity
+			when moving from the current to a new version of the policy:
+
+			  ec validate image --image registry/name:tag --policy github.com/user/repo//policy?ref=v2 \
+			    --baseline-policy github.com/user/repo//policy?ref=v1
+
+			Validate the images of an offline bundle, created by "ec bundle export",
+			without network access:
+
+			  ec validate image --offline-bundle bundle.tar.gz
+
+			Sign the Verification Summary Attestation (VSA) with a key, write it to a
+			file, attach it to the validated image and upload it to Rekor:
+
+			  ec validate image --image registry/name:tag --policy my-policy \
+			    --vsa-signing-key cosign.key --output vsa=vsa.json --vsa-attach --vsa-upload
+
+			Sign the VSA keyless, using a certificate issued by Fulcio:
+
+			  ec validate image --image registry/name:tag --policy my-policy \
+			    --vsa-keyless --vsa-identity-token "$(cat token)" --vsa-upload --output vsa=vsa.json
 		`),
 
 		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
@@ -197,6 +386,59 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				cmd.SetContext(ctx)
 			}
 
+			if data.explain {
+				ctx = evaluator.WithExplain(ctx)
+				cmd.SetContext(ctx)
+			}
+
+			if data.strictInput {
+				ctx = application_snapshot_image.WithStrictInput(ctx)
+				cmd.SetContext(ctx)
+			}
+
+			// Share the registry lookups, e.g. of common base images, between
+			// all the components and policies validated
+			ctx = oci.WithMemo(ctx)
+			// Allow validating the images in local OCI layouts and archives
+			ctx = oci.WithLocalImages(ctx)
+			cmd.SetContext(ctx)
+
+			if err := enableCoverage(cmd, data.coverage, data.profile); err != nil {
+				return err
+			}
+			ctx = cmd.Context()
+
+			if data.offlineBundle != "" {
+				b, err := offline.Open(data.offlineBundle)
+				if err != nil {
+					return err
+				}
+				data.bundle = b
+				defer func() {
+					if allErrors != nil {
+						_ = b.Close()
+					}
+				}()
+
+				// all lookups, including the sigstore trust material, are
+				// served from the bundle set in the context
+				ctx = offline.WithBundle(ctx, b)
+				cmd.SetContext(ctx)
+
+				// default to the policy and the images the bundle was exported with
+				m := b.Manifest()
+				if data.policyConfiguration == "" {
+					data.policyConfiguration = m.Policy
+				}
+				if data.imageRef == "" && data.images == "" && data.input == "" && data.filePath == "" && data.snapshot == "" && m.Snapshot != nil {
+					images, err := json.Marshal(m.Snapshot)
+					if err != nil {
+						return err
+					}
+					data.images = string(images)
+				}
+			}
+
 			if s, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{
 				File:     data.filePath,
 				JSON:     data.input,
@@ -209,6 +451,27 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				data.spec = s
 			}
 
+			if (data.vsaAttach || data.vsaUpload) && !data.vsa.Enabled() {
+				allErrors = errors.Join(allErrors, errors.New("--vsa-attach and --vsa-upload require either --vsa-signing-key or --vsa-keyless"))
+			}
+			if data.vsaUpload {
+				data.vsa.RekorURL = data.vsaRekorURL
+			}
+
+			if data.evaluator != "conftest" && data.evaluator != "opa" {
+				allErrors = errors.Join(allErrors, fmt.Errorf("unsupported evaluator %q, use one of: conftest, opa", data.evaluator))
+			}
+
+			if data.coverage != "" || data.profile != "" {
+				// The coverage and profiling tracers are attached to the
+				// evaluation of the policies, only the "opa" evaluator
+				// accepts them.
+				if cmd.Flags().Changed("evaluator") && data.evaluator != "opa" {
+					allErrors = errors.Join(allErrors, errors.New("--coverage and --profile require the opa evaluator"))
+				}
+				data.evaluator = "opa"
+			}
+
 			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
 			if err != nil {
 				allErrors = errors.Join(allErrors, err)
@@ -235,57 +498,22 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 			if p, _, err := policy.PreProcessPolicy(ctx, policyOptions); err != nil {
 				allErrors = errors.Join(allErrors, err)
 			} else {
-				// inject extra variables into rule data per source
-				if len(data.extraRuleData) > 0 {
-					policySpec := p.Spec()
-					sources := policySpec.Sources
-					for i := range sources {
-						src := sources[i]
-						var rule_data_raw []byte
-						unmarshaled := make(map[string]interface{})
-
-						if src.RuleData != nil {
-							rule_data_raw, err = src.RuleData.MarshalJSON()
-							if err != nil {
-								log.Errorf("Unable to parse ruledata to raw data")
-							}
-							err = json.Unmarshal(rule_data_raw, &unmarshaled)
-							if err != nil {
-								log.Errorf("Unable to parse ruledata into standard JSON object")
-							}
-						} else {
-							sources[i].RuleData = new(extv1.JSON)
-						}
-
-						for j := range data.extraRuleData {
-							parts := strings.SplitN(data.extraRuleData[j], "=", 2)
-							if len(parts) < 2 {
-								log.Errorf("Incorrect syntax for --extra-rule-data")
-							}
-							extraRuleDataPolicyConfig, err := validate_utils.GetPolicyConfig(ctx, parts[1])
-							if err != nil {
-								log.Errorf("Unable to load data from extraRuleData: %s", err.Error())
-							}
-							unmarshaled[parts[0]] = extraRuleDataPolicyConfig
-						}
-						rule_data_raw, err = json.Marshal(unmarshaled)
-						if err != nil {
-							log.Errorf("Unable to parse updated ruledata: %s", err.Error())
-						}
+				data.policy = withExtraRuleData(ctx, p, data.extraRuleData)
+			}
 
-						if rule_data_raw == nil {
-							log.Errorf("Invalid rule data JSON")
-						}
+			if data.baselinePolicyConfiguration != "" {
+				baselinePolicyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.baselinePolicyConfiguration)
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					return
+				}
 
-						err = sources[i].RuleData.UnmarshalJSON(rule_data_raw)
-						if err != nil {
-							log.Errorf("Unable to marshal updated JSON: %s", err.Error())
-						}
-					}
-					policySpec.Sources = sources
-					p = p.WithSpec(policySpec)
+				policyOptions.PolicyRef = baselinePolicyConfiguration
+				if p, _, err := policy.PreProcessPolicy(ctx, policyOptions); err != nil {
+					allErrors = errors.Join(allErrors, fmt.Errorf("unable to load the baseline policy: %w", err))
+				} else {
+					data.baselinePolicy = withExtraRuleData(ctx, p, data.extraRuleData)
 				}
-				data.policy = p
 			}
 
 			return
@@ -298,6 +526,10 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				defer task.End()
 			}
 
+			if data.bundle != nil {
+				defer data.bundle.Close()
+			}
+
 			type result struct {
 				err         error
 				component   applicationsnapshot.Component
@@ -305,139 +537,203 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 				policyInput []byte
 			}
 
-			appComponents := data.spec.Components
-			evaluators := []evaluator.Evaluator{}
-
-			// Return an evaluator for each of these
-			for _, sourceGroup := range data.policy.Spec().Sources {
-				// Todo: Make each fetch run concurrently
-				log.Debugf("Fetching policy source group '%s'", sourceGroup.Name)
-				policySources := source.PolicySourcesFrom(sourceGroup)
-
-				for _, policySource := range policySources {
-					log.Debugf("policySource: %#v", policySource)
-				}
+			// evaluation holds the outcome of validating all the components
+			// against a policy
+			type evaluation struct {
+				components    []applicationsnapshot.Component
+				data          [][]evaluator.Data
+				policyInput   [][]byte
+				policySources []source.PolicySource
+			}
 
-				c, err := newConftestEvaluator(cmd.Context(), policySources, data.policy, sourceGroup)
-				if err != nil {
-					log.Debug("Failed to initialize the conftest evaluator!")
-					return err
-				}
+			appComponents := data.spec.Components
 
-				evaluators = append(evaluators, c)
-				defer c.Destroy()
+			newEvaluator := newConftestEvaluator
+			if data.evaluator == "opa" {
+				newEvaluator = newOPAEvaluator
 			}
 
 			showSuccesses, _ := cmd.Flags().GetBool("show-successes")
 
-			// worker is responsible for processing one component at a time from the jobs channel,
-			// and for emitting a corresponding result for the component on the results channel.
-			worker := func(id int, jobs <-chan app.SnapshotComponent, results chan<- result) {
-				log.Debugf("Starting worker %d", id)
-				for comp := range jobs {
-					ctx := cmd.Context()
-					var task *trace.Task
-					if trace.IsEnabled() {
-						ctx, task = trace.NewTask(ctx, "ec:validate-component")
-						trace.Logf(ctx, "", "workerID=%d", id)
+			evaluate := func(ctx context.Context, p policy.Policy) (*evaluation, error) {
+				// The policy sources of each component are evaluated
+				// concurrently, sharing the workers with the components.
+				ctx = image.WithEvaluationPool(ctx, data.workers)
+				if slices.ContainsFunc(data.output, isPolicyInputOutput) {
+					ctx = image.WithPolicyInput(ctx)
+				}
+				evaluators := []evaluator.Evaluator{}
+				e := evaluation{}
+
+				// Return an evaluator for each of these
+				for _, sourceGroup := range p.Spec().Sources {
+					// Todo: Make each fetch run concurrently
+					log.Debugf("Fetching policy source group '%s'", sourceGroup.Name)
+					policySources := source.PolicySourcesFrom(sourceGroup)
+					e.policySources = append(e.policySources, policySources...)
+
+					for _, policySource := range policySources {
+						log.Debugf("policySource: %#v", policySource)
 					}
 
-					log.Debugf("Worker %d got a component %q", id, comp.ContainerImage)
-					out, err := validate(ctx, comp, data.spec, data.policy, evaluators, data.info)
-					res := result{
-						err: err,
-						component: applicationsnapshot.Component{
-							SnapshotComponent: comp,
-							Success:           err == nil,
-						},
+					c, err := newEvaluator(ctx, policySources, p, sourceGroup)
+					if err != nil {
+						log.Debugf("Failed to initialize the %s evaluator!", data.evaluator)
+						return nil, err
 					}
 
-					// Skip on err to not panic. Error is return on routine completion.
-					if err == nil {
-						res.component.Violations = out.Violations()
-						res.component.Warnings = out.Warnings()
+					evaluators = append(evaluators, c)
+					defer c.Destroy()
+				}
+
+				// worker is responsible for processing one component at a time from the jobs channel,
+				// and for emitting a corresponding result for the component on the results channel.
+				worker := func(id int, jobs <-chan app.SnapshotComponent, results chan<- result) {
+					log.Debugf("Starting worker %d", id)
+					for comp := range jobs {
+						ctx := ctx
+						var task *trace.Task
+						if trace.IsEnabled() {
+							ctx, task = trace.NewTask(ctx, "ec:validate-component")
+							trace.Logf(ctx, "", "workerID=%d", id)
+						}
 
-						successes := out.Successes()
-						res.component.SuccessCount = len(successes)
-						if showSuccesses {
-							res.component.Successes = successes
+						log.Debugf("Worker %d got a component %q", id, comp.ContainerImage)
+						out, err := validate(ctx, comp, data.spec, p, evaluators, data.info)
+						res := result{
+							err: err,
+							component: applicationsnapshot.Component{
+								SnapshotComponent: comp,
+								Success:           err == nil,
+							},
 						}
 
-						res.component.Signatures = out.Signatures
-						res.component.Attestations = out.Attestations
-						res.component.ContainerImage = out.ImageURL
-						res.data = out.Data
-						res.component.Attestations = out.Attestations
-						res.policyInput = out.PolicyInput
-					}
-					res.component.Success = err == nil && len(res.component.Violations) == 0
+						// Skip on err to not panic. Error is return on routine completion.
+						if err == nil {
+							res.component.Violations = out.Violations()
+							res.component.Warnings = out.Warnings()
+
+							successes := out.Successes()
+							res.component.SuccessCount = len(successes)
+							if showSuccesses {
+								res.component.Successes = successes
+							}
 
-					if task != nil {
-						task.End()
+							res.component.Signatures = out.Signatures
+							res.component.Attestations = out.Attestations
+							res.component.ContainerImage = out.ImageURL
+							res.data = out.Data
+							res.component.Attestations = out.Attestations
+							res.policyInput = out.PolicyInput
+						}
+						res.component.Success = err == nil && len(res.component.Violations) == 0
+						// local images are reported as referenced by the user
+						res.component.ContainerImage = oci.LocalReference(ctx, res.component.ContainerImage)
+
+						if task != nil {
+							task.End()
+						}
+						results <- res
 					}
-					results <- res
+					log.Debugf("Done with worker %d", id)
 				}
-				log.Debugf("Done with worker %d", id)
-			}
-
-			numComponents := len(appComponents)
 
-			// Set numWorkers to the value from our flag. The default is 5.
-			numWorkers := data.workers
+				numComponents := len(appComponents)
 
-			jobs := make(chan app.SnapshotComponent, numComponents)
-			results := make(chan result, numComponents)
-			// Initialize each worker. They will wait patiently until a job is sent to the jobs
-			// channel, or the jobs channel is closed.
-			for i := 0; i <= numWorkers; i++ {
-				go worker(i, jobs, results)
-			}
-			// Initialize all the jobs. Each worker will pick a job from the channel when the worker
-			// is ready to consume a new job.
-			for _, c := range appComponents {
-				jobs <- c
-			}
-			close(jobs)
+				// Set numWorkers to the value from our flag. The default is 5.
+				numWorkers := data.workers
 
-			var components []applicationsnapshot.Component
-			var manyData [][]evaluator.Data
-			var manyPolicyInput [][]byte
-			var allErrors error = nil
-			for i := 0; i < numComponents; i++ {
-				r := <-results
-				if r.err != nil {
-					e := fmt.Errorf("error validating image %s of component %s: %w", r.component.ContainerImage, r.component.Name, r.err)
-					allErrors = errors.Join(allErrors, e)
-				} else {
-					components = append(components, r.component)
-					manyData = append(manyData, r.data)
-					manyPolicyInput = append(manyPolicyInput, r.policyInput)
+				jobs := make(chan app.SnapshotComponent, numComponents)
+				results := make(chan result, numComponents)
+				// Initialize each worker. They will wait patiently until a job is sent to the jobs
+				// channel, or the jobs channel is closed.
+				for i := 0; i <= numWorkers; i++ {
+					go worker(i, jobs, results)
 				}
-			}
-			close(results)
-			if allErrors != nil {
-				return allErrors
+				// Initialize all the jobs. Each worker will pick a job from the channel when the worker
+				// is ready to consume a new job.
+				for _, c := range appComponents {
+					jobs <- c
+				}
+				close(jobs)
+
+				var allErrors error = nil
+				for i := 0; i < numComponents; i++ {
+					r := <-results
+					if r.err != nil {
+						err := fmt.Errorf("error validating image %s of component %s: %w", r.component.ContainerImage, r.component.Name, r.err)
+						allErrors = errors.Join(allErrors, err)
+					} else {
+						e.components = append(e.components, r.component)
+						e.data = append(e.data, r.data)
+						e.policyInput = append(e.policyInput, r.policyInput)
+					}
+				}
+				close(results)
+				if allErrors != nil {
+					return nil, allErrors
+				}
+
+				// Ensure some consistency in output.
+				sort.Slice(e.components, func(i, j int) bool {
+					return e.components[i].ContainerImage > e.components[j].ContainerImage
+				})
+
+				return &e, nil
 			}
 
-			// Ensure some consistency in output.
-			sort.Slice(components, func(i, j int) bool {
-				return components[i].ContainerImage > components[j].ContainerImage
-			})
+			e, err := evaluate(cmd.Context(), data.policy)
+			if err != nil {
+				return err
+			}
 
 			if len(data.outputFile) > 0 {
 				data.output = append(data.output, fmt.Sprintf("%s=%s", applicationsnapshot.JSON, data.outputFile))
 			}
 
-			report, err := applicationsnapshot.NewReport(data.snapshot, components, data.policy, manyData, manyPolicyInput, showSuccesses)
+			report, err := applicationsnapshot.NewReport(data.snapshot, e.components, data.policy, e.data, e.policyInput, showSuccesses)
 			if err != nil {
 				return err
 			}
+			report.PolicySources = pinnedPolicySources(e.policySources)
+
+			if data.baselinePolicy != nil {
+				// The coverage and the profile are of the policy being
+				// validated against, not of the baseline policy.
+				b, err := evaluate(coverage.WithCollector(cmd.Context(), nil), data.baselinePolicy)
+				if err != nil {
+					return fmt.Errorf("unable to validate against the baseline policy: %w", err)
+				}
+
+				baseline, err := applicationsnapshot.NewReport(data.snapshot, b.components, data.baselinePolicy, b.data, b.policyInput, showSuccesses)
+				if err != nil {
+					return err
+				}
+				report.Baseline = &baseline
+
+				// the diff is included in the JSON and YAML formats, it is
+				// written to stdout only if nothing else is
+				if !slices.ContainsFunc(data.output, isDiffOutput) && !slices.ContainsFunc(data.output, isStdoutOutput) {
+					data.output = append(data.output, applicationsnapshot.Diff)
+				}
+			}
+
+			if data.vsa.Enabled() {
+				if err := signVSA(cmd.Context(), &report, data.vsa, data.vsaAttach); err != nil {
+					return err
+				}
+			}
+
 			p := format.NewTargetParser(applicationsnapshot.JSON, format.Options{ShowSuccesses: showSuccesses}, cmd.OutOrStdout(), utils.FS(cmd.Context()))
 			utils.SetColorEnabled(data.noColor, data.forceColor)
 			if err := report.WriteAll(data.output, p); err != nil {
 				return err
 			}
 
+			if err := writeCoverage(cmd, data.coverage, data.profile); err != nil {
+				return err
+			}
+
 			if data.strict && !report.Success {
 				return errors.New("success criteria not met")
 			}
@@ -453,7 +749,16 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		  * git reference (github.com/user/repo//default?ref=main), or
 		  * inline JSON ('{sources: {...}, identity: {...}}')")`))
 
-	cmd.Flags().StringVarP(&data.imageRef, "image", "i", data.imageRef, "OCI image reference")
+	cmd.Flags().StringVar(&data.baselinePolicyConfiguration, "baseline-policy", data.baselinePolicyConfiguration, hd.Doc(`
+		Baseline policy configuration, in the same forms as --policy. The images are
+		validated against both policies and the rules newly failing, newly passing
+		or with changed severity compared to the baseline policy are reported per
+		component in the "diff" output format, and included in the "json" and "yaml"
+		output formats. Unless the "diff" format is requested with --output, or
+		another output is written to stdout, the differences are written to stdout.`))
+
+	cmd.Flags().StringVarP(&data.imageRef, "image", "i", data.imageRef,
+		"OCI image reference, or oci:<path>[@<digest>|:<tag>] for an OCI layout directory, or docker-archive:<path>[:<tag>] for an archive created by docker save")
 
 	cmd.Flags().StringVarP(&data.publicKey, "public-key", "k", data.publicKey,
 		"path to the public key. Overrides publicKey from EnterpriseContractPolicy")
@@ -501,6 +806,11 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 	cmd.Flags().BoolVarP(&data.strict, "strict", "s", data.strict,
 		"Return non-zero status on non-successful validation. Defaults to true. Use --strict=false to return a zero status code.")
 
+	cmd.Flags().BoolVar(&data.strictInput, "strict-input", data.strictInput, hd.Doc(`
+		Validate the policy input of each image against the policy input JSON schema, see
+		"ec inspect input-schema", before evaluating the policies. An image with an input
+		not matching the schema fails validation.`))
+
 	cmd.Flags().StringVar(&data.effectiveTime, "effective-time", policy.Now, hd.Doc(`
 		Run policy checks with the provided time. Useful for testing rules with
 		effective dates in the future. The value can be "now" (default) - for
@@ -521,6 +831,14 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		violations, include the title and the description of the failed policy
 		rule.`))
 
+	cmd.Flags().BoolVar(&data.explain, "explain", data.explain, hd.Doc(`
+		Explain the violations and warnings. For each, include the input and data
+		values evaluated by the policy rule that produced it and the rule's
+		evaluation trace. The text output shows the values, the JSON and YAML
+		output include the complete explanation.`))
+
+	addCoverageFlags(cmd, &data.coverage, &data.profile)
+
 	cmd.Flags().BoolVar(&data.noColor, "no-color", data.info, hd.Doc(`
 		Disable color when using text output even when the current terminal supports it`))
 
@@ -528,7 +846,53 @@ func validateImageCmd(validate imageValidationFunc) *cobra.Command {
 		Enable color when using text output even when the current terminal does not support it`))
 
 	cmd.Flags().IntVar(&data.workers, "workers", data.workers, hd.Doc(`
-		Number of workers to use for validation. Defaults to 5.`))
+		Number of workers to use for validation. Defaults to 5. The workers are
+		shared by the components and the policy sources evaluated for each component.
+		Regardless of the number of workers, at most 10 concurrent requests are sent to
+		each registry, this can be changed via the EC_REGISTRY_CONCURRENCY environment
+		variable.`))
+
+	cmd.Flags().StringVar(&data.evaluator, "evaluator", data.evaluator, hd.Doc(`
+		Policy evaluator to use, one of "conftest" or "opa". The "opa" evaluator
+		compiles the policies only once and evaluates the policy input in-memory,
+		which is faster when validating many images. The "opa" evaluator is always
+		used with the --coverage and --profile flags.`))
+
+	cmd.Flags().StringVar(&data.offlineBundle, "offline-bundle", data.offlineBundle, hd.Doc(`
+		Path to an offline bundle created by the "ec bundle export" command. All
+		images, signatures, attestations, policy and data sources, Rekor entries and
+		the TUF root are read from the bundle, the network is not accessed. Validation
+		fails if anything needed is missing from the bundle. By default the policy
+		and the images the bundle was exported with are used.`))
+
+	cmd.Flags().StringVar(&data.vsa.Key, "vsa-signing-key", data.vsa.Key, hd.Doc(`
+		Reference to the private key used to sign the Verification Summary Attestation
+		(VSA): a file path, KMS URI or Kubernetes secret (k8s://<namespace>/<name>). The
+		password of the key is read from the COSIGN_PASSWORD environment variable. When
+		signing is enabled the "vsa" output format outputs the signed DSSE envelope.`))
+
+	cmd.Flags().BoolVar(&data.vsa.Keyless, "vsa-keyless", data.vsa.Keyless, hd.Doc(`
+		Sign the VSA keyless, using a short-lived certificate issued by Fulcio.`))
+
+	cmd.Flags().StringVar(&data.vsa.IdentityToken, "vsa-identity-token", data.vsa.IdentityToken, hd.Doc(`
+		OIDC identity token, or path to a file containing it, presented to Fulcio
+		when signing the VSA keyless.`))
+
+	cmd.Flags().StringVar(&data.vsa.FulcioURL, "vsa-fulcio-url", vsa.DefaultFulcioURL,
+		"Fulcio URL used when signing the VSA keyless")
+
+	cmd.Flags().StringVar(&data.vsa.OIDCIssuer, "vsa-oidc-issuer", vsa.DefaultOIDCIssuer,
+		"OIDC issuer URL used when signing the VSA keyless")
+
+	cmd.Flags().BoolVar(&data.vsaAttach, "vsa-attach", data.vsaAttach, hd.Doc(`
+		Attach the signed VSA to each of the validated images as a cosign attestation,
+		replacing any previously attached VSA.`))
+
+	cmd.Flags().BoolVar(&data.vsaUpload, "vsa-upload", data.vsaUpload,
+		"Upload the signed VSA to the Rekor transparency log")
+
+	cmd.Flags().StringVar(&data.vsaRekorURL, "vsa-rekor-url", data.vsaRekorURL,
+		"Rekor URL the signed VSA is uploaded to")
 
 	if len(data.input) > 0 || len(data.filePath) > 0 || len(data.images) > 0 {
 		if err := cmd.MarkFlagRequired("image"); err != nil {
@@ -24,11 +24,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path"
 	"strings"
 	"testing"
 	"time"
 
 	hd "github.com/MakeNowJust/heredoc"
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	ociMetadata "github.com/enterprise-contract/go-gather/metadata/oci"
 	"github.com/gkampitakis/go-snaps/snaps"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
@@ -38,15 +42,19 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
 
 	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
 	"github.com/enterprise-contract/ec-cli/internal/output"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
 )
 
 type data struct {
@@ -877,6 +885,31 @@ func Test_ValidateErrorCommand(t *testing.T) {
 			expected: `unable to parse Snapshot specification from {"invalid": "json""}: error converting YAML to JSON: yaml: found unexpected end of stream
 unable to parse EnterpriseContractPolicySpec: error converting YAML to JSON: yaml: found unexpected end of stream`,
 		},
+		{
+			name: "unsupported evaluator",
+			args: []string{
+				"--image",
+				"registry/image:tag",
+				"--policy",
+				fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+				"--evaluator",
+				"unknown",
+			},
+			expected: `unsupported evaluator "unknown", use one of: conftest, opa`,
+		},
+		{
+			name: "coverage with the conftest evaluator",
+			args: []string{
+				"--image",
+				"registry/image:tag",
+				"--policy",
+				fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+				"--evaluator",
+				"conftest",
+				"--coverage",
+			},
+			expected: `--coverage and --profile require the opa evaluator`,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -1301,3 +1334,324 @@ func TestValidateImageDefaultOutput(t *testing.T) {
 		assert.Equal(t, c.expected, out.String())
 	}
 }
+
+func Test_ValidateImageCommandOfflineBundle(t *testing.T) {
+	tufRoot := t.TempDir()
+	t.Setenv("TUF_ROOT", tufRoot)
+	defaultTransport := http.DefaultTransport
+
+	recorder, err := offline.NewRecorder()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = recorder.Close() })
+
+	bundle := path.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, recorder.Write(bundle, offline.Manifest{
+		Policy: fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		Snapshot: &app.SnapshotSpec{
+			Components: []app.SnapshotComponent{{Name: "bacon", ContainerImage: "registry.localhost/bacon:v2.0"}},
+		},
+	}))
+
+	validator := happyValidator()
+	validateImageCmd := validateImageCmd(func(ctx context.Context, component app.SnapshotComponent, spec *app.SnapshotSpec, p policy.Policy, evaluators []evaluator.Evaluator, detailed bool) (*output.Output, error) {
+		assert.NotNil(t, offline.BundleFromContext(ctx))
+		return validator(ctx, component, spec, p, evaluators, detailed)
+	})
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = oci.WithClient(ctx, &client)
+	cmd.SetContext(ctx)
+
+	effectiveTimeTest := time.Now().UTC().Format(time.RFC3339Nano)
+
+	cmd.SetArgs(append(rootArgs, []string{
+		"--offline-bundle",
+		bundle,
+		"--effective-time",
+		effectiveTimeTest,
+	}...))
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	err = cmd.Execute()
+	assert.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{
+		"success": true,
+		"ec-version": "development",
+		"effective-time": %q,
+		"key": %s,
+		"components": [
+		  {
+			"name": "bacon",
+			"containerImage": "registry.localhost/bacon:v2.0",
+			"source": {},
+			"success": true
+		  }
+		],
+		"policy": {
+			"publicKey": %s
+		}
+	  }`, effectiveTimeTest, utils.TestPublicKeyJSON, utils.TestPublicKeyJSON), out.String())
+
+	// the bundle is used through the context, the global state is unchanged
+	assert.Same(t, defaultTransport, http.DefaultTransport)
+	assert.Equal(t, tufRoot, os.Getenv("TUF_ROOT"))
+}
+
+func Test_ValidateImageCommandSignedVSA(t *testing.T) {
+	t.Setenv("COSIGN_PASSWORD", "s3cr3t")
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("s3cr3t"), nil })
+	require.NoError(t, err)
+	keyPath := path.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(keyPath, keys.PrivateBytes, 0600))
+
+	var attached []string
+	attach := attachVSA
+	t.Cleanup(func() { attachVSA = attach })
+	attachVSA = func(_ context.Context, image string, signed *vsa.Signed) error {
+		assert.Equal(t, applicationsnapshot.PredicateVSAProvenance, signed.PredicateType)
+		attached = append(attached, image)
+		return nil
+	}
+
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+	ctx = oci.WithClient(ctx, &client)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs([]string{
+		"validate",
+		"image",
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--output",
+		"vsa=/vsa.json",
+		"--vsa-signing-key",
+		keyPath,
+		"--vsa-attach",
+	})
+
+	utils.SetTestRekorPublicKey(t)
+
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, []string{"registry/image:tag"}, attached)
+
+	envelope, err := afero.ReadFile(fs, "/vsa.json")
+	require.NoError(t, err)
+
+	var dsse struct {
+		PayloadType string `json:"payloadType"`
+		Payload     []byte `json:"payload"`
+		Signatures  []any  `json:"signatures"`
+	}
+	require.NoError(t, json.Unmarshal(envelope, &dsse))
+	assert.Equal(t, "application/vnd.in-toto+json", dsse.PayloadType)
+	assert.Len(t, dsse.Signatures, 1)
+
+	var statement applicationsnapshot.ProvenanceStatementVSA
+	require.NoError(t, json.Unmarshal(dsse.Payload, &statement))
+	assert.Equal(t, applicationsnapshot.PredicateVSAProvenance, statement.PredicateType)
+	assert.True(t, statement.Predicate.Success)
+}
+
+func Test_ValidateImageCommandVSAAttachWithoutSigning(t *testing.T) {
+	validateImageCmd := validateImageCmd(happyValidator())
+	cmd := setUpCobra(validateImageCmd)
+	cmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+
+	cmd.SetArgs([]string{
+		"validate",
+		"image",
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--vsa-attach",
+	})
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	assert.ErrorContains(t, err, "--vsa-attach and --vsa-upload require either --vsa-signing-key or --vsa-keyless")
+}
+
+// baselineValidator reports the same rule as a violation with the "strict"
+// policy and as a warning otherwise.
+func baselineValidator() imageValidationFunc {
+	return func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, p policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		result := evaluator.Result{
+			Message:  "Not nice",
+			Metadata: map[string]interface{}{"code": "policy.nice"},
+		}
+		outcome := evaluator.Outcome{FileName: "test.json", Namespace: "test.main"}
+		if p.Spec().Description == "strict" {
+			outcome.Failures = []evaluator.Result{result}
+		} else {
+			outcome.Warnings = []evaluator.Result{result}
+		}
+
+		return &output.Output{
+			ImageSignatureCheck:       output.VerificationStatus{Passed: true},
+			ImageAccessibleCheck:      output.VerificationStatus{Passed: true},
+			AttestationSignatureCheck: output.VerificationStatus{Passed: true},
+			AttestationSyntaxCheck:    output.VerificationStatus{Passed: true},
+			PolicyCheck:               []evaluator.Outcome{outcome},
+			ImageURL:                  component.ContainerImage,
+		}, nil
+	}
+}
+
+func runBaselinePolicy(t *testing.T, output ...string) (string, afero.Fs) {
+	validateImageCmd := validateImageCmd(baselineValidator())
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+	ctx = oci.WithClient(ctx, &client)
+	cmd.SetContext(ctx)
+
+	args := []string{
+		"validate",
+		"image",
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s, "description": "strict"}`, utils.TestPublicKeyJSON),
+		"--baseline-policy",
+		fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON),
+		"--strict=false",
+	}
+	for _, o := range output {
+		args = append(args, "--output", o)
+	}
+	cmd.SetArgs(args)
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+
+	return out.String(), fs
+}
+
+const baselineDiffText = `Component: Unnamed
+ImageRef: registry/image:tag
+  Changed severity:
+    policy.nice (warning -> violation)
+      Not nice
+
+Changes: 0 newly failing, 0 newly passing, 1 changed severity, 0 removed
+`
+
+func Test_ValidateImageCommandBaselinePolicy(t *testing.T) {
+	out, _ := runBaselinePolicy(t, "diff")
+	assert.Equal(t, baselineDiffText, out)
+}
+
+func Test_ValidateImageCommandBaselinePolicyReportFile(t *testing.T) {
+	for _, f := range []string{"json", "yaml"} {
+		t.Run(f, func(t *testing.T) {
+			out, fs := runBaselinePolicy(t, f+"=report."+f)
+			assert.Equal(t, baselineDiffText, out)
+
+			data, err := afero.ReadFile(fs, "report."+f)
+			require.NoError(t, err)
+
+			var report applicationsnapshot.Report
+			require.NoError(t, yaml.Unmarshal(data, &report))
+			require.NotNil(t, report.Diff)
+			assert.Equal(t, 1, report.Diff.SeverityChanged)
+		})
+	}
+}
+
+func Test_ValidateImageCommandBaselinePolicyStdout(t *testing.T) {
+	out, _ := runBaselinePolicy(t, "json")
+
+	var report applicationsnapshot.Report
+	require.NoError(t, json.Unmarshal([]byte(out), &report))
+	require.NotNil(t, report.Diff)
+	assert.Equal(t, 1, report.Diff.SeverityChanged)
+}
+
+func Test_ValidateImageCommandCoverageEvaluator(t *testing.T) {
+	used := ""
+	fakeEvaluator := func(name string) func(context.Context, []source.PolicySource, evaluator.ConfigProvider, ecc.Source) (evaluator.Evaluator, error) {
+		return func(context.Context, []source.PolicySource, evaluator.ConfigProvider, ecc.Source) (evaluator.Evaluator, error) {
+			used = name
+			e := mockEvaluator{}
+			e.On("Destroy")
+			return &e, nil
+		}
+	}
+	newConftestEvaluator = fakeEvaluator("conftest")
+	newOPAEvaluator = fakeEvaluator("opa")
+	t.Cleanup(func() {
+		newConftestEvaluator = evaluator.NewConftestEvaluator
+		newOPAEvaluator = evaluator.NewOPAEvaluator
+	})
+
+	validate := func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		return &output.Output{
+			ImageSignatureCheck:       output.VerificationStatus{Passed: true},
+			ImageAccessibleCheck:      output.VerificationStatus{Passed: true},
+			AttestationSignatureCheck: output.VerificationStatus{Passed: true},
+			AttestationSyntaxCheck:    output.VerificationStatus{Passed: true},
+			ImageURL:                  component.ContainerImage,
+		}, nil
+	}
+
+	validateImageCmd := validateImageCmd(validate)
+	cmd := setUpCobra(validateImageCmd)
+
+	client := fake.FakeClient{}
+	commonMockClient(&client)
+	fs := afero.NewMemMapFs()
+	ctx := utils.WithFS(context.Background(), fs)
+	ctx = oci.WithClient(ctx, &client)
+	mdl := MockDownloader{}
+	mdl.On("Download", mock.Anything, mock.Anything, false).Return(&ociMetadata.OCIMetadata{Digest: "sha256:da54bca5477bf4e3449bc37de1822888fa0fbb8d89c640218cb31b987374d357"}, nil)
+	ctx = context.WithValue(ctx, source.DownloaderFuncKey, &mdl)
+	cmd.SetContext(ctx)
+
+	cmd.SetArgs([]string{
+		"validate",
+		"image",
+		"--image",
+		"registry/image:tag",
+		"--policy",
+		fmt.Sprintf(`{"publicKey": %s, "sources": [{"policy": ["policy"]}]}`, utils.TestPublicKeyJSON),
+		"--coverage=json=coverage.json",
+	})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	utils.SetTestRekorPublicKey(t)
+
+	err := cmd.Execute()
+	require.NoError(t, err)
+	assert.Equal(t, "opa", used)
+
+	exists, err := afero.Exists(fs, "coverage.json")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -42,13 +42,16 @@ type InputValidationFunc func(context.Context, string, policy.Policy, bool) (*ou
 
 func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 	data := struct {
+		coverage            string
 		effectiveTime       string
+		explain             bool
 		filePaths           []string
 		info                bool
 		namespaces          []string
 		output              []string
 		policy              policy.Policy
 		policyConfiguration string
+		profile             string
 		strict              bool
 	}{
 		strict: true,
@@ -88,6 +91,16 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, args []string) (allErrors error) {
 			ctx := cmd.Context()
 
+			if data.explain {
+				ctx = evaluator.WithExplain(ctx)
+				cmd.SetContext(ctx)
+			}
+
+			if err := enableCoverage(cmd, data.coverage, data.profile); err != nil {
+				return err
+			}
+			ctx = cmd.Context()
+
 			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
 			if err != nil {
 				allErrors = errors.Join(allErrors, err)
@@ -199,6 +212,10 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 				return err
 			}
 
+			if err := writeCoverage(cmd, data.coverage, data.profile); err != nil {
+				return err
+			}
+
 			if data.strict && !report.Success {
 				return errors.New("success criteria not met")
 			}
@@ -237,6 +254,13 @@ func validateInputCmd(validate InputValidationFunc) *cobra.Command {
 		violations, include the title and the description of the failed policy
 		rule.`))
 
+	cmd.Flags().BoolVar(&data.explain, "explain", data.explain, hd.Doc(`
+		Explain the violations and warnings. For each, include the input and data
+		values evaluated by the policy rule that produced it and the rule's
+		evaluation trace.`))
+
+	addCoverageFlags(cmd, &data.coverage, &data.profile)
+
 	if err := cmd.MarkFlagRequired("file"); err != nil {
 		panic(err)
 	}
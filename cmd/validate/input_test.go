@@ -17,3 +17,80 @@
 //go:build unit
 
 package validate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/input"
+)
+
+func Test_ValidateInputCommandImageScopedCriteria(t *testing.T) {
+	dir := t.TempDir()
+	policyDir := path.Join(dir, "policy")
+	require.NoError(t, os.MkdirAll(policyDir, 0755))
+	require.NoError(t, os.WriteFile(path.Join(policyDir, "main.rego"), []byte(`package main
+
+import rego.v1
+
+# METADATA
+# title: Spam
+# custom:
+#   short_name: spam
+deny contains result if {
+	input.spam
+	result := {"code": "main.spam", "msg": "No spam"}
+}
+
+# METADATA
+# title: Ham
+# custom:
+#   short_name: ham
+deny contains result if {
+	input.ham
+	result := {"code": "main.ham", "msg": "No ham"}
+}
+`), 0600))
+
+	file := path.Join(dir, "input.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"spam": true, "ham": true}`), 0600))
+
+	// the image scoped exclusion does not apply to arbitrary input
+	p := fmt.Sprintf(`{
+		"sources": [{
+			"policy": [%q],
+			"volatileConfig": {
+				"exclude": [
+					{"value": "main.spam", "imageRef": "quay.io/org/*"},
+					{"value": "main.ham"}
+				]
+			}
+		}]
+	}`, policyDir)
+
+	cmd := setUpCobra(validateInputCmd(input.ValidateInput))
+	cmd.SetContext(context.Background())
+	cmd.SetArgs([]string{"validate", "input", "--file", file, "--policy", p, "--output", "json", "--strict=false"})
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+
+	require.NoError(t, cmd.Execute())
+
+	var report input.Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.FilePaths, 1)
+	assert.False(t, report.Success)
+
+	violations := report.FilePaths[0].Violations
+	require.Len(t, violations, 1)
+	assert.Equal(t, "No spam", violations[0].Message)
+}
@@ -0,0 +1,183 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
+)
+
+type vsaVerificationFunc func(context.Context, string, policy.Policy, vsa.VerifyOptions) (*vsa.Verified, error)
+
+func verifyVSACmd(verify vsaVerificationFunc) *cobra.Command {
+	data := struct {
+		certificateIdentity         string
+		certificateIdentityRegExp   string
+		certificateOIDCIssuer       string
+		certificateOIDCIssuerRegExp string
+		ignoreRekor                 bool
+		images                      []string
+		options                     vsa.VerifyOptions
+		policy                      policy.Policy
+		policyConfiguration         string
+		publicKey                   string
+		rekorURL                    string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "vsa",
+		Short: "Verify the Verification Summary Attestation (VSA) attached to images",
+
+		Long: hd.Doc(`
+			Verify the Verification Summary Attestation (VSA) attached to images
+
+			Fetches the VSAs attached to each image, as produced by "ec validate image"
+			with the --vsa-attach flag, and verifies their signatures using either the
+			provided public key or the keyless identity. The VSA is then checked: it must
+			record a successful validation, and if configured, the digests of the policy
+			sources used in the validation must match the expected digests and the VSA
+			must not be older than the maximum age.
+
+			The command fails if any of the images does not have a VSA that passes the
+			verification. This allows skipping the full validation of the images when a
+			trusted VSA is present.
+		`),
+
+		Example: hd.Doc(`
+			Verify that the image has a VSA signed with the key, produced within the last
+			day by a validation using the policy source with the given digest:
+
+			  ec verify vsa --image registry/name@sha256:... --public-key vsa.pub \
+			    --max-age 24h --policy-source-digest sha256:...
+
+			Verify the keyless signed VSA of an image:
+
+			  ec verify vsa --image registry/name:tag \
+			    --certificate-identity 'https://github.com/user/repo/.github/workflows/ec.yaml@refs/heads/main' \
+			    --certificate-oidc-issuer 'https://token.actions.githubusercontent.com'
+		`),
+
+		Args: cobra.NoArgs,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, data.policyConfiguration)
+			if err != nil {
+				return err
+			}
+
+			p, err := policy.NewPolicy(ctx, policy.Options{
+				EffectiveTime: policy.Now,
+				Identity: cosign.Identity{
+					Issuer:        data.certificateOIDCIssuer,
+					IssuerRegExp:  data.certificateOIDCIssuerRegExp,
+					Subject:       data.certificateIdentity,
+					SubjectRegExp: data.certificateIdentityRegExp,
+				},
+				IgnoreRekor: data.ignoreRekor,
+				PolicyRef:   policyConfiguration,
+				PublicKey:   data.publicKey,
+				RekorURL:    data.rekorURL,
+			})
+			if err != nil {
+				return err
+			}
+			data.policy = p
+
+			return nil
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var allErrors error
+			verified := make([]*vsa.Verified, 0, len(data.images))
+			for _, image := range data.images {
+				v, err := verify(cmd.Context(), image, data.policy, data.options)
+				if err != nil {
+					allErrors = errors.Join(allErrors, err)
+					continue
+				}
+				verified = append(verified, v)
+			}
+
+			out, err := json.Marshal(verified)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(out))
+
+			return allErrors
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&data.images, "image", "i", data.images,
+		"OCI image reference, may be used multiple times")
+
+	cmd.Flags().StringVarP(&data.policyConfiguration, "policy", "p", data.policyConfiguration, hd.Doc(`
+		Policy configuration providing the public key or the identity the VSA is
+		verified with, as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}, identity: {...}}')")`))
+
+	cmd.Flags().StringVarP(&data.publicKey, "public-key", "k", data.publicKey,
+		"path to the public key the VSA is verified with. Overrides publicKey from EnterpriseContractPolicy")
+
+	cmd.Flags().StringVarP(&data.rekorURL, "rekor-url", "r", data.rekorURL,
+		"Rekor URL. Overrides rekorURL from EnterpriseContractPolicy")
+
+	cmd.Flags().BoolVar(&data.ignoreRekor, "ignore-rekor", data.ignoreRekor,
+		"Skip Rekor transparency log checks during verification.")
+
+	cmd.Flags().StringVar(&data.certificateIdentity, "certificate-identity", data.certificateIdentity,
+		"URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateIdentityRegExp, "certificate-identity-regexp", data.certificateIdentityRegExp,
+		"Regular expression for the URL of the certificate identity for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuer, "certificate-oidc-issuer", data.certificateOIDCIssuer,
+		"URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringVar(&data.certificateOIDCIssuerRegExp, "certificate-oidc-issuer-regexp", data.certificateOIDCIssuerRegExp,
+		"Regular expresssion for the URL of the certificate OIDC issuer for keyless verification")
+
+	cmd.Flags().StringSliceVar(&data.options.PolicySourceDigests, "policy-source-digest", data.options.PolicySourceDigests, hd.Doc(`
+		Expected digest of a policy or data source used in the validation, the image
+		digest for OCI sources and the commit for git sources. May be used multiple
+		times, the digests of the sources recorded in the VSA must match the provided
+		set.`))
+
+	cmd.Flags().DurationVar(&data.options.MaxAge, "max-age", data.options.MaxAge, hd.Doc(`
+		Maximum age of the VSA, for example 24h. By default the age is not checked.`))
+
+	if err := cmd.MarkFlagRequired("image"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
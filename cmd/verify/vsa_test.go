@@ -0,0 +1,91 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/enterprise-contract/ec-cli/cmd/root"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/vsa"
+)
+
+func run(verify vsaVerificationFunc, args ...string) (string, error) {
+	verifyCmd := NewVerifyCmd()
+	verifyCmd.AddCommand(verifyVSACmd(verify))
+
+	rootCmd := root.NewRootCmd()
+	rootCmd.AddCommand(verifyCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetContext(utils.WithFS(context.Background(), afero.NewMemMapFs()))
+	rootCmd.SetArgs(append([]string{"verify", "vsa"}, args...))
+
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestVerifyVSA(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+
+	var options vsa.VerifyOptions
+	verify := func(_ context.Context, image string, p policy.Policy, opts vsa.VerifyOptions) (*vsa.Verified, error) {
+		options = opts
+		key, err := p.PublicKeyPEM()
+		assert.NoError(t, err)
+		assert.Equal(t, utils.TestPublicKey, string(key))
+
+		if image == "registry/bad:tag" {
+			return nil, fmt.Errorf("no signed VSA found for image %s", image)
+		}
+
+		return &vsa.Verified{Image: image, Success: true}, nil
+	}
+
+	out, err := run(verify,
+		"--image", "registry/image:tag",
+		"--public-key", utils.TestPublicKey,
+		"--policy-source-digest", "sha256:abc",
+		"--policy-source-digest", "f00",
+		"--max-age", "24h")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"image": "registry/image:tag", "success": true}]`, out)
+	assert.Equal(t, vsa.VerifyOptions{PolicySourceDigests: []string{"sha256:abc", "f00"}, MaxAge: 24 * time.Hour}, options)
+
+	out, err = run(verify,
+		"--image", "registry/image:tag",
+		"--image", "registry/bad:tag",
+		"--public-key", utils.TestPublicKey)
+	assert.EqualError(t, err, "no signed VSA found for image registry/bad:tag")
+	assert.JSONEq(t, `[{"image": "registry/image:tag", "success": true}]`, out)
+}
+
+func TestVerifyVSAIdentityRequired(t *testing.T) {
+	_, err := run(nil, "--image", "registry/image:tag")
+	assert.ErrorContains(t, err, "certificate OIDC issuer must be provided for keyless workflow")
+}
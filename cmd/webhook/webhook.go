@@ -0,0 +1,189 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	_ "github.com/enterprise-contract/ec-cli/internal/rego"
+	"github.com/enterprise-contract/ec-cli/internal/server"
+)
+
+var WebhookCmd *cobra.Command
+
+func init() {
+	WebhookCmd = NewWebhookCmd()
+}
+
+func NewWebhookCmd() *cobra.Command {
+	opts := server.WebhookOptions{
+		Options: server.Options{
+			Address:        ":8443",
+			PolicyTTL:      10 * time.Minute,
+			RequestTimeout: 25 * time.Second,
+			Workers:        5,
+		},
+		PolicyLabel: server.DefaultPolicyLabel,
+		VerdictTTL:  10 * time.Minute,
+	}
+	evaluatorName := "conftest"
+
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Run a Kubernetes validating admission webhook for container images",
+
+		Long: hd.Doc(`
+			Run a Kubernetes validating admission webhook for container images
+
+			The webhook validates the container images of Pods and of the workloads
+			creating Pods: Deployments, ReplicaSets, StatefulSets, DaemonSets, Jobs,
+			CronJobs and ReplicationControllers. The images are validated the same way
+			the "ec validate image" command does, and workloads with images violating
+			the policy are denied admission. The condensed violations are included in
+			the response.
+
+			The policy is the EnterpriseContractPolicy, from the namespace of the
+			workload, named by the policy label of the namespace. The default policy,
+			if provided, is used for namespaces without the label. Workloads in
+			namespaces with neither are denied admission, unless the --fail-open flag
+			is set in which case they are admitted without validation. Alternatively,
+			limit the namespaces sent to the webhook using the namespaceSelector of the
+			ValidatingWebhookConfiguration.
+
+			Verdicts on images are cached by the image digest for the duration set by
+			the --verdict-ttl flag, so that e.g. the Pods created by a Deployment are
+			not validated again.
+
+			With --dry-run all workloads are admitted, violations are returned as
+			warnings instead which allows auditing the workloads before enforcing the
+			policy.
+
+			The following endpoints are available:
+
+			  POST /v1/admission - handles the AdmissionReview requests, this is the
+			  path to set in the ValidatingWebhookConfiguration
+
+			  GET /healthz - responds with 200 OK while the service is running
+
+			  GET /readyz - responds with 200 OK once the default policy, if configured,
+			  has been prepared
+
+			The Kubernetes API server requires webhooks to be served over TLS, provide
+			the certificate and key with the --tls-cert-file and --tls-key-file flags.
+			The service account running the webhook needs permission to get namespaces
+			and enterprisecontractpolicies.
+		`),
+
+		Example: hd.Doc(`
+			Run the webhook enforcing the policy named by the namespace label:
+
+			  ec webhook --tls-cert-file tls.crt --tls-key-file tls.key
+
+			Run the webhook in audit mode with a default policy:
+
+			  ec webhook --tls-cert-file tls.crt --tls-key-file tls.key --dry-run \
+			    --policy policies/default
+		`),
+
+		Args: cobra.NoArgs,
+
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			if evaluatorName != "conftest" && evaluatorName != "opa" {
+				return fmt.Errorf("unsupported evaluator %q, use one of: conftest, opa", evaluatorName)
+			}
+			if (opts.CertFile == "") != (opts.KeyFile == "") {
+				return fmt.Errorf("both --tls-cert-file and --tls-key-file need to be provided")
+			}
+			return nil
+		},
+
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// Same as for "ec server", the global timeout doesn't apply to
+			// long running services.
+			ctx := context.WithoutCancel(cmd.Context())
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			newEvaluator := evaluator.NewConftestEvaluator
+			if evaluatorName == "opa" {
+				newEvaluator = evaluator.NewOPAEvaluator
+			}
+
+			w, err := server.NewWebhook(ctx, opts, image.ValidateImage, newEvaluator)
+			if err != nil {
+				return err
+			}
+
+			return w.Run(ctx)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Address, "address", opts.Address, "address to listen on")
+
+	cmd.Flags().StringVar(&opts.CertFile, "tls-cert-file", opts.CertFile, "path to the TLS certificate to serve with")
+
+	cmd.Flags().StringVar(&opts.KeyFile, "tls-key-file", opts.KeyFile, "path to the TLS private key to serve with")
+
+	cmd.Flags().StringVarP(&opts.Policy, "policy", "p", opts.Policy, hd.Doc(`
+		Default policy configuration used for namespaces without the policy label, as:
+		  * Kubernetes reference ([<namespace>/]<name>)
+		  * file (policy.yaml)
+		  * git reference (github.com/user/repo//default?ref=main), or
+		  * inline JSON ('{sources: {...}, identity: {...}}')`))
+
+	cmd.Flags().StringVar(&opts.PolicyLabel, "policy-label", opts.PolicyLabel, hd.Doc(`
+		Namespace label holding the name of the EnterpriseContractPolicy, from the
+		same namespace, to validate the workloads against.`))
+
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", opts.DryRun, hd.Doc(`
+		Admit all workloads and return the violations as warnings.`))
+
+	cmd.Flags().BoolVar(&opts.FailOpen, "fail-open", opts.FailOpen, hd.Doc(`
+		Admit, without validation, the workloads in namespaces with neither the
+		policy label nor a default policy. Such workloads are denied otherwise.`))
+
+	cmd.Flags().DurationVar(&opts.VerdictTTL, "verdict-ttl", opts.VerdictTTL, hd.Doc(`
+		Duration for which the verdict on an image digest is reused. Use 0 to
+		validate the images on every admission request.`))
+
+	cmd.Flags().DurationVar(&opts.PolicyTTL, "policy-ttl", opts.PolicyTTL, hd.Doc(`
+		Duration for which a prepared policy is reused. Once expired the policy
+		sources are downloaded again. Use 0 to never expire prepared policies.`))
+
+	cmd.Flags().DurationVar(&opts.RequestTimeout, "request-timeout", opts.RequestTimeout, hd.Doc(`
+		max duration of a single admission request, should not exceed the timeout
+		of the ValidatingWebhookConfiguration`))
+
+	cmd.Flags().IntVar(&opts.Workers, "workers", opts.Workers,
+		"Number of workers to use for validation of the images within a single request.")
+
+	cmd.Flags().StringVar(&evaluatorName, "evaluator", evaluatorName, hd.Doc(`
+		Policy evaluator to use, one of "conftest" or "opa". The "opa" evaluator
+		compiles the policies only once and reuses them for subsequent requests.`))
+
+	return cmd
+}
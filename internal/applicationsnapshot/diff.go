@@ -0,0 +1,302 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package applicationsnapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+// Kinds of rule changes between two reports.
+const (
+	// NewlyFailing rules produce violations which they did not before
+	NewlyFailing = "newly-failing"
+	// NewlyPassing rules no longer produce violations
+	NewlyPassing = "newly-passing"
+	// SeverityChanged rules produce warnings instead of violations or the
+	// other way around, or started or stopped producing warnings
+	SeverityChanged = "severity-changed"
+	// Removed components are present in the baseline report but not in the
+	// current report
+	Removed = "removed"
+)
+
+// Outcomes of a rule within a component.
+const (
+	outcomeViolation = "violation"
+	outcomeWarning   = "warning"
+	outcomeSuccess   = "success"
+)
+
+// RuleChange describes how the outcome of a single rule changed.
+type RuleChange struct {
+	// Rule is the code of the rule, or the message for results without one
+	Rule   string `json:"rule"`
+	Change string `json:"change"`
+	// Baseline and Current are the outcomes of the rule, empty if the rule
+	// wasn't reported
+	Baseline string   `json:"baseline,omitempty"`
+	Current  string   `json:"current,omitempty"`
+	Messages []string `json:"messages,omitempty"`
+}
+
+// ComponentDiff holds the rule changes of a single component.
+type ComponentDiff struct {
+	Name           string `json:"name"`
+	ContainerImage string `json:"containerImage"`
+	// Change is set for changes of the whole component, i.e. when it was
+	// removed from the snapshot
+	Change  string       `json:"change,omitempty"`
+	Changes []RuleChange `json:"changes"`
+}
+
+// ReportDiff holds the differences between two reports of the same snapshot,
+// e.g. validated against two policy versions.
+type ReportDiff struct {
+	Components      []ComponentDiff `json:"components"`
+	NewlyFailing    int             `json:"newly-failing"`
+	NewlyPassing    int             `json:"newly-passing"`
+	SeverityChanged int             `json:"severity-changed"`
+	Removed         int             `json:"removed"`
+}
+
+// DiffReports compares the current report to the baseline report. Components
+// are matched by name, or by image for unnamed components, and rules by their
+// code. Only components with changes are included. Components no longer in
+// the current report are reported as removed, their rules are not compared.
+func DiffReports(baseline, current Report) ReportDiff {
+	baselineOutcomes := map[string]map[string]*ruleOutcome{}
+	for _, c := range baseline.Components {
+		baselineOutcomes[componentKey(c)] = outcomes(c)
+	}
+
+	diff := ReportDiff{Components: []ComponentDiff{}}
+	seen := map[string]bool{}
+	add := func(c Component, before, after map[string]*ruleOutcome) {
+		cd := ComponentDiff{Name: c.Name, ContainerImage: c.ContainerImage}
+		for _, change := range changes(before, after) {
+			switch change.Change {
+			case NewlyFailing:
+				diff.NewlyFailing++
+			case NewlyPassing:
+				diff.NewlyPassing++
+			case SeverityChanged:
+				diff.SeverityChanged++
+			}
+			cd.Changes = append(cd.Changes, change)
+		}
+
+		if len(cd.Changes) > 0 {
+			diff.Components = append(diff.Components, cd)
+		}
+	}
+
+	for _, c := range current.Components {
+		key := componentKey(c)
+		seen[key] = true
+		add(c, baselineOutcomes[key], outcomes(c))
+	}
+
+	for _, c := range baseline.Components {
+		if key := componentKey(c); !seen[key] {
+			seen[key] = true
+			diff.Removed++
+			diff.Components = append(diff.Components, ComponentDiff{
+				Name:           c.Name,
+				ContainerImage: c.ContainerImage,
+				Change:         Removed,
+				Changes:        []RuleChange{},
+			})
+		}
+	}
+
+	return diff
+}
+
+// Text returns the differences in a human readable form.
+func (d ReportDiff) Text() []byte {
+	buf := bytes.Buffer{}
+
+	titles := []struct {
+		change string
+		title  string
+	}{
+		{NewlyFailing, "Newly failing"},
+		{NewlyPassing, "Newly passing"},
+		{SeverityChanged, "Changed severity"},
+	}
+
+	for _, c := range d.Components {
+		fmt.Fprintf(&buf, "Component: %s\n", c.Name)
+		fmt.Fprintf(&buf, "ImageRef: %s\n", c.ContainerImage)
+		if c.Change == Removed {
+			buf.WriteString("  Removed from the snapshot\n")
+		}
+		for _, t := range titles {
+			header := false
+			for _, change := range c.Changes {
+				if change.Change != t.change {
+					continue
+				}
+				if !header {
+					fmt.Fprintf(&buf, "  %s:\n", t.title)
+					header = true
+				}
+				fmt.Fprintf(&buf, "    %s (%s -> %s)\n", change.Rule, outcomeName(change.Baseline), outcomeName(change.Current))
+				for _, msg := range change.Messages {
+					fmt.Fprintf(&buf, "      %s\n", msg)
+				}
+			}
+		}
+		buf.WriteString("\n")
+	}
+
+	fmt.Fprintf(&buf, "Changes: %d newly failing, %d newly passing, %d changed severity, %d removed\n", d.NewlyFailing, d.NewlyPassing, d.SeverityChanged, d.Removed)
+
+	return buf.Bytes()
+}
+
+// Format returns the differences in the given format, text, JSON or YAML.
+func (d ReportDiff) Format(format string) ([]byte, error) {
+	switch format {
+	case Text:
+		return d.Text(), nil
+	case JSON:
+		return json.Marshal(d)
+	case YAML:
+		return yaml.Marshal(d)
+	default:
+		return nil, fmt.Errorf("%q is not a valid diff format", format)
+	}
+}
+
+func outcomeName(outcome string) string {
+	if outcome == "" {
+		return "not reported"
+	}
+	return outcome
+}
+
+type ruleOutcome struct {
+	outcome  string
+	messages []string
+}
+
+func componentKey(c Component) string {
+	if c.Name == "" || c.Name == unnamed {
+		return c.ContainerImage
+	}
+	return c.Name
+}
+
+// outcomes returns the outcome of each rule of the component, by the rule
+// code. The most severe outcome is used for rules with several results.
+func outcomes(c Component) map[string]*ruleOutcome {
+	o := map[string]*ruleOutcome{}
+	record := func(outcome string, results []evaluator.Result) {
+		for _, r := range results {
+			rule := r.Message
+			if code, ok := r.Metadata["code"]; ok {
+				rule = fmt.Sprint(code)
+			}
+
+			existing, ok := o[rule]
+			if !ok {
+				existing = &ruleOutcome{outcome: outcome}
+				o[rule] = existing
+			}
+			if existing.outcome == outcome && outcome != outcomeSuccess {
+				existing.messages = append(existing.messages, r.Message)
+			}
+		}
+	}
+
+	// from the most severe, as the first recorded outcome is kept
+	record(outcomeViolation, c.Violations)
+	record(outcomeWarning, c.Warnings)
+	record(outcomeSuccess, c.Successes)
+
+	return o
+}
+
+// changes returns the rule changes between the outcomes, sorted by rule.
+func changes(before, after map[string]*ruleOutcome) []RuleChange {
+	rules := map[string]bool{}
+	for r := range before {
+		rules[r] = true
+	}
+	for r := range after {
+		rules[r] = true
+	}
+
+	var result []RuleChange
+	for rule := range rules {
+		var b, a string
+		var messages []string
+		if o, ok := before[rule]; ok {
+			b = o.outcome
+		}
+		if o, ok := after[rule]; ok {
+			a = o.outcome
+			messages = o.messages
+		}
+
+		if change := classify(b, a); change != "" {
+			result = append(result, RuleChange{
+				Rule:     rule,
+				Change:   change,
+				Baseline: b,
+				Current:  a,
+				Messages: messages,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Rule < result[j].Rule
+	})
+
+	return result
+}
+
+// classify returns the kind of change between the two outcomes, or an empty
+// string if the change is not significant. Successes are reported only when
+// requested, so a success and a missing outcome are considered the same.
+func classify(before, after string) string {
+	switch {
+	case before == after:
+		return ""
+	case after == outcomeViolation && before == outcomeWarning:
+		return SeverityChanged
+	case after == outcomeViolation:
+		return NewlyFailing
+	case before == outcomeViolation && after == outcomeWarning:
+		return SeverityChanged
+	case before == outcomeViolation:
+		return NewlyPassing
+	case before == outcomeWarning || after == outcomeWarning:
+		return SeverityChanged
+	}
+
+	return ""
+}
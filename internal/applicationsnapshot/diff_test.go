@@ -0,0 +1,197 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package applicationsnapshot
+
+import (
+	"testing"
+
+	hd "github.com/MakeNowJust/heredoc"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+func result(code, msg string) evaluator.Result {
+	return evaluator.Result{Message: msg, Metadata: map[string]any{"code": code}}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		before string
+		after  string
+		change string
+	}{
+		{"", "", ""},
+		{outcomeViolation, outcomeViolation, ""},
+		{outcomeSuccess, "", ""},
+		{"", outcomeSuccess, ""},
+		{"", outcomeViolation, NewlyFailing},
+		{outcomeSuccess, outcomeViolation, NewlyFailing},
+		{outcomeViolation, "", NewlyPassing},
+		{outcomeViolation, outcomeSuccess, NewlyPassing},
+		{outcomeViolation, outcomeWarning, SeverityChanged},
+		{outcomeWarning, outcomeViolation, SeverityChanged},
+		{outcomeWarning, outcomeSuccess, SeverityChanged},
+		{"", outcomeWarning, SeverityChanged},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.change, classify(c.before, c.after), "%q -> %q", c.before, c.after)
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	component := func(name, image string, violations, warnings, successes []evaluator.Result) Component {
+		return Component{
+			SnapshotComponent: app.SnapshotComponent{Name: name, ContainerImage: image},
+			Violations:        violations,
+			Warnings:          warnings,
+			Successes:         successes,
+		}
+	}
+
+	baseline := Report{
+		Components: []Component{
+			component("a", "registry.io/a:1",
+				[]evaluator.Result{result("rule.fixed", "fixed"), result("rule.relaxed", "relaxed")},
+				[]evaluator.Result{result("rule.tightened", "tightened")},
+				nil),
+			component("removed", "registry.io/removed:1",
+				[]evaluator.Result{result("rule.failing", "failing")}, nil, nil),
+			component(unnamed, "registry.io/unnamed:1",
+				nil, nil, []evaluator.Result{result("rule.new", "passing")}),
+		},
+	}
+
+	current := Report{
+		Components: []Component{
+			component("a", "registry.io/a:2",
+				[]evaluator.Result{result("rule.tightened", "tightened"), result("rule.new", "one"), result("rule.new", "two")},
+				[]evaluator.Result{result("rule.relaxed", "relaxed")},
+				[]evaluator.Result{result("rule.fixed", "fixed")}),
+			component("unchanged", "registry.io/unchanged:1",
+				nil, nil, nil),
+			component(unnamed, "registry.io/unnamed:1",
+				[]evaluator.Result{{Message: "no code"}}, nil, nil),
+		},
+	}
+
+	diff := DiffReports(baseline, current)
+
+	assert.Equal(t, ReportDiff{
+		Components: []ComponentDiff{
+			{
+				Name:           "a",
+				ContainerImage: "registry.io/a:2",
+				Changes: []RuleChange{
+					{Rule: "rule.fixed", Change: NewlyPassing, Baseline: outcomeViolation, Current: outcomeSuccess},
+					{Rule: "rule.new", Change: NewlyFailing, Current: outcomeViolation, Messages: []string{"one", "two"}},
+					{Rule: "rule.relaxed", Change: SeverityChanged, Baseline: outcomeViolation, Current: outcomeWarning, Messages: []string{"relaxed"}},
+					{Rule: "rule.tightened", Change: SeverityChanged, Baseline: outcomeWarning, Current: outcomeViolation, Messages: []string{"tightened"}},
+				},
+			},
+			{
+				Name:           unnamed,
+				ContainerImage: "registry.io/unnamed:1",
+				Changes: []RuleChange{
+					{Rule: "no code", Change: NewlyFailing, Current: outcomeViolation, Messages: []string{"no code"}},
+				},
+			},
+			{
+				Name:           "removed",
+				ContainerImage: "registry.io/removed:1",
+				Change:         Removed,
+				Changes:        []RuleChange{},
+			},
+		},
+		NewlyFailing:    2,
+		NewlyPassing:    1,
+		SeverityChanged: 2,
+		Removed:         1,
+	}, diff)
+
+	assert.Equal(t, hd.Doc(`
+		Component: a
+		ImageRef: registry.io/a:2
+		  Newly failing:
+		    rule.new (not reported -> violation)
+		      one
+		      two
+		  Newly passing:
+		    rule.fixed (violation -> success)
+		  Changed severity:
+		    rule.relaxed (violation -> warning)
+		      relaxed
+		    rule.tightened (warning -> violation)
+		      tightened
+
+		Component: Unnamed
+		ImageRef: registry.io/unnamed:1
+		  Newly failing:
+		    no code (not reported -> violation)
+		      no code
+
+		Component: removed
+		ImageRef: registry.io/removed:1
+		  Removed from the snapshot
+
+		Changes: 2 newly failing, 1 newly passing, 2 changed severity, 1 removed
+	`), string(diff.Text()))
+
+	data, err := diff.Format(JSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"components": [
+			{"name": "a", "containerImage": "registry.io/a:2", "changes": [
+				{"rule": "rule.fixed", "change": "newly-passing", "baseline": "violation", "current": "success"},
+				{"rule": "rule.new", "change": "newly-failing", "current": "violation", "messages": ["one", "two"]},
+				{"rule": "rule.relaxed", "change": "severity-changed", "baseline": "violation", "current": "warning", "messages": ["relaxed"]},
+				{"rule": "rule.tightened", "change": "severity-changed", "baseline": "warning", "current": "violation", "messages": ["tightened"]}
+			]},
+			{"name": "Unnamed", "containerImage": "registry.io/unnamed:1", "changes": [
+				{"rule": "no code", "change": "newly-failing", "current": "violation", "messages": ["no code"]}
+			]},
+			{"name": "removed", "containerImage": "registry.io/removed:1", "change": "removed", "changes": []}
+		],
+		"newly-failing": 2,
+		"newly-passing": 1,
+		"severity-changed": 2,
+		"removed": 1
+	}`, string(data))
+
+	yamlData, err := diff.Format(YAML)
+	require.NoError(t, err)
+	assert.YAMLEq(t, string(data), string(yamlData))
+
+	_, err = diff.Format("xml")
+	assert.EqualError(t, err, `"xml" is not a valid diff format`)
+}
+
+func TestDiffFormatRequiresBaseline(t *testing.T) {
+	r := Report{}
+	_, err := r.toFormat(Diff)
+	assert.EqualError(t, err, "the diff format requires a baseline policy")
+
+	r.Baseline = &Report{}
+	data, err := r.toFormat(Diff)
+	require.NoError(t, err)
+	assert.Equal(t, "Changes: 0 newly failing, 0 newly passing, 0 changed severity, 0 removed\n", string(data))
+}
@@ -164,6 +164,10 @@ func DetermineInputSpec(ctx context.Context, input Input) (*app.SnapshotSpec, er
 		log.Debug("No application snapshot available")
 		return nil, errors.New("neither Snapshot nor image reference provided to validate")
 	}
+
+	if err := resolveLocalImages(ctx, &snapshot.SnapshotSpec); err != nil {
+		return nil, err
+	}
 	expandImageIndex(ctx, &snapshot.SnapshotSpec)
 
 	return &snapshot.SnapshotSpec, nil
@@ -181,6 +185,27 @@ func readSnapshotSource(input []byte) (app.SnapshotSpec, error) {
 	return file, nil
 }
 
+// resolveLocalImages replaces the references to the images in local OCI
+// layouts and docker archives with the references to those images loaded in
+// memory.
+func resolveLocalImages(ctx context.Context, snap *app.SnapshotSpec) error {
+	var allErrors error
+	for i, component := range snap.Components {
+		if !oci.IsLocal(component.ContainerImage) {
+			continue
+		}
+
+		ref, err := oci.ResolveLocal(ctx, component.ContainerImage)
+		if err != nil {
+			allErrors = errors.Join(allErrors, err)
+			continue
+		}
+		snap.Components[i].ContainerImage = ref
+	}
+
+	return allErrors
+}
+
 func expandImageIndex(ctx context.Context, snap *app.SnapshotSpec) {
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:expand-image-index")
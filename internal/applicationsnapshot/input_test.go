@@ -378,3 +378,25 @@ func TestExpandImageImage_Errors(t *testing.T) {
 		log.StandardLogger().ReplaceHooks(make(log.LevelHooks))
 	}
 }
+
+func TestResolveLocalImages(t *testing.T) {
+	snap := &app.SnapshotSpec{
+		Components: []app.SnapshotComponent{
+			{
+				Name:           "remote",
+				ContainerImage: "registry.io/repository/image:tag",
+			},
+			{
+				Name:           "local",
+				ContainerImage: "oci:" + t.TempDir(),
+			},
+		},
+	}
+
+	err := resolveLocalImages(context.Background(), snap)
+	assert.ErrorContains(t, err, "local images are not supported")
+
+	err = resolveLocalImages(oci.WithLocalImages(context.Background()), snap)
+	assert.ErrorContains(t, err, "loading the local image")
+	assert.Equal(t, "registry.io/repository/image:tag", snap.Components[0].ContainerImage)
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/sarif"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/version"
@@ -61,6 +62,20 @@ type Report struct {
 	EffectiveTime time.Time                        `json:"effective-time"`
 	PolicyInput   [][]byte                         `json:"-"`
 	ShowSuccesses bool                             `json:"-"`
+	// PolicySources holds the URLs of the policy and data sources used in the
+	// evaluation, pinned to the digest of the fetched content
+	PolicySources []string `json:"policy-sources,omitempty"`
+	// VerifiedTime is the time of the validation, set only in the VSA
+	VerifiedTime *time.Time `json:"verified-time,omitempty"`
+	// SignedVSA holds the DSSE envelope of the signed VSA, when set it is
+	// output instead of the unsigned VSA statement
+	SignedVSA []byte `json:"-"`
+	// Baseline holds the report of the same snapshot validated against the
+	// baseline policy, used by the diff format
+	Baseline *Report `json:"-"`
+	// Diff holds the differences to the baseline report, set only when the
+	// report is written in the JSON or YAML format
+	Diff *ReportDiff `json:"diff,omitempty"`
 }
 
 type summary struct {
@@ -108,6 +123,8 @@ const (
 	Attestation     = "attestation"
 	PolicyInput     = "policy-input"
 	VSA             = "vsa"
+	SARIF           = "sarif"
+	Diff            = "diff"
 	// Deprecated old version of appstudio. Remove some day.
 	HACBS = "hacbs"
 )
@@ -124,6 +141,7 @@ var OutputFormats = []string{
 	Attestation,
 	PolicyInput,
 	VSA,
+	SARIF,
 }
 
 // WriteReport returns a new instance of Report representing the state of
@@ -197,13 +215,13 @@ func (r Report) WriteAll(targets []string, p format.TargetParser) (allErrors err
 func (r *Report) toFormat(format string) (data []byte, err error) {
 	switch format {
 	case JSON:
-		data, err = json.Marshal(r)
+		data, err = json.Marshal(r.withDiff())
 	case YAML:
-		data, err = yaml.Marshal(r)
+		data, err = yaml.Marshal(r.withDiff())
 	case Text:
 		data, err = generateTextReport(r)
 	case AppStudio, HACBS:
-		data, err = json.Marshal(r.toAppstudioReport())
+		data, err = json.Marshal(r.ToAppstudioReport())
 	case Summary:
 		data, err = json.Marshal(r.toSummary())
 	case SummaryMarkdown:
@@ -218,13 +236,38 @@ func (r *Report) toFormat(format string) (data []byte, err error) {
 		data = bytes.Join(r.PolicyInput, []byte("\n"))
 	case VSA:
 		data, err = r.toVSA()
+	case SARIF:
+		data, err = json.Marshal(r.toSARIF())
+	case Diff:
+		if r.Baseline == nil {
+			return nil, errors.New("the diff format requires a baseline policy")
+		}
+		data = DiffReports(*r.Baseline, *r).Text()
 	default:
 		return nil, fmt.Errorf("%q is not a valid report format", format)
 	}
 	return
 }
 
+// withDiff returns the report including the differences to the baseline
+// report, if there is one.
+func (r *Report) withDiff() *Report {
+	if r.Baseline == nil {
+		return r
+	}
+
+	d := DiffReports(*r.Baseline, *r)
+	withDiff := *r
+	withDiff.Diff = &d
+
+	return &withDiff
+}
+
 func (r *Report) toVSA() ([]byte, error) {
+	if len(r.SignedVSA) > 0 {
+		return r.SignedVSA, nil
+	}
+
 	vsa, err := NewVSA(*r)
 	if err != nil {
 		return []byte{}, err
@@ -232,6 +275,18 @@ func (r *Report) toVSA() ([]byte, error) {
 	return json.Marshal(vsa)
 }
 
+// toSARIF returns a version of the report in SARIF format, with the
+// violations and warnings of each image as results.
+func (r *Report) toSARIF() sarif.Log {
+	b := sarif.NewBuilder(r.EcVersion)
+	for _, c := range r.Components {
+		b.Add(c.ContainerImage, sarif.LevelError, c.Violations)
+		b.Add(c.ContainerImage, sarif.LevelWarning, c.Warnings)
+	}
+
+	return b.Log()
+}
+
 // toSummary returns a condensed version of the report.
 func (r *Report) toSummary() summary {
 	pr := summary{
@@ -330,7 +385,7 @@ func generateTextReport(r *Report) ([]byte, error) {
 		Report: r,
 		// This has useful stuff we want to output, so let's reuse it
 		// even though this is not what it was originally designed for
-		TestReport: r.toAppstudioReport(),
+		TestReport: r.ToAppstudioReport(),
 	}
 
 	return utils.RenderFromTemplatesWithMain(input, "text_report.tmpl", efs)
@@ -341,9 +396,9 @@ func writeMarkdownField(buffer *bytes.Buffer, name string, value any, icon strin
 	buffer.WriteString(fmt.Sprintf("| %s | %s | %s |\n", name, valueStr, icon))
 }
 
-// toAppstudioReport returns a version of the report that conforms to the
+// ToAppstudioReport returns a version of the report that conforms to the
 // TEST_OUTPUT format, usually written to the TEST_OUTPUT Tekton task result
-func (r *Report) toAppstudioReport() TestReport {
+func (r *Report) ToAppstudioReport() TestReport {
 	result := TestReport{
 		Timestamp: fmt.Sprint(r.created.UTC().Unix()),
 		// EC generally runs with the AllNamespaces flag set to true
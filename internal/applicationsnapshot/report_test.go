@@ -153,6 +153,44 @@ policy:
 	assert.False(t, report.Success)
 }
 
+func Test_ReportSARIF(t *testing.T) {
+	var snapshot app.SnapshotSpec
+	err := json.Unmarshal([]byte(testSnapshot), &snapshot)
+	assert.NoError(t, err)
+
+	components := testComponentsFor(snapshot)
+
+	ctx := context.Background()
+	report, err := NewReport("snappy", components, createTestPolicy(t, ctx), "data here", nil, true)
+	assert.NoError(t, err)
+
+	location := func(uri string, index int) string {
+		return fmt.Sprintf(`[{"physicalLocation": {"artifactLocation": {"uri": %q, "index": %d}}}]`, uri, index)
+	}
+
+	expected := fmt.Sprintf(`{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [{
+			"tool": {"driver": {"name": "ec", "informationUri": "https://enterprisecontract.dev", "version": "development"}},
+			"artifacts": [
+				{"location": {"uri": "quay.io/caf/spam@sha256:123…"}},
+				{"location": {"uri": "quay.io/caf/bacon@sha256:234…"}},
+				{"location": {"uri": "quay.io/caf/eggs@sha256:345…"}}
+			],
+			"results": [
+				{"level": "error", "message": {"text": "violation1"}, "locations": %s},
+				{"level": "warning", "message": {"text": "warning1"}, "locations": %s},
+				{"level": "error", "message": {"text": "violation2"}, "locations": %s}
+			]
+		}]
+	}`, location("quay.io/caf/spam@sha256:123…", 0), location("quay.io/caf/spam@sha256:123…", 0), location("quay.io/caf/bacon@sha256:234…", 1))
+
+	sarif, err := report.toFormat(SARIF)
+	assert.NoError(t, err)
+	assert.JSONEq(t, expected, string(sarif))
+}
+
 func Test_GenerateMarkdownSummary(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -921,6 +959,34 @@ func Test_TextReport(t *testing.T) {
 				},
 			},
 		}},
+		{"explained", Report{
+			Components: []Component{
+				{
+					SnapshotComponent: app.SnapshotComponent{
+						ContainerImage: "registry.io/repository/component-1:tag",
+					},
+					Violations: []evaluator.Result{
+						{
+							Metadata: map[string]interface{}{
+								"code": "violation-1",
+							},
+							Message: "Violation 1 message",
+							Explanation: &evaluator.Explanation{
+								Rule: "policy/release/rule.rego:10",
+								Inputs: []evaluator.Reference{
+									{Path: `input.image.ref`, Value: "registry.io/repository/component-1:tag"},
+									{Path: `input.attestations[0].statement.predicateType`},
+								},
+								Data: []evaluator.Reference{
+									{Path: `data.rule_data.allowed_registries`, Value: []any{"registry.io/trusted"}},
+								},
+								Trace: []string{"Enter data.release.deny"},
+							},
+						},
+					},
+				},
+			},
+		}},
 	}
 
 	for _, c := range cases {
@@ -17,7 +17,12 @@
 package applicationsnapshot
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/in-toto/in-toto-golang/in_toto"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -37,6 +42,11 @@ func NewVSA(report Report) (ProvenanceStatementVSA, error) {
 		return ProvenanceStatementVSA{}, err
 	}
 
+	if !report.created.IsZero() {
+		verified := report.created
+		report.VerifiedTime = &verified
+	}
+
 	return ProvenanceStatementVSA{
 		StatementHeader: in_toto.StatementHeader{
 			Type:          StatmentVSA,
@@ -54,8 +64,35 @@ func getSubjects(report Report) ([]in_toto.Subject, error) {
 	}
 
 	var subjects []in_toto.Subject
+	seen := map[string]bool{}
+	add := func(subject in_toto.Subject) {
+		key := fmt.Sprintf("%s %v", subject.Name, subject.Digest)
+		if !seen[key] {
+			seen[key] = true
+			subjects = append(subjects, subject)
+		}
+	}
+
+	// The validated images are the subjects of the VSA regardless of the
+	// subjects of their attestations
+	for _, c := range report.Components {
+		ref, err := name.NewDigest(c.ContainerImage)
+		if err != nil {
+			log.Debugf("Not including %q in the VSA subjects: %v", c.ContainerImage, err)
+			continue
+		}
+
+		algorithm, digest, _ := strings.Cut(ref.DigestStr(), ":")
+		add(in_toto.Subject{
+			Name:   ref.Context().Name(),
+			Digest: map[string]string{algorithm: digest},
+		})
+	}
+
 	for _, stmt := range statements {
-		subjects = append(subjects, stmt.Subject...)
+		for _, subject := range stmt.Subject {
+			add(subject)
+		}
 	}
 	return subjects, nil
 }
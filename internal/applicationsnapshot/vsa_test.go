@@ -22,12 +22,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/in-toto/in-toto-golang/in_toto"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
@@ -90,13 +92,17 @@ func TestNewVSA(t *testing.T) {
 	report, err := NewReport("snappy", components, testPolicy, "data here", nil, true)
 	assert.NoError(t, err)
 
+	// the VSA records the time of the validation
+	predicate := report
+	predicate.VerifiedTime = &report.created
+
 	expected := ProvenanceStatementVSA{
 		StatementHeader: in_toto.StatementHeader{
 			Type:          "https://in-toto.io/Statement/v1",
 			PredicateType: "https://enterprisecontract.dev/verification_summary/v1",
 			Subject:       nil,
 		},
-		Predicate: report,
+		Predicate: predicate,
 	}
 	vsa, err := NewVSA(report)
 	assert.NoError(t, err)
@@ -141,6 +147,49 @@ func TestSubjects(t *testing.T) {
 	assert.Equal(t, expected, subjects)
 }
 
+func TestSubjectsIncludeComponentImages(t *testing.T) {
+	digest := "sha256:1ad4c9b3dc6f5a2c1b1ba7a5a1b2d0bd5ea4a5d9b06b65dd3e93bcf0d1b44c9a"
+	image := in_toto.Subject{
+		Name:   "registry.io/repository/image",
+		Digest: map[string]string{"sha256": strings.TrimPrefix(digest, "sha256:")},
+	}
+	other := in_toto.Subject{
+		Name:   "registry.io/repository/other",
+		Digest: map[string]string{"sha256": "abc"},
+	}
+
+	statement := in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Subject: []in_toto.Subject{image, other},
+		},
+	}
+	data, err := json.Marshal(statement)
+	require.NoError(t, err)
+
+	report := Report{Components: []Component{
+		{
+			SnapshotComponent: app.SnapshotComponent{ContainerImage: "registry.io/repository/image@" + digest},
+			Attestations:      []attestation.Attestation{provenance{statement: statement, data: data}},
+		},
+		{
+			// images without attestations are subjects too
+			SnapshotComponent: app.SnapshotComponent{ContainerImage: "registry.io/repository/lonely@" + digest},
+		},
+		{
+			// not resolved to a digest
+			SnapshotComponent: app.SnapshotComponent{ContainerImage: "registry.io/repository/image:latest"},
+		},
+	}}
+
+	subjects, err := getSubjects(report)
+	require.NoError(t, err)
+	assert.Equal(t, []in_toto.Subject{
+		image,
+		{Name: "registry.io/repository/lonely", Digest: image.Digest},
+		other,
+	}, subjects)
+}
+
 func toJson(policy any) string {
 	newInline, err := json.Marshal(policy)
 	if err != nil {
@@ -148,3 +197,11 @@ func toJson(policy any) string {
 	}
 	return string(newInline)
 }
+
+func TestSignedVSAOutput(t *testing.T) {
+	report := Report{SignedVSA: []byte(`{"payloadType": "application/vnd.in-toto+json"}`)}
+
+	data, err := report.toFormat(VSA)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"payloadType": "application/vnd.in-toto+json"}`, string(data))
+}
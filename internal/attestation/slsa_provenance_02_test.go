@@ -38,12 +38,19 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/enterprise-contract/ec-cli/internal/signature"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 )
 
 type mockSignature struct {
 	*mock.Mock
 }
 
+// Discovery reports the signatures as found by the cosign tag schema, as the
+// client does
+func (l mockSignature) Discovery() string {
+	return oci.DiscoveryTag
+}
+
 func (l mockSignature) Annotations() (map[string]string, error) {
 	args := l.Called()
 
@@ -0,0 +1,137 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ct "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLSAProvenanceV1FromSignature(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "unsupported statement type",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v2",
+				"predicateType": "https://slsa.dev/provenance/v1"
+			}`,
+			err: "unsupported attestation type: https://in-toto.io/Statement/v2",
+		},
+		{
+			name: "unexpected predicate type",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://slsa.dev/provenance/v0.2"
+			}`,
+			err: "unsupported attestation predicate type: https://slsa.dev/provenance/v0.2",
+		},
+		{
+			name: "malformed time",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicate": {"runDetails": {"metadata": {"finishedOn": "yesterday"}}}
+			}`,
+			err: `malformed attestation data: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`,
+		},
+		{
+			name: "valid v0.1 statement",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicate": {"buildDefinition": {"buildType": "https://my.build.type"}}
+			}`,
+		},
+		{
+			name: "valid v1 statement",
+			data: `{
+				"_type": "https://in-toto.io/Statement/v1",
+				"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "dabbad00"}}],
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicate": {
+					"buildDefinition": {
+						"buildType": "https://my.build.type",
+						"externalParameters": {"a": 1},
+						"extra": "kept"
+					},
+					"runDetails": {"builder": {"id": "https://my.builder"}}
+				}
+			}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sig := mockSignature{&mock.Mock{}}
+			sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+			sig.On("Uncompressed").Return(buffy(
+				fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]}`, encode(c.data)),
+			), nil)
+			sig.On("Base64Signature").Return("", nil)
+			sig.On("Cert").Return(&x509.Certificate{}, nil)
+			sig.On("Chain").Return([]*x509.Certificate{}, nil)
+
+			sp, err := SLSAProvenanceV1FromSignature(sig)
+			if c.err != "" {
+				assert.Nil(t, sp)
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, PredicateSLSAProvenanceV1, sp.PredicateType())
+			// the statement is provided as is, including any fields unknown
+			// to the SLSA v1.0 predicate
+			assert.JSONEq(t, c.data, string(sp.Statement()))
+			require.Len(t, sp.Signatures(), 1)
+			assert.Equal(t, "key-id-1", sp.Signatures()[0].KeyID)
+
+			var statement map[string]any
+			require.NoError(t, json.Unmarshal([]byte(c.data), &statement))
+			assert.Equal(t, statement["_type"], sp.Type())
+
+			j, err := json.Marshal(sp)
+			require.NoError(t, err)
+			assert.JSONEq(t, fmt.Sprintf(`{
+				"type": %q,
+				"predicateType": "https://slsa.dev/provenance/v1",
+				"predicateBuildType": "https://my.build.type",
+				"signatures": [{"keyid": "key-id-1", "sig": "sig-1", "discovery": "tag"}]
+			}`, statement["_type"]), string(j))
+		})
+	}
+}
+
+func TestSLSAProvenanceV1FromSignatureNilSignature(t *testing.T) {
+	sp, err := SLSAProvenanceV1FromSignature(nil)
+	assert.EqualError(t, err, "no attestation found")
+	assert.Nil(t, sp)
+}
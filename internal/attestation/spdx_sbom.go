@@ -16,9 +16,22 @@
 
 package attestation
 
+import "strings"
+
 const (
 	PredicateSpdxDocument = "https://spdx.dev/Document"
+	PredicateCycloneDX    = "https://cyclonedx.org/bom"
 )
 
-// Todo: More code here in future probably, e.g. for unmarshaling an SPDX
-// document to confirm it is formatted correctly
+// IsSBOM returns true if the predicate type is of a SPDX or a CycloneDX SBOM,
+// including the versioned predicate types, e.g. https://cyclonedx.org/bom/v1.5.
+// The SBOMs are parsed by the sbom package.
+func IsSBOM(predicateType string) bool {
+	for _, t := range []string{PredicateSpdxDocument, PredicateCycloneDX} {
+		if predicateType == t || strings.HasPrefix(predicateType, t+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,524 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+
+	"github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+const (
+	// PredicateCosignVuln is the predicate type of the attestations created by
+	// `cosign attest --type vuln`
+	PredicateCosignVuln = "https://cosign.sigstore.dev/attestation/vuln/v1"
+
+	// PredicateSARIF is the predicate type of the attestations holding a SARIF
+	// 2.1.0 report
+	PredicateSARIF = "https://docs.oasis-open.org/sarif/sarif/2.1.0"
+
+	// PredicateTrivy is the predicate type of the attestations holding a Trivy
+	// JSON report
+	PredicateTrivy = "https://trivy.dev/report"
+)
+
+// The normalized severities
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityUnknown  = "unknown"
+)
+
+// Finding is a vulnerability found by a scanner, normalized across the
+// supported report formats. Scanner and ScanTime are copied from the scan
+// that found it for the convenience of the policy rules.
+type Finding struct {
+	ID               string `json:"id"`
+	Severity         string `json:"severity"`
+	Package          string `json:"package,omitempty"`
+	InstalledVersion string `json:"installedVersion,omitempty"`
+	Purl             string `json:"purl,omitempty"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	PublishedDate    string `json:"publishedDate,omitempty"`
+	Scanner          string `json:"scanner"`
+	ScanTime         string `json:"scanTime,omitempty"`
+}
+
+// VulnerabilityScan describes the scan that produced a vulnerability report.
+// When the findings of the report could not be normalized Error holds the
+// reason.
+type VulnerabilityScan struct {
+	PredicateType  string `json:"predicateType"`
+	Scanner        string `json:"scanner"`
+	ScannerVersion string `json:"scannerVersion,omitempty"`
+	ScanTime       string `json:"scanTime,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// VulnerabilityReport is an attestation holding the results of a vulnerability
+// scan
+type VulnerabilityReport interface {
+	Attestation
+	Scan() VulnerabilityScan
+	Findings() []Finding
+}
+
+// vulnerabilityParsers parse the predicate, by predicate type, into the scan
+// and its findings
+var vulnerabilityParsers = map[string]func(json.RawMessage) (VulnerabilityScan, []Finding, error){
+	PredicateCosignVuln: parseCosignVuln,
+	PredicateSARIF:      parseSARIF,
+	PredicateTrivy:      parseTrivy,
+}
+
+// IsVulnerabilityReport returns true if the predicate type is of one of the
+// supported vulnerability reports
+func IsVulnerabilityReport(predicateType string) bool {
+	_, ok := vulnerabilityParsers[predicateType]
+	return ok
+}
+
+// VulnerabilityReportFromSignature parses the vulnerability report from the
+// provided OCI layer. Expects that the layer contains DSSE JSON with an
+// embedded statement with one of the supported vulnerability report
+// predicates.
+func VulnerabilityReportFromSignature(sig oci.Signature) (VulnerabilityReport, error) {
+	payload, err := payloadFromSig(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	embedded, err := decodedPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var statement struct {
+		in_toto.StatementHeader
+		Predicate json.RawMessage `json:"predicate"`
+	}
+	if err := json.Unmarshal(embedded, &statement); err != nil {
+		return nil, fmt.Errorf("malformed attestation data: %w", err)
+	}
+
+	if statement.Type != in_toto.StatementInTotoV01 && statement.Type != StatementInTotoV1 {
+		return nil, fmt.Errorf("unsupported attestation type: %s", statement.Type)
+	}
+
+	parse, ok := vulnerabilityParsers[statement.PredicateType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported attestation predicate type: %s", statement.PredicateType)
+	}
+
+	// A report that cannot be normalized does not prevent the validation of the
+	// image, the problem is recorded in the scan and the policy decides how to
+	// treat it, the attested report is still provided as it was attested.
+	var scan VulnerabilityScan
+	var findings []Finding
+	if len(statement.Predicate) == 0 || statement.Predicate[0] != '{' {
+		err = errors.New("the predicate is not an object")
+	} else {
+		scan, findings, err = parse(statement.Predicate)
+	}
+	if err != nil {
+		scan = VulnerabilityScan{Error: fmt.Sprintf("malformed attestation data: %s", err)}
+		findings = []Finding{}
+	}
+	scan.PredicateType = statement.PredicateType
+
+	for i := range findings {
+		findings[i].Scanner = scan.Scanner
+		findings[i].ScanTime = scan.ScanTime
+	}
+
+	signatures, err := createEntitySignatures(sig, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create signed entity: %w", err)
+	}
+
+	return vulnerabilityReport{
+		header:     statement.StatementHeader,
+		data:       embedded,
+		signatures: signatures,
+		scan:       scan,
+		findings:   findings,
+	}, nil
+}
+
+type vulnerabilityReport struct {
+	header     in_toto.StatementHeader
+	data       []byte
+	signatures []signature.EntitySignature
+	scan       VulnerabilityScan
+	findings   []Finding
+}
+
+func (a vulnerabilityReport) Type() string {
+	return a.header.Type
+}
+
+func (a vulnerabilityReport) PredicateType() string {
+	return a.header.PredicateType
+}
+
+// This returns the raw json, the report is provided to the policy as it was
+// attested, the normalized findings are provided separately
+func (a vulnerabilityReport) Statement() []byte {
+	return a.data
+}
+
+func (a vulnerabilityReport) Signatures() []signature.EntitySignature {
+	return a.signatures
+}
+
+func (a vulnerabilityReport) Subject() []in_toto.Subject {
+	return a.header.Subject
+}
+
+func (a vulnerabilityReport) Scan() VulnerabilityScan {
+	return a.scan
+}
+
+func (a vulnerabilityReport) Findings() []Finding {
+	return a.findings
+}
+
+// See the equivalent method in slsa_provenance_02.go
+func (a vulnerabilityReport) MarshalJSON() ([]byte, error) {
+	val := struct {
+		Type          string                      `json:"type"`
+		PredicateType string                      `json:"predicateType"`
+		Scanner       string                      `json:"scanner"`
+		Signatures    []signature.EntitySignature `json:"signatures"`
+	}{
+		Type:          a.header.Type,
+		PredicateType: a.header.PredicateType,
+		Scanner:       a.scan.Scanner,
+		Signatures:    a.signatures,
+	}
+
+	return json.Marshal(val)
+}
+
+// normalizeSeverity maps the severities used by the different scanners to
+// one of the normalized severities
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return SeverityCritical
+	case "high", "important":
+		return SeverityHigh
+	case "medium", "moderate":
+		return SeverityMedium
+	case "low", "negligible", "minor":
+		return SeverityLow
+	default:
+		return SeverityUnknown
+	}
+}
+
+// parseCosignVuln parses the cosign vuln predicate, the findings are taken from
+// the scanner result which is expected to be either a Trivy JSON or a SARIF
+// report. Other results are recorded as an error of the scan.
+func parseCosignVuln(predicate json.RawMessage) (VulnerabilityScan, []Finding, error) {
+	var vuln struct {
+		Scanner struct {
+			URI     string          `json:"uri"`
+			Version string          `json:"version"`
+			Result  json.RawMessage `json:"result"`
+		} `json:"scanner"`
+		Metadata struct {
+			ScanStartedOn  string `json:"scanStartedOn"`
+			ScanFinishedOn string `json:"scanFinishedOn"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(predicate, &vuln); err != nil {
+		return VulnerabilityScan{}, nil, err
+	}
+
+	if vuln.Scanner.URI == "" {
+		return VulnerabilityScan{}, nil, errors.New("missing scanner.uri")
+	}
+
+	scan := VulnerabilityScan{
+		Scanner:        vuln.Scanner.URI,
+		ScannerVersion: vuln.Scanner.Version,
+		ScanTime:       vuln.Metadata.ScanFinishedOn,
+	}
+	if scan.ScanTime == "" {
+		scan.ScanTime = vuln.Metadata.ScanStartedOn
+	}
+
+	result := vuln.Scanner.Result
+	if len(result) == 0 || string(result) == "null" {
+		return scan, []Finding{}, nil
+	}
+
+	var probe struct {
+		SchemaVersion int             `json:"SchemaVersion"`
+		Runs          json.RawMessage `json:"runs"`
+	}
+	if err := json.Unmarshal(result, &probe); err != nil {
+		return VulnerabilityScan{}, nil, fmt.Errorf("malformed scanner.result: %w", err)
+	}
+
+	var findings []Finding
+	var err error
+	switch {
+	case probe.SchemaVersion != 0:
+		_, findings, err = parseTrivy(result)
+	case len(probe.Runs) != 0:
+		_, findings, err = parseSARIF(result)
+	default:
+		err = errors.New("unsupported scanner result, expecting a Trivy JSON or a SARIF report")
+	}
+	if err != nil {
+		scan.Error = err.Error()
+		findings = []Finding{}
+	}
+
+	return scan, findings, nil
+}
+
+// parseTrivy parses the Trivy JSON report
+func parseTrivy(predicate json.RawMessage) (VulnerabilityScan, []Finding, error) {
+	var report struct {
+		SchemaVersion int    `json:"SchemaVersion"`
+		CreatedAt     string `json:"CreatedAt"`
+		Trivy         struct {
+			Version string `json:"Version"`
+		} `json:"Trivy"`
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string `json:"VulnerabilityID"`
+				PkgName          string `json:"PkgName"`
+				InstalledVersion string `json:"InstalledVersion"`
+				FixedVersion     string `json:"FixedVersion"`
+				Severity         string `json:"Severity"`
+				PublishedDate    string `json:"PublishedDate"`
+				PkgIdentifier    struct {
+					PURL string `json:"PURL"`
+				} `json:"PkgIdentifier"`
+			} `json:"Vulnerabilities"`
+		} `json:"Results"`
+	}
+	if err := json.Unmarshal(predicate, &report); err != nil {
+		return VulnerabilityScan{}, nil, err
+	}
+
+	if report.SchemaVersion == 0 {
+		return VulnerabilityScan{}, nil, errors.New("missing SchemaVersion")
+	}
+
+	scan := VulnerabilityScan{
+		Scanner:        "trivy",
+		ScannerVersion: report.Trivy.Version,
+		ScanTime:       report.CreatedAt,
+	}
+
+	findings := []Finding{}
+	// Trivy omits the Results when nothing was scanned, i.e. no findings
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			if v.VulnerabilityID == "" {
+				return VulnerabilityScan{}, nil, errors.New("missing VulnerabilityID")
+			}
+			findings = append(findings, Finding{
+				ID:               v.VulnerabilityID,
+				Severity:         normalizeSeverity(v.Severity),
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				Purl:             v.PkgIdentifier.PURL,
+				FixedVersion:     v.FixedVersion,
+				PublishedDate:    v.PublishedDate,
+			})
+		}
+	}
+
+	return scan, findings, nil
+}
+
+// sarifSeverity returns the severity from the CVSS score in the
+// security-severity property, if present, or from the SARIF level otherwise
+func sarifSeverity(securitySeverity json.Number, level string) string {
+	if score, err := securitySeverity.Float64(); err == nil && securitySeverity != "" {
+		switch {
+		case score >= 9.0:
+			return SeverityCritical
+		case score >= 7.0:
+			return SeverityHigh
+		case score >= 4.0:
+			return SeverityMedium
+		case score > 0:
+			return SeverityLow
+		}
+	}
+
+	switch level {
+	case "error":
+		return SeverityHigh
+	case "warning":
+		return SeverityMedium
+	case "note":
+		return SeverityLow
+	default:
+		return SeverityUnknown
+	}
+}
+
+// parseSARIF parses the SARIF report. SARIF does not define where the package
+// and the fixed version are held, these are taken from the purl and the
+// fixedVersion properties of the results, or the package URL from the logical
+// locations, as used by Grype.
+func parseSARIF(predicate json.RawMessage) (VulnerabilityScan, []Finding, error) {
+	type properties struct {
+		SecuritySeverity json.Number `json:"security-severity"`
+		Purl             string      `json:"purl"`
+		FixedVersion     string      `json:"fixedVersion"`
+	}
+	var report struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+					Rules   []struct {
+						ID                   string     `json:"id"`
+						Properties           properties `json:"properties"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Invocations []struct {
+				StartTimeUTC string `json:"startTimeUtc"`
+				EndTimeUTC   string `json:"endTimeUtc"`
+			} `json:"invocations"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex *int   `json:"ruleIndex"`
+				Rule      struct {
+					ID    string `json:"id"`
+					Index *int   `json:"index"`
+				} `json:"rule"`
+				Level      string     `json:"level"`
+				Properties properties `json:"properties"`
+				Locations  []struct {
+					LogicalLocations []struct {
+						FullyQualifiedName string `json:"fullyQualifiedName"`
+					} `json:"logicalLocations"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(predicate, &report); err != nil {
+		return VulnerabilityScan{}, nil, err
+	}
+
+	if report.Version != "2.1.0" {
+		return VulnerabilityScan{}, nil, fmt.Errorf("unsupported SARIF version %q, expecting 2.1.0", report.Version)
+	}
+
+	if len(report.Runs) == 0 {
+		return VulnerabilityScan{}, nil, errors.New("missing runs")
+	}
+
+	var scan VulnerabilityScan
+	findings := []Finding{}
+	for i, run := range report.Runs {
+		driver := run.Tool.Driver
+		if driver.Name == "" {
+			return VulnerabilityScan{}, nil, fmt.Errorf("missing runs[%d].tool.driver.name", i)
+		}
+
+		// the first run describes the scan
+		if i == 0 {
+			scan.Scanner = strings.ToLower(driver.Name)
+			scan.ScannerVersion = driver.Version
+			if len(run.Invocations) > 0 {
+				scan.ScanTime = run.Invocations[0].EndTimeUTC
+				if scan.ScanTime == "" {
+					scan.ScanTime = run.Invocations[0].StartTimeUTC
+				}
+			}
+		}
+
+		rules := make(map[string]int, len(driver.Rules))
+		for j, r := range driver.Rules {
+			rules[r.ID] = j
+		}
+
+		for _, r := range run.Results {
+			// The rule can be referenced by ruleId, rule.id or, when the
+			// rule has no id, by its index in the rules of the driver
+			id := r.RuleID
+			if id == "" {
+				id = r.Rule.ID
+			}
+			index := r.RuleIndex
+			if index == nil {
+				index = r.Rule.Index
+			}
+			if id == "" && index != nil && *index >= 0 && *index < len(driver.Rules) {
+				id = driver.Rules[*index].ID
+			}
+			if id == "" {
+				return VulnerabilityScan{}, nil, fmt.Errorf("missing runs[%d].results.ruleId", i)
+			}
+
+			props := r.Properties
+			level := r.Level
+			if j, ok := rules[id]; ok {
+				rule := driver.Rules[j]
+				if props.SecuritySeverity == "" {
+					props.SecuritySeverity = rule.Properties.SecuritySeverity
+				}
+				if level == "" {
+					level = rule.DefaultConfiguration.Level
+				}
+			}
+
+			finding := Finding{
+				ID:           id,
+				Severity:     sarifSeverity(props.SecuritySeverity, level),
+				Purl:         props.Purl,
+				FixedVersion: props.FixedVersion,
+			}
+			for _, l := range r.Locations {
+				for _, ll := range l.LogicalLocations {
+					if finding.Purl == "" && strings.HasPrefix(ll.FullyQualifiedName, "pkg:") {
+						finding.Purl = ll.FullyQualifiedName
+					}
+				}
+			}
+
+			findings = append(findings, finding)
+		}
+	}
+
+	return scan, findings, nil
+}
@@ -0,0 +1,399 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package attestation
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ct "github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const trivyReport = `{
+	"SchemaVersion": 2,
+	"CreatedAt": "2024-06-01T10:00:00Z",
+	"ArtifactName": "registry.io/repository/image",
+	"Results": [
+		{
+			"Target": "registry.io/repository/image (redhat 9.4)",
+			"Vulnerabilities": [
+				{
+					"VulnerabilityID": "CVE-2024-0001",
+					"PkgName": "openssl",
+					"PkgIdentifier": {"PURL": "pkg:rpm/redhat/openssl@3.0.7-16.el9_2?arch=x86_64"},
+					"InstalledVersion": "1:3.0.7-16.el9_2",
+					"FixedVersion": "1:3.0.7-18.el9_2",
+					"Severity": "CRITICAL",
+					"PublishedDate": "2024-05-01T00:00:00Z"
+				},
+				{
+					"VulnerabilityID": "CVE-2024-0002",
+					"PkgName": "curl",
+					"InstalledVersion": "7.76.1-26.el9",
+					"Severity": "MODERATE"
+				}
+			]
+		},
+		{"Target": "app"}
+	]
+}`
+
+const sarifReport = `{
+	"version": "2.1.0",
+	"runs": [
+		{
+			"tool": {
+				"driver": {
+					"name": "Grype",
+					"version": "0.79.0",
+					"rules": [
+						{"id": "CVE-2024-0001-openssl", "properties": {"security-severity": "9.8"}},
+						{"id": "CVE-2024-0003-zlib", "defaultConfiguration": {"level": "warning"}}
+					]
+				}
+			},
+			"invocations": [{"startTimeUtc": "2024-06-01T09:59:00Z", "endTimeUtc": "2024-06-01T10:00:00Z"}],
+			"results": [
+				{
+					"ruleId": "CVE-2024-0001-openssl",
+					"properties": {"fixedVersion": "1:3.0.7-18.el9_2"},
+					"locations": [{"logicalLocations": [{"fullyQualifiedName": "pkg:rpm/redhat/openssl@3.0.7-16.el9_2"}]}]
+				},
+				{"ruleId": "CVE-2024-0003-zlib", "properties": {"purl": "pkg:rpm/redhat/zlib@1.2.11"}},
+				{"ruleId": "CVE-2024-0004", "level": "note"}
+			]
+		}
+	]
+}`
+
+func statement(predicateType, predicate string) string {
+	return fmt.Sprintf(`{
+		"_type": "https://in-toto.io/Statement/v0.1",
+		"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "dabbad00"}}],
+		"predicateType": %q,
+		"predicate": %s
+	}`, predicateType, predicate)
+}
+
+func TestVulnerabilityReportFromSignature(t *testing.T) {
+	trivyFindings := []Finding{
+		{
+			ID:               "CVE-2024-0001",
+			Severity:         SeverityCritical,
+			Package:          "openssl",
+			InstalledVersion: "1:3.0.7-16.el9_2",
+			Purl:             "pkg:rpm/redhat/openssl@3.0.7-16.el9_2?arch=x86_64",
+			FixedVersion:     "1:3.0.7-18.el9_2",
+			PublishedDate:    "2024-05-01T00:00:00Z",
+			Scanner:          "trivy",
+			ScanTime:         "2024-06-01T10:00:00Z",
+		},
+		{
+			ID:               "CVE-2024-0002",
+			Severity:         SeverityMedium,
+			Package:          "curl",
+			InstalledVersion: "7.76.1-26.el9",
+			Scanner:          "trivy",
+			ScanTime:         "2024-06-01T10:00:00Z",
+		},
+	}
+
+	cases := []struct {
+		name     string
+		data     string
+		scan     VulnerabilityScan
+		findings []Finding
+		err      string
+	}{
+		{
+			name: "unsupported statement type",
+			data: `{"_type": "https://in-toto.io/Statement/v2", "predicateType": "https://trivy.dev/report"}`,
+			err:  "unsupported attestation type: https://in-toto.io/Statement/v2",
+		},
+		{
+			name: "unexpected predicate type",
+			data: statement("https://slsa.dev/provenance/v1", "{}"),
+			err:  "unsupported attestation predicate type: https://slsa.dev/provenance/v1",
+		},
+		{
+			name: "predicate not an object",
+			data: statement(PredicateTrivy, `"report"`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateTrivy,
+				Error:         "malformed attestation data: the predicate is not an object",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "trivy",
+			data: statement(PredicateTrivy, trivyReport),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateTrivy,
+				Scanner:       "trivy",
+				ScanTime:      "2024-06-01T10:00:00Z",
+			},
+			findings: trivyFindings,
+		},
+		{
+			name: "trivy without results",
+			data: statement(PredicateTrivy, `{"SchemaVersion": 2, "CreatedAt": "2024-06-01T10:00:00Z"}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateTrivy,
+				Scanner:       "trivy",
+				ScanTime:      "2024-06-01T10:00:00Z",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "trivy without schema version",
+			data: statement(PredicateTrivy, `{"Results": []}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateTrivy,
+				Error:         "malformed attestation data: missing SchemaVersion",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "trivy without vulnerability ID",
+			data: statement(PredicateTrivy, `{"SchemaVersion": 2, "Results": [{"Vulnerabilities": [{"PkgName": "curl"}]}]}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateTrivy,
+				Error:         "malformed attestation data: missing VulnerabilityID",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "sarif",
+			data: statement(PredicateSARIF, sarifReport),
+			scan: VulnerabilityScan{
+				PredicateType:  PredicateSARIF,
+				Scanner:        "grype",
+				ScannerVersion: "0.79.0",
+				ScanTime:       "2024-06-01T10:00:00Z",
+			},
+			findings: []Finding{
+				{
+					ID:           "CVE-2024-0001-openssl",
+					Severity:     SeverityCritical,
+					Purl:         "pkg:rpm/redhat/openssl@3.0.7-16.el9_2",
+					FixedVersion: "1:3.0.7-18.el9_2",
+					Scanner:      "grype",
+					ScanTime:     "2024-06-01T10:00:00Z",
+				},
+				{
+					ID:       "CVE-2024-0003-zlib",
+					Severity: SeverityMedium,
+					Purl:     "pkg:rpm/redhat/zlib@1.2.11",
+					Scanner:  "grype",
+					ScanTime: "2024-06-01T10:00:00Z",
+				},
+				{
+					ID:       "CVE-2024-0004",
+					Severity: SeverityLow,
+					Scanner:  "grype",
+					ScanTime: "2024-06-01T10:00:00Z",
+				},
+			},
+		},
+		{
+			name: "sarif referencing rules by index",
+			data: statement(PredicateSARIF, `{"version": "2.1.0", "runs": [{
+				"tool": {"driver": {"name": "Grype", "rules": [
+					{"id": "CVE-2024-0001", "properties": {"security-severity": "9.8"}},
+					{"id": "CVE-2024-0002", "defaultConfiguration": {"level": "note"}}
+				]}},
+				"results": [
+					{"ruleIndex": 1},
+					{"rule": {"id": "CVE-2024-0001"}},
+					{"rule": {"index": 0}}
+				]
+			}]}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateSARIF,
+				Scanner:       "grype",
+			},
+			findings: []Finding{
+				{ID: "CVE-2024-0002", Severity: SeverityLow, Scanner: "grype"},
+				{ID: "CVE-2024-0001", Severity: SeverityCritical, Scanner: "grype"},
+				{ID: "CVE-2024-0001", Severity: SeverityCritical, Scanner: "grype"},
+			},
+		},
+		{
+			name: "sarif without rule reference",
+			data: statement(PredicateSARIF, `{"version": "2.1.0", "runs": [{"tool": {"driver": {"name": "Grype"}}, "results": [{"ruleIndex": 3}]}]}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateSARIF,
+				Error:         "malformed attestation data: missing runs[0].results.ruleId",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "sarif unsupported version",
+			data: statement(PredicateSARIF, `{"version": "2.0.0", "runs": []}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateSARIF,
+				Error:         `malformed attestation data: unsupported SARIF version "2.0.0", expecting 2.1.0`,
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "sarif without runs",
+			data: statement(PredicateSARIF, `{"version": "2.1.0"}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateSARIF,
+				Error:         "malformed attestation data: missing runs",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "sarif without tool name",
+			data: statement(PredicateSARIF, `{"version": "2.1.0", "runs": [{"tool": {"driver": {}}}]}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateSARIF,
+				Error:         "malformed attestation data: missing runs[0].tool.driver.name",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "cosign vuln with trivy result",
+			data: statement(PredicateCosignVuln, fmt.Sprintf(`{
+				"scanner": {"uri": "pkg:github/aquasecurity/trivy@v0.52.0", "version": "0.52.0", "result": %s},
+				"metadata": {"scanStartedOn": "2024-06-01T09:59:00Z", "scanFinishedOn": "2024-06-01T10:00:00Z"}
+			}`, trivyReport)),
+			scan: VulnerabilityScan{
+				PredicateType:  PredicateCosignVuln,
+				Scanner:        "pkg:github/aquasecurity/trivy@v0.52.0",
+				ScannerVersion: "0.52.0",
+				ScanTime:       "2024-06-01T10:00:00Z",
+			},
+			findings: func() []Finding {
+				findings := make([]Finding, 0, len(trivyFindings))
+				for _, f := range trivyFindings {
+					f.Scanner = "pkg:github/aquasecurity/trivy@v0.52.0"
+					findings = append(findings, f)
+				}
+				return findings
+			}(),
+		},
+		{
+			name: "cosign vuln without result",
+			data: statement(PredicateCosignVuln, `{
+				"scanner": {"uri": "pkg:github/aquasecurity/trivy@v0.52.0"},
+				"metadata": {"scanStartedOn": "2024-06-01T09:59:00Z"}
+			}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateCosignVuln,
+				Scanner:       "pkg:github/aquasecurity/trivy@v0.52.0",
+				ScanTime:      "2024-06-01T09:59:00Z",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "cosign vuln with unsupported result",
+			data: statement(PredicateCosignVuln, `{
+				"scanner": {"uri": "pkg:github/anchore/grype@v0.79.0", "result": {"matches": []}}
+			}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateCosignVuln,
+				Scanner:       "pkg:github/anchore/grype@v0.79.0",
+				Error:         "unsupported scanner result, expecting a Trivy JSON or a SARIF report",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "cosign vuln with malformed result",
+			data: statement(PredicateCosignVuln, `{
+				"scanner": {"uri": "pkg:github/aquasecurity/trivy@v0.52.0", "result": {"SchemaVersion": 2, "Results": [{"Vulnerabilities": [{}]}]}}
+			}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateCosignVuln,
+				Scanner:       "pkg:github/aquasecurity/trivy@v0.52.0",
+				Error:         "missing VulnerabilityID",
+			},
+			findings: []Finding{},
+		},
+		{
+			name: "cosign vuln without scanner",
+			data: statement(PredicateCosignVuln, `{"scanner": {}}`),
+			scan: VulnerabilityScan{
+				PredicateType: PredicateCosignVuln,
+				Error:         "malformed attestation data: missing scanner.uri",
+			},
+			findings: []Finding{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sig := mockSignature{&mock.Mock{}}
+			sig.On("MediaType").Return(types.MediaType(ct.DssePayloadType), nil)
+			sig.On("Uncompressed").Return(buffy(
+				fmt.Sprintf(`{"payload": "%s", "signatures": [{"keyid": "key-id-1", "sig": "sig-1"}]}`, encode(c.data)),
+			), nil)
+			sig.On("Base64Signature").Return("", nil)
+			sig.On("Cert").Return(&x509.Certificate{}, nil)
+			sig.On("Chain").Return([]*x509.Certificate{}, nil)
+
+			vr, err := VulnerabilityReportFromSignature(sig)
+			if c.err != "" {
+				assert.Nil(t, vr)
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, c.scan, vr.Scan())
+			assert.Equal(t, c.findings, vr.Findings())
+			assert.Equal(t, c.scan.PredicateType, vr.PredicateType())
+			assert.Equal(t, "https://in-toto.io/Statement/v0.1", vr.Type())
+			assert.JSONEq(t, c.data, string(vr.Statement()))
+			require.Len(t, vr.Subject(), 1)
+			require.Len(t, vr.Signatures(), 1)
+			assert.Equal(t, "key-id-1", vr.Signatures()[0].KeyID)
+
+			j, err := json.Marshal(vr)
+			require.NoError(t, err)
+			assert.JSONEq(t, fmt.Sprintf(`{
+				"type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": %q,
+				"scanner": %q,
+				"signatures": [{"keyid": "key-id-1", "sig": "sig-1", "discovery": "tag"}]
+			}`, c.scan.PredicateType, c.scan.Scanner), string(j))
+		})
+	}
+}
+
+func TestVulnerabilityReportFromSignatureNilSignature(t *testing.T) {
+	vr, err := VulnerabilityReportFromSignature(nil)
+	assert.EqualError(t, err, "no attestation found")
+	assert.Nil(t, vr)
+}
+
+func TestIsVulnerabilityReport(t *testing.T) {
+	assert.True(t, IsVulnerabilityReport(PredicateCosignVuln))
+	assert.True(t, IsVulnerabilityReport(PredicateSARIF))
+	assert.True(t, IsVulnerabilityReport(PredicateTrivy))
+	assert.False(t, IsVulnerabilityReport(PredicateSLSAProvenanceV1))
+}
@@ -0,0 +1,297 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package auth provides the credentials for accessing the container image
+// registries. Besides the default Docker/Podman configuration, credentials can
+// be provided explicitly via an auth file, per-registry token files, image pull
+// secrets from a Kubernetes cluster and Docker credential helpers.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+)
+
+type contextKey string
+
+const keychainContextKey contextKey = "ec.auth.keychain"
+
+// dockerHub is the registry name Docker Hub credentials are stored under
+const dockerHub = "index.docker.io"
+
+// Options holds the explicit credential sources
+type Options struct {
+	// AuthFile is the path to a Docker config.json formatted file
+	AuthFile string
+	// TokenFiles maps registries to files holding the registry token
+	TokenFiles []string
+	// PullSecrets are references, [<namespace>/]<name>, to Kubernetes image
+	// pull secrets
+	PullSecrets []string
+	// CredentialHelpers maps registries to Docker credential helpers
+	CredentialHelpers []string
+}
+
+var options Options
+
+// AddFlags adds the flags for the explicit credential sources.
+func AddFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&options.AuthFile, "registry-auth-file", "",
+		"path to the Docker config.json formatted file with the registry credentials")
+	cmd.PersistentFlags().StringArrayVar(&options.TokenFiles, "registry-token", nil,
+		"registry token in the format REGISTRY=FILE, where the FILE contains the token for the REGISTRY, can be repeated")
+	cmd.PersistentFlags().StringArrayVar(&options.PullSecrets, "registry-pull-secret", nil,
+		"Kubernetes image pull secret with the registry credentials in the format [<namespace>/]<name>, can be repeated")
+	cmd.PersistentFlags().StringArrayVar(&options.CredentialHelpers, "registry-credential-helper", nil,
+		"Docker credential helper in the format REGISTRY=HELPER providing the credentials for the REGISTRY, where HELPER is the path to the helper or the suffix of the docker-credential-<HELPER> executable, can be repeated")
+}
+
+// Keychain resolves the registry credentials from the explicit credential
+// sources in order: token files, credential helpers, the auth file and the
+// pull secrets, falling back to the default Docker/Podman configuration.
+type Keychain struct {
+	explicit []authn.Keychain
+}
+
+// Resolve implements authn.Keychain.
+func (k *Keychain) Resolve(r authn.Resource) (authn.Authenticator, error) {
+	a, err := k.resolveExplicit(r)
+	if err != nil || a != authn.Anonymous {
+		return a, err
+	}
+
+	return authn.DefaultKeychain.Resolve(r)
+}
+
+func (k *Keychain) resolveExplicit(r authn.Resource) (authn.Authenticator, error) {
+	return authn.NewMultiKeychain(k.explicit...).Resolve(r)
+}
+
+// NewKeychain creates a Keychain from the explicit credential sources set via
+// the command line flags.
+func NewKeychain(ctx context.Context) (*Keychain, error) {
+	return NewKeychainFrom(ctx, options)
+}
+
+// NewKeychainFrom creates a Keychain from the given explicit credential
+// sources. Image pull secrets are fetched from the Kubernetes cluster.
+func NewKeychainFrom(ctx context.Context, opts Options) (*Keychain, error) {
+	k := &Keychain{}
+
+	if len(opts.TokenFiles) > 0 {
+		tokens := staticKeychain{}
+		for _, t := range opts.TokenFiles {
+			registry, file, err := keyValue(t)
+			if err != nil {
+				return nil, fmt.Errorf("invalid registry token %q: %w", t, err)
+			}
+			token, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("reading the token for %s: %w", registry, err)
+			}
+			tokens.add(registry, authn.AuthConfig{RegistryToken: strings.TrimSpace(string(token))})
+		}
+		k.explicit = append(k.explicit, tokens)
+	}
+
+	if len(opts.CredentialHelpers) > 0 {
+		helpers := helperKeychain{}
+		for _, h := range opts.CredentialHelpers {
+			registry, helper, err := keyValue(h)
+			if err != nil {
+				return nil, fmt.Errorf("invalid registry credential helper %q: %w", h, err)
+			}
+			helpers[normalize(registry)] = helper
+		}
+		k.explicit = append(k.explicit, helpers)
+	}
+
+	if opts.AuthFile != "" {
+		data, err := os.ReadFile(opts.AuthFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading the registry auth file: %w", err)
+		}
+		keychains, err := parseDockerConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the registry auth file %s: %w", opts.AuthFile, err)
+		}
+		k.explicit = append(k.explicit, keychains...)
+	}
+
+	if len(opts.PullSecrets) > 0 {
+		client, err := kubernetes.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range opts.PullSecrets {
+			data, err := client.FetchSecret(ctx, ref)
+			if err != nil {
+				return nil, fmt.Errorf("fetching the image pull secret %s: %w", ref, err)
+			}
+			config, ok := data[".dockerconfigjson"]
+			if !ok {
+				config, ok = data[".dockercfg"]
+			}
+			if !ok {
+				return nil, fmt.Errorf("the image pull secret %s has neither .dockerconfigjson nor .dockercfg data", ref)
+			}
+			keychains, err := parseDockerConfig(config)
+			if err != nil {
+				return nil, fmt.Errorf("parsing the image pull secret %s: %w", ref, err)
+			}
+			k.explicit = append(k.explicit, keychains...)
+		}
+	}
+
+	log.Debugf("Using %d explicit registry credential sources", len(k.explicit))
+
+	return k, nil
+}
+
+// WithKeychain returns a context in which the registry credentials are
+// resolved using the given Keychain.
+func WithKeychain(ctx context.Context, k *Keychain) context.Context {
+	return context.WithValue(ctx, keychainContextKey, k)
+}
+
+// KeychainFromContext returns the Keychain to resolve the registry
+// credentials with, by default the Docker/Podman configuration.
+func KeychainFromContext(ctx context.Context) authn.Keychain {
+	if k, ok := ctx.Value(keychainContextKey).(*Keychain); ok && k != nil {
+		return k
+	}
+
+	return authn.DefaultKeychain
+}
+
+func keyValue(s string) (string, string, error) {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" || v == "" {
+		return "", "", errors.New("expected the format REGISTRY=VALUE")
+	}
+
+	return k, v, nil
+}
+
+// normalize returns the registry, optionally with the repository, as used for
+// matching credentials, e.g. "https://index.docker.io/v1/" becomes
+// "index.docker.io".
+func normalize(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	key = strings.TrimSuffix(key, "/")
+	key = strings.TrimSuffix(key, "/v1")
+	key = strings.TrimSuffix(key, "/v2")
+
+	registry, repository, _ := strings.Cut(key, "/")
+	if registry == name.DefaultRegistry || registry == "registry-1.docker.io" {
+		registry = dockerHub
+	}
+
+	if repository == "" {
+		return registry
+	}
+
+	return registry + "/" + repository
+}
+
+// candidates returns the keys credentials for the resource can be stored
+// under, from the most specific, e.g. "registry.io/org/repo",
+// "registry.io/org" and "registry.io".
+func candidates(r authn.Resource) []string {
+	key := normalize(r.String())
+
+	keys := []string{key}
+	for i := strings.LastIndex(key, "/"); i != -1; i = strings.LastIndex(key, "/") {
+		key = key[:i]
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// staticKeychain holds credentials by registry, optionally with the
+// repository
+type staticKeychain map[string]authn.Authenticator
+
+func (s staticKeychain) add(key string, config authn.AuthConfig) {
+	if config == (authn.AuthConfig{}) {
+		return
+	}
+	s[normalize(key)] = authn.FromConfig(config)
+}
+
+func (s staticKeychain) Resolve(r authn.Resource) (authn.Authenticator, error) {
+	for _, key := range candidates(r) {
+		if a, ok := s[key]; ok {
+			return a, nil
+		}
+	}
+
+	return authn.Anonymous, nil
+}
+
+type dockerConfig struct {
+	Auths       map[string]authn.AuthConfig `json:"auths"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+}
+
+// parseDockerConfig returns the keychains for the credentials in the Docker
+// config.json, or the legacy .dockercfg, format.
+func parseDockerConfig(data []byte) ([]authn.Keychain, error) {
+	config := dockerConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Auths == nil && config.CredHelpers == nil {
+		// the legacy format holds just the credentials by registry
+		if err := json.Unmarshal(data, &config.Auths); err != nil {
+			return nil, err
+		}
+	}
+
+	var keychains []authn.Keychain
+
+	if len(config.CredHelpers) > 0 {
+		helpers := helperKeychain{}
+		for registry, helper := range config.CredHelpers {
+			helpers[normalize(registry)] = helper
+		}
+		keychains = append(keychains, helpers)
+	}
+
+	if len(config.Auths) > 0 {
+		auths := staticKeychain{}
+		for registry, a := range config.Auths {
+			auths.add(registry, a)
+		}
+		keychains = append(keychains, auths)
+	}
+
+	return keychains, nil
+}
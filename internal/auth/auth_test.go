@@ -0,0 +1,211 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+)
+
+type fakeKubernetesClient struct {
+	secrets map[string]map[string][]byte
+}
+
+func (fakeKubernetesClient) FetchEnterpriseContractPolicy(context.Context, string) (*ecc.EnterpriseContractPolicy, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeKubernetesClient) FetchSnapshot(context.Context, string) (*app.Snapshot, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeKubernetesClient) FetchNamespaceLabels(context.Context, string) (map[string]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f fakeKubernetesClient) FetchSecret(_ context.Context, ref string) (map[string][]byte, error) {
+	if s, ok := f.secrets[ref]; ok {
+		return s, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func write(t *testing.T, name, content string, perm os.FileMode) string {
+	p := path.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(p, []byte(content), perm))
+	return p
+}
+
+func authorization(t *testing.T, k authn.Keychain, ref string) *authn.AuthConfig {
+	repository, err := name.NewRepository(ref)
+	require.NoError(t, err)
+
+	a, err := k.Resolve(repository)
+	require.NoError(t, err)
+
+	if a == authn.Anonymous {
+		return nil
+	}
+
+	config, err := a.Authorization()
+	require.NoError(t, err)
+
+	// the encoded username and password are set when parsing
+	c := *config
+	c.Auth = ""
+
+	return &c
+}
+
+func TestKeychain(t *testing.T) {
+	// no default credentials
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("REGISTRY_AUTH_FILE", path.Join(t.TempDir(), "auth.json"))
+
+	authFile := write(t, "config.json", `{
+		"auths": {
+			"registry.io": {"auth": "dXNlcjpwYXNz"},
+			"registry.io/org/special": {"username": "special", "password": "secret"},
+			"https://index.docker.io/v1/": {"username": "hub", "password": "hub"}
+		}
+	}`, 0600)
+	token := write(t, "token", "t0k3n\n", 0600)
+	helper := write(t, "helper", `#!/bin/sh
+read server
+echo "{\"Username\": \"<token>\", \"Secret\": \"identity-for-$server\"}"
+`, 0700)
+
+	ctx := kubernetes.WithClient(context.Background(), fakeKubernetesClient{
+		secrets: map[string]map[string][]byte{
+			"ns/pull":   {".dockerconfigjson": []byte(`{"auths": {"secret.io": {"username": "from", "password": "secret"}}}`)},
+			"ns/legacy": {".dockercfg": []byte(`{"legacy.io": {"username": "legacy", "password": "secret"}}`)},
+		},
+	})
+
+	k, err := NewKeychainFrom(ctx, Options{
+		AuthFile:          authFile,
+		TokenFiles:        []string{"token.io=" + token, "registry.io/org/tokened=" + token},
+		PullSecrets:       []string{"ns/pull", "ns/legacy"},
+		CredentialHelpers: []string{"helper.io=" + helper},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		ref      string
+		expected *authn.AuthConfig
+	}{
+		{"registry.io/org/repo", &authn.AuthConfig{Username: "user", Password: "pass"}},
+		{"registry.io/org/special", &authn.AuthConfig{Username: "special", Password: "secret"}},
+		{"registry.io/org/special/nested", &authn.AuthConfig{Username: "special", Password: "secret"}},
+		{"registry.io/org/tokened", &authn.AuthConfig{RegistryToken: "t0k3n"}},
+		{"token.io/repo", &authn.AuthConfig{RegistryToken: "t0k3n"}},
+		{"docker.io/library/busybox", &authn.AuthConfig{Username: "hub", Password: "hub"}},
+		{"secret.io/repo", &authn.AuthConfig{Username: "from", Password: "secret"}},
+		{"legacy.io/repo", &authn.AuthConfig{Username: "legacy", Password: "secret"}},
+		{"helper.io/repo", &authn.AuthConfig{IdentityToken: "identity-for-helper.io"}},
+		{"unknown.io/repo", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.ref, func(t *testing.T) {
+			assert.Equal(t, c.expected, authorization(t, k, c.ref))
+		})
+	}
+}
+
+func TestKeychainErrors(t *testing.T) {
+	ctx := kubernetes.WithClient(context.Background(), fakeKubernetesClient{
+		secrets: map[string]map[string][]byte{
+			"ns/opaque": {"key": []byte("value")},
+		},
+	})
+
+	cases := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{"invalid token", Options{TokenFiles: []string{"registry.io"}}, `invalid registry token "registry.io": expected the format REGISTRY=VALUE`},
+		{"missing token", Options{TokenFiles: []string{"registry.io=/nonexistent"}}, "reading the token for registry.io: open /nonexistent: no such file or directory"},
+		{"invalid helper", Options{CredentialHelpers: []string{"=helper"}}, `invalid registry credential helper "=helper": expected the format REGISTRY=VALUE`},
+		{"missing auth file", Options{AuthFile: "/nonexistent"}, "reading the registry auth file: open /nonexistent: no such file or directory"},
+		{"missing pull secret", Options{PullSecrets: []string{"ns/missing"}}, "fetching the image pull secret ns/missing: not found"},
+		{"opaque pull secret", Options{PullSecrets: []string{"ns/opaque"}}, "the image pull secret ns/opaque has neither .dockerconfigjson nor .dockercfg data"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewKeychainFrom(ctx, c.opts)
+			assert.EqualError(t, err, c.expected)
+		})
+	}
+}
+
+func TestCredentialHelperFailure(t *testing.T) {
+	helper := write(t, "helper", "#!/bin/sh\necho 'credentials not found' >&2\nexit 1\n", 0700)
+
+	k, err := NewKeychainFrom(context.Background(), Options{CredentialHelpers: []string{"helper.io=" + helper}})
+	require.NoError(t, err)
+
+	repository, err := name.NewRepository("helper.io/repo")
+	require.NoError(t, err)
+	a, err := k.Resolve(repository)
+	require.NoError(t, err)
+
+	_, err = a.Authorization()
+	assert.ErrorContains(t, err, "credentials not found")
+}
+
+func TestCredentialHelperFailureOmitsOutput(t *testing.T) {
+	helper := write(t, "helper", `#!/bin/sh
+echo '{"Username":"user","Secret":"s3cr3t"}'
+exit 1
+`, 0700)
+
+	k, err := NewKeychainFrom(context.Background(), Options{CredentialHelpers: []string{"helper.io=" + helper}})
+	require.NoError(t, err)
+
+	repository, err := name.NewRepository("helper.io/repo")
+	require.NoError(t, err)
+	a, err := k.Resolve(repository)
+	require.NoError(t, err)
+
+	_, err = a.Authorization()
+	assert.ErrorContains(t, err, "exit status 1")
+	assert.NotContains(t, err.Error(), "s3cr3t")
+}
+
+func TestKeychainFromContext(t *testing.T) {
+	assert.Same(t, authn.DefaultKeychain, KeychainFromContext(context.Background()))
+
+	k := &Keychain{}
+	assert.Same(t, k, KeychainFromContext(WithKeychain(context.Background(), k)))
+}
@@ -0,0 +1,102 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// identityTokenUsername is the username credential helpers report for
+// identity tokens
+const identityTokenUsername = "<token>"
+
+// helperKeychain holds the Docker credential helpers by registry
+type helperKeychain map[string]string
+
+func (h helperKeychain) Resolve(r authn.Resource) (authn.Authenticator, error) {
+	registry := normalize(r.RegistryStr())
+	helper, ok := h[registry]
+	if !ok {
+		return authn.Anonymous, nil
+	}
+
+	return &helperAuthenticator{helper: helper, serverURL: r.RegistryStr()}, nil
+}
+
+// helperAuthenticator runs the credential helper once, when the credentials
+// are needed, using the Docker credential helper protocol: the server URL is
+// provided on the standard input of the `get` command, which outputs the
+// credentials as JSON.
+type helperAuthenticator struct {
+	helper    string
+	serverURL string
+	once      sync.Once
+	config    *authn.AuthConfig
+	err       error
+}
+
+type helperCredentials struct {
+	Username string
+	Secret   string
+}
+
+func (h *helperAuthenticator) Authorization() (*authn.AuthConfig, error) {
+	h.once.Do(func() {
+		h.config, h.err = h.run()
+	})
+
+	return h.config, h.err
+}
+
+func (h *helperAuthenticator) run() (*authn.AuthConfig, error) {
+	command := h.helper
+	if !strings.ContainsRune(command, '/') {
+		command = "docker-credential-" + command
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	cmd := exec.Command(command, "get") // #nosec G204 -- the helper is provided by the user
+	cmd.Stdin = strings.NewReader(h.serverURL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// the standard output is never included, it can hold the credentials
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("running the credential helper %s for %s: %w: %s", command, h.serverURL, err, msg)
+		}
+		return nil, fmt.Errorf("running the credential helper %s for %s: %w", command, h.serverURL, err)
+	}
+
+	creds := helperCredentials{}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return nil, fmt.Errorf("parsing the output of the credential helper %s for %s: %w", command, h.serverURL, err)
+	}
+
+	if creds.Username == identityTokenUsername {
+		return &authn.AuthConfig{IdentityToken: creds.Secret}, nil
+	}
+
+	return &authn.AuthConfig{Username: creds.Username, Password: creds.Secret}, nil
+}
@@ -0,0 +1,115 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// setupTimeout limits the time spent on the ping and the token exchange when
+// setting up the authenticating transport of a repository
+const setupTimeout = 30 * time.Second
+
+// repositoryPath matches the repository name in the registry API paths
+var repositoryPath = regexp.MustCompile(`^/v2/(.+)/(?:manifests|blobs|tags|referrers)/`)
+
+type transportKey struct {
+	keychain *Keychain
+	scope    string
+}
+
+// authTransport authenticates the registry requests with the explicit
+// credentials from the Keychain of the request context.
+type authTransport struct {
+	base       http.RoundTripper
+	mu         sync.Mutex
+	transports map[transportKey]http.RoundTripper
+}
+
+// NewTransport returns a RoundTripper authenticating the registry requests
+// using the explicit credentials of the Keychain in the request context. This
+// is for clients which do not resolve the credentials using the Keychain, e.g.
+// OCI policy downloads. Requests without explicit credentials are sent as is.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return &authTransport{base: base, transports: map[transportKey]http.RoundTripper{}}
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	k, ok := ctx.Value(keychainContextKey).(*Keychain)
+	if !ok || k == nil || len(k.explicit) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	m := repositoryPath.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	var opts []name.Option
+	if req.URL.Scheme == "http" {
+		opts = append(opts, name.Insecure)
+	}
+	repository, err := name.NewRepository(fmt.Sprintf("%s/%s", req.URL.Host, m[1]), opts...)
+	if err != nil {
+		return t.base.RoundTrip(req)
+	}
+
+	a, err := k.resolveExplicit(repository)
+	if err != nil {
+		return nil, err
+	}
+	if a == authn.Anonymous {
+		return t.base.RoundTrip(req)
+	}
+
+	scope := repository.Scope(transport.PullScope)
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+	default:
+		scope = repository.Scope(transport.PushScope)
+	}
+
+	key := transportKey{k, scope}
+	t.mu.Lock()
+	rt, ok := t.transports[key]
+	t.mu.Unlock()
+	if !ok {
+		// the transport is reused by other requests, so its setup must not
+		// be bound to the cancellation of this request
+		setupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), setupTimeout)
+		rt, err = transport.NewWithContext(setupCtx, repository.Registry, a, t.base, []string{scope})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		t.mu.Lock()
+		t.transports[key] = rt
+		t.mu.Unlock()
+	}
+
+	return rt.RoundTrip(req)
+}
@@ -0,0 +1,103 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	handler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	authFile := write(t, "config.json", fmt.Sprintf(`{"auths": {"%s/org": {"username": "user", "password": "pass"}}}`, u.Host), 0600)
+	k, err := NewKeychainFrom(context.Background(), Options{AuthFile: authFile})
+	require.NoError(t, err)
+
+	get := func(ctx context.Context, path string) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+
+		resp, err := NewTransport(http.DefaultTransport).RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	ctx := WithKeychain(context.Background(), k)
+
+	// authenticated using the explicit credentials
+	assert.Equal(t, http.StatusNotFound, get(ctx, "/v2/org/repo/manifests/latest"))
+	// no credentials for the repository
+	assert.Equal(t, http.StatusUnauthorized, get(ctx, "/v2/other/repo/manifests/latest"))
+	// no keychain
+	assert.Equal(t, http.StatusUnauthorized, get(context.Background(), "/v2/org/repo/manifests/latest"))
+}
+
+func TestTransportDetachedFromRequest(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	authFile := write(t, "config.json", fmt.Sprintf(`{"auths": {"%s/org": {"username": "user", "password": "pass"}}}`, u.Host), 0600)
+	k, err := NewKeychainFrom(context.Background(), Options{AuthFile: authFile})
+	require.NoError(t, err)
+
+	rt := NewTransport(http.DefaultTransport)
+
+	// the request is canceled, but the transport of the repository is set up
+	ctx, cancel := context.WithCancel(WithKeychain(context.Background(), k))
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v2/org/repo/manifests/latest", nil)
+	require.NoError(t, err)
+	_, err = rt.RoundTrip(req)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, rt.(*authTransport).transports, 1)
+
+	// and reused by the following requests
+	req, err = http.NewRequestWithContext(WithKeychain(context.Background(), k), http.MethodGet, server.URL+"/v2/org/repo/manifests/latest", nil)
+	require.NoError(t, err)
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
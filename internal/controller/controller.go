@@ -0,0 +1,415 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package controller implements a Kubernetes controller validating Snapshot
+// resources. Each Snapshot is validated against the EnterpriseContractPolicy
+// it references and the result is written back to the Snapshot annotations.
+// Snapshots are validated again whenever they, or the referenced policy,
+// change.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/server"
+)
+
+const (
+	// PolicyAnnotation is the Snapshot annotation holding the reference, as
+	// [<namespace>/]<name>, to the EnterpriseContractPolicy to validate the
+	// Snapshot against. The namespace defaults to the one of the Snapshot.
+	PolicyAnnotation = "enterprisecontract.dev/policy"
+
+	// ResultAnnotation is the Snapshot annotation the result of the validation
+	// is written to, in the same format as the TEST_OUTPUT Tekton task result.
+	ResultAnnotation = "enterprisecontract.dev/result"
+
+	// ValidatedAnnotation is the Snapshot annotation recording the generations
+	// of the Snapshot and of the policy that were last validated, as
+	// <snapshot generation>:<policy namespace>/<policy name>:<policy generation>
+	ValidatedAnnotation = "enterprisecontract.dev/validated"
+)
+
+var (
+	snapshotsResource = app.GroupVersion.WithResource("snapshots")
+	policiesResource  = ecc.GroupVersion.WithResource("enterprisecontractpolicies")
+)
+
+// Validator validates the images of a Snapshot.
+type Validator interface {
+	Validate(context.Context, server.ValidateImageRequest) (applicationsnapshot.Report, error)
+}
+
+// Options configure the Controller.
+type Options struct {
+	// Namespace to watch, all namespaces if empty.
+	Namespace string
+	// Policy is the reference, as <namespace>/<name>, to the
+	// EnterpriseContractPolicy used for Snapshots without the policy
+	// annotation. Such Snapshots are not validated when it is not set.
+	Policy string
+	// Workers is the number of Snapshots validated concurrently.
+	Workers int
+	// ResyncPeriod is the interval at which all Snapshots are reconciled
+	// again, zero disables the periodic resync.
+	ResyncPeriod time.Duration
+}
+
+// Controller validates Snapshot resources.
+type Controller struct {
+	opts      Options
+	client    dynamic.Interface
+	validator Validator
+	queue     workqueue.TypedRateLimitingInterface[string]
+	snapshots cache.GenericLister
+
+	mu sync.Mutex
+	// dependents holds the keys of the Snapshots validated against each
+	// policy, by the key of the policy
+	dependents map[string]map[string]bool
+	// validated holds the value of the validated annotation written to each
+	// Snapshot, by the key of the Snapshot, as the cached Snapshots might not
+	// reflect the update yet
+	validated map[string]string
+}
+
+// New creates a Controller using the client to watch and update the
+// resources, and the validator to validate the Snapshots.
+func New(client dynamic.Interface, validator Validator, opts Options) *Controller {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	return &Controller{
+		opts:       opts,
+		client:     client,
+		validator:  validator,
+		queue:      workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[string]()),
+		dependents: map[string]map[string]bool{},
+		validated:  map[string]string{},
+	}
+}
+
+// Run watches the Snapshot and EnterpriseContractPolicy resources and
+// validates the Snapshots until the given context is done.
+func (c *Controller) Run(ctx context.Context) error {
+	defer c.queue.ShutDown()
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.client, c.opts.ResyncPeriod, c.opts.Namespace, nil)
+
+	snapshots := factory.ForResource(snapshotsResource)
+	if _, err := snapshots.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, obj any) { c.enqueue(obj) },
+		DeleteFunc: c.enqueue,
+	}); err != nil {
+		return err
+	}
+	c.snapshots = snapshots.Lister()
+
+	policies := factory.ForResource(policiesResource)
+	if _, err := policies.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.policyChanged,
+		UpdateFunc: func(_, obj any) { c.policyChanged(obj) },
+	}); err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+
+	if !cache.WaitForCacheSync(ctx.Done(), snapshots.Informer().HasSynced, policies.Informer().HasSynced) {
+		return errors.New("unable to sync the Snapshot and EnterpriseContractPolicy caches")
+	}
+
+	log.Infof("Watching Snapshots with %d workers", c.opts.Workers)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < c.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c.processNext(ctx) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	log.Info("Shutting down")
+	c.queue.ShutDown()
+	wg.Wait()
+
+	return nil
+}
+
+func (c *Controller) enqueue(obj any) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Warnf("Unable to determine the key of %v: %v", obj, err)
+		return
+	}
+
+	c.queue.Add(key)
+}
+
+// policyChanged queues the Snapshots validated against the policy, so that
+// they're validated against the changed policy.
+func (c *Controller) policyChanged(obj any) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Warnf("Unable to determine the key of %v: %v", obj, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for snapshot := range c.dependents[key] {
+		log.Debugf("Policy %s changed, validating Snapshot %s", key, snapshot)
+		c.queue.Add(snapshot)
+	}
+}
+
+// track records that the Snapshot is validated against the policy, an empty
+// policy removes the Snapshot from tracking.
+func (c *Controller) track(snapshot, policy string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, snapshots := range c.dependents {
+		if key != policy {
+			delete(snapshots, snapshot)
+			if len(snapshots) == 0 {
+				delete(c.dependents, key)
+			}
+		}
+	}
+
+	if policy == "" {
+		delete(c.validated, snapshot)
+		return
+	}
+
+	if _, ok := c.dependents[policy]; !ok {
+		c.dependents[policy] = map[string]bool{}
+	}
+	c.dependents[policy][snapshot] = true
+}
+
+func (c *Controller) processNext(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.reconcile(ctx, key); err != nil {
+		log.Warnf("Unable to validate Snapshot %s, retrying: %v", key, err)
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+
+	return true
+}
+
+// reconcile validates the Snapshot with the given key, unless the Snapshot
+// was already validated against the current version of the policy.
+func (c *Controller) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	obj, err := c.snapshots.ByNamespace(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		c.track(key, "")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	snapshot := app.Snapshot{}
+	if err := fromUnstructured(obj, &snapshot); err != nil {
+		return err
+	}
+
+	policyRef := c.policyRef(snapshot)
+	c.track(key, policyRef)
+	if policyRef == "" {
+		log.Debugf("No policy configured for Snapshot %s", key)
+		return nil
+	}
+
+	policyNamespace, policyName, err := cache.SplitMetaNamespaceKey(policyRef)
+	if err != nil {
+		return err
+	}
+
+	policyObj, err := c.client.Resource(policiesResource).Namespace(policyNamespace).Get(ctx, policyName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// validated once the policy is created
+		return c.writeResult(ctx, snapshot, "", applicationsnapshot.AppstudioReportForError("policy not found", err))
+	} else if err != nil {
+		return err
+	}
+
+	policy := ecc.EnterpriseContractPolicy{}
+	if err := fromUnstructured(policyObj, &policy); err != nil {
+		return err
+	}
+
+	validated := fmt.Sprintf("%d:%s:%d", snapshot.Generation, policyRef, policy.Generation)
+	if snapshot.Annotations[ValidatedAnnotation] == validated || c.isValidated(key, validated) {
+		log.Debugf("Snapshot %s already validated", key)
+		return nil
+	}
+
+	// the policy is provided inline, so that any changes to it are picked up
+	// regardless of the caching of prepared policies
+	policyConfig, err := json.Marshal(ecc.EnterpriseContractPolicy{TypeMeta: policy.TypeMeta, Spec: policy.Spec})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Validating Snapshot %s against policy %s", key, policyRef)
+	report, err := c.validator.Validate(ctx, server.ValidateImageRequest{
+		Snapshot: snapshot.Spec,
+		Policy:   string(policyConfig),
+	})
+	if err != nil {
+		// the failure is recorded, the validation is retried
+		return errors.Join(err, c.writeResult(ctx, snapshot, "", applicationsnapshot.AppstudioReportForError("validation failed", err)))
+	}
+
+	result := report.ToAppstudioReport()
+	result.DeriveNote()
+
+	if err := c.writeResult(ctx, snapshot, validated, result); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.validated[key] = validated
+
+	return nil
+}
+
+func (c *Controller) isValidated(key, validated string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.validated[key] == validated
+}
+
+// policyRef returns the key, as <namespace>/<name>, of the policy to validate
+// the Snapshot against.
+func (c *Controller) policyRef(snapshot app.Snapshot) string {
+	ref, ok := snapshot.Annotations[PolicyAnnotation]
+	if !ok {
+		ref = c.opts.Policy
+	}
+
+	if ref == "" || strings.Contains(ref, "/") {
+		return ref
+	}
+
+	return fmt.Sprintf("%s/%s", snapshot.Namespace, ref)
+}
+
+// writeResult records the result of the validation in the Snapshot
+// annotations. The Snapshot is not updated if the result didn't change, as
+// each update leads to another reconciliation of the Snapshot.
+func (c *Controller) writeResult(ctx context.Context, snapshot app.Snapshot, validated string, result applicationsnapshot.TestReport) error {
+	if sameResult(snapshot, validated, result) {
+		return nil
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]any{
+		ResultAnnotation: string(value),
+	}
+	if validated != "" {
+		annotations[ValidatedAnnotation] = validated
+	} else {
+		// removes the annotation so that the Snapshot is validated again
+		annotations[ValidatedAnnotation] = nil
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.Resource(snapshotsResource).Namespace(snapshot.Namespace).Patch(ctx, snapshot.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+
+	return err
+}
+
+// sameResult returns true if the Snapshot annotations hold the same result,
+// regardless of the time of the validation.
+func sameResult(snapshot app.Snapshot, validated string, result applicationsnapshot.TestReport) bool {
+	if snapshot.Annotations[ValidatedAnnotation] != validated {
+		return false
+	}
+
+	var existing applicationsnapshot.TestReport
+	if err := json.Unmarshal([]byte(snapshot.Annotations[ResultAnnotation]), &existing); err != nil {
+		return false
+	}
+	existing.Timestamp = result.Timestamp
+
+	return existing == result
+}
+
+func fromUnstructured(obj runtime.Object, into any) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), into)
+}
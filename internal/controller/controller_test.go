@@ -0,0 +1,237 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/server"
+)
+
+type fakeValidator struct {
+	mu       sync.Mutex
+	requests []server.ValidateImageRequest
+	err      error
+}
+
+func (v *fakeValidator) Validate(_ context.Context, req server.ValidateImageRequest) (applicationsnapshot.Report, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.requests = append(v.requests, req)
+
+	if v.err != nil {
+		return applicationsnapshot.Report{}, v.err
+	}
+
+	var components []applicationsnapshot.Component
+	for _, c := range req.Snapshot.Components {
+		component := applicationsnapshot.Component{SnapshotComponent: c, Success: true, SuccessCount: 1}
+		if c.Name == "bad" {
+			component.Success = false
+			component.SuccessCount = 0
+			component.Violations = []evaluator.Result{{Message: "bad"}}
+		}
+		components = append(components, component)
+	}
+
+	return applicationsnapshot.Report{Components: components}, nil
+}
+
+func (v *fakeValidator) calls() []server.ValidateImageRequest {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]server.ValidateImageRequest{}, v.requests...)
+}
+
+func toUnstructured(t *testing.T, obj any) *unstructured.Unstructured {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: u}
+}
+
+func snapshot(name string, annotations map[string]string, components ...string) *app.Snapshot {
+	s := &app.Snapshot{
+		TypeMeta:   metav1.TypeMeta{APIVersion: app.GroupVersion.String(), Kind: "Snapshot"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Generation: 1, Annotations: annotations},
+	}
+	for _, c := range components {
+		s.Spec.Components = append(s.Spec.Components, app.SnapshotComponent{Name: c, ContainerImage: "registry.io/" + c})
+	}
+	return s
+}
+
+func policy(name, key string, generation int64) *ecc.EnterpriseContractPolicy {
+	return &ecc.EnterpriseContractPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: ecc.GroupVersion.String(), Kind: "EnterpriseContractPolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Generation: generation},
+		Spec:       ecc.EnterpriseContractPolicySpec{PublicKey: key},
+	}
+}
+
+func newClient(t *testing.T, objects ...runtime.Object) *fake.FakeDynamicClient {
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		snapshotsResource: "SnapshotList",
+		policiesResource:  "EnterpriseContractPolicyList",
+	}, objects...)
+}
+
+func annotations(t *testing.T, client *fake.FakeDynamicClient, name string) map[string]string {
+	obj, err := client.Resource(snapshotsResource).Namespace("test").Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return obj.GetAnnotations()
+}
+
+func result(t *testing.T, annotations map[string]string) applicationsnapshot.TestReport {
+	var r applicationsnapshot.TestReport
+	require.NoError(t, json.Unmarshal([]byte(annotations[ResultAnnotation]), &r))
+	return r
+}
+
+func run(t *testing.T, c *Controller) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+}
+
+func TestController(t *testing.T) {
+	client := newClient(t,
+		toUnstructured(t, policy("ecp", "key", 1)),
+		toUnstructured(t, snapshot("good", map[string]string{PolicyAnnotation: "ecp"}, "a", "b")),
+		toUnstructured(t, snapshot("bad", map[string]string{PolicyAnnotation: "test/ecp"}, "bad")),
+		toUnstructured(t, snapshot("unvalidated", nil, "a")),
+	)
+	validator := fakeValidator{}
+
+	run(t, New(client, &validator, Options{Workers: 2}))
+
+	require.Eventually(t, func() bool {
+		return annotations(t, client, "good")[ValidatedAnnotation] != "" && annotations(t, client, "bad")[ValidatedAnnotation] != ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	good := annotations(t, client, "good")
+	assert.Equal(t, "1:test/ecp:1", good[ValidatedAnnotation])
+	assert.Equal(t, "SUCCESS", result(t, good).Result)
+	assert.Equal(t, 2, result(t, good).Successes)
+
+	bad := annotations(t, client, "bad")
+	assert.Equal(t, "1:test/ecp:1", bad[ValidatedAnnotation])
+	assert.Equal(t, "FAILURE", result(t, bad).Result)
+	assert.Equal(t, "Failures detected", result(t, bad).Note)
+
+	assert.NotContains(t, annotations(t, client, "unvalidated"), ResultAnnotation)
+
+	calls := validator.calls()
+	require.Len(t, calls, 2)
+	var p ecc.EnterpriseContractPolicy
+	require.NoError(t, json.Unmarshal([]byte(calls[0].Policy), &p))
+	assert.Equal(t, "key", p.Spec.PublicKey)
+
+	// changes to the policy lead to validating the Snapshots again
+	_, err := client.Resource(policiesResource).Namespace("test").Update(context.Background(), toUnstructured(t, policy("ecp", "changed", 2)), metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return annotations(t, client, "good")[ValidatedAnnotation] == "1:test/ecp:2" && annotations(t, client, "bad")[ValidatedAnnotation] == "1:test/ecp:2"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	calls = validator.calls()
+	require.Len(t, calls, 4)
+	require.NoError(t, json.Unmarshal([]byte(calls[3].Policy), &p))
+	assert.Equal(t, "changed", p.Spec.PublicKey)
+}
+
+func TestControllerDefaultPolicy(t *testing.T) {
+	client := newClient(t,
+		toUnstructured(t, policy("default", "key", 1)),
+		toUnstructured(t, snapshot("snapshot", nil, "a")),
+	)
+	validator := fakeValidator{}
+
+	run(t, New(client, &validator, Options{Policy: "test/default"}))
+
+	require.Eventually(t, func() bool {
+		return annotations(t, client, "snapshot")[ValidatedAnnotation] == "1:test/default:1"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestControllerErrors(t *testing.T) {
+	client := newClient(t,
+		toUnstructured(t, policy("ecp", "key", 1)),
+		toUnstructured(t, snapshot("missing", map[string]string{PolicyAnnotation: "missing"}, "a")),
+		toUnstructured(t, snapshot("failing", map[string]string{PolicyAnnotation: "ecp"}, "a")),
+	)
+	validator := fakeValidator{err: errors.New("kaboom")}
+
+	run(t, New(client, &validator, Options{}))
+
+	require.Eventually(t, func() bool {
+		return annotations(t, client, "missing")[ResultAnnotation] != "" && annotations(t, client, "failing")[ResultAnnotation] != ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	missing := annotations(t, client, "missing")
+	assert.NotContains(t, missing, ValidatedAnnotation)
+	assert.Equal(t, "ERROR", result(t, missing).Result)
+	assert.Contains(t, result(t, missing).Note, "Error: policy not found")
+
+	failing := annotations(t, client, "failing")
+	assert.NotContains(t, failing, ValidatedAnnotation)
+	assert.Equal(t, "ERROR", result(t, failing).Result)
+	assert.Equal(t, "Error: validation failed: kaboom", result(t, failing).Note)
+
+	// the policy is created
+	_, err := client.Resource(policiesResource).Namespace("test").Create(context.Background(), toUnstructured(t, policy("missing", "key", 1)), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// the Snapshot is validated once the policy exists
+	require.Eventually(t, func() bool {
+		return result(t, annotations(t, client, "missing")).Note == "Error: validation failed: kaboom"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPolicyRef(t *testing.T) {
+	c := New(nil, nil, Options{Policy: "default/ecp"})
+
+	assert.Equal(t, "test/ecp", c.policyRef(*snapshot("s", map[string]string{PolicyAnnotation: "ecp"})))
+	assert.Equal(t, "other/ecp", c.policyRef(*snapshot("s", map[string]string{PolicyAnnotation: "other/ecp"})))
+	assert.Equal(t, "default/ecp", c.policyRef(*snapshot("s", nil)))
+	assert.Equal(t, "", New(nil, nil, Options{}).policyRef(*snapshot("s", nil)))
+}
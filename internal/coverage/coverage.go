@@ -0,0 +1,299 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package coverage collects rule-level coverage and profiling information
+// while evaluating policies. It relies on the OPA cover and profiler query
+// tracers, and maps their line based results back to the policy rules, as
+// identified by their code. The information collected from all evaluations,
+// e.g. of all components in a snapshot, is merged into a single report.
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/profiler"
+	"github.com/open-policy-agent/opa/topdown"
+
+	"github.com/enterprise-contract/ec-cli/internal/opa/rule"
+)
+
+type contextKey string
+
+const collectorKey contextKey = "ec.coverage.collector"
+
+// Collector merges the coverage and profiling information recorded in
+// multiple policy evaluations. It is safe for concurrent use.
+type Collector struct {
+	coverage bool
+	profile  bool
+	mu       sync.Mutex
+	rules    map[string]*ruleStats
+}
+
+// ruleStats holds the information collected for a single rule, the lines are
+// tracked as sets so that the coverage can be merged across evaluations.
+type ruleStats struct {
+	code        string
+	location    string
+	covered     map[int]bool
+	coverable   map[int]bool
+	evaluations int
+	timeNs      int64
+}
+
+// Recording holds the tracers used in a single policy evaluation.
+type Recording struct {
+	cover       *cover.Cover
+	profiler    *profiler.Profiler
+	evaluations evaluationCounter
+}
+
+// evaluationCounter is a query tracer counting how many times each rule was
+// evaluated, by the location of the rule.
+type evaluationCounter map[string]int
+
+func (evaluationCounter) Enabled() bool {
+	return true
+}
+
+func (evaluationCounter) Config() topdown.TraceConfig {
+	return topdown.TraceConfig{}
+}
+
+func (c evaluationCounter) TraceEvent(e topdown.Event) {
+	if r, ok := e.Node.(*ast.Rule); ok && e.Op == topdown.EnterOp && r.Location != nil {
+		c[ruleKey(r)]++
+	}
+}
+
+func ruleKey(r *ast.Rule) string {
+	return fmt.Sprintf("%s:%d:%d", r.Location.File, r.Location.Row, r.Location.Col)
+}
+
+// NewCollector creates a Collector collecting the coverage, the profiling
+// information or both.
+func NewCollector(coverage, profile bool) *Collector {
+	return &Collector{
+		coverage: coverage,
+		profile:  profile,
+		rules:    map[string]*ruleStats{},
+	}
+}
+
+// WithCollector returns a context in which the evaluators record the
+// coverage and profiling information with the given Collector.
+func WithCollector(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey, c)
+}
+
+// FromContext returns the Collector from the context, or nil if there is none.
+func FromContext(ctx context.Context) *Collector {
+	if c, ok := ctx.Value(collectorKey).(*Collector); ok {
+		return c
+	}
+
+	return nil
+}
+
+// Start returns a new Recording for a single policy evaluation. The tracers of
+// the Recording are not safe for concurrent use, a Recording must not be
+// shared between concurrent evaluations.
+func (c *Collector) Start() *Recording {
+	r := Recording{}
+	if c.coverage {
+		r.cover = cover.New()
+	}
+	if c.profile {
+		r.profiler = profiler.New()
+		r.evaluations = evaluationCounter{}
+	}
+
+	return &r
+}
+
+// Tracers returns the query tracers to provide to the policy evaluation.
+func (r *Recording) Tracers() []topdown.QueryTracer {
+	var tracers []topdown.QueryTracer
+	if r.cover != nil {
+		tracers = append(tracers, r.cover)
+	}
+	if r.profiler != nil {
+		tracers = append(tracers, r.profiler, r.evaluations)
+	}
+
+	return tracers
+}
+
+// Record merges the information of the Recording into the Collector. Only
+// rules with a code, i.e. with annotations providing the short name, are
+// considered. The locations of the rules are reported relative to the
+// provided policy directory.
+func (c *Collector) Record(r *Recording, compiler *ast.Compiler, policyDir string) {
+	var coverReport cover.Report
+	if r.cover != nil {
+		coverReport = r.cover.Report(compiler.Modules)
+	}
+
+	var profilerReport profiler.Report
+	if r.profiler != nil {
+		profilerReport = r.profiler.ReportByFile()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, a := range compiler.GetAnnotationSet().Flatten() {
+		rl := a.GetRule()
+		if rl == nil || rl.Location == nil {
+			continue
+		}
+
+		info := rule.RuleInfo(a)
+		if info.ShortName == "" {
+			continue
+		}
+
+		file := rl.Location.File
+		if rel, err := filepath.Rel(policyDir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		location := fmt.Sprintf("%s:%d", file, rl.Location.Row)
+
+		stats, ok := c.rules[location]
+		if !ok {
+			stats = &ruleStats{
+				code:      info.Code,
+				location:  location,
+				covered:   map[int]bool{},
+				coverable: map[int]bool{},
+			}
+			c.rules[location] = stats
+		}
+
+		first, last := rl.Location.Row, rl.Location.Row+strings.Count(string(rl.Location.Text), "\n")
+
+		if r.cover != nil {
+			if fr, ok := coverReport.Files[rl.Location.File]; ok {
+				for row := first; row <= last; row++ {
+					if fr.IsCovered(row) {
+						stats.covered[row] = true
+						stats.coverable[row] = true
+					} else if fr.IsNotCovered(row) {
+						stats.coverable[row] = true
+					}
+				}
+			}
+		}
+
+		if r.profiler != nil {
+			if fr, ok := profilerReport.Files[rl.Location.File]; ok {
+				stats.timeNs += bodyTime(rl, fr.Result)
+			}
+			stats.evaluations += r.evaluations[ruleKey(rl)]
+		}
+	}
+}
+
+// bodyTime returns the time spent evaluating the expressions of the rule
+// body. Only the top level expressions of the body are considered, their
+// evaluation time includes the time spent evaluating any nested expressions.
+func bodyTime(rl *ast.Rule, results []profiler.ExprStats) int64 {
+	rows := map[int]bool{}
+	for _, expr := range rl.Body {
+		if expr.Location != nil {
+			rows[expr.Location.Row] = true
+		}
+	}
+
+	var timeNs int64
+	for _, s := range results {
+		if s.Location != nil && s.Location.File == rl.Location.File && rows[s.Location.Row] {
+			timeNs += s.ExprTimeNs
+		}
+	}
+
+	return timeNs
+}
+
+// CoverageReport returns the coverage of the rules recorded so far.
+func (c *Collector) CoverageReport() CoverageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := CoverageReport{Rules: []RuleCoverage{}}
+	for _, s := range c.rules {
+		rc := RuleCoverage{
+			Code:            s.code,
+			Location:        s.location,
+			Hit:             len(s.covered) > 0,
+			CoveredLines:    len(s.covered),
+			NotCoveredLines: len(s.coverable) - len(s.covered),
+		}
+
+		report.Rules = append(report.Rules, rc)
+		report.TotalRules++
+		if rc.Hit {
+			report.HitRules++
+		}
+	}
+
+	if report.TotalRules > 0 {
+		report.Coverage = 100.0 * float64(report.HitRules) / float64(report.TotalRules)
+	}
+
+	sort.Slice(report.Rules, func(i, j int) bool {
+		if report.Rules[i].Code == report.Rules[j].Code {
+			return report.Rules[i].Location < report.Rules[j].Location
+		}
+		return report.Rules[i].Code < report.Rules[j].Code
+	})
+
+	return report
+}
+
+// ProfileReport returns the evaluation time of the rules recorded so far,
+// the most expensive rules first.
+func (c *Collector) ProfileReport() ProfileReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := ProfileReport{Rules: []RuleProfile{}}
+	for _, s := range c.rules {
+		report.Rules = append(report.Rules, RuleProfile{
+			Code:        s.code,
+			Location:    s.location,
+			Evaluations: s.evaluations,
+			TimeNs:      s.timeNs,
+		})
+		report.TotalTimeNs += s.timeNs
+	}
+
+	sort.Slice(report.Rules, func(i, j int) bool {
+		if report.Rules[i].TimeNs == report.Rules[j].TimeNs {
+			return report.Rules[i].Location < report.Rules[j].Location
+		}
+		return report.Rules[i].TimeNs > report.Rules[j].TimeNs
+	})
+
+	return report
+}
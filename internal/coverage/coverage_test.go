@@ -0,0 +1,257 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/format"
+)
+
+const policy = `package main
+
+# METADATA
+# custom:
+#   short_name: registry
+deny contains result if {
+	input.registry != "registry.io"
+	result := "Untrusted registry"
+}
+
+# METADATA
+# custom:
+#   short_name: tag
+deny contains result if {
+	input.tag == "latest"
+	result := "Latest tag"
+}
+
+# METADATA
+# custom:
+#   short_name: unused
+warn contains result if {
+	input.unused
+	result := "Unused"
+}
+
+# not annotated, not reported
+deny contains result if {
+	false
+	result := "Not annotated"
+}
+`
+
+func compile(t *testing.T) *ast.Compiler {
+	t.Helper()
+
+	compiler, err := ast.CompileModulesWithOpt(map[string]string{"/policy/dir/main.rego": policy}, ast.CompileOpts{
+		ParserOptions: ast.ParserOptions{ProcessAnnotation: true, RegoVersion: ast.RegoV1},
+	})
+	require.NoError(t, err)
+
+	return compiler
+}
+
+func evaluate(t *testing.T, c *Collector, compiler *ast.Compiler, input map[string]any) {
+	t.Helper()
+
+	recording := c.Start()
+	options := []func(*rego.Rego){
+		rego.Compiler(compiler),
+		rego.Query("data.main.deny"),
+		rego.Input(input),
+	}
+	for _, tracer := range recording.Tracers() {
+		options = append(options, rego.QueryTracer(tracer))
+	}
+
+	_, err := rego.New(options...).Eval(context.Background())
+	require.NoError(t, err)
+
+	c.Record(recording, compiler, "/policy")
+}
+
+func TestCoverage(t *testing.T) {
+	compiler := compile(t)
+	c := NewCollector(true, false)
+
+	// the coverage is merged from multiple evaluations, evaluated concurrently
+	wg := sync.WaitGroup{}
+	for _, input := range []map[string]any{
+		{"registry": "registry.io", "tag": "v1"},
+		{"registry": "example.io", "tag": "latest"},
+	} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			evaluate(t, c, compiler, input)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, CoverageReport{
+		Rules: []RuleCoverage{
+			{Code: "main.registry", Location: "dir/main.rego:6", Hit: true, CoveredLines: 3, NotCoveredLines: 0},
+			{Code: "main.tag", Location: "dir/main.rego:14", Hit: true, CoveredLines: 3, NotCoveredLines: 0},
+			{Code: "main.unused", Location: "dir/main.rego:22", Hit: false, CoveredLines: 0, NotCoveredLines: 3},
+		},
+		HitRules:   2,
+		TotalRules: 3,
+		Coverage:   2.0 / 3.0 * 100,
+	}, c.CoverageReport())
+
+	// profiling was not requested
+	for _, r := range c.ProfileReport().Rules {
+		assert.Zero(t, r.TimeNs)
+		assert.Zero(t, r.Evaluations)
+	}
+}
+
+func TestPartialCoverage(t *testing.T) {
+	compiler := compile(t)
+	c := NewCollector(true, false)
+
+	evaluate(t, c, compiler, map[string]any{"registry": "registry.io", "tag": "v1"})
+
+	report := c.CoverageReport()
+	require.Len(t, report.Rules, 3)
+	// the first expression is evaluated, the rule does not produce a result
+	assert.Equal(t, RuleCoverage{Code: "main.registry", Location: "dir/main.rego:6", Hit: true, CoveredLines: 1, NotCoveredLines: 2}, report.Rules[0])
+}
+
+func TestProfile(t *testing.T) {
+	compiler := compile(t)
+	c := NewCollector(false, true)
+
+	evaluate(t, c, compiler, map[string]any{"registry": "example.io", "tag": "latest"})
+	evaluate(t, c, compiler, map[string]any{"registry": "example.io", "tag": "latest"})
+
+	report := c.ProfileReport()
+	require.Len(t, report.Rules, 3)
+
+	evaluations := map[string]int{}
+	var total int64
+	for _, r := range report.Rules {
+		evaluations[r.Code] = r.Evaluations
+		total += r.TimeNs
+	}
+	assert.Equal(t, map[string]int{"main.registry": 2, "main.tag": 2, "main.unused": 0}, evaluations)
+	assert.Equal(t, total, report.TotalTimeNs)
+	assert.Positive(t, total)
+
+	// most expensive first
+	for i := 1; i < len(report.Rules); i++ {
+		assert.GreaterOrEqual(t, report.Rules[i-1].TimeNs, report.Rules[i].TimeNs)
+	}
+
+	// coverage was not requested
+	assert.Zero(t, c.CoverageReport().HitRules)
+}
+
+func TestFormat(t *testing.T) {
+	coverageReport := CoverageReport{
+		Rules: []RuleCoverage{
+			{Code: "main.registry", Location: "main.rego:6", Hit: true, CoveredLines: 3},
+			{Code: "main.unused", Location: "main.rego:22", NotCoveredLines: 3},
+		},
+		HitRules:   1,
+		TotalRules: 2,
+		Coverage:   50,
+	}
+
+	text, err := coverageReport.Format(Text)
+	require.NoError(t, err)
+	assert.Equal(t, `Rule coverage: 1/2 rules hit (50.0%)
+
+CODE           HIT  LINES  LOCATION
+main.registry  yes  3/3    main.rego:6
+main.unused    no   0/3    main.rego:22
+`, string(text))
+
+	j, err := coverageReport.Format(JSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"rules": [
+			{"code": "main.registry", "location": "main.rego:6", "hit": true, "covered_lines": 3, "not_covered_lines": 0},
+			{"code": "main.unused", "location": "main.rego:22", "hit": false, "covered_lines": 0, "not_covered_lines": 3}
+		],
+		"hit_rules": 1,
+		"total_rules": 2,
+		"coverage": 50
+	}`, string(j))
+
+	profileReport := ProfileReport{
+		Rules: []RuleProfile{
+			{Code: "main.tag", Location: "main.rego:14", Evaluations: 2, TimeNs: 1500000},
+			{Code: "main.registry", Location: "main.rego:6", Evaluations: 2, TimeNs: 500},
+		},
+		TotalTimeNs: 1500500,
+	}
+
+	text, err = profileReport.Format(Text)
+	require.NoError(t, err)
+	assert.Equal(t, `Rule evaluation time: 1.5005ms
+
+CODE           TIME   EVALUATIONS  LOCATION
+main.tag       1.5ms  2            main.rego:14
+main.registry  500ns  2            main.rego:6
+`, string(text))
+
+	_, err = profileReport.Format("xml")
+	assert.EqualError(t, err, `"xml" is not a valid profile report format`)
+}
+
+func TestCheckTarget(t *testing.T) {
+	assert.NoError(t, CheckTarget(""))
+	assert.NoError(t, CheckTarget("text"))
+	assert.NoError(t, CheckTarget("json=/tmp/coverage.json"))
+	assert.EqualError(t, CheckTarget("xml=/tmp/coverage.xml"), `"xml" is not a valid report format, use one of: text, json`)
+}
+
+func TestWriteReports(t *testing.T) {
+	compiler := compile(t)
+	c := NewCollector(true, true)
+	evaluate(t, c, compiler, map[string]any{"registry": "registry.io"})
+
+	fs := afero.NewMemMapFs()
+	out := bytes.Buffer{}
+	p := format.NewTargetParser(Text, format.Options{}, &out, fs)
+
+	require.NoError(t, c.WriteReports(p, "", "json=profile.json"))
+	assert.Empty(t, out.String())
+
+	data, err := afero.ReadFile(fs, "profile.json")
+	require.NoError(t, err)
+	var profile ProfileReport
+	require.NoError(t, json.Unmarshal(data, &profile))
+	assert.Len(t, profile.Rules, 3)
+
+	require.NoError(t, c.WriteReports(p, "text", ""))
+	assert.Contains(t, out.String(), "Rule coverage: 1/3 rules hit")
+}
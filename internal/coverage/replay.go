@@ -0,0 +1,81 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package coverage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/parser"
+	conftest "github.com/open-policy-agent/conftest/policy"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// Replay evaluates again the queries of the Conftest check results, with the
+// tracers of a new Recording, and records the outcome. The Conftest test
+// runner does not accept query tracers, replaying the queries is how the
+// coverage and profiling information is collected when it is used.
+func (c *Collector) Replay(ctx context.Context, engine *conftest.Engine, results []output.CheckResult, policyDir string) error {
+	recording := c.Start()
+
+	options := []func(*rego.Rego){
+		rego.Compiler(engine.Compiler()),
+		rego.Store(engine.Store()),
+	}
+	for _, t := range recording.Tracers() {
+		options = append(options, rego.QueryTracer(t))
+	}
+
+	documents := map[string][]any{}
+	for _, res := range results {
+		docs, ok := documents[res.FileName]
+		if !ok {
+			configs, err := parser.ParseConfigurations([]string{res.FileName})
+			if err != nil {
+				return fmt.Errorf("parse %s: %w", res.FileName, err)
+			}
+
+			// multi-document inputs are evaluated one document at a time, as
+			// the Conftest test runner does
+			if docs, ok = configs[res.FileName].([]any); !ok {
+				docs = []any{configs[res.FileName]}
+			}
+			documents[res.FileName] = docs
+		}
+
+		// the queries are repeated for each document of multi-document inputs
+		queries := map[string]bool{}
+		for _, q := range res.Queries {
+			if queries[q.Query] {
+				continue
+			}
+			queries[q.Query] = true
+
+			for _, doc := range docs {
+				r := rego.New(append(options, rego.Query(q.Query), rego.Input(doc))...)
+				if _, err := r.Eval(ctx); err != nil {
+					return fmt.Errorf("replaying %s: %w", q.Query, err)
+				}
+			}
+		}
+	}
+
+	c.Record(recording, engine.Compiler(), policyDir)
+
+	return nil
+}
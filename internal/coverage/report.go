@@ -0,0 +1,169 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package coverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/enterprise-contract/ec-cli/internal/format"
+)
+
+// Supported report formats
+const (
+	Text = "text"
+	JSON = "json"
+)
+
+var Formats = []string{Text, JSON}
+
+// RuleCoverage holds the coverage of a single rule.
+type RuleCoverage struct {
+	Code            string `json:"code"`
+	Location        string `json:"location"`
+	Hit             bool   `json:"hit"`
+	CoveredLines    int    `json:"covered_lines"`
+	NotCoveredLines int    `json:"not_covered_lines"`
+}
+
+// CoverageReport holds the coverage of all rules, a rule is hit if any of its
+// lines were evaluated.
+type CoverageReport struct {
+	Rules      []RuleCoverage `json:"rules"`
+	HitRules   int            `json:"hit_rules"`
+	TotalRules int            `json:"total_rules"`
+	Coverage   float64        `json:"coverage"`
+}
+
+// RuleProfile holds the evaluation time of a single rule.
+type RuleProfile struct {
+	Code        string `json:"code"`
+	Location    string `json:"location"`
+	Evaluations int    `json:"evaluations"`
+	TimeNs      int64  `json:"time_ns"`
+}
+
+// ProfileReport holds the evaluation time of all rules.
+type ProfileReport struct {
+	Rules       []RuleProfile `json:"rules"`
+	TotalTimeNs int64         `json:"total_time_ns"`
+}
+
+// Format returns the coverage report in the given format.
+func (r CoverageReport) Format(format string) ([]byte, error) {
+	switch format {
+	case JSON:
+		return json.Marshal(r)
+	case Text:
+		buf := bytes.Buffer{}
+		fmt.Fprintf(&buf, "Rule coverage: %d/%d rules hit (%.1f%%)\n\n", r.HitRules, r.TotalRules, r.Coverage)
+
+		w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tHIT\tLINES\tLOCATION")
+		for _, rule := range r.Rules {
+			hit := "no"
+			if rule.Hit {
+				hit = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n", rule.Code, hit, rule.CoveredLines, rule.CoveredLines+rule.NotCoveredLines, rule.Location)
+		}
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%q is not a valid coverage report format", format)
+	}
+}
+
+// Format returns the profile report in the given format.
+func (r ProfileReport) Format(format string) ([]byte, error) {
+	switch format {
+	case JSON:
+		return json.Marshal(r)
+	case Text:
+		buf := bytes.Buffer{}
+		fmt.Fprintf(&buf, "Rule evaluation time: %s\n\n", time.Duration(r.TotalTimeNs))
+
+		w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tTIME\tEVALUATIONS\tLOCATION")
+		for _, rule := range r.Rules {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", rule.Code, time.Duration(rule.TimeNs), rule.Evaluations, rule.Location)
+		}
+		if err := w.Flush(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%q is not a valid profile report format", format)
+	}
+}
+
+// CheckTarget returns an error if the format of the report target, given as
+// <format>[=<path>], is not supported.
+func CheckTarget(target string) error {
+	f, _, _ := strings.Cut(target, "=")
+	if f != "" && !slices.Contains(Formats, f) {
+		return fmt.Errorf("%q is not a valid report format, use one of: %s", f, strings.Join(Formats, ", "))
+	}
+
+	return nil
+}
+
+// WriteReports writes the coverage and the profile report to the targets,
+// given as <format>[=<path>]. The report is not written if its target is
+// empty.
+func (c *Collector) WriteReports(p format.TargetParser, coverageTarget, profileTarget string) (allErrors error) {
+	write := func(target string, report func(string) ([]byte, error)) {
+		if target == "" {
+			return
+		}
+
+		t, err := p.Parse(target)
+		if err != nil {
+			allErrors = errors.Join(allErrors, err)
+			return
+		}
+
+		data, err := report(t.Format)
+		if err != nil {
+			allErrors = errors.Join(allErrors, err)
+			return
+		}
+
+		if !bytes.HasSuffix(data, []byte{'\n'}) {
+			data = append(data, '\n')
+		}
+
+		if _, err := t.Write(data); err != nil {
+			allErrors = errors.Join(allErrors, err)
+		}
+	}
+
+	write(coverageTarget, c.CoverageReport().Format)
+	write(profileTarget, c.ProfileReport().Format)
+
+	return
+}
@@ -32,7 +32,10 @@ import (
 	"github.com/sirupsen/logrus"
 	"oras.land/oras-go/v2/registry/remote/retry"
 
+	"github.com/enterprise-contract/ec-cli/internal/auth"
 	"github.com/enterprise-contract/ec-cli/internal/http"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
 )
 
 type key int
@@ -56,6 +59,9 @@ var _initialize = func() {
 		ghttp.Transport = http.NewTracingRoundTripperWithLogger(ghttp.Transport)
 	}
 
+	// authenticate with the explicitly configured registry credentials
+	goci.Transport = auth.NewTransport(goci.Transport)
+
 	backoff := retry.ExponentialBackoff(http.DefaultBackoff.Duration, http.DefaultBackoff.Factor, http.DefaultBackoff.Jitter)
 	policy := &retry.GenericPolicy{
 		Retryable: retry.DefaultPredicate,
@@ -79,17 +85,53 @@ var _initialize = func() {
 
 var initialize = sync.OnceFunc(_initialize)
 
+// diskCache holds the persistent cache of downloaded sources, nil if disabled
+var diskCache = sync.OnceValue(cache.DefaultDiskCache)
+
 // WithDownloadImpl replaces the downloadImpl implementation used
 func WithDownloadImpl(ctx context.Context, d downloadImpl) context.Context {
 	return context.WithValue(ctx, downloadImplKey, d)
 }
 
-// Download is used to download files from various sources.
+// Download is used to download files from various sources. When validating
+// offline the files are served only from the offline bundle, and when
+// recording an offline bundle the downloaded files are recorded.
 func Download(ctx context.Context, destDir string, sourceUrl string, showMsg bool) (metadata.Metadata, error) {
 	if !isSecure(sourceUrl) {
 		return nil, fmt.Errorf("attempting to download from insecure source: %s", sourceUrl)
 	}
 
+	if b := offline.BundleFromContext(ctx); b != nil {
+		if m, ok := b.Sources().Get(sourceUrl, destDir); ok {
+			log.Debugf("Using %s from the offline bundle in %s", sourceUrl, destDir)
+			return m, nil
+		}
+		return nil, fmt.Errorf("%w: %s", offline.ErrNotInBundle, sourceUrl)
+	}
+
+	m, err := download(ctx, destDir, sourceUrl, showMsg)
+	if err != nil {
+		return m, err
+	}
+
+	if r := offline.RecorderFromContext(ctx); r != nil {
+		if err := r.Sources().Put(sourceUrl, destDir, m); err != nil {
+			return m, fmt.Errorf("recording %s: %w", sourceUrl, err)
+		}
+	}
+
+	return m, nil
+}
+
+func download(ctx context.Context, destDir string, sourceUrl string, showMsg bool) (metadata.Metadata, error) {
+	c := diskCache()
+	if c != nil {
+		if m, ok := c.Get(sourceUrl, destDir); ok {
+			log.Debugf("Using cached %s in %s", sourceUrl, destDir)
+			return m, nil
+		}
+	}
+
 	msg := fmt.Sprintf("Downloading %s to %s", sourceUrl, destDir)
 	log.Debug(msg)
 	if showMsg {
@@ -99,8 +141,16 @@ func Download(ctx context.Context, destDir string, sourceUrl string, showMsg boo
 	m, err := gatherFunc(ctx, sourceUrl, destDir)
 	if err != nil {
 		log.Debug("Download failed!")
+		return m, err
+	}
+
+	if c != nil {
+		if err := c.Put(sourceUrl, destDir, m); err != nil {
+			log.Debugf("Unable to cache %s: %v", sourceUrl, err)
+		}
 	}
-	return m, err
+
+	return m, nil
 }
 
 // matches insecure protocols, such as `git::http://...`
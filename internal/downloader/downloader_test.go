@@ -27,15 +27,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"path"
 	"regexp"
 	"runtime/trace"
 	"sync"
 	"testing"
+	"time"
 
 	ghttp "github.com/enterprise-contract/go-gather/gather/http"
 	goci "github.com/enterprise-contract/go-gather/gather/oci"
 	"github.com/enterprise-contract/go-gather/metadata"
+	gitMetadata "github.com/enterprise-contract/go-gather/metadata/git"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/registry"
 	"github.com/google/go-containerregistry/pkg/v1/random"
@@ -48,6 +51,8 @@ import (
 	"oras.land/oras-go/v2/registry/remote/retry"
 
 	echttp "github.com/enterprise-contract/ec-cli/internal/http"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
 )
 
 type mockDownloader struct {
@@ -60,7 +65,19 @@ func (m *mockDownloader) Download(ctx context.Context, dest string, sourceUrls [
 	return args.Error(0)
 }
 
+// withDiskCache replaces the persistent cache for the duration of the test,
+// tests never use the cache in the user's cache directory
+func withDiskCache(t *testing.T, c *cache.DiskCache) {
+	originalDiskCache := diskCache
+	t.Cleanup(func() {
+		diskCache = originalDiskCache
+	})
+	diskCache = func() *cache.DiskCache { return c }
+}
+
 func TestDownloader_Download(t *testing.T) {
+	withDiskCache(t, cache.NewDiskCache(t.TempDir(), time.Hour))
+
 	tests := []struct {
 		name        string
 		dest        string
@@ -116,6 +133,89 @@ func TestDownloader_Download(t *testing.T) {
 	}
 }
 
+func TestDownloader_DownloadCached(t *testing.T) {
+	originalGatherFunction := gatherFunc
+	t.Cleanup(func() {
+		gatherFunc = originalGatherFunction
+	})
+
+	withDiskCache(t, cache.NewDiskCache(t.TempDir(), time.Hour))
+
+	downloads := 0
+	gatherFunc = func(_ context.Context, _ string, dest string) (metadata.Metadata, error) {
+		downloads++
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path.Join(dest, "policy.rego"), []byte("package policy"), 0600); err != nil {
+			return nil, err
+		}
+		return &gitMetadata.GitMetadata{LatestCommit: "6c3a1b4a1b7e8a3f0c9c2f5d6e8a9b0c1d2e3f4a"}, nil
+	}
+
+	source := "git::https://example.com/org/repo.git?ref=main"
+	for i := 0; i < 2; i++ {
+		dest := path.Join(t.TempDir(), "dl")
+		m, err := Download(context.Background(), dest, source, false)
+		require.NoError(t, err)
+
+		pinned, err := m.GetPinnedURL(source)
+		require.NoError(t, err)
+		assert.Equal(t, "git::example.com/org/repo.git?ref=6c3a1b4a1b7e8a3f0c9c2f5d6e8a9b0c1d2e3f4a", pinned)
+		assert.FileExists(t, path.Join(dest, "policy.rego"))
+	}
+
+	assert.Equal(t, 1, downloads)
+}
+
+func TestDownloader_DownloadOffline(t *testing.T) {
+	t.Setenv("TUF_ROOT", t.TempDir())
+	originalGatherFunction := gatherFunc
+	t.Cleanup(func() {
+		gatherFunc = originalGatherFunction
+	})
+
+	withDiskCache(t, nil)
+
+	downloads := 0
+	gatherFunc = func(_ context.Context, _ string, dest string) (metadata.Metadata, error) {
+		downloads++
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path.Join(dest, "policy.rego"), []byte("package policy"), 0600); err != nil {
+			return nil, err
+		}
+		return &gitMetadata.GitMetadata{LatestCommit: "6c3a1b4a1b7e8a3f0c9c2f5d6e8a9b0c1d2e3f4a"}, nil
+	}
+
+	recorder, err := offline.NewRecorder()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = recorder.Close() })
+
+	source := "git::https://example.com/org/repo.git?ref=main"
+	_, err = Download(offline.WithRecorder(context.Background(), recorder), path.Join(t.TempDir(), "dl"), source, false)
+	require.NoError(t, err)
+
+	bundlePath := path.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, recorder.Write(bundlePath, offline.Manifest{}))
+	b, err := offline.Open(bundlePath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	ctx := offline.WithBundle(context.Background(), b)
+	dest := path.Join(t.TempDir(), "dl")
+	_, err = Download(ctx, dest, source, false)
+	require.NoError(t, err)
+	assert.FileExists(t, path.Join(dest, "policy.rego"))
+
+	_, err = Download(ctx, path.Join(t.TempDir(), "dl"), "git::https://example.com/org/other.git", false)
+	assert.ErrorIs(t, err, offline.ErrNotInBundle)
+
+	// only the recorded download reached the source
+	assert.Equal(t, 1, downloads)
+}
+
 func TestIsSecure(t *testing.T) {
 	secure := []string{
 		"./foo",
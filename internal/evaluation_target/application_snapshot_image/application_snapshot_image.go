@@ -22,9 +22,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"runtime/trace"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
@@ -38,6 +40,7 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/fetchers/oci/config"
 	"github.com/enterprise-contract/ec-cli/internal/fetchers/oci/files"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/sbom"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
@@ -46,6 +49,7 @@ import (
 
 var attestationSchemas = map[string]*jsonschema.Schema{
 	"https://slsa.dev/provenance/v0.2": schema.SLSA_Provenance_v0_2,
+	"https://slsa.dev/provenance/v1":   schema.SLSA_Provenance_v1,
 }
 
 // ApplicationSnapshotImage represents the structure needed to evaluate an Application Snapshot Image
@@ -61,6 +65,15 @@ type ApplicationSnapshotImage struct {
 	files            map[string]json.RawMessage
 	component        app.SnapshotComponent
 	snapshot         app.SnapshotSpec
+	sboms            []fetchedSBOM
+}
+
+// fetchedSBOM is a SBOM fetched from a blob referenced by the image or from
+// an artifact referring to the image
+type fetchedSBOM struct {
+	source sbom.Source
+	doc    *sbom.Document
+	err    error
 }
 
 func (a ApplicationSnapshotImage) GetReference() name.Reference {
@@ -143,6 +156,217 @@ func (a *ApplicationSnapshotImage) FetchImageFiles(ctx context.Context) error {
 	return err
 }
 
+// FetchSBOMs fetches and parses the SBOM blob referenced by the image using
+// the oci.SBOMAnnotation annotation, and the SBOMs attached to the image as
+// OCI referrers with the SPDX or the CycloneDX artifact type. SBOMs that
+// cannot be parsed are recorded along with the error.
+func (a *ApplicationSnapshotImage) FetchSBOMs(ctx context.Context) error {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:image-fetch-sboms")
+		defer region.End()
+	}
+
+	client := oci.NewClient(ctx)
+
+	return errors.Join(a.fetchSBOMBlob(client), a.fetchSBOMReferrers(client))
+}
+
+// fetchSBOMBlob fetches the SBOM blob referenced by the oci.SBOMAnnotation
+// annotation. The annotation holds either the digest of a blob in the image
+// repository or a full blob reference.
+func (a *ApplicationSnapshotImage) fetchSBOMBlob(client oci.Client) error {
+	img, err := client.Image(a.reference)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+
+	value := manifest.Annotations[oci.SBOMAnnotation]
+	if value == "" {
+		return nil
+	}
+
+	var ref name.Digest
+	if strings.Contains(value, "@") {
+		ref, err = name.NewDigest(value)
+	} else {
+		ref, err = name.NewDigest(fmt.Sprintf("%s@%s", a.reference.Context().Name(), value))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse the SBOM reference %q: %w", value, err)
+	}
+
+	blob := fetchedSBOM{source: sbom.Source{Origin: sbom.OriginBlob, Ref: ref.String()}}
+	blob.doc, blob.err = fetchSBOMBlob(client, ref)
+	a.sboms = append(a.sboms, blob)
+
+	return nil
+}
+
+func fetchSBOMBlob(client oci.Client, ref name.Digest) (*sbom.Document, error) {
+	layer, err := client.Layer(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return sbom.Parse(data)
+}
+
+// fetchSBOMReferrers fetches the SBOMs attached to the image as OCI referrers
+// with the SPDX or the CycloneDX artifact type
+func (a *ApplicationSnapshotImage) fetchSBOMReferrers(client oci.Client) error {
+	subject, ok := a.reference.(name.Digest)
+	if !ok {
+		digest, err := client.ResolveDigest(a.reference)
+		if err != nil {
+			return err
+		}
+		subject = a.reference.Context().Digest(digest)
+	}
+
+	index, err := client.Referrers(subject)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range index.Manifests {
+		if !isSBOMMediaType(string(d.ArtifactType)) {
+			continue
+		}
+
+		ref := subject.Context().Digest(d.Digest.String())
+		r := fetchedSBOM{source: sbom.Source{Origin: sbom.OriginReferrer, Ref: ref.String()}}
+		r.doc, r.err = fetchSBOMReferrer(client, ref)
+		a.sboms = append(a.sboms, r)
+	}
+
+	return nil
+}
+
+func isSBOMMediaType(mediaType string) bool {
+	return mediaType == oci.SPDXMediaType || mediaType == oci.CycloneDXMediaType
+}
+
+// fetchSBOMReferrer parses the first layer of the artifact with a SBOM media
+// type
+func fetchSBOMReferrer(client oci.Client, ref name.Digest) (*sbom.Document, error) {
+	img, err := client.Image(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if !isSBOMMediaType(string(mediaType)) {
+			continue
+		}
+
+		content, err := layer.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, err
+		}
+
+		return sbom.Parse(data)
+	}
+
+	return nil, errors.New("no layer with a SBOM media type found")
+}
+
+// sbomInput returns the SBOMs from the attestations, the blob referenced by
+// the image and the referrers of the image, or nil if there are none
+func (a *ApplicationSnapshotImage) sbomInput() *sbom.SBOM {
+	s := &sbom.SBOM{}
+	for _, att := range a.attestations {
+		pt := att.PredicateType()
+		if !attestation.IsSBOM(pt) {
+			continue
+		}
+
+		var statement struct {
+			Predicate json.RawMessage `json:"predicate"`
+		}
+		var doc *sbom.Document
+		err := json.Unmarshal(att.Statement(), &statement)
+		if err == nil {
+			doc, err = sbom.Parse(statement.Predicate)
+		}
+		if err != nil {
+			log.Warnf("Unable to parse the SBOM from the %s attestation: %v", pt, err)
+		}
+		s.Add(sbom.Source{Origin: sbom.OriginAttestation, Ref: pt}, doc, err)
+	}
+
+	for _, f := range a.sboms {
+		if f.err != nil {
+			log.Warnf("Unable to parse the SBOM from %s: %v", f.source.Ref, f.err)
+		}
+		s.Add(f.source, f.doc, f.err)
+	}
+
+	if s.Empty() {
+		return nil
+	}
+
+	return s
+}
+
+// vulnerabilitiesInput returns the normalized findings of the vulnerability
+// reports, or nil if there are none
+func (a *ApplicationSnapshotImage) vulnerabilitiesInput() *vulnerabilities {
+	var v *vulnerabilities
+	for _, att := range a.attestations {
+		report, ok := att.(attestation.VulnerabilityReport)
+		if !ok {
+			continue
+		}
+
+		if v == nil {
+			v = &vulnerabilities{
+				Scans:    []attestation.VulnerabilityScan{},
+				Findings: []attestation.Finding{},
+			}
+		}
+
+		scan := report.Scan()
+		if scan.Error != "" {
+			log.Warnf("Unable to normalize the findings of the %s vulnerability report: %s", scan.Scanner, scan.Error)
+		}
+		v.Scans = append(v.Scans, scan)
+		v.Findings = append(v.Findings, report.Findings()...)
+	}
+
+	return v
+}
+
 // ValidateImageSignature executes the cosign.VerifyImageSignature method on the ApplicationSnapshotImage image ref.
 func (a *ApplicationSnapshotImage) ValidateImageSignature(ctx context.Context) error {
 	// Set the ClaimVerifier on a shallow *copy* of CheckOpts to avoid unexpected side-effects
@@ -196,6 +420,20 @@ func (a *ApplicationSnapshotImage) ValidateAttestationSignature(ctx context.Cont
 			}
 			a.attestations = append(a.attestations, sp)
 
+		case attestation.PredicateSLSAProvenanceV1:
+			sp, err := attestation.SLSAProvenanceV1FromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse as SLSA v1.0: %w", err)
+			}
+			a.attestations = append(a.attestations, sp)
+
+		case attestation.PredicateCosignVuln, attestation.PredicateSARIF, attestation.PredicateTrivy:
+			vr, err := attestation.VulnerabilityReportFromSignature(sig)
+			if err != nil {
+				return fmt.Errorf("unable to parse the vulnerability report: %w", err)
+			}
+			a.attestations = append(a.attestations, vr)
+
 		case attestation.PredicateSpdxDocument:
 			// It's an SPDX format SBOM
 			// Todo maybe: We could unmarshal it into a suitable SPDX struct
@@ -337,16 +575,23 @@ type image struct {
 	Source     any                         `json:"source,omitempty"`
 }
 
-type Input struct {
-	Attestations []attestationData `json:"attestations"`
-	Image        image             `json:"image"`
-	AppSnapshot  app.SnapshotSpec  `json:"snapshot"`
+type vulnerabilities struct {
+	Scans    []attestation.VulnerabilityScan `json:"scans"`
+	Findings []attestation.Finding           `json:"findings"`
 }
 
-// WriteInputFile writes the JSON from the attestations to input.json in a random temp dir
-func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string, []byte, error) {
-	log.Debugf("Attempting to write %d attestations to input file", len(a.attestations))
+type Input struct {
+	Attestations    []attestationData `json:"attestations" jsonschema:"nullable"`
+	Image           image             `json:"image"`
+	AppSnapshot     app.SnapshotSpec  `json:"snapshot"`
+	SBOM            *sbom.SBOM        `json:"sbom,omitempty"`
+	Vulnerabilities *vulnerabilities  `json:"vulnerabilities,omitempty"`
+}
 
+// PolicyInput returns the policy input of the image, built from its
+// attestations, signatures, config, files and SBOMs. With strict input it is
+// validated against the policy input schema.
+func (a *ApplicationSnapshotImage) PolicyInput(ctx context.Context) (Input, error) {
 	var attestations []attestationData
 	for _, a := range a.attestations {
 		attestations = append(attestations, attestationData{
@@ -364,7 +609,9 @@ func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string,
 			Files:      a.files,
 			Source:     a.component.Source,
 		},
-		AppSnapshot: a.snapshot,
+		AppSnapshot:     a.snapshot,
+		SBOM:            a.sbomInput(),
+		Vulnerabilities: a.vulnerabilitiesInput(),
 	}
 
 	if a.parentRef != nil {
@@ -374,6 +621,34 @@ func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string,
 		}
 	}
 
+	if isStrictInput(ctx) {
+		inputJSON, err := json.Marshal(input)
+		if err != nil {
+			return Input{}, fmt.Errorf("input to JSON: %w", err)
+		}
+
+		if err := validateInput(inputJSON); err != nil {
+			return Input{}, err
+		}
+	}
+
+	return input, nil
+}
+
+// WriteInputFile writes the JSON of the policy input to input.json in a random temp dir
+func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string, []byte, error) {
+	log.Debugf("Attempting to write %d attestations to input file", len(a.attestations))
+
+	input, err := a.PolicyInput(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("input to JSON: %w", err)
+	}
+
 	fs := utils.FS(ctx)
 	inputDir, err := afero.TempDir(fs, "", "ecp_input.")
 	if err != nil {
@@ -390,11 +665,6 @@ func (a *ApplicationSnapshotImage) WriteInputFile(ctx context.Context) (string,
 	}
 	defer f.Close()
 
-	inputJSON, err := json.Marshal(input)
-	if err != nil {
-		return "", nil, fmt.Errorf("input to JSON: %w", err)
-	}
-
 	if _, err := f.Write(inputJSON); err != nil {
 		return "", nil, fmt.Errorf("write input to file: %w", err)
 	}
@@ -24,6 +24,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
@@ -32,7 +33,9 @@ import (
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	gcrStatic "github.com/google/go-containerregistry/pkg/v1/static"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
@@ -51,6 +54,7 @@ import (
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/sbom"
 	"github.com/enterprise-contract/ec-cli/internal/signature"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 	o "github.com/enterprise-contract/ec-cli/internal/utils/oci"
@@ -93,6 +97,39 @@ func (f fakeAtt) Subject() []in_toto.Subject {
 	return []in_toto.Subject{}
 }
 
+// fakeSBOMAtt is a SPDX attestation with the given predicate
+type fakeSBOMAtt struct {
+	fakeAtt
+	predicate string
+}
+
+func (f fakeSBOMAtt) Statement() []byte {
+	return []byte(fmt.Sprintf(`{"_type":%q,"predicateType":%q,"subject":[],"predicate":%s}`,
+		in_toto.StatementInTotoV01, attestation.PredicateSpdxDocument, f.predicate))
+}
+
+func (f fakeSBOMAtt) PredicateType() string {
+	return attestation.PredicateSpdxDocument
+}
+
+type fakeVulnAtt struct {
+	fakeAtt
+	scan     attestation.VulnerabilityScan
+	findings []attestation.Finding
+}
+
+func (f fakeVulnAtt) PredicateType() string {
+	return f.scan.PredicateType
+}
+
+func (f fakeVulnAtt) Scan() attestation.VulnerabilityScan {
+	return f.scan
+}
+
+func (f fakeVulnAtt) Findings() []attestation.Finding {
+	return f.findings
+}
+
 type opts func(*fakeAtt)
 
 func createSimpleAttestation(statement *in_toto.ProvenanceStatementSLSA02, o ...opts) attestation.Attestation {
@@ -192,6 +229,80 @@ func TestWriteInputFile(t *testing.T) {
 				})},
 			},
 		},
+		{
+			name: "SBOM",
+			snapshot: ApplicationSnapshotImage{
+				reference: name.MustParseReference("registry.io/repository/image:tag"),
+				sboms: []fetchedSBOM{
+					{
+						source: sbom.Source{Origin: sbom.OriginBlob, Ref: "registry.io/repository/image@sha256:ab"},
+						doc: &sbom.Document{
+							Format:  sbom.FormatSPDX,
+							Version: "SPDX-2.3",
+							Name:    "image",
+							Packages: []sbom.Package{
+								{ID: "SPDXRef-image", Name: "image"},
+								{ID: "SPDXRef-openssl", Name: "openssl", Purl: "pkg:rpm/redhat/openssl@3.0.7", Licenses: []string{"Apache-2.0"}},
+							},
+							Relationships: []sbom.Relationship{
+								{From: "SPDXRef-image", To: "SPDXRef-openssl", Type: "CONTAINS"},
+							},
+						},
+					},
+					{
+						source: sbom.Source{Origin: sbom.OriginReferrer, Ref: "registry.io/repository/image@sha256:cd"},
+						err:    errors.New("unknown SBOM format, expecting SPDX or CycloneDX JSON"),
+					},
+				},
+			},
+		},
+		{
+			name: "SBOM attestations",
+			snapshot: ApplicationSnapshotImage{
+				reference: name.MustParseReference("registry.io/repository/image:tag"),
+				attestations: []attestation.Attestation{
+					createSimpleAttestation(nil),
+					fakeSBOMAtt{predicate: `{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "name": "image", "packages": [{"SPDXID": "SPDXRef-image", "name": "image"}]}`},
+					fakeSBOMAtt{predicate: `{"unknown": "format"}`},
+				},
+			},
+		},
+		{
+			name: "vulnerability reports",
+			snapshot: ApplicationSnapshotImage{
+				reference: name.MustParseReference("registry.io/repository/image:tag"),
+				attestations: []attestation.Attestation{
+					createSimpleAttestation(nil),
+					fakeVulnAtt{
+						scan: attestation.VulnerabilityScan{
+							PredicateType: attestation.PredicateTrivy,
+							Scanner:       "trivy",
+							ScanTime:      "2024-06-01T10:00:00Z",
+						},
+						findings: []attestation.Finding{
+							{
+								ID:               "CVE-2024-0001",
+								Severity:         attestation.SeverityCritical,
+								Package:          "openssl",
+								InstalledVersion: "1:3.0.7-16.el9_2",
+								Purl:             "pkg:rpm/redhat/openssl@3.0.7-16.el9_2",
+								FixedVersion:     "1:3.0.7-18.el9_2",
+								Scanner:          "trivy",
+								ScanTime:         "2024-06-01T10:00:00Z",
+							},
+						},
+					},
+					fakeVulnAtt{
+						scan: attestation.VulnerabilityScan{
+							PredicateType: attestation.PredicateCosignVuln,
+							Scanner:       "pkg:github/anchore/grype@v0.79.0",
+							Error:         "unsupported scanner result, expecting a Trivy JSON or a SARIF report",
+						},
+						findings: []attestation.Finding{},
+					},
+				},
+			},
+		},
 		{
 			name: "component with source",
 			snapshot: ApplicationSnapshotImage{
@@ -239,6 +350,9 @@ func TestWriteInputFile(t *testing.T) {
 			snaps.MatchJSON(t, bytes)
 
 			assert.JSONEq(t, string(inputJSON), string(bytes))
+
+			// the policy input must match the published schema
+			assert.NoError(t, validateInput(bytes))
 		})
 	}
 }
@@ -279,6 +393,29 @@ func TestWriteInputFileMultipleAttestations(t *testing.T) {
 	assert.JSONEq(t, string(inputJSON), string(bytes))
 }
 
+func TestPolicyInputSBOMAttestations(t *testing.T) {
+	predicate := `{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "name": "image", "packages": [{"SPDXID": "SPDXRef-image", "name": "image"}]}`
+	a := ApplicationSnapshotImage{
+		reference:    name.MustParseReference("registry.io/repository/image:tag"),
+		attestations: []attestation.Attestation{fakeSBOMAtt{predicate: predicate}},
+	}
+
+	input, err := a.PolicyInput(context.Background())
+	require.NoError(t, err)
+
+	// the SBOM attestations are kept as is, along with the normalized SBOM
+	require.Len(t, input.Attestations, 1)
+	var statement struct {
+		Predicate json.RawMessage `json:"predicate"`
+	}
+	require.NoError(t, json.Unmarshal(input.Attestations[0].Statement, &statement))
+	assert.JSONEq(t, predicate, string(statement.Predicate))
+
+	require.NotNil(t, input.SBOM)
+	require.Len(t, input.SBOM.Sources, 1)
+	assert.Equal(t, sbom.OriginAttestation, input.SBOM.Sources[0].Origin)
+}
+
 func TestNewApplicationSnapshotImage(t *testing.T) {
 	ctx := context.Background()
 
@@ -416,6 +553,84 @@ func TestSyntaxValidation(t *testing.T) {
 	}
 }
 
+func TestSLSAProvenanceV1(t *testing.T) {
+	statement := func(builderID string) oci.Signature {
+		data := `{
+			"_type": "https://in-toto.io/Statement/v1",
+			"subject": [{"name": "registry.io/repository/image", "digest": {"sha256": "dabbad00"}}],
+			"predicateType": "https://slsa.dev/provenance/v1",
+			"predicate": {
+				"buildDefinition": {
+					"buildType": "https://tekton.dev/chains/v2/slsa",
+					"externalParameters": {"runSpec": {"pipelineRef": {"name": "build"}}},
+					"resolvedDependencies": [{"uri": "git+https://github.com/org/repo", "digest": {"sha1": "abcdef0123456789"}}]
+				},
+				"runDetails": {
+					"builder": {"id": "` + builderID + `"},
+					"metadata": {"startedOn": "2024-01-02T03:04:05Z", "finishedOn": "2024-01-02T04:05:06Z"}
+				}
+			}
+		}`
+
+		payload, err := json.Marshal(dsse.Envelope{
+			PayloadType: "application/vnd.in-toto+json",
+			Payload:     base64.StdEncoding.EncodeToString([]byte(data)),
+			Signatures:  []dsse.Signature{{KeyID: "key-id", Sig: "sig"}},
+		})
+		require.NoError(t, err)
+
+		sig, err := static.NewSignature(payload, "", static.WithLayerMediaType(cosignTypes.DssePayloadType))
+		require.NoError(t, err)
+
+		return sig
+	}
+
+	cases := []struct {
+		name      string
+		builderID string
+		err       string
+	}{
+		{
+			name:      "valid",
+			builderID: "https://tekton.dev/chains/v2",
+		},
+		{
+			name:      "invalid builder id",
+			builderID: "invalid",
+			err:       "attestation syntax validation failed: jsonschema: '/predicate/runDetails/builder/id' does not validate with https://slsa.dev/provenance/v1#/properties/predicate/properties/runDetails/properties/builder/properties/id/format: 'invalid' is not valid 'uri'",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ref := name.MustParseReference("registry.io/repository/image:tag")
+			a := ApplicationSnapshotImage{
+				reference: ref,
+			}
+
+			client := fake.FakeClient{}
+			client.On("VerifyImageAttestations", ref, mock.Anything).Return([]oci.Signature{statement(c.builderID)}, false, nil)
+			ctx := o.WithClient(context.Background(), &client)
+
+			require.NoError(t, a.ValidateAttestationSignature(ctx))
+			require.Len(t, a.attestations, 1)
+			assert.Equal(t, attestation.PredicateSLSAProvenanceV1, a.attestations[0].PredicateType())
+			assert.Equal(t, attestation.StatementInTotoV1, a.attestations[0].Type())
+
+			j, err := json.Marshal(a.attestations[0])
+			require.NoError(t, err)
+			assert.Contains(t, string(j), `"predicateBuildType":"https://tekton.dev/chains/v2/slsa"`)
+
+			err = a.ValidateAttestationSyntax(ctx)
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, c.err)
+			}
+		})
+	}
+}
+
 func TestValidateImageSignatureClaims(t *testing.T) {
 	ref := name.MustParseReference("registry.io/repository/image:tag")
 	a := ApplicationSnapshotImage{
@@ -675,7 +890,7 @@ func TestValidateImageSignatureWithCertificates(t *testing.T) {
 	)
 	require.NoError(t, err)
 
-	c.On("VerifyImageSignatures", ref, mock.Anything).Return([]oci.Signature{sig}, false, nil)
+	c.On("VerifyImageSignatures", ref, mock.Anything).Return([]oci.Signature{o.WithDiscovery(sig, o.DiscoveryTag)}, false, nil)
 
 	err = a.ValidateImageSignature(ctx)
 	require.NoError(t, err)
@@ -756,3 +971,100 @@ kind: ClusterServiceVersion`),
 		"manifests/csv.yaml": json.RawMessage(`{"apiVersion":"operators.coreos.com/v1alpha1","kind":"ClusterServiceVersion"}`),
 	}, a.files)
 }
+
+func TestFetchSBOMs(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	imageDigest := "sha256:" + strings.Repeat("a", 64)
+	subject := ref.Context().Digest(imageDigest)
+
+	data := []byte(`{"spdxVersion": "SPDX-2.3", "SPDXID": "SPDXRef-DOCUMENT", "name": "image", "packages": [{"SPDXID": "SPDXRef-image", "name": "image"}]}`)
+	doc := &sbom.Document{
+		Format:   sbom.FormatSPDX,
+		Version:  "SPDX-2.3",
+		Name:     "image",
+		Packages: []sbom.Package{{ID: "SPDXRef-image", Name: "image"}},
+	}
+
+	layer := gcrStatic.NewLayer(data, types.MediaType(o.SPDXMediaType))
+	blobDigest, err := layer.Digest()
+	require.NoError(t, err)
+
+	artifact, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	artifactDigest, err := artifact.Digest()
+	require.NoError(t, err)
+
+	other, err := mutate.AppendLayers(empty.Image, gcrStatic.NewLayer([]byte(`{}`), "application/json"))
+	require.NoError(t, err)
+	otherDigest, err := other.Digest()
+	require.NoError(t, err)
+
+	signatureDigest := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("b", 64)}
+
+	referrers := []fetchedSBOM{
+		{
+			source: sbom.Source{Origin: sbom.OriginReferrer, Ref: "registry.io/repository/image@" + artifactDigest.String()},
+			doc:    doc,
+		},
+		{
+			source: sbom.Source{Origin: sbom.OriginReferrer, Ref: "registry.io/repository/image@" + otherDigest.String()},
+			err:    errors.New("no layer with a SBOM media type found"),
+		},
+	}
+
+	cases := []struct {
+		name       string
+		annotation string
+		expected   []fetchedSBOM
+	}{
+		{
+			name:     "referrers only",
+			expected: referrers,
+		},
+		{
+			name:       "annotation with digest",
+			annotation: blobDigest.String(),
+			expected: append([]fetchedSBOM{
+				{source: sbom.Source{Origin: sbom.OriginBlob, Ref: "registry.io/repository/image@" + blobDigest.String()}, doc: doc},
+			}, referrers...),
+		},
+		{
+			name:       "annotation with reference",
+			annotation: "registry.io/sboms@" + blobDigest.String(),
+			expected: append([]fetchedSBOM{
+				{source: sbom.Source{Origin: sbom.OriginBlob, Ref: "registry.io/sboms@" + blobDigest.String()}, doc: doc},
+			}, referrers...),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			image := empty.Image
+			if c.annotation != "" {
+				image = mutate.Annotations(image, map[string]string{o.SBOMAnnotation: c.annotation}).(v1.Image)
+			}
+
+			client := fake.FakeClient{}
+			client.On("Image", ref, mock.Anything).Return(image, nil)
+			client.On("Layer", mock.Anything).Return(layer, nil)
+			client.On("ResolveDigest", ref).Return(imageDigest, nil)
+			client.On("Referrers", subject).Return(&v1.IndexManifest{
+				Manifests: []v1.Descriptor{
+					{Digest: artifactDigest, ArtifactType: o.SPDXMediaType},
+					{Digest: otherDigest, ArtifactType: o.CycloneDXMediaType},
+					{Digest: signatureDigest, ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json"},
+				},
+			}, nil)
+			client.On("Image", subject.Context().Digest(artifactDigest.String())).Return(artifact, nil)
+			client.On("Image", subject.Context().Digest(otherDigest.String())).Return(other, nil)
+
+			ctx := o.WithClient(context.Background(), &client)
+
+			a := ApplicationSnapshotImage{reference: ref}
+			require.NoError(t, a.FetchSBOMs(ctx))
+			assert.Equal(t, c.expected, a.sboms)
+
+			client.AssertNotCalled(t, "Image", subject.Context().Digest(signatureDigest.String()))
+		})
+	}
+}
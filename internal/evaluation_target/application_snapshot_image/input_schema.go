@@ -0,0 +1,101 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package application_snapshot_image
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	schemaExporter "github.com/invopop/jsonschema"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/enterprise-contract/ec-cli/pkg/schema"
+)
+
+type strictInputKey struct{}
+
+// WithStrictInput enables the validation of the policy input against the
+// policy input JSON schema before it is written
+func WithStrictInput(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictInputKey{}, true)
+}
+
+func isStrictInput(ctx context.Context) bool {
+	strict, ok := ctx.Value(strictInputKey{}).(bool)
+	return ok && strict
+}
+
+// validateInput validates the policy input against the policy input JSON
+// schema
+func validateInput(inputJSON []byte) error {
+	var input any
+	if err := json.Unmarshal(inputJSON, &input); err != nil {
+		return err
+	}
+
+	if err := schema.Policy_Input_v1.Validate(input); err != nil {
+		return fmt.Errorf("the policy input does not match the %s schema: %w", schema.Policy_Input_v1_URI, err)
+	}
+
+	return nil
+}
+
+// InputSchema generates the JSON schema of the policy input from the Input
+// type, the generated schema is shipped as pkg/schema/policy_input_v1.json.
+// The version in the schema ID needs to be bumped on breaking changes of the
+// policy input.
+func InputSchema() ([]byte, error) {
+	rawMessage := reflect.TypeOf(json.RawMessage{})
+	unstructured := reflect.TypeOf(apiextensionsv1.JSON{})
+	r := schemaExporter.Reflector{
+		ExpandedStruct: true,
+		// The raw JSON values, e.g. the image config or the attestation
+		// statements, are provided to the policy as they were fetched
+		Mapper: func(t reflect.Type) *schemaExporter.Schema {
+			if t == rawMessage || t == unstructured {
+				return &schemaExporter.Schema{}
+			}
+			return nil
+		},
+		// The unexported types would otherwise be named in lowercase
+		Namer: func(t reflect.Type) string {
+			n := t.Name()
+			if n == "" {
+				return n
+			}
+			return strings.ToUpper(n[:1]) + n[1:]
+		},
+	}
+
+	s := r.Reflect(&Input{})
+
+	// The types of the parent image and of the source are not known from the
+	// image type
+	if i, ok := s.Definitions["Image"]; ok {
+		i.Properties.Set("parent", &schemaExporter.Schema{Ref: "#/$defs/Image"})
+		i.Properties.Set("source", &schemaExporter.Schema{Ref: "#/$defs/ComponentSource"})
+	}
+
+	s.ID = schemaExporter.ID(schema.Policy_Input_v1_URI)
+	s.Title = "Enterprise Contract policy input"
+	s.Description = "The input provided to the policy rules when validating an image"
+
+	return json.MarshalIndent(s, "", "  ")
+}
@@ -0,0 +1,98 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package application_snapshot_image
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/attestation"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/pkg/schema"
+)
+
+func TestInputSchema(t *testing.T) {
+	s, err := InputSchema()
+	require.NoError(t, err)
+
+	// If this fails the policy input has changed, run `go generate ./pkg/schema`
+	// to update the schema, and bump its version for breaking changes
+	assert.JSONEq(t, schema.Policy_Input_v1_JSON, string(s))
+}
+
+func TestValidateInput(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{
+			name:  "valid",
+			input: `{"attestations": null, "image": {"ref": "registry.io/repository/image:tag"}, "snapshot": {"application": ""}}`,
+		},
+		{
+			name:  "missing image",
+			input: `{"attestations": [], "snapshot": {"application": ""}}`,
+			err:   "missing properties: 'image'",
+		},
+		{
+			name:  "unknown property",
+			input: `{"attestations": [], "image": {"ref": "registry.io/repository/image:tag", "digest": "sha256:0"}, "snapshot": {"application": ""}}`,
+			err:   "additionalProperties 'digest' not allowed",
+		},
+		{
+			name:  "unexpected type",
+			input: `{"attestations": {}, "image": {"ref": "registry.io/repository/image:tag"}, "snapshot": {"application": ""}}`,
+			err:   "expected array, but got object",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateInput([]byte(c.input))
+			if c.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, "the policy input does not match the "+schema.Policy_Input_v1_URI+" schema")
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
+
+func TestWriteInputFileStrict(t *testing.T) {
+	a := ApplicationSnapshotImage{
+		reference:    name.MustParseReference("registry.io/repository/image:tag"),
+		attestations: []attestation.Attestation{createSimpleAttestation(nil)},
+	}
+
+	fs := afero.NewMemMapFs()
+	ctx := WithStrictInput(utils.WithFS(context.Background(), fs))
+	assert.True(t, isStrictInput(ctx))
+	assert.False(t, isStrictInput(context.Background()))
+
+	inputPath, _, err := a.WriteInputFile(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, inputPath)
+}
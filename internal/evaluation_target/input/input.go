@@ -21,12 +21,16 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/enterprise-contract/ec-cli/internal/coverage"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 )
 
-var newConftestEvaluator = evaluator.NewConftestEvaluator
+var (
+	newConftestEvaluator = evaluator.NewConftestEvaluator
+	newOPAEvaluator      = evaluator.NewOPAEvaluator
+)
 
 // Input represents the structure needed to evaluate a generic file input
 type Input struct {
@@ -40,6 +44,13 @@ func NewInput(ctx context.Context, paths []string, p policy.Policy) (*Input, err
 		Paths: paths,
 	}
 
+	newEvaluator := newConftestEvaluator
+	if coverage.FromContext(ctx) != nil {
+		// only the OPA evaluator attaches the coverage and profiling tracers
+		// to the evaluation of the policies
+		newEvaluator = newOPAEvaluator
+	}
+
 	for _, sourceGroup := range p.Spec().Sources {
 		// Todo: Make each fetch run concurrently
 		policySources := source.PolicySourcesFrom(sourceGroup)
@@ -48,13 +59,13 @@ func NewInput(ctx context.Context, paths []string, p policy.Policy) (*Input, err
 			log.Debugf("policySource: %#v", policySource)
 		}
 
-		c, err := newConftestEvaluator(ctx, policySources, p, sourceGroup)
+		c, err := newEvaluator(ctx, policySources, p, sourceGroup)
 		if err != nil {
-			log.Debug("Failed to initialize the conftest evaluator!")
+			log.Debug("Failed to initialize the evaluator!")
 			return nil, err
 		}
 
-		log.Debug("Conftest evaluator initialized")
+		log.Debug("Evaluator initialized")
 		i.Evaluators = append(i.Evaluators, c)
 
 	}
@@ -0,0 +1,39 @@
+# Policies used to test explaining the results
+package explain
+
+# METADATA
+# title: Registry
+# custom:
+#   short_name: registry
+deny[result] {
+	input.image.registry == "registry.io"
+	data.config.policy.when_ns > 0
+	result := {
+		"code": "explain.registry",
+		"msg": sprintf("Image from %s", [input.image.registry]),
+	}
+}
+
+# METADATA
+# title: Tag
+# custom:
+#   short_name: tag
+warn[result] {
+	input.image.tag == "latest"
+	result := {
+		"code": "explain.tag",
+		"msg": "Image uses the latest tag",
+	}
+}
+
+# METADATA
+# title: Digest
+# custom:
+#   short_name: digest
+deny[result] {
+	not input.image.digest
+	result := {
+		"code": "explain.digest",
+		"msg": "Image is not pinned to a digest",
+	}
+}
@@ -30,10 +30,13 @@ import (
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/parser"
 	conftest "github.com/open-policy-agent/conftest/policy"
 	"github.com/open-policy-agent/conftest/runner"
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -178,6 +181,7 @@ const (
 	metadataSolution    = "solution"
 	metadataTerm        = "term"
 	metadataTitle       = "title"
+	metadataImageScope  = "image_scope"
 )
 
 const (
@@ -191,6 +195,7 @@ type ConfigProvider interface {
 	EffectiveTime() time.Time
 	SigstoreOpts() (policy.SigstoreOpts, error)
 	Spec() ecc.EnterpriseContractPolicySpec
+	Exceptions() []policy.Exception
 }
 
 // ConftestEvaluator represents a structure which can be used to evaluate targets
@@ -220,6 +225,39 @@ func (r conftestRunner) Run(ctx context.Context, fileList []string) (result []Ou
 		return
 	}
 
+	// we can't reference the engine from the test runner or from the results so
+	// we need to recreate it, this needs to remain the same as in
+	// runner.TestRunner's Run function
+	var engine *conftest.Engine
+	engine, err = conftest.LoadWithData(r.Policy, r.Data, r.Capabilities, r.Strict)
+	if err != nil {
+		return
+	}
+
+	store := engine.Store()
+
+	var txn storage.Transaction
+	txn, err = store.NewTransaction(ctx)
+	if err != nil {
+		return
+	}
+	defer store.Abort(ctx, txn)
+
+	ids := []string{} // everything
+
+	var d any
+	d, err = store.Read(ctx, txn, ids)
+	if err != nil {
+		return
+	}
+
+	var ok bool
+	if data, ok = d.(map[string]any); !ok {
+		err = fmt.Errorf("could not retrieve data from the policy engine: Data is: %v", d)
+		return
+	}
+
+	explainEnabled := isExplainEnabled(ctx)
 	for _, res := range conftestResult {
 		if log.IsLevelEnabled(log.TraceLevel) {
 			for _, q := range res.Queries {
@@ -236,7 +274,7 @@ func (r conftestRunner) Run(ctx context.Context, fileList []string) (result []Ou
 			}
 		}
 
-		result = append(result, Outcome{
+		outcome := Outcome{
 			FileName:  res.FileName,
 			Namespace: res.Namespace,
 			// Conftest doesn't give us a list of successes, just a count. Here we turn that count
@@ -246,40 +284,72 @@ func (r conftestRunner) Run(ctx context.Context, fileList []string) (result []Ou
 			Warnings:   toRules(res.Warnings),
 			Failures:   toRules(res.Failures),
 			Exceptions: toRules(res.Exceptions),
-		})
-	}
+		}
 
-	// we can't reference the engine from the test runner or from the results so
-	// we need to recreate it, this needs to remain the same as in
-	// runner.TestRunner's Run function
-	var engine *conftest.Engine
-	engine, err = conftest.LoadWithData(r.Policy, r.Data, r.Capabilities, r.Strict)
-	if err != nil {
-		return
+		if explainEnabled {
+			if err = r.explain(ctx, engine, res, data, &outcome); err != nil {
+				return
+			}
+		}
+
+		result = append(result, outcome)
 	}
 
-	store := engine.Store()
+	return
+}
 
-	var txn storage.Transaction
-	txn, err = store.NewTransaction(ctx)
+// explain adds explanations to the failures and warnings of the outcome. The
+// Conftest test runner provides only the textual evaluation trace, so the
+// queries that produced failures or warnings are evaluated again with a
+// tracer capturing the trace events.
+func (r conftestRunner) explain(ctx context.Context, engine *conftest.Engine, res output.CheckResult, data Data, outcome *Outcome) error {
+	configs, err := parser.ParseConfigurations([]string{res.FileName})
 	if err != nil {
-		return
+		return err
 	}
 
-	ids := []string{} // everything
+	// multi-document inputs are evaluated one document at a time, as the
+	// Conftest test runner does
+	documents, ok := configs[res.FileName].([]any)
+	if !ok {
+		documents = []any{configs[res.FileName]}
+	}
 
-	var d any
-	d, err = store.Read(ctx, txn, ids)
+	dataValue, err := ast.InterfaceToValue(map[string]any(data))
 	if err != nil {
-		return
+		return err
 	}
 
-	var ok bool
-	if data, ok = d.(map[string]any); !ok {
-		err = fmt.Errorf("could not retrieve data from the policy engine: Data is: %v", d)
+	for _, q := range res.Queries {
+		if q.Passed() {
+			continue
+		}
+
+		for _, document := range documents {
+			input, err := ast.InterfaceToValue(document)
+			if err != nil {
+				return err
+			}
+
+			tracer := topdown.NewBufferTracer()
+			_, err = rego.New(
+				rego.Query(q.Query),
+				rego.Compiler(engine.Compiler()),
+				rego.Store(engine.Store()),
+				rego.ParsedInput(input),
+				rego.QueryTracer(tracer),
+			).Eval(ctx)
+			if err != nil {
+				return fmt.Errorf("explaining %s: %w", q.Query, err)
+			}
+
+			explanations := explain(*tracer, q.Query, input, dataValue)
+			addExplanations(outcome.Failures, explanations)
+			addExplanations(outcome.Warnings, explanations)
+		}
 	}
 
-	return
+	return nil
 }
 
 // NewConftestEvaluator returns initialized conftestEvaluator implementing
@@ -365,13 +435,59 @@ func (r *policyRules) collect(a *ast.AnnotationsRef) error {
 }
 
 func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget) ([]Outcome, Data, error) {
-	var results []Outcome
-
 	if trace.IsEnabled() {
 		region := trace.StartRegion(ctx, "ec:conftest-evaluate")
 		defer region.End()
 	}
 
+	rules, err := c.collectRules(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var r testRunner
+	var ok bool
+	if r, ok = ctx.Value(runnerKey).(testRunner); r == nil || !ok {
+
+		// should there be a namespace defined or not
+		allNamespaces := true
+		if len(c.namespace) > 0 {
+			allNamespaces = false
+		}
+
+		r = &conftestRunner{
+			runner.TestRunner{
+				Data:          []string{c.dataDir},
+				Policy:        []string{c.policyDir},
+				Namespace:     c.namespace,
+				AllNamespaces: allNamespaces,
+				NoFail:        true,
+				Output:        c.outputFormat,
+				Capabilities:  c.CapabilitiesPath(),
+			},
+		}
+	}
+
+	log.Debugf("runner: %#v", r)
+	log.Debugf("inputs: %#v", target.Inputs)
+
+	runResults, data, err := r.Run(ctx, target.Inputs)
+	if err != nil {
+		// TODO do we want to evaluate further policies instead of erroring out?
+		return nil, nil, err
+	}
+
+	results, err := c.processResults(ctx, runResults, rules, target.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return results, data, nil
+}
+
+// collectRules downloads all policy sources and collects the annotations of
+// the rules within them.
+func (c conftestEvaluator) collectRules(ctx context.Context) (policyRules, error) {
 	// hold all rule annotations from all policy sources
 	// NOTE: emphasis on _all rules from all sources_; meaning that if two rules
 	// exist with the same code in two separate sources the collected rule
@@ -383,7 +499,7 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 		if err != nil {
 			log.Debugf("Unable to download source from %s!", s.PolicyUrl())
 			// TODO do we want to download other policies instead of erroring out?
-			return nil, nil, err
+			return nil, err
 		}
 		annotations := []*ast.AnnotationsRef{}
 		fs := utils.FS(ctx)
@@ -396,7 +512,7 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 					// Let's try to give some more robust messaging to the user.
 					policyURL, err := url.Parse(s.PolicyUrl())
 					if err != nil {
-						return nil, nil, errMsg
+						return nil, errMsg
 					}
 					// Do we have a prefix at the end of the URL path?
 					// If not, this means we aren't trying to access a specific file.
@@ -410,7 +526,7 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 						}
 					}
 				}
-				return nil, nil, errMsg
+				return nil, errMsg
 			}
 		}
 
@@ -419,46 +535,33 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 				continue
 			}
 			if err := rules.collect(a); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 		}
 	}
 
-	var r testRunner
-	var ok bool
-	if r, ok = ctx.Value(runnerKey).(testRunner); r == nil || !ok {
-
-		// should there be a namespace defined or not
-		allNamespaces := true
-		if len(c.namespace) > 0 {
-			allNamespaces = false
-		}
-
-		r = &conftestRunner{
-			runner.TestRunner{
-				Data:          []string{c.dataDir},
-				Policy:        []string{c.policyDir},
-				Namespace:     c.namespace,
-				AllNamespaces: allNamespaces,
-				NoFail:        true,
-				Output:        c.outputFormat,
-				Capabilities:  c.CapabilitiesPath(),
-			},
-		}
-	}
+	return rules, nil
+}
 
-	log.Debugf("runner: %#v", r)
-	log.Debugf("inputs: %#v", target.Inputs)
+// processResults applies the policy configuration to the results of the policy
+// evaluation: it adds the rule metadata, filters the results according to the
+// include and exclude criteria, waives the results matching the exceptions,
+// handles severity and effective_on, computes the successes and removes the
+// results depending on reported rules.
+func (c conftestEvaluator) processResults(ctx context.Context, runResults []Outcome, rules policyRules, target string) ([]Outcome, error) {
+	var results []Outcome
 
-	runResults, data, err := r.Run(ctx, target.Inputs)
-	if err != nil {
-		// TODO do we want to evaluate further policies instead of erroring out?
-		return nil, nil, err
+	// The image scoped criteria apply only to image targets, e.g. not when
+	// validating arbitrary input, or when the image digest is unknown
+	if target == "" && (c.include.scoped() || c.exclude.scoped()) {
+		log.Debug("No evaluation target, skipping the include and exclude criteria scoped to images")
 	}
 
 	effectiveTime := c.policy.EffectiveTime()
 	ctx = context.WithValue(ctx, effectiveTimeKey, effectiveTime)
 
+	waivers := newExceptions(c.policy, target, effectiveTime)
+
 	// Track how many rules have been processed. This is used later on to determine if anything
 	// at all was processed.
 	totalRules := 0
@@ -476,10 +579,18 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 			warning := result.Warnings[i]
 			addRuleMetadata(ctx, &warning, rules)
 
-			if !c.isResultIncluded(warning, target.Target) {
+			if !c.isResultIncluded(warning, target) {
 				log.Debugf("Skipping result warning: %#v", warning)
 				continue
 			}
+			c.addImageScope(&warning, target)
+
+			waived, expired := waivers.waive(&warning)
+			warnings = append(warnings, expired...)
+			if waived {
+				exceptions = append(exceptions, warning)
+				continue
+			}
 
 			if getSeverity(warning) == severityFailure {
 				failures = append(failures, warning)
@@ -492,10 +603,18 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 			failure := result.Failures[i]
 			addRuleMetadata(ctx, &failure, rules)
 
-			if !c.isResultIncluded(failure, target.Target) {
+			if !c.isResultIncluded(failure, target) {
 				log.Debugf("Skipping result failure: %#v", failure)
 				continue
 			}
+			c.addImageScope(&failure, target)
+
+			waived, expired := waivers.waive(&failure)
+			warnings = append(warnings, expired...)
+			if waived {
+				exceptions = append(exceptions, failure)
+				continue
+			}
 
 			if getSeverity(failure) == severityWarning || !isResultEffective(failure, effectiveTime) {
 				warnings = append(warnings, failure)
@@ -522,9 +641,9 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 		result.Skipped = skipped
 
 		// Replace the placeholder successes slice with the actual successes.
-		result.Successes = c.computeSuccesses(result, rules, target.Target)
+		result.Successes = c.computeSuccesses(result, rules, target)
 
-		totalRules += len(result.Warnings) + len(result.Failures) + len(result.Successes)
+		totalRules += len(result.Warnings) + len(result.Failures) + len(result.Successes) + len(result.Exceptions)
 
 		results = append(results, result)
 	}
@@ -535,10 +654,10 @@ func (c conftestEvaluator) Evaluate(ctx context.Context, target EvaluationTarget
 	// ran due to input error, etc.
 	if totalRules == 0 {
 		log.Error("no successes, warnings, or failures, check input")
-		return nil, nil, fmt.Errorf("no successes, warnings, or failures, check input")
+		return nil, fmt.Errorf("no successes, warnings, or failures, check input")
 	}
 
-	return results, data, nil
+	return results, nil
 }
 
 func toRules(results []output.Result) []Result {
@@ -613,6 +732,7 @@ func (c conftestEvaluator) computeSuccesses(result Outcome, rules policyRules, t
 			log.Debugf("Skipping result success: %#v", success)
 			continue
 		}
+		c.addImageScope(&success, target)
 
 		if rule.EffectiveOn != "" {
 			success.Metadata[metadataEffectiveOn] = rule.EffectiveOn
@@ -851,6 +971,22 @@ func (c conftestEvaluator) isResultIncluded(result Result, target string) bool {
 	return includeScore > excludeScore
 }
 
+// addImageScope records, in the result metadata, the imageRef of the include
+// or exclude criteria specific to the image that matched the result, if any.
+// This allows auditing which image scoped criteria applied to the result.
+func (c conftestEvaluator) addImageScope(result *Result, target string) {
+	matchers := makeMatchers(*result)
+	for _, criteria := range []*Criteria{c.include, c.exclude} {
+		if imageRef, items := criteria.scope(target); scoreMatches(matchers, items) > 0 {
+			if result.Metadata == nil {
+				result.Metadata = map[string]any{}
+			}
+			result.Metadata[metadataImageScope] = imageRef
+			return
+		}
+	}
+}
+
 // scoreMatches returns the combined score for every match between needles and haystack.
 func scoreMatches(needles, haystack []string) int {
 	var s int
@@ -51,6 +51,13 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/utils"
 )
 
+// TestMain keeps the tests from using the persistent cache in the user's
+// cache directory
+func TestMain(m *testing.M) {
+	os.Setenv("EC_CACHE", "false")
+	os.Exit(m.Run())
+}
+
 type mockTestRunner struct {
 	mock.Mock
 }
@@ -1313,6 +1320,88 @@ func TestConftestEvaluatorIncludeExclude(t *testing.T) {
 	}
 }
 
+func TestConftestEvaluatorExceptions(t *testing.T) {
+	results := []Outcome{
+		{
+			Failures: []Result{
+				{Message: "spam", Metadata: map[string]any{"code": "breakfast.spam"}},
+				{Message: "eggs", Metadata: map[string]any{"code": "breakfast.eggs", "term": "scrambled"}},
+				{Message: "bacon", Metadata: map[string]any{"code": "breakfast.bacon"}},
+				{Message: "ham", Metadata: map[string]any{"code": "lunch.ham"}},
+			},
+			Warnings: []Result{
+				{Message: "hash", Metadata: map[string]any{"code": "breakfast.hash"}},
+			},
+		},
+	}
+
+	r := mockTestRunner{}
+	dl := mockDownloader{}
+	inputs := EvaluationTarget{Inputs: []string{"inputs"}, Target: "sha256:abc"}
+	ctx := setupTestContext(&r, &dl)
+	r.On("Run", ctx, inputs.Inputs).Return(results, Data(nil), nil)
+
+	eTime, err := time.Parse(policy.DateFormat, "2024-06-01")
+	require.NoError(t, err)
+	config := &mockConfigProvider{}
+	config.On("EffectiveTime").Return(eTime)
+	config.On("SigstoreOpts").Return(policy.SigstoreOpts{}, nil)
+	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+	config.On("Exceptions").Return([]policy.Exception{
+		{Value: "breakfast.spam", ExpiresOn: "2024-07-01", Owner: "team", Ticket: "JIRA-1", Reason: "waiting on upstream"},
+		{Value: "breakfast.eggs:scrambled", ImageRef: "sha256:abc"},
+		{Value: "breakfast.bacon", ExpiresOn: "2024-05-01T00:00:00Z", Owner: "team"},
+		{Value: "lunch.ham", ImageRef: "sha256:def"},
+		{Value: "breakfast.hash"},
+	})
+
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{
+		testPolicySource{},
+	}, config, ecc.Source{})
+	require.NoError(t, err)
+
+	got, _, err := evaluator.Evaluate(ctx, inputs)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Outcome{
+		{
+			Failures: []Result{
+				{Message: "bacon", Metadata: map[string]any{"code": "breakfast.bacon"}},
+				{Message: "ham", Metadata: map[string]any{"code": "lunch.ham"}},
+			},
+			Warnings: []Result{
+				{
+					Message: `Exception for "breakfast.bacon" expired on 2024-05-01T00:00:00Z and no longer applies`,
+					Metadata: map[string]any{
+						"code":  "builtin.exception.expired",
+						"title": "Expired exception",
+						"exception": map[string]any{
+							"value":      "breakfast.bacon",
+							"expires_on": "2024-05-01T00:00:00Z",
+							"owner":      "team",
+						},
+					},
+				},
+			},
+			Exceptions: []Result{
+				{Message: "hash", Metadata: map[string]any{"code": "breakfast.hash", "exception": map[string]any{"value": "breakfast.hash"}}},
+				{Message: "spam", Metadata: map[string]any{"code": "breakfast.spam", "exception": map[string]any{
+					"value":      "breakfast.spam",
+					"expires_on": "2024-07-01",
+					"owner":      "team",
+					"ticket":     "JIRA-1",
+					"reason":     "waiting on upstream",
+				}}},
+				{Message: "eggs", Metadata: map[string]any{"code": "breakfast.eggs", "term": "scrambled", "exception": map[string]any{
+					"value":     "breakfast.eggs:scrambled",
+					"image_ref": "sha256:abc",
+				}}},
+			},
+			Skipped: []Result{},
+		},
+	}, got)
+}
+
 func TestMakeMatchers(t *testing.T) {
 	cases := []struct {
 		name string
@@ -1816,6 +1905,7 @@ func TestConftestEvaluatorEvaluate(t *testing.T) {
 		PublicKey:                   utils.TestPublicKey,
 	}, nil)
 	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+	config.On("Exceptions").Return([]policy.Exception(nil))
 
 	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{
 		&source.PolicyUrl{
@@ -1864,6 +1954,11 @@ func (o *mockConfigProvider) Spec() ecc.EnterpriseContractPolicySpec {
 	return args.Get(0).(ecc.EnterpriseContractPolicySpec)
 }
 
+func (o *mockConfigProvider) Exceptions() []policy.Exception {
+	args := o.Called()
+	return args.Get(0).([]policy.Exception)
+}
+
 func TestUnconformingRule(t *testing.T) {
 	dir := t.TempDir()
 	require.NoError(t, os.MkdirAll(path.Join(dir, "inputs"), 0755))
@@ -1892,6 +1987,117 @@ func TestUnconformingRule(t *testing.T) {
 	assert.EqualError(t, err, `the rule "deny = true { true }" returns an unsupported value, at no_msg.rego:3`)
 }
 
+func TestCriteriaScope(t *testing.T) {
+	c := Criteria{}
+	c.addItem("", "default")
+	c.addItem("sha256:abc", "digest")
+	c.addItem("quay.io/org/app@sha256:def", "reference")
+	c.addItem("quay.io/org/app", "repository")
+	c.addItem("quay.io/org/team-*", "glob")
+	c.addItem("regex:^quay\\.io/org/(api|web)-", "regex")
+	c.addItem("quay.io/org/team-a*", "second glob")
+	c.addItem("regex:(", "invalid")
+	c.addItem("quay.io/[", "invalid")
+
+	cases := []struct {
+		target string
+		scope  string
+		items  []string
+	}{
+		{"", "", []string{"default"}},
+		{"sha256:abc", "sha256:abc", []string{"digest", "default"}},
+		{"quay.io/org/app@sha256:abc", "sha256:abc", []string{"digest", "default"}},
+		{"quay.io/org/app@sha256:def", "quay.io/org/app@sha256:def", []string{"reference", "default"}},
+		{"quay.io/org/app@sha256:123", "quay.io/org/app", []string{"repository", "default"}},
+		{"quay.io/org/team-a@sha256:123", "quay.io/org/team-*", []string{"glob", "default"}},
+		{"quay.io/org/team-a/nested@sha256:123", "", []string{"default"}},
+		{"quay.io/org/api-server@sha256:123", "regex:^quay\\.io/org/(api|web)-", []string{"regex", "default"}},
+		{"quay.io/other/app@sha256:123", "", []string{"default"}},
+		{"sha256:123", "", []string{"default"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.target, func(t *testing.T) {
+			scope, _ := c.scope(tc.target)
+			assert.Equal(t, tc.scope, scope)
+			assert.Equal(t, tc.items, c.get(tc.target))
+		})
+	}
+
+	assert.Equal(t, 7, c.len())
+}
+
+func TestMatchesImage(t *testing.T) {
+	assert.True(t, matchesImage("sha256:abc", "quay.io/org/app@sha256:abc"))
+	assert.True(t, matchesImage("quay.io/org/app", "quay.io/org/app@sha256:abc"))
+	assert.True(t, matchesImage("quay.io/org/*", "quay.io/org/app@sha256:abc"))
+	assert.True(t, matchesImage("regex:/app$", "quay.io/org/app@sha256:abc"))
+	assert.False(t, matchesImage("quay.io/org/other", "quay.io/org/app@sha256:abc"))
+	assert.False(t, matchesImage("quay.io/*", "quay.io/org/app@sha256:abc"))
+	assert.False(t, matchesImage("sha256:abc", ""))
+}
+
+func TestConftestEvaluatorImageScope(t *testing.T) {
+	results := func() []Outcome {
+		return []Outcome{
+			{
+				Failures: []Result{
+					{Metadata: map[string]any{"code": "breakfast.spam"}},
+					{Metadata: map[string]any{"code": "breakfast.eggs"}},
+					{Metadata: map[string]any{"code": "lunch.ham"}},
+				},
+			},
+		}
+	}
+
+	r := mockTestRunner{}
+	dl := mockDownloader{}
+	inputs := EvaluationTarget{Inputs: []string{"inputs"}, Target: "quay.io/org/team-a@sha256:abc"}
+	ctx := setupTestContext(&r, &dl)
+	r.On("Run", ctx, inputs.Inputs).Return(results(), Data(nil), nil).Once()
+	r.On("Run", ctx, inputs.Inputs).Return(results(), Data(nil), nil).Once()
+
+	p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+	require.NoError(t, err)
+
+	evaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{
+		testPolicySource{},
+	}, p, ecc.Source{
+		VolatileConfig: &ecc.VolatileSourceConfig{
+			Include: []ecc.VolatileCriteria{
+				{Value: "*"},
+				{Value: "lunch", ImageRef: "quay.io/org/team-*"},
+			},
+			Exclude: []ecc.VolatileCriteria{
+				{Value: "breakfast.spam", ImageRef: "quay.io/org/team-*"},
+				{Value: "breakfast.eggs", ImageRef: "quay.io/org/team-b"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	got, _, err := evaluator.Evaluate(ctx, inputs)
+	require.NoError(t, err)
+
+	require.Len(t, got, 1)
+	assert.Equal(t, []Result{
+		{Metadata: map[string]any{"code": "breakfast.eggs"}},
+		{Metadata: map[string]any{"code": "lunch.ham", "image_scope": "quay.io/org/team-*"}},
+	}, got[0].Failures)
+	assert.Empty(t, got[0].Exceptions)
+
+	// the image scoped criteria are skipped without an evaluation target
+	got, _, err = evaluator.Evaluate(ctx, EvaluationTarget{Inputs: inputs.Inputs})
+	require.NoError(t, err)
+
+	require.Len(t, got, 1)
+	assert.Equal(t, []Result{
+		{Metadata: map[string]any{"code": "breakfast.spam"}},
+		{Metadata: map[string]any{"code": "breakfast.eggs"}},
+		{Metadata: map[string]any{"code": "lunch.ham"}},
+	}, got[0].Failures)
+}
+
 func TestNewConftestEvaluatorComputeIncludeExclude(t *testing.T) {
 	cases := []struct {
 		name            string
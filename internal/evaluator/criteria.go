@@ -18,6 +18,10 @@ package evaluator
 
 import (
 	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
@@ -26,36 +30,80 @@ import (
 
 // contains include/exclude items
 // digestItems stores include/exclude items that are specific with an imageRef
-// - the imageRef is the key, value is the policy to include/exclude.
+// - the imageRef is the key, value is the policy to include/exclude. The
+// imageRef is either an image digest, an image reference with a digest or an
+// image repository.
+// patternItems stores include/exclude items specific to images matching a
+// glob or a regular expression, in the order they were added.
 // defaultItems are include/exclude items without an imageRef
 type Criteria struct {
 	digestItems  map[string][]string
+	patternItems []patternItems
 	defaultItems []string
 }
 
+// regexPrefix marks an imageRef as a regular expression
+const regexPrefix = "regex:"
+
+// patternItems holds the include/exclude items for images matching a pattern,
+// either a glob, e.g. "quay.io/org/team-*", or a regular expression prefixed
+// with "regex:", e.g. "regex:^quay\.io/org/team-(a|b)$".
+type patternItems struct {
+	pattern string
+	regex   *regexp.Regexp
+	items   []string
+}
+
+// isImagePattern returns true if the imageRef is a glob or a regular
+// expression rather than an exact image digest, reference or repository.
+func isImagePattern(imageRef string) bool {
+	return strings.HasPrefix(imageRef, regexPrefix) || strings.ContainsAny(imageRef, "*?[")
+}
+
+// matches returns true if the image repository matches the pattern. Globs
+// match the complete repository, with "*" not matching the "/" separator,
+// regular expressions match any part of the repository unless anchored.
+func (p patternItems) matches(repository string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(repository)
+	}
+
+	ok, _ := path.Match(p.pattern, repository)
+	return ok
+}
+
+// splitTarget splits the evaluation target, an image reference with a digest,
+// into the image repository and the digest. Targets holding just the digest
+// have no repository.
+func splitTarget(target string) (repository, digest string) {
+	if i := strings.LastIndex(target, "@"); i != -1 {
+		return target[:i], target[i+1:]
+	}
+	return "", target
+}
+
 func (c *Criteria) len() int {
 	totalLength := len(c.defaultItems)
 	for _, items := range c.digestItems {
 		totalLength += len(items)
 	}
+	for _, p := range c.patternItems {
+		totalLength += len(p.items)
+	}
 	return totalLength
 }
 
 func (c *Criteria) addItem(key, value string) {
-	if key == "" {
-		c.defaultItems = append(c.defaultItems, value)
-	} else {
-		if c.digestItems == nil {
-			c.digestItems = make(map[string][]string)
-		}
-		c.digestItems[key] = append(c.digestItems[key], value)
-	}
+	c.addArray(key, []string{value})
 }
 
 func (c *Criteria) addArray(key string, values []string) {
-	if key == "" {
+	switch {
+	case key == "":
 		c.defaultItems = append(c.defaultItems, values...)
-	} else {
+	case isImagePattern(key):
+		c.addPattern(key, values)
+	default:
 		if c.digestItems == nil {
 			c.digestItems = make(map[string][]string)
 		}
@@ -63,10 +111,80 @@ func (c *Criteria) addArray(key string, values []string) {
 	}
 }
 
-func (c *Criteria) get(key string) []string {
-	if items, ok := c.digestItems[key]; ok {
-		items = append(items, c.defaultItems...)
-		return items
+func (c *Criteria) addPattern(pattern string, values []string) {
+	for i := range c.patternItems {
+		if c.patternItems[i].pattern == pattern {
+			c.patternItems[i].items = append(c.patternItems[i].items, values...)
+			return
+		}
+	}
+
+	p := patternItems{pattern: pattern, items: values}
+	if expr, ok := strings.CutPrefix(pattern, regexPrefix); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			log.Warnf("ignoring criteria %q, unable to parse the regular expression %q: %v", values, expr, err)
+			return
+		}
+		p.regex = regex
+	} else if _, err := path.Match(pattern, ""); err != nil {
+		log.Warnf("ignoring criteria %q, unable to parse the pattern %q: %v", values, pattern, err)
+		return
+	}
+
+	c.patternItems = append(c.patternItems, p)
+}
+
+// scope returns the imageRef and the items of the most specific scope matching
+// the target. The precedence is: the exact image digest or reference, the
+// image repository, and then the patterns in the order they were added. An
+// empty imageRef is returned if no scope matches.
+func (c *Criteria) scope(target string) (string, []string) {
+	if target == "" {
+		return "", nil
+	}
+
+	repository, digest := splitTarget(target)
+	for _, key := range []string{target, digest, repository} {
+		if key == "" {
+			continue
+		}
+		if items, ok := c.digestItems[key]; ok {
+			return key, items
+		}
+	}
+
+	if repository != "" {
+		for _, p := range c.patternItems {
+			if p.matches(repository) {
+				return p.pattern, p.items
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// scoped returns true if any of the items is scoped to an image, repository
+// or pattern.
+func (c *Criteria) scoped() bool {
+	return len(c.digestItems) > 0 || len(c.patternItems) > 0
+}
+
+// matchesImage returns true if the imageRef, either an exact image digest,
+// reference or repository, or a pattern, applies to the target.
+func matchesImage(imageRef, target string) bool {
+	c := Criteria{}
+	c.addItem(imageRef, "")
+	key, _ := c.scope(target)
+	return key != ""
+}
+
+// get returns the items of the most specific scope matching the target along
+// with the default items.
+func (c *Criteria) get(target string) []string {
+	if _, items := c.scope(target); items != nil {
+		return slices.Concat(items, c.defaultItems)
 	}
 	return c.defaultItems
 }
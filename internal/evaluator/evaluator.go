@@ -23,6 +23,11 @@ import (
 type EvaluationTarget struct {
 	Inputs []string
 	Target string
+	// Input, when set, holds the input already in memory. Evaluators able to
+	// evaluate in-memory inputs use it instead of reading the Inputs files. Raw
+	// JSON can be provided as json.RawMessage, inputs shared by several
+	// evaluators are best converted once using PrepareInput.
+	Input any
 }
 
 type Evaluator interface {
@@ -35,6 +40,19 @@ type Evaluator interface {
 	CapabilitiesPath() string
 }
 
+// inMemoryEvaluator is implemented by the evaluators able to evaluate the
+// in-memory Input of the EvaluationTarget.
+type inMemoryEvaluator interface {
+	evaluatesInMemory()
+}
+
+// NeedsInputFiles returns true if the evaluator reads the input from the
+// Inputs files of the EvaluationTarget, and not from its in-memory Input.
+func NeedsInputFiles(e Evaluator) bool {
+	_, ok := e.(inMemoryEvaluator)
+	return !ok
+}
+
 type Data map[string]any
 
 type Outcome struct {
@@ -51,4 +69,7 @@ type Result struct {
 	Message  string                 `json:"msg"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 	Outputs  []string               `json:"outputs,omitempty"`
+	// Explanation describes how the result was reached, captured only when
+	// explaining the evaluation, see WithExplain
+	Explanation *Explanation `json:"explanation,omitempty"`
 }
@@ -0,0 +1,135 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evaluator
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+)
+
+const (
+	// metadataException holds the exception, from the policy configuration,
+	// waiving the result
+	metadataException = "exception"
+	// expiredExceptionCode is the code of the warnings about expired
+	// exceptions
+	expiredExceptionCode = "builtin.exception.expired"
+)
+
+// exceptions tracks the exceptions from the policy configuration matching the
+// results of a single evaluation.
+type exceptions struct {
+	all []policy.Exception
+	// applies holds, for each of the exceptions, whether it applies to the
+	// image being evaluated
+	applies []bool
+	at      time.Time
+	// expired holds the indexes of the expired exceptions that matched, these
+	// are reported only once per evaluation
+	expired map[int]bool
+}
+
+func newExceptions(p ConfigProvider, target string, at time.Time) *exceptions {
+	all := p.Exceptions()
+	applies := make([]bool, len(all))
+	for i, e := range all {
+		applies[i] = e.ImageRef == "" || matchesImage(e.ImageRef, target)
+	}
+
+	return &exceptions{
+		all:     all,
+		applies: applies,
+		at:      at,
+		expired: map[int]bool{},
+	}
+}
+
+// waive returns true if the result is waived by an active exception, the
+// exception is then recorded in the metadata of the result. Expired
+// exceptions matching the result no longer apply, for those newly seen a
+// warning is returned.
+func (e *exceptions) waive(result *Result) (bool, []Result) {
+	if len(e.all) == 0 {
+		return false, nil
+	}
+
+	matchers := makeMatchers(*result)
+
+	var warnings []Result
+	for i, exception := range e.all {
+		if !e.applies[i] {
+			continue
+		}
+
+		if !slices.Contains(matchers, exception.Value) {
+			continue
+		}
+
+		if exception.Expired(e.at) {
+			if !e.expired[i] {
+				e.expired[i] = true
+				warnings = append(warnings, expiredWarning(exception))
+			}
+			continue
+		}
+
+		if result.Metadata == nil {
+			result.Metadata = map[string]any{}
+		}
+		result.Metadata[metadataException] = exceptionMetadata(exception)
+
+		return true, warnings
+	}
+
+	return false, warnings
+}
+
+func expiredWarning(exception policy.Exception) Result {
+	return Result{
+		Message: fmt.Sprintf("Exception for %q expired on %s and no longer applies", exception.Value, exception.ExpiresOn),
+		Metadata: map[string]any{
+			metadataCode:      expiredExceptionCode,
+			metadataTitle:     "Expired exception",
+			metadataException: exceptionMetadata(exception),
+		},
+	}
+}
+
+// exceptionMetadata returns the exception as result metadata, the keys follow
+// the naming of the rule metadata.
+func exceptionMetadata(exception policy.Exception) map[string]any {
+	m := map[string]any{
+		"value": exception.Value,
+	}
+
+	for k, v := range map[string]string{
+		"image_ref":  exception.ImageRef,
+		"expires_on": exception.ExpiresOn,
+		"owner":      exception.Owner,
+		"ticket":     exception.Ticket,
+		"reason":     exception.Reason,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+
+	return m
+}
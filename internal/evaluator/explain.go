@@ -0,0 +1,245 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+const explainKey contextKey = "ec.evaluator.explain"
+
+// maxReferenceValueLength limits the length of the values shown in the
+// condensed form of the explanation
+const maxReferenceValueLength = 80
+
+// Explanation describes how the policy evaluation arrived at a violation or a
+// warning. It is captured from the OPA evaluation trace of the rule that
+// produced the result.
+type Explanation struct {
+	// Rule is the location of the rule that produced the result
+	Rule string `json:"rule,omitempty"`
+	// Inputs holds the input paths, and their values, evaluated by the rule
+	Inputs []Reference `json:"inputs,omitempty"`
+	// Data holds the data paths, and their values, evaluated by the rule
+	Data []Reference `json:"data,omitempty"`
+	// Trace is the evaluation trace of the rule
+	Trace []string `json:"trace,omitempty"`
+}
+
+// Reference is a path within the input or data documents along with the
+// value found at that path, the value is nil if the path is not defined.
+type Reference struct {
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// String returns the reference in the condensed form, e.g. input.a.b = "c".
+// Long values are truncated.
+func (r Reference) String() string {
+	if r.Value == nil {
+		return fmt.Sprintf("%s is undefined", r.Path)
+	}
+
+	value, err := json.Marshal(r.Value)
+	if err != nil {
+		value = []byte(fmt.Sprint(r.Value))
+	}
+
+	if len(value) > maxReferenceValueLength {
+		value = append(value[:maxReferenceValueLength-3], "..."...)
+	}
+
+	return fmt.Sprintf("%s = %s", r.Path, value)
+}
+
+// WithExplain returns a context in which the evaluators capture the policy
+// evaluation trace and explain each violation and warning.
+func WithExplain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explainKey, true)
+}
+
+func isExplainEnabled(ctx context.Context) bool {
+	enabled, ok := ctx.Value(explainKey).(bool)
+	return ok && enabled
+}
+
+// explain returns explanations, keyed by the result message, of the results
+// produced by evaluating the query of a rule. The explanations are assembled
+// from the trace events of the evaluation of the rule bodies. The input and
+// data documents are used to resolve the values of the paths referenced in
+// the evaluated expressions.
+func explain(events []*topdown.Event, query string, input ast.Value, data ast.Value) map[string]*Explanation {
+	explanations := map[string]*Explanation{}
+
+	var rule *ast.Rule
+	var queryID uint64
+	var segment []*topdown.Event
+	for _, e := range events {
+		if r, ok := e.Node.(*ast.Rule); ok && e.Op == topdown.EnterOp && r.Path().String() == query {
+			rule, queryID, segment = r, e.QueryID, nil
+		}
+
+		if rule == nil {
+			continue
+		}
+
+		segment = append(segment, e)
+
+		// the body of the rule evaluated to true producing a value
+		if e.Op != topdown.ExitOp || e.Node != rule || e.QueryID != queryID || rule.Head.Key == nil {
+			continue
+		}
+
+		msg := message(plug(rule.Head.Key, e.Locals))
+		if _, ok := explanations[msg]; ok || msg == "" {
+			continue
+		}
+
+		explanations[msg] = newExplanation(rule, segment, input, data)
+	}
+
+	return explanations
+}
+
+func newExplanation(rule *ast.Rule, events []*topdown.Event, input ast.Value, data ast.Value) *Explanation {
+	explanation := Explanation{}
+	if rule.Location != nil {
+		explanation.Rule = rule.Location.String()
+	}
+
+	seen := map[string]bool{}
+	for _, e := range events {
+		expr, ok := e.Node.(*ast.Expr)
+		if !ok || e.Op != topdown.EvalOp {
+			continue
+		}
+
+		ast.WalkRefs(plug(expr, e.Locals), func(ref ast.Ref) bool {
+			prefix := ref.ConstantPrefix()
+			if len(prefix) < 2 {
+				return false
+			}
+
+			path := prefix.String()
+			if seen[path] {
+				return false
+			}
+
+			switch {
+			case prefix.HasPrefix(ast.InputRootRef):
+				seen[path] = true
+				explanation.Inputs = append(explanation.Inputs, Reference{Path: path, Value: lookup(input, prefix)})
+			case prefix.HasPrefix(ast.DefaultRootRef):
+				// references not found in the data document are references
+				// to rules or functions, those are not included
+				if value := lookup(data, prefix); value != nil {
+					seen[path] = true
+					explanation.Data = append(explanation.Data, Reference{Path: path, Value: value})
+				}
+			}
+
+			return false
+		})
+	}
+
+	buf := bytes.Buffer{}
+	topdown.PrettyTraceWithOpts(&buf, events, topdown.PrettyTraceOptions{Locations: true})
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if len(line) > 0 {
+			explanation.Trace = append(explanation.Trace, line)
+		}
+	}
+
+	return &explanation
+}
+
+// plug replaces the variables in x with the values bound to them
+func plug(x any, locals *ast.ValueMap) any {
+	if locals == nil {
+		return x
+	}
+
+	plugged, err := ast.TransformVars(x, func(v ast.Var) (ast.Value, error) {
+		if value := locals.Get(v); value != nil {
+			return value, nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		return x
+	}
+
+	return plugged
+}
+
+// lookup returns the value found at the path, without the root document,
+// within the document, or nil if the path is not defined
+func lookup(document ast.Value, path ast.Ref) any {
+	if document == nil {
+		return nil
+	}
+
+	value, err := document.Find(path[1:])
+	if err != nil {
+		return nil
+	}
+
+	v, err := ast.JSON(value)
+	if err != nil {
+		return nil
+	}
+
+	return v
+}
+
+// message returns the message of the value produced by a rule, rules produce
+// either the message or an object holding the message in the "msg" attribute
+func message(value any) string {
+	if t, ok := value.(*ast.Term); ok {
+		value = t.Value
+	}
+
+	switch v := value.(type) {
+	case ast.String:
+		return string(v)
+	case ast.Object:
+		if msg := v.Get(ast.StringTerm("msg")); msg != nil {
+			if s, ok := msg.Value.(ast.String); ok {
+				return string(s)
+			}
+		}
+	}
+
+	return ""
+}
+
+// addExplanations sets the explanation of each result found in the
+// explanations by the result message
+func addExplanations(results []Result, explanations map[string]*Explanation) {
+	for i := range results {
+		if e, ok := explanations[results[i].Message]; ok {
+			results[i].Explanation = e
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package evaluator
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func TestExplain(t *testing.T) {
+	dir := t.TempDir()
+	input := path.Join(dir, "input.json")
+	require.NoError(t, os.WriteFile(input, []byte(`{"image": {"registry": "registry.io", "tag": "latest"}}`), 0600))
+
+	rego, err := fs.Sub(policies, "__testdir__/explain")
+	require.NoError(t, err)
+
+	rules, err := rulesArchive(t, rego)
+	require.NoError(t, err)
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	eTime, err := time.Parse(policy.DateFormat, "2014-05-31")
+	require.NoError(t, err)
+	config := &mockConfigProvider{}
+	config.On("EffectiveTime").Return(eTime)
+	config.On("SigstoreOpts").Return(policy.SigstoreOpts{PublicKey: utils.TestPublicKey}, nil)
+	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+	config.On("Exceptions").Return([]policy.Exception(nil))
+
+	evaluators := map[string]func(context.Context, []source.PolicySource, ConfigProvider, ecc.Source) (Evaluator, error){
+		"conftest": NewConftestEvaluator,
+		"opa":      NewOPAEvaluator,
+	}
+
+	for name, newEvaluator := range evaluators {
+		evaluator, err := newEvaluator(ctx, []source.PolicySource{
+			&source.PolicyUrl{Url: rules, Kind: source.PolicyKind},
+		}, config, ecc.Source{})
+		require.NoError(t, err)
+		t.Cleanup(evaluator.Destroy)
+
+		t.Run(name, func(t *testing.T) {
+			results, _, err := evaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{input}})
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+
+			// not explained unless requested
+			for _, r := range append(results[0].Failures, results[0].Warnings...) {
+				assert.Nil(t, r.Explanation)
+			}
+
+			results, _, err = evaluator.Evaluate(WithExplain(ctx), EvaluationTarget{Inputs: []string{input}})
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+
+			explanations := map[string]*Explanation{}
+			for _, r := range append(results[0].Failures, results[0].Warnings...) {
+				require.NotNil(t, r.Explanation, r.Message)
+				assert.NotEmpty(t, r.Explanation.Trace)
+				explanations[r.Metadata[metadataCode].(string)] = r.Explanation
+			}
+			require.Len(t, explanations, 3)
+
+			registry := explanations["explain.registry"]
+			assert.True(t, strings.HasSuffix(registry.Rule, "explain.rego:8"), registry.Rule)
+			assert.Equal(t, []Reference{{Path: "input.image.registry", Value: "registry.io"}}, registry.Inputs)
+			require.Len(t, registry.Data, 1)
+			assert.Equal(t, "data.config.policy.when_ns", registry.Data[0].Path)
+
+			tag := explanations["explain.tag"]
+			assert.True(t, strings.HasSuffix(tag.Rule, "explain.rego:21"), tag.Rule)
+			assert.Equal(t, []Reference{{Path: "input.image.tag", Value: "latest"}}, tag.Inputs)
+			assert.Empty(t, tag.Data)
+
+			digest := explanations["explain.digest"]
+			assert.Equal(t, []Reference{{Path: "input.image.digest"}}, digest.Inputs)
+		})
+	}
+}
+
+func TestReferenceString(t *testing.T) {
+	cases := []struct {
+		name      string
+		reference Reference
+		expected  string
+	}{
+		{
+			name:      "undefined",
+			reference: Reference{Path: "input.a"},
+			expected:  "input.a is undefined",
+		},
+		{
+			name:      "string",
+			reference: Reference{Path: "input.a", Value: "b"},
+			expected:  `input.a = "b"`,
+		},
+		{
+			name:      "object",
+			reference: Reference{Path: "data.a", Value: map[string]any{"b": []any{1, 2}}},
+			expected:  `data.a = {"b":[1,2]}`,
+		},
+		{
+			name:      "truncated",
+			reference: Reference{Path: "input.a", Value: strings.Repeat("x", 100)},
+			expected:  `input.a = "` + strings.Repeat("x", 76) + "...",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, c.reference.String())
+		})
+	}
+}
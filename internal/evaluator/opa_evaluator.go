@@ -0,0 +1,595 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/open-policy-agent/conftest/output"
+	"github.com/open-policy-agent/conftest/parser"
+	conftest "github.com/open-policy-agent/conftest/policy"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/print"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/coverage"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/tracing"
+)
+
+// Rule names considered when checking the policies, these need to remain the
+// same as the ones used by Conftest.
+var (
+	warningRuleRegex = regexp.MustCompile("^warn(_[a-zA-Z0-9]+)*$")
+	failureRuleRegex = regexp.MustCompile("^(deny|violation)(_[a-zA-Z0-9]+)*$")
+)
+
+// opaEvaluator evaluates policies using OPA directly, without the Conftest
+// test runner. Policy sources are downloaded and compiled, and the queries
+// prepared, only once on the first evaluation. Subsequent evaluations reuse
+// the prepared queries. The results are processed in the same way as in the
+// conftestEvaluator.
+type opaEvaluator struct {
+	conftestEvaluator
+	mu       sync.Mutex
+	compiled *compiledPolicy
+}
+
+// compiledPolicy holds the prepared queries for each policy namespace along
+// with the rule annotations and the data from all policy sources.
+type compiledPolicy struct {
+	rules      policyRules
+	namespaces []compiledNamespace
+	data       Data
+	compiler   *ast.Compiler
+}
+
+type compiledNamespace struct {
+	name  string
+	rules []compiledRule
+	// data is the data document used to explain the results
+	data ast.Value
+}
+
+type compiledRule struct {
+	name           string
+	failure        bool
+	query          rego.PreparedEvalQuery
+	exceptionQuery string
+	exceptions     rego.PreparedEvalQuery
+}
+
+// evaluationInput holds the parsed input and the name of the file it
+// originates from, if any. Multi-document inputs, like YAML files with several
+// documents, hold multiple values evaluated independently.
+type evaluationInput struct {
+	fileName string
+	values   []ast.Value
+}
+
+// NewOPAEvaluator returns initialized opaEvaluator implementing Evaluator
+// interface
+func NewOPAEvaluator(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source) (Evaluator, error) {
+	return NewOPAEvaluatorWithNamespace(ctx, policySources, p, source, nil)
+}
+
+// NewOPAEvaluatorWithNamespace returns initialized opaEvaluator limited to the
+// given policy namespaces
+func NewOPAEvaluatorWithNamespace(ctx context.Context, policySources []source.PolicySource, p ConfigProvider, source ecc.Source, namespace []string) (Evaluator, error) {
+	e, err := NewConftestEvaluatorWithNamespace(ctx, policySources, p, source, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("OPA evaluator created")
+	return &opaEvaluator{conftestEvaluator: e.(conftestEvaluator)}, nil
+}
+
+// evaluatesInMemory marks the opaEvaluator as able to evaluate the in-memory
+// Input of the EvaluationTarget, see NeedsInputFiles.
+func (*opaEvaluator) evaluatesInMemory() {}
+
+func (e *opaEvaluator) Evaluate(ctx context.Context, target EvaluationTarget) ([]Outcome, Data, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:opa-evaluate")
+		defer region.End()
+	}
+
+	compiled, err := e.compile(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	inputs, err := parseInputs(target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var recording *coverage.Recording
+	var tracers []topdown.QueryTracer
+	collector := coverage.FromContext(ctx)
+	if collector != nil {
+		recording = collector.Start()
+		tracers = recording.Tracers()
+	}
+
+	var runResults []Outcome
+	for _, input := range inputs {
+		for _, namespace := range compiled.namespaces {
+			result, err := namespace.check(ctx, input, tracers)
+			if err != nil {
+				return nil, nil, err
+			}
+			runResults = append(runResults, result)
+		}
+	}
+
+	if collector != nil {
+		collector.Record(recording, compiled.compiler, e.policyDir)
+	}
+
+	results, err := e.processResults(ctx, runResults, compiled.rules, target.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return results, compiled.data, nil
+}
+
+// compile downloads the policy sources, compiles the policies and prepares
+// the queries, this is done only once. Failures are not retained so that a
+// subsequent evaluation can try again.
+func (e *opaEvaluator) compile(ctx context.Context) (*compiledPolicy, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.compiled != nil {
+		return e.compiled, nil
+	}
+
+	if trace.IsEnabled() {
+		region := trace.StartRegion(ctx, "ec:opa-compile")
+		defer region.End()
+	}
+
+	rules, err := e.collectRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := conftest.LoadWithData([]string{e.policyDir}, []string{e.dataDir}, e.CapabilitiesPath(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	store := engine.Store()
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Abort(ctx, txn)
+
+	d, err := store.Read(ctx, txn, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := d.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("could not retrieve data from the policy engine: Data is: %v", d)
+	}
+
+	compiled := compiledPolicy{
+		rules:    rules,
+		data:     data,
+		compiler: engine.Compiler(),
+	}
+
+	dataValue, err := ast.InterfaceToValue(data)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceRules := map[string][]string{}
+	modules := engine.Modules()
+	files := make([]string, 0, len(modules))
+	for f := range modules {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		module := modules[f]
+		namespace := strings.Replace(module.Package.Path.String(), "data.", "", 1)
+		if _, ok := namespaceRules[namespace]; !ok {
+			namespaceRules[namespace] = []string{}
+		}
+		for _, r := range module.Rules {
+			name := r.Head.Name.String()
+			if !failureRuleRegex.MatchString(name) && !warningRuleRegex.MatchString(name) {
+				continue
+			}
+			if !contains(namespaceRules[namespace], name) {
+				namespaceRules[namespace] = append(namespaceRules[namespace], name)
+			}
+		}
+	}
+
+	namespaces := e.namespace
+	if len(namespaces) == 0 {
+		for namespace := range namespaceRules {
+			namespaces = append(namespaces, namespace)
+		}
+		sort.Strings(namespaces)
+	}
+
+	for _, namespace := range namespaces {
+		n := compiledNamespace{name: namespace, data: dataValue}
+		for _, name := range namespaceRules[namespace] {
+			r := compiledRule{
+				name:    name,
+				failure: failureRuleRegex.MatchString(name),
+				// When matching rules for exceptions, only the name of the
+				// rule is queried, so the severity prefix must be removed.
+				exceptionQuery: fmt.Sprintf("data.%s.exception[_][_] == %q", namespace, removeRulePrefix(name)),
+			}
+
+			if r.exceptions, err = prepareQuery(ctx, engine, r.exceptionQuery); err != nil {
+				return nil, err
+			}
+
+			if r.query, err = prepareQuery(ctx, engine, fmt.Sprintf("data.%s.%s", namespace, name)); err != nil {
+				return nil, err
+			}
+
+			n.rules = append(n.rules, r)
+		}
+		compiled.namespaces = append(compiled.namespaces, n)
+	}
+
+	log.Debugf("Prepared queries for %d namespaces", len(compiled.namespaces))
+
+	e.compiled = &compiled
+	return e.compiled, nil
+}
+
+func prepareQuery(ctx context.Context, engine *conftest.Engine, query string) (rego.PreparedEvalQuery, error) {
+	return rego.New(
+		rego.Query(query),
+		rego.Compiler(engine.Compiler()),
+		rego.Store(engine.Store()),
+	).PrepareForEval(ctx)
+}
+
+// check evaluates the rules of the namespace against the input, the returned
+// Outcome is equivalent to the one produced by the Conftest test runner. The
+// provided tracers are used in the evaluation of all queries.
+func (n compiledNamespace) check(ctx context.Context, input evaluationInput, tracers []topdown.QueryTracer) (Outcome, error) {
+	result := Outcome{
+		FileName:  input.fileName,
+		Namespace: n.name,
+	}
+
+	for _, value := range input.values {
+		if err := n.checkValue(ctx, value, &result, tracers); err != nil {
+			return Outcome{}, err
+		}
+	}
+
+	return result, nil
+}
+
+func (n compiledNamespace) checkValue(ctx context.Context, value ast.Value, result *Outcome, tracers []topdown.QueryTracer) error {
+	for _, r := range n.rules {
+		exceptionResults, err := query(ctx, r.exceptions, r.exceptionQuery, value, tracers...)
+		if err != nil {
+			return fmt.Errorf("query exception: %w", err)
+		}
+
+		var exceptions []output.Result
+		for _, exception := range exceptionResults {
+			// When an exception is found, set the message of the exception
+			// to the query that triggered the exception so that it is known
+			// which exception was triggered.
+			if exception.Passed() {
+				exception.Message = r.exceptionQuery
+				exceptions = append(exceptions, exception)
+			}
+		}
+
+		ruleTracers := tracers
+		var tracer *topdown.BufferTracer
+		if isExplainEnabled(ctx) {
+			tracer = topdown.NewBufferTracer()
+			ruleTracers = append(slices.Clone(tracers), tracer)
+		}
+
+		name := fmt.Sprintf("data.%s.%s", n.name, r.name)
+		ruleResults, err := query(ctx, r.query, name, value, ruleTracers...)
+		if err != nil {
+			return fmt.Errorf("query rule: %w", err)
+		}
+
+		var explanations map[string]*Explanation
+		if tracer != nil {
+			explanations = explain(*tracer, name, value, n.data)
+		}
+
+		// Exceptions have already been accounted for in the exception query
+		// so the rule results are skipped to avoid doubling the result.
+		if len(exceptions) > 0 {
+			result.Exceptions = append(result.Exceptions, toRules(exceptions)...)
+			continue
+		}
+
+		for _, ruleResult := range ruleResults {
+			if ruleResult.Passed() {
+				continue
+			}
+
+			results := toRules([]output.Result{ruleResult})
+			addExplanations(results, explanations)
+
+			if r.failure {
+				result.Failures = append(result.Failures, results...)
+			} else {
+				result.Warnings = append(result.Warnings, results...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// query evaluates the prepared query against the input converting the values
+// of the resulting expressions to results. Rules are expected to produce a set
+// of strings or objects with the "msg" attribute, e.g. deny contains msg or
+// deny contains {"msg": msg}. The provided tracers are used in the
+// evaluation.
+func query(ctx context.Context, q rego.PreparedEvalQuery, name string, input ast.Value, tracers ...topdown.QueryTracer) ([]output.Result, error) {
+	hook := printHook{query: name}
+	options := []rego.EvalOption{
+		rego.EvalParsedInput(input),
+		rego.EvalPrintHook(&hook),
+	}
+
+	var tracer *topdown.BufferTracer
+	if tracing.FromContext(ctx).Enabled(tracing.Opa) && log.IsLevelEnabled(log.TraceLevel) {
+		tracer = topdown.NewBufferTracer()
+		tracers = append(slices.Clone(tracers), tracer)
+	}
+
+	for _, t := range tracers {
+		options = append(options, rego.EvalQueryTracer(t))
+	}
+
+	resultSet, err := q.Eval(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policy: %w", err)
+	}
+
+	if tracer != nil {
+		buf := bytes.Buffer{}
+		topdown.PrettyTrace(&buf, *tracer)
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if len(line) > 0 {
+				log.Tracef("[%s] %s", name, line)
+			}
+		}
+	}
+
+	var results []output.Result
+	for _, r := range resultSet {
+		for _, expression := range r.Expressions {
+			values, _ := expression.Value.([]any)
+			if len(values) == 0 {
+				results = append(results, output.Result{})
+				continue
+			}
+
+			for _, v := range values {
+				switch val := v.(type) {
+				case string:
+					results = append(results, output.Result{Message: val})
+				case map[string]any:
+					result, err := output.NewResult(val)
+					if err != nil {
+						return nil, fmt.Errorf("new result: %w", err)
+					}
+					results = append(results, result)
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// parseInputs returns the inputs of the evaluation target. The in-memory input
+// is used if provided, otherwise the input files are parsed.
+func parseInputs(target EvaluationTarget) ([]evaluationInput, error) {
+	if target.Input != nil {
+		fileName := ""
+		if len(target.Inputs) > 0 {
+			fileName = target.Inputs[0]
+		}
+
+		var input any
+		switch i := target.Input.(type) {
+		case ast.Value:
+			// already converted, see PrepareInput
+			return []evaluationInput{{fileName: fileName, values: []ast.Value{i}}}, nil
+		case json.RawMessage:
+			if err := json.Unmarshal(i, &input); err != nil {
+				return nil, fmt.Errorf("parse input: %w", err)
+			}
+		case []byte:
+			if err := json.Unmarshal(i, &input); err != nil {
+				return nil, fmt.Errorf("parse input: %w", err)
+			}
+		default:
+			input = i
+		}
+
+		parsed, err := toEvaluationInput(fileName, input)
+		if err != nil {
+			return nil, err
+		}
+
+		return []evaluationInput{parsed}, nil
+	}
+
+	files, err := inputFiles(target.Inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	configurations, err := parser.ParseConfigurations(files)
+	if err != nil {
+		return nil, fmt.Errorf("parse configurations: %w", err)
+	}
+
+	inputs := make([]evaluationInput, 0, len(files))
+	for _, fileName := range files {
+		parsed, err := toEvaluationInput(fileName, configurations[fileName])
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, parsed)
+	}
+
+	return inputs, nil
+}
+
+// PrepareInput converts the JSON of the input to the OPA value evaluated by
+// the evaluators able to evaluate in-memory inputs. The returned value is to
+// be set as the Input of the EvaluationTarget, it is converted once and shared
+// by all evaluators evaluating the same input.
+func PrepareInput(data []byte) (any, error) {
+	value, err := ast.ValueFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse input: %w", err)
+	}
+
+	return value, nil
+}
+
+// toEvaluationInput converts the input to OPA values, inputs holding multiple
+// documents are converted to a value per document, same as Conftest does.
+func toEvaluationInput(fileName string, input any) (evaluationInput, error) {
+	documents, ok := input.([]any)
+	if !ok {
+		documents = []any{input}
+	}
+
+	parsed := evaluationInput{fileName: fileName}
+	for _, document := range documents {
+		value, err := ast.InterfaceToValue(document)
+		if err != nil {
+			return evaluationInput{}, fmt.Errorf("parse input %s: %w", fileName, err)
+		}
+		parsed.values = append(parsed.values, value)
+	}
+
+	return parsed, nil
+}
+
+// inputFiles expands any directories within the given list of input files to
+// the supported files within them, same as Conftest does.
+func inputFiles(fileList []string) ([]string, error) {
+	var files []string
+	for _, file := range fileList {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("get file info: %w", err)
+		}
+
+		if !info.IsDir() {
+			files = append(files, file)
+			continue
+		}
+
+		err = filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("walk path: %w", err)
+			}
+
+			if !info.IsDir() && parser.FileSupported(path) {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get files from directory: %w", err)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no files found")
+	}
+
+	return files, nil
+}
+
+// printHook logs the output of print statements within the policies
+type printHook struct {
+	query string
+}
+
+func (h *printHook) Print(_ print.Context, msg string) error {
+	log.Debugf("[%s] %s", h.query, msg)
+	return nil
+}
+
+func removeRulePrefix(rule string) string {
+	if rule == "violation" || rule == "deny" || rule == "warn" {
+		return ""
+	}
+	rule = strings.TrimPrefix(rule, "violation_")
+	rule = strings.TrimPrefix(rule, "deny_")
+	rule = strings.TrimPrefix(rule, "warn_")
+
+	return rule
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
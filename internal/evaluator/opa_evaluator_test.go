@@ -0,0 +1,208 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package evaluator
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+type countingPolicySource struct {
+	source.PolicySource
+	count atomic.Int32
+}
+
+func (c *countingPolicySource) GetPolicy(ctx context.Context, dest string, showMsg bool) (string, error) {
+	c.count.Add(1)
+	return c.PolicySource.GetPolicy(ctx, dest, showMsg)
+}
+
+func sortedOutcomes(results []Outcome) []Outcome {
+	sort.Slice(results, func(l, r int) bool {
+		return strings.Compare(results[l].Namespace, results[r].Namespace) < 0
+	})
+
+	for i := range results {
+		sort.Slice(results[i].Successes, func(l, r int) bool {
+			return strings.Compare(results[i].Successes[l].Metadata[metadataCode].(string), results[i].Successes[r].Metadata[metadataCode].(string)) < 0
+		})
+	}
+
+	return results
+}
+
+func TestOPAEvaluatorEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	input := path.Join(dir, "inputs", "data.json")
+	require.NoError(t, os.MkdirAll(path.Dir(input), 0755))
+	require.NoError(t, os.WriteFile(input, []byte("{}"), 0600))
+
+	rego, err := fs.Sub(policies, "__testdir__/simple")
+	require.NoError(t, err)
+
+	rules, err := rulesArchive(t, rego)
+	require.NoError(t, err)
+
+	ctx := withCapabilities(context.Background(), testCapabilities)
+
+	eTime, err := time.Parse(policy.DateFormat, "2014-05-31")
+	require.NoError(t, err)
+	config := &mockConfigProvider{}
+	config.On("EffectiveTime").Return(eTime)
+	config.On("SigstoreOpts").Return(policy.SigstoreOpts{PublicKey: utils.TestPublicKey}, nil)
+	config.On("Spec").Return(ecc.EnterpriseContractPolicySpec{})
+	config.On("Exceptions").Return([]policy.Exception(nil))
+
+	conftestEvaluator, err := NewConftestEvaluator(ctx, []source.PolicySource{
+		&source.PolicyUrl{Url: rules, Kind: source.PolicyKind},
+	}, config, ecc.Source{})
+	require.NoError(t, err)
+	t.Cleanup(conftestEvaluator.Destroy)
+
+	expected, expectedData, err := conftestEvaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{input}})
+	require.NoError(t, err)
+
+	policySource := &countingPolicySource{PolicySource: &source.PolicyUrl{Url: rules, Kind: source.PolicyKind}}
+	opaEvaluator, err := NewOPAEvaluator(ctx, []source.PolicySource{policySource}, config, ecc.Source{})
+	require.NoError(t, err)
+	t.Cleanup(opaEvaluator.Destroy)
+
+	t.Run("input files", func(t *testing.T) {
+		results, data, err := opaEvaluator.Evaluate(ctx, EvaluationTarget{Inputs: []string{path.Join(dir, "inputs")}})
+		require.NoError(t, err)
+
+		assert.Equal(t, sortedOutcomes(expected), sortedOutcomes(results))
+		assert.Equal(t, expectedData, data)
+	})
+
+	t.Run("in-memory input", func(t *testing.T) {
+		results, data, err := opaEvaluator.Evaluate(ctx, EvaluationTarget{
+			Inputs: []string{input},
+			Input:  json.RawMessage("{}"),
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, sortedOutcomes(expected), sortedOutcomes(results))
+		assert.Equal(t, expectedData, data)
+	})
+
+	// policies are downloaded and compiled only once
+	assert.Equal(t, int32(1), policySource.count.Load())
+
+	// only the Conftest evaluator reads the input files
+	assert.True(t, NeedsInputFiles(conftestEvaluator))
+	assert.False(t, NeedsInputFiles(opaEvaluator))
+}
+
+func TestOPAEvaluatorInputs(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(dir, "single.json"), []byte(`{"a": 1}`), 0600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "multi.yaml"), []byte("a: 1\n---\na: 2\n"), 0600))
+	require.NoError(t, os.WriteFile(path.Join(dir, "ignored.txt"), []byte("text"), 0600))
+
+	prepared, err := PrepareInput([]byte(`[{"a": 1}, {"a": 2}]`))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		target   EvaluationTarget
+		expected map[string]int
+		err      string
+	}{
+		{
+			name:     "directory",
+			target:   EvaluationTarget{Inputs: []string{dir}},
+			expected: map[string]int{path.Join(dir, "multi.yaml"): 2, path.Join(dir, "single.json"): 1},
+		},
+		{
+			name:     "file",
+			target:   EvaluationTarget{Inputs: []string{path.Join(dir, "single.json")}},
+			expected: map[string]int{path.Join(dir, "single.json"): 1},
+		},
+		{
+			name:     "raw JSON",
+			target:   EvaluationTarget{Input: json.RawMessage(`{"a": 1}`)},
+			expected: map[string]int{"": 1},
+		},
+		{
+			name:     "struct",
+			target:   EvaluationTarget{Inputs: []string{"input.json"}, Input: struct{ A int }{1}},
+			expected: map[string]int{"input.json": 1},
+		},
+		{
+			name:     "prepared",
+			target:   EvaluationTarget{Inputs: []string{"input.json"}, Input: prepared},
+			expected: map[string]int{"input.json": 1},
+		},
+		{
+			name:   "invalid raw JSON",
+			target: EvaluationTarget{Input: json.RawMessage(`{`)},
+			err:    "parse input: unexpected end of JSON input",
+		},
+		{
+			name:   "no files",
+			target: EvaluationTarget{},
+			err:    "no files found",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inputs, err := parseInputs(c.target)
+			if c.err != "" {
+				assert.EqualError(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			got := map[string]int{}
+			for _, i := range inputs {
+				got[i.fileName] = len(i.values)
+			}
+			assert.Equal(t, c.expected, got)
+		})
+	}
+}
+
+func TestPrepareInput(t *testing.T) {
+	input, err := PrepareInput([]byte(`{"a": [1, "b"]}`))
+	require.NoError(t, err)
+	require.IsType(t, ast.NewObject(), input)
+	assert.Zero(t, ast.MustParseTerm(`{"a": [1, "b"]}`).Value.Compare(input.(ast.Value)))
+
+	_, err = PrepareInput([]byte(`{`))
+	assert.ErrorContains(t, err, "parse input: ")
+}
@@ -0,0 +1,213 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxRateLimitPause caps the time requests to a host are paused for when the
+// host signals it is rate limiting, e.g. Docker Hub reports the window of the
+// pull limit which spans hours.
+var MaxRateLimitPause = 1 * time.Minute
+
+// RequestCount holds the number of requests sent to a host, and the number
+// of those the host throttled.
+type RequestCount struct {
+	Host      string
+	Requests  int
+	Throttled int
+}
+
+// RateLimitingRoundTripper limits the number of concurrent requests to each
+// host, and pauses sending requests to a host when it responds with the
+// Retry-After header or signals that the rate limit has been reached via the
+// RateLimit-Remaining and RateLimit-Reset headers, or their X- prefixed
+// variants. Retrying the throttled requests is left to the caller.
+type RateLimitingRoundTripper struct {
+	base        http.RoundTripper
+	concurrency int
+	mu          sync.Mutex
+	hosts       map[string]*hostLimit
+}
+
+type hostLimit struct {
+	slots     chan struct{}
+	mu        sync.Mutex
+	notBefore time.Time
+	requests  int
+	throttled int
+}
+
+// NewRateLimitingRoundTripper creates a RateLimitingRoundTripper sending at
+// most concurrency requests at a time to each host using the transport.
+func NewRateLimitingRoundTripper(transport http.RoundTripper, concurrency int) *RateLimitingRoundTripper {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &RateLimitingRoundTripper{
+		base:        transport,
+		concurrency: concurrency,
+		hosts:       map[string]*hostLimit{},
+	}
+}
+
+func (r *RateLimitingRoundTripper) host(host string) *hostLimit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.hosts[host]
+	if !ok {
+		h = &hostLimit{slots: make(chan struct{}, r.concurrency)}
+		r.hosts[host] = h
+	}
+
+	return h
+}
+
+func (r *RateLimitingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	h := r.host(req.URL.Host)
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-h.slots }()
+
+	h.mu.Lock()
+	wait := time.Until(h.notBefore)
+	h.mu.Unlock()
+	if wait > 0 {
+		log.Debugf("Waiting %s before sending the request to %s", wait.Round(time.Millisecond), req.URL.Host)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	resp, err := r.base.RoundTrip(req)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests++
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		h.throttled++
+	}
+
+	if pause, ok := rateLimitPause(resp, time.Now()); ok {
+		if pause > MaxRateLimitPause {
+			pause = MaxRateLimitPause
+		}
+		if until := time.Now().Add(pause); until.After(h.notBefore) {
+			log.Debugf("Rate limited by %s, pausing requests for %s", req.URL.Host, pause.Round(time.Millisecond))
+			h.notBefore = until
+		}
+	}
+
+	return resp, err
+}
+
+// RequestCounts returns the number of requests sent to each host, sorted by
+// the host.
+func (r *RateLimitingRoundTripper) RequestCounts() []RequestCount {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make([]RequestCount, 0, len(r.hosts))
+	for host, h := range r.hosts {
+		h.mu.Lock()
+		counts = append(counts, RequestCount{Host: host, Requests: h.requests, Throttled: h.throttled})
+		h.mu.Unlock()
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Host < counts[j].Host
+	})
+
+	return counts
+}
+
+// rateLimitPause returns the time to wait before sending another request
+// based on the rate limiting headers of the response.
+func rateLimitPause(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return d, true
+		}
+	}
+
+	for _, prefix := range []string{"", "X-"} {
+		remaining, ok := headerInt(resp.Header.Get(prefix + "RateLimit-Remaining"))
+		if !ok || remaining > 0 {
+			continue
+		}
+
+		if reset, ok := headerInt(resp.Header.Get(prefix + "RateLimit-Reset")); ok {
+			// some registries report the reset as a Unix time instead of
+			// the number of seconds
+			if reset > now.Unix()/2 {
+				return time.Unix(reset, 0).Sub(now), true
+			}
+			return time.Duration(reset) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
+
+// retryAfter parses the Retry-After header value given either as the number
+// of seconds or as a HTTP date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now), true
+	}
+
+	return 0, false
+}
+
+// headerInt parses the leading integer of the header value, ignoring any
+// parameters, e.g. "0;w=21600" as sent by Docker Hub.
+func headerInt(value string) (int64, bool) {
+	value, _, _ = strings.Cut(value, ";")
+	i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	return i, err == nil
+}
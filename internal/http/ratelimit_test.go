@@ -0,0 +1,198 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func request(t *testing.T, rawURL string) *http.Request {
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+
+	return &http.Request{Method: http.MethodGet, URL: u}
+}
+
+func TestRateLimitPause(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		status   int
+		header   http.Header
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "no headers", status: http.StatusOK},
+		{
+			name:     "retry after seconds",
+			status:   http.StatusTooManyRequests,
+			header:   http.Header{"Retry-After": []string{"7"}},
+			expected: 7 * time.Second,
+			ok:       true,
+		},
+		{
+			name:     "retry after date",
+			status:   http.StatusServiceUnavailable,
+			header:   http.Header{"Retry-After": []string{now.Add(30 * time.Second).Format(http.TimeFormat)}},
+			expected: 30 * time.Second,
+			ok:       true,
+		},
+		{
+			name:   "retry after on success",
+			status: http.StatusOK,
+			header: http.Header{"Retry-After": []string{"7"}},
+		},
+		{
+			name:   "invalid retry after",
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Retry-After": []string{"soon"}},
+		},
+		{
+			name:     "rate limit exhausted",
+			status:   http.StatusOK,
+			header:   http.Header{"Ratelimit-Remaining": []string{"0"}, "Ratelimit-Reset": []string{"12"}},
+			expected: 12 * time.Second,
+			ok:       true,
+		},
+		{
+			name:   "rate limit remaining",
+			status: http.StatusOK,
+			header: http.Header{"Ratelimit-Remaining": []string{"5"}, "Ratelimit-Reset": []string{"12"}},
+		},
+		{
+			name:     "rate limit reset as Unix time",
+			status:   http.StatusOK,
+			header:   http.Header{"X-Ratelimit-Remaining": []string{"0"}, "X-Ratelimit-Reset": []string{strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}},
+			expected: time.Minute,
+			ok:       true,
+		},
+		{
+			name:   "rate limit without reset",
+			status: http.StatusOK,
+			header: http.Header{"Ratelimit-Remaining": []string{"0;w=21600"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			header := c.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			pause, ok := rateLimitPause(&http.Response{StatusCode: c.status, Header: header}, now)
+			assert.Equal(t, c.ok, ok)
+			assert.Equal(t, c.expected, pause)
+		})
+	}
+}
+
+func TestRateLimitingConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running := map[string]int{}
+	maxRunning := map[string]int{}
+
+	limiter := NewRateLimitingRoundTripper(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		running[req.URL.Host]++
+		maxRunning[req.URL.Host] = max(maxRunning[req.URL.Host], running[req.URL.Host])
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running[req.URL.Host]--
+		mu.Unlock()
+
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, nil
+	}), 2)
+
+	wg := sync.WaitGroup{}
+	for i := range 10 {
+		host := "a.registry.io"
+		if i%2 == 0 {
+			host = "b.registry.io"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := limiter.RoundTrip(request(t, "https://"+host+"/v2/"))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]int{"a.registry.io": 2, "b.registry.io": 2}, maxRunning)
+	assert.Equal(t, []RequestCount{
+		{Host: "a.registry.io", Requests: 5},
+		{Host: "b.registry.io", Requests: 5},
+	}, limiter.RequestCounts())
+}
+
+func TestRateLimitingPausesHost(t *testing.T) {
+	maxPause := MaxRateLimitPause
+	t.Cleanup(func() { MaxRateLimitPause = maxPause })
+	MaxRateLimitPause = 100 * time.Millisecond
+
+	var calls atomic.Int32
+	limiter := NewRateLimitingRoundTripper(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "throttled.io" && calls.Add(1) == 1 {
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"60"}}}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}, nil
+	}), 5)
+
+	resp, err := limiter.RoundTrip(request(t, "https://throttled.io/v2/"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	// other hosts are not paused
+	start := time.Now()
+	_, err = limiter.RoundTrip(request(t, "https://other.io/v2/"))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), MaxRateLimitPause)
+
+	// the pause is capped
+	_, err = limiter.RoundTrip(request(t, "https://throttled.io/v2/"))
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 90*time.Millisecond)
+	assert.Less(t, elapsed, 60*time.Second)
+
+	assert.Equal(t, []RequestCount{
+		{Host: "other.io", Requests: 1},
+		{Host: "throttled.io", Requests: 2, Throttled: 1},
+	}, limiter.RequestCounts())
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/in-toto/in-toto-golang/in_toto"
 	v02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 
 	"github.com/enterprise-contract/ec-cli/internal/signature"
 )
@@ -52,3 +53,31 @@ func (f fakeAtt) Signatures() []signature.EntitySignature {
 func (f fakeAtt) Subject() []in_toto.Subject {
 	return []in_toto.Subject{}
 }
+
+type fakeAttV1 struct {
+	statement in_toto.ProvenanceStatementSLSA1
+}
+
+func (f fakeAttV1) Statement() []byte {
+	bytes, err := json.Marshal(f.statement)
+	if err != nil {
+		panic(err)
+	}
+	return bytes
+}
+
+func (f fakeAttV1) Type() string {
+	return in_toto.StatementInTotoV01
+}
+
+func (f fakeAttV1) PredicateType() string {
+	return v1.PredicateSLSAProvenance
+}
+
+func (f fakeAttV1) Signatures() []signature.EntitySignature {
+	return []signature.EntitySignature{}
+}
+
+func (f fakeAttV1) Subject() []in_toto.Subject {
+	return []in_toto.Subject{}
+}
@@ -23,10 +23,11 @@ import (
 	"runtime/trace"
 	"strings"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/enterprise-contract/ec-cli/internal/auth"
 )
 
 type key string
@@ -92,7 +93,7 @@ func (i ImageReference) resolveDigest(ctx context.Context, opts ...name.Option)
 	if rh, ok := ctx.Value(RemoteHead).(func(name.Reference, ...remote.Option) (*v1.Descriptor, error)); ok {
 		remoteHead = rh
 	}
-	descriptor, err := remoteHead(i.ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	descriptor, err := remoteHead(i.ref, remote.WithAuthFromKeychain(auth.KeychainFromContext(ctx)))
 	if err != nil {
 		return nil, err
 	}
@@ -19,13 +19,17 @@ package image
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"runtime/trace"
+	"slices"
 	"sort"
 	"time"
 
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/qri-io/jsonpointer"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/application_snapshot_image"
@@ -72,6 +76,9 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 	if err := a.FetchImageFiles(ctx); err != nil {
 		log.Debugf("Unable to fetch image manifests: %s", err)
 	}
+	if err := a.FetchSBOMs(ctx); err != nil {
+		log.Debugf("Unable to fetch image SBOMs: %s", err)
+	}
 
 	out.SetImageSignatureCheckFromError(a.ValidateImageSignature(ctx))
 
@@ -106,34 +113,60 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 		return out, nil
 	}
 
-	inputPath, inputJSON, err := a.WriteInputFile(ctx)
-	if err != nil {
-		log.Debug("Problem writing input files!")
-		return nil, err
+	// The input file is written only for the evaluators reading it, others
+	// evaluate the policy input in memory. The policy input is converted for
+	// those once, and shared by all of them. The JSON of the policy input is
+	// retained only if it is to be reported.
+	target := evaluator.EvaluationTarget{}
+	var inputJSON []byte
+	if slices.ContainsFunc(evaluators, evaluator.NeedsInputFiles) {
+		inputPath, j, err := a.WriteInputFile(ctx)
+		if err != nil {
+			log.Debug("Problem writing input files!")
+			return nil, err
+		}
+		target.Inputs = []string{inputPath}
+		inputJSON = j
+	} else {
+		input, err := a.PolicyInput(ctx)
+		if err != nil {
+			log.Debug("Problem preparing the policy input!")
+			return nil, err
+		}
+
+		if inputJSON, err = json.Marshal(input); err != nil {
+			return nil, fmt.Errorf("input to JSON: %w", err)
+		}
+	}
+
+	if slices.ContainsFunc(evaluators, func(e evaluator.Evaluator) bool { return !evaluator.NeedsInputFiles(e) }) {
+		if target.Input, err = evaluator.PrepareInput(inputJSON); err != nil {
+			return nil, err
+		}
 	}
 
 	var allResults []evaluator.Outcome
 
-	for _, e := range evaluators {
-		// Todo maybe: Handle each one concurrently
-		target := evaluator.EvaluationTarget{Inputs: []string{inputPath}}
+	if len(evaluators) > 0 {
 		if digest, err := a.ResolveDigest(ctx); err != nil {
-			log.Debugf("Problem parsing digest from image")
+			log.Debugf("Problem parsing digest from image: %v", err)
 		} else {
-			target.Target = digest
+			// The repository allows include/exclude criteria scoped to
+			// repositories or repository patterns
+			target.Target = fmt.Sprintf("%s@%s", a.GetReference().Context().Name(), digest)
 		}
-		results, data, err := e.Evaluate(ctx, target)
-		log.Debug("\n\nRunning conftest policy check\n\n")
 
+		log.Debug("\n\nRunning conftest policy check\n\n")
+		allResults, out.Data, err = evaluate(ctx, evaluators, target)
 		if err != nil {
 			log.Debug("Problem running conftest policy check!")
 			return nil, err
 		}
-		allResults = append(allResults, results...)
-		out.Data = append(out.Data, data)
 	}
 
-	out.PolicyInput = inputJSON
+	if isPolicyInputRetained(ctx) {
+		out.PolicyInput = inputJSON
+	}
 
 	log.Debug("Conftest policy check complete")
 	out.SetPolicyCheck(allResults)
@@ -141,6 +174,67 @@ func ValidateImage(ctx context.Context, comp app.SnapshotComponent, snap *app.Sn
 	return out, nil
 }
 
+type evaluationPoolKey struct{}
+
+type policyInputKey struct{}
+
+// WithPolicyInput returns a context in which the policy input of the
+// validated images is retained in their output, e.g. to be reported.
+func WithPolicyInput(ctx context.Context) context.Context {
+	return context.WithValue(ctx, policyInputKey{}, true)
+}
+
+func isPolicyInputRetained(ctx context.Context) bool {
+	retained, _ := ctx.Value(policyInputKey{}).(bool)
+	return retained
+}
+
+// WithEvaluationPool returns a context in which at most size policy
+// evaluations run concurrently, shared by all the images validated with it.
+func WithEvaluationPool(ctx context.Context, size int) context.Context {
+	if size < 1 {
+		size = 1
+	}
+	return context.WithValue(ctx, evaluationPoolKey{}, semaphore.NewWeighted(int64(size)))
+}
+
+// evaluate runs the evaluators concurrently, bounded by the evaluation pool
+// of the context if there is one. The outcomes and data are in the order of
+// the evaluators. An error from any evaluator cancels the remaining ones.
+func evaluate(ctx context.Context, evaluators []evaluator.Evaluator, target evaluator.EvaluationTarget) ([]evaluator.Outcome, []evaluator.Data, error) {
+	pool, _ := ctx.Value(evaluationPoolKey{}).(*semaphore.Weighted)
+
+	outcomes := make([][]evaluator.Outcome, len(evaluators))
+	data := make([]evaluator.Data, len(evaluators))
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i, e := range evaluators {
+		g.Go(func() error {
+			if pool != nil {
+				if err := pool.Acquire(ctx, 1); err != nil {
+					return err
+				}
+				defer pool.Release(1)
+			}
+
+			results, d, err := e.Evaluate(ctx, target)
+			if err != nil {
+				return err
+			}
+			outcomes[i] = results
+			data[i] = d
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return slices.Concat(outcomes...), data, nil
+}
+
 func resolveAndSetImageUrl(ctx context.Context, url string, asi *application_snapshot_image.ApplicationSnapshotImage) (string, error) {
 	// Ensure image URL contains a digest to avoid ambiguity in the next
 	// validation steps
@@ -167,18 +261,23 @@ func resolveAndSetImageUrl(ctx context.Context, url string, asi *application_sna
 	return resolved, nil
 }
 
+// attestationTimePointers holds, per predicate type, the JSON Pointers to the
+// attestation time in order of preference
+var attestationTimePointers = map[string][]string{
+	attestation.PredicateSLSAProvenanceV1: {
+		"/predicate/runDetails/metadata/finishedOn",
+		"/predicate/runDetails/metadata/startedOn",
+	},
+}
+
+var defaultAttestationTimePointers = []string{"/predicate/metadata/buildFinishedOn"}
+
 func determineAttestationTime(ctx context.Context, attestations []attestation.Attestation) *time.Time {
 	if len(attestations) == 0 {
 		log.Debug("No attestations provided to determine attestation time")
 		return nil
 	}
 
-	pointer, err := jsonpointer.Parse("/predicate/metadata/buildFinishedOn")
-	if err != nil {
-		log.Debugf("Failed to parse the fixed JSON Pointer: %v", err)
-		panic(err)
-	}
-
 	times := make([]time.Time, 0, len(attestations))
 	for i, attestation := range attestations {
 		data := attestation.Statement()
@@ -186,25 +285,18 @@ func determineAttestationTime(ctx context.Context, attestations []attestation.At
 		if err := json.Unmarshal(data, &obj); err != nil {
 			continue
 		}
-		maybeFinishTime, err := pointer.Eval(obj)
-		if err != nil {
-			log.Debugf("Failed to evaluate JSON Pointer %s for attestation at %d", pointer, i)
-			continue
-		}
 
-		finishTime, ok := maybeFinishTime.(string)
+		pointers, ok := attestationTimePointers[attestation.PredicateType()]
 		if !ok {
-			log.Debugf("Unexpected buildFinishedOn value for attestation at %d: %v", i, maybeFinishTime)
-			continue
+			pointers = defaultAttestationTimePointers
 		}
 
-		time, err := time.Parse(time.RFC3339, finishTime)
-		if err != nil {
-			log.Debugf("Unable to parse buildFinishedOn `%s` as RFC3339 time of attestation at %d", finishTime, i)
-			continue
+		for _, p := range pointers {
+			if t := attestationTime(obj, p, i); t != nil {
+				times = append(times, *t)
+				break
+			}
 		}
-
-		times = append(times, time.UTC())
 	}
 
 	if len(times) == 0 {
@@ -223,3 +315,34 @@ func determineAttestationTime(ctx context.Context, attestations []attestation.At
 
 	return &attestationTime
 }
+
+// attestationTime returns the time found in the attestation at the given JSON
+// Pointer, or nil if not found or not a RFC3339 formatted time.
+func attestationTime(obj map[string]any, p string, i int) *time.Time {
+	pointer, err := jsonpointer.Parse(p)
+	if err != nil {
+		log.Debugf("Failed to parse the fixed JSON Pointer: %v", err)
+		panic(err)
+	}
+
+	maybeTime, err := pointer.Eval(obj)
+	if err != nil {
+		log.Debugf("Failed to evaluate JSON Pointer %s for attestation at %d", p, i)
+		return nil
+	}
+
+	value, ok := maybeTime.(string)
+	if !ok {
+		log.Debugf("Unexpected %s value for attestation at %d: %v", p, i, maybeTime)
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Debugf("Unable to parse %s `%s` as RFC3339 time of attestation at %d", p, value, i)
+		return nil
+	}
+
+	t = t.UTC()
+	return &t
+}
@@ -23,7 +23,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -35,6 +38,7 @@ import (
 	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	v02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/sigstore/cosign/v2/pkg/oci"
 	"github.com/sigstore/cosign/v2/pkg/oci/static"
@@ -43,6 +47,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/enterprise-contract/ec-cli/internal/attestation"
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
@@ -205,6 +210,45 @@ func TestDetermineAttestationTime(t *testing.T) {
 		},
 	}
 
+	time3 := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	time4 := time.Date(2030, 1, 2, 3, 4, 5, 6, time.UTC)
+	att4 := fakeAttV1{
+		statement: in_toto.ProvenanceStatementSLSA1{
+			StatementHeader: in_toto.StatementHeader{
+				PredicateType: slsa1.PredicateSLSAProvenance,
+			},
+			Predicate: slsa1.ProvenancePredicate{
+				RunDetails: slsa1.ProvenanceRunDetails{
+					BuildMetadata: slsa1.BuildMetadata{
+						StartedOn:  &time2,
+						FinishedOn: &time3,
+					},
+				},
+			},
+		},
+	}
+	att5 := fakeAttV1{
+		statement: in_toto.ProvenanceStatementSLSA1{
+			StatementHeader: in_toto.StatementHeader{
+				PredicateType: slsa1.PredicateSLSAProvenance,
+			},
+			Predicate: slsa1.ProvenancePredicate{
+				RunDetails: slsa1.ProvenanceRunDetails{
+					BuildMetadata: slsa1.BuildMetadata{
+						StartedOn: &time4,
+					},
+				},
+			},
+		},
+	}
+	att6 := fakeAttV1{
+		statement: in_toto.ProvenanceStatementSLSA1{
+			StatementHeader: in_toto.StatementHeader{
+				PredicateType: slsa1.PredicateSLSAProvenance,
+			},
+		},
+	}
+
 	cases := []struct {
 		name         string
 		attestations []attestation.Attestation
@@ -214,6 +258,9 @@ func TestDetermineAttestationTime(t *testing.T) {
 		{name: "one attestation", attestations: []attestation.Attestation{att1}, expected: &time1},
 		{name: "two attestations", attestations: []attestation.Attestation{att1, att2}, expected: &time2},
 		{name: "two attestations and one without time", attestations: []attestation.Attestation{att1, att2, att3}, expected: &time2},
+		{name: "SLSA v1 finished time", attestations: []attestation.Attestation{att1, att4}, expected: &time3},
+		{name: "SLSA v1 started time", attestations: []attestation.Attestation{att4, att5}, expected: &time4},
+		{name: "SLSA v1 without time", attestations: []attestation.Attestation{att1, att6}, expected: &time1},
 	}
 
 	for _, c := range cases {
@@ -315,6 +362,7 @@ func TestEvaluatorLifecycle(t *testing.T) {
 	client.On("VerifyImageSignatures", refNoTag, mock.Anything).Return([]oci.Signature{validSignature}, true, nil)
 	client.On("VerifyImageAttestations", refNoTag, mock.Anything).Return([]oci.Signature{validAttestation}, true, nil)
 	client.On("ResolveDigest", refNoTag).Return("@sha256:"+imageDigest, nil)
+	client.On("Referrers", mock.Anything).Return(&v1.IndexManifest{}, nil)
 	ctx = ecoci.WithClient(ctx, &client)
 
 	component := app.SnapshotComponent{
@@ -325,7 +373,7 @@ func TestEvaluatorLifecycle(t *testing.T) {
 	require.NoError(t, err)
 
 	e := &mockEvaluator{}
-	e.On("Evaluate", ctx, mock.Anything).Return([]evaluator.Outcome{}, evaluator.Data{}, nil)
+	e.On("Evaluate", mock.Anything, mock.Anything).Return([]evaluator.Outcome{}, evaluator.Data{}, nil)
 
 	// e.Destroy() should not be invoked
 
@@ -346,3 +394,160 @@ func TestEvaluatorLifecycle(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestPolicyInputFile(t *testing.T) {
+	cases := []struct {
+		name        string
+		evaluators  int
+		retained    bool
+		file        bool
+		policyInput bool
+	}{
+		{name: "no evaluators"},
+		{name: "no evaluators retained", retained: true, policyInput: true},
+		{name: "file evaluator", evaluators: 1, file: true},
+		{name: "file evaluator retained", evaluators: 1, retained: true, file: true, policyInput: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			ctx := utils.WithFS(context.Background(), fs)
+			if c.retained {
+				ctx = WithPolicyInput(ctx)
+			}
+			client := fake.FakeClient{}
+			client.On("Image", name.MustParseReference(imageRegistry+"@sha256:"+imageDigest), mock.Anything).Return(empty.Image, nil)
+			client.On("Head", ref).Return(&gcr.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
+			client.On("VerifyImageSignatures", refNoTag, mock.Anything).Return([]oci.Signature{validSignature}, true, nil)
+			client.On("VerifyImageAttestations", refNoTag, mock.Anything).Return([]oci.Signature{validAttestation}, true, nil)
+			client.On("ResolveDigest", refNoTag).Return("@sha256:"+imageDigest, nil)
+			client.On("Referrers", mock.Anything).Return(&v1.IndexManifest{}, nil)
+			ctx = ecoci.WithClient(ctx, &client)
+
+			p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+			require.NoError(t, err)
+
+			var inputs []string
+			evaluators := []evaluator.Evaluator{}
+			for range c.evaluators {
+				e := &mockEvaluator{}
+				e.On("Evaluate", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					inputs = args.Get(1).([]string)
+				}).Return([]evaluator.Outcome{}, evaluator.Data{}, nil)
+				evaluators = append(evaluators, e)
+			}
+
+			component := app.SnapshotComponent{ContainerImage: imageRef}
+			out, err := ValidateImage(ctx, component, &app.SnapshotSpec{Components: []app.SnapshotComponent{component}}, p, evaluators, false)
+			require.NoError(t, err)
+
+			files := []string{}
+			require.NoError(t, afero.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+				if err == nil && info.Name() == "input.json" {
+					files = append(files, path)
+				}
+				return err
+			}))
+
+			if c.file {
+				require.Len(t, files, 1)
+				assert.Equal(t, files, inputs)
+			} else {
+				assert.Empty(t, files)
+			}
+
+			if c.policyInput {
+				assert.Contains(t, string(out.PolicyInput), `"ref":"`+imageRegistry+"@sha256:"+imageDigest+`"`)
+			} else {
+				assert.Nil(t, out.PolicyInput)
+			}
+		})
+	}
+}
+
+type funcEvaluator func(ctx context.Context) ([]evaluator.Outcome, evaluator.Data, error)
+
+func (f funcEvaluator) Evaluate(ctx context.Context, _ evaluator.EvaluationTarget) ([]evaluator.Outcome, evaluator.Data, error) {
+	return f(ctx)
+}
+
+func (funcEvaluator) Destroy() {}
+
+func (funcEvaluator) CapabilitiesPath() string {
+	return ""
+}
+
+func TestEvaluateOrdering(t *testing.T) {
+	evaluators := make([]evaluator.Evaluator, 0, 5)
+	for i := range 5 {
+		evaluators = append(evaluators, funcEvaluator(func(context.Context) ([]evaluator.Outcome, evaluator.Data, error) {
+			// finish in the reverse order
+			time.Sleep(time.Duration(5-i) * 10 * time.Millisecond)
+			return []evaluator.Outcome{{Namespace: fmt.Sprint(i)}}, evaluator.Data{"i": i}, nil
+		}))
+	}
+
+	outcomes, data, err := evaluate(context.Background(), evaluators, evaluator.EvaluationTarget{})
+	require.NoError(t, err)
+
+	namespaces := make([]string, 0, len(outcomes))
+	for _, o := range outcomes {
+		namespaces = append(namespaces, o.Namespace)
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, namespaces)
+	assert.Equal(t, []evaluator.Data{{"i": 0}, {"i": 1}, {"i": 2}, {"i": 3}, {"i": 4}}, data)
+}
+
+func TestEvaluateCancelsOnError(t *testing.T) {
+	expected := errors.New("expected")
+
+	canceled := false
+	evaluators := []evaluator.Evaluator{
+		funcEvaluator(func(ctx context.Context) ([]evaluator.Outcome, evaluator.Data, error) {
+			<-ctx.Done()
+			canceled = true
+			return nil, nil, ctx.Err()
+		}),
+		funcEvaluator(func(context.Context) ([]evaluator.Outcome, evaluator.Data, error) {
+			return nil, nil, expected
+		}),
+	}
+
+	_, _, err := evaluate(context.Background(), evaluators, evaluator.EvaluationTarget{})
+	assert.ErrorIs(t, err, expected)
+	assert.True(t, canceled)
+}
+
+func TestEvaluatePool(t *testing.T) {
+	ctx := WithEvaluationPool(context.Background(), 2)
+
+	var running, maxRunning atomic.Int32
+	evaluators := make([]evaluator.Evaluator, 0, 6)
+	for range 6 {
+		evaluators = append(evaluators, funcEvaluator(func(context.Context) ([]evaluator.Outcome, evaluator.Data, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil, nil, nil
+		}))
+	}
+
+	// two images validated at the same time share the pool
+	g := errgroup.Group{}
+	for range 2 {
+		g.Go(func() error {
+			_, _, err := evaluate(ctx, evaluators, evaluator.EvaluationTarget{})
+			return err
+		})
+	}
+	require.NoError(t, g.Wait())
+
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/enterprise-contract/ec-cli/internal/evaluator"
 	"github.com/enterprise-contract/ec-cli/internal/format"
 	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/sarif"
 	"github.com/enterprise-contract/ec-cli/internal/version"
 )
 
@@ -88,6 +89,7 @@ const (
 	JSON    = "json"
 	YAML    = "yaml"
 	Summary = "summary"
+	SARIF   = "sarif"
 )
 
 // WriteReport returns a new instance of Report representing the state of
@@ -155,12 +157,26 @@ func (r *Report) toFormat(format string) (data []byte, err error) {
 		data, err = yaml.Marshal(r)
 	case Summary:
 		data, err = json.Marshal(r.toSummary())
+	case SARIF:
+		data, err = json.Marshal(r.toSARIF())
 	default:
 		return nil, fmt.Errorf("%q is not a valid report format", format)
 	}
 	return
 }
 
+// toSARIF returns a version of the report in SARIF format, with the
+// violations and warnings of each input file as results.
+func (r *Report) toSARIF() sarif.Log {
+	b := sarif.NewBuilder(r.EcVersion)
+	for _, i := range r.FilePaths {
+		b.Add(i.FilePath, sarif.LevelError, i.Violations)
+		b.Add(i.FilePath, sarif.LevelWarning, i.Warnings)
+	}
+
+	return b.Log()
+}
+
 // toSummary returns a condensed version of the report.
 func (r *Report) toSummary() summary {
 	pr := summary{}
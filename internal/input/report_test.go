@@ -307,3 +307,52 @@ sources:
 	assert.NoError(t, err)
 	return p
 }
+
+func Test_ReportSARIF(t *testing.T) {
+	inputs := []Input{{
+		FilePath: "/path/to/file1.yaml",
+		Violations: []evaluator.Result{
+			{Message: "violation1", Metadata: map[string]any{"code": "pkg.rule1", "title": "Rule 1"}},
+		},
+		Warnings: []evaluator.Result{
+			{Message: "warning1", Metadata: map[string]any{"code": "pkg.rule2"}},
+		},
+	}}
+
+	report, err := NewReport(inputs, createTestPolicy(t, context.Background()), "data here", nil)
+	assert.NoError(t, err)
+
+	data, err := report.toFormat(SARIF)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [{
+			"tool": {
+				"driver": {
+					"name": "ec",
+					"informationUri": "https://enterprisecontract.dev",
+					"version": "development",
+					"rules": [{"id": "pkg.rule1", "shortDescription": {"text": "Rule 1"}}, {"id": "pkg.rule2"}]
+				}
+			},
+			"artifacts": [{"location": {"uri": "/path/to/file1.yaml"}}],
+			"results": [
+				{
+					"ruleId": "pkg.rule1",
+					"ruleIndex": 0,
+					"level": "error",
+					"message": {"text": "violation1"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "/path/to/file1.yaml", "index": 0}}}]
+				},
+				{
+					"ruleId": "pkg.rule2",
+					"ruleIndex": 1,
+					"level": "warning",
+					"message": {"text": "warning1"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "/path/to/file1.yaml", "index": 0}}}]
+				}
+			]
+		}]
+	}`, string(data))
+}
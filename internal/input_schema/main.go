@@ -0,0 +1,43 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Generates the JSON schema of the policy input shipped in pkg/schema
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluation_target/application_snapshot_image"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s <schema file>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	s, err := application_snapshot_image.InputSchema()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(os.Args[1], append(s, '\n'), 0644); err != nil { // #nosec G306
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
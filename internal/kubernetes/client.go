@@ -24,9 +24,11 @@ import (
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -39,6 +41,8 @@ const clientContextKey contextKey = "ec.kubernetes.client"
 type Client interface {
 	FetchEnterpriseContractPolicy(ctx context.Context, ref string) (*ecc.EnterpriseContractPolicy, error)
 	FetchSnapshot(ctx context.Context, ref string) (*app.Snapshot, error)
+	FetchNamespaceLabels(ctx context.Context, namespace string) (map[string]string, error)
+	FetchSecret(ctx context.Context, ref string) (map[string][]byte, error)
 }
 
 type kubernetesClient struct {
@@ -73,6 +77,12 @@ func NewClient(ctx context.Context) (Client, error) {
 	}, nil
 }
 
+// NewDynamicClient returns a dynamic client for the cluster configured in the
+// kubeconfig, e.g. to watch resources.
+func NewDynamicClient() (dynamic.Interface, error) {
+	return createK8SClient()
+}
+
 func createK8SClient() (client dynamic.Interface, err error) {
 	rules := clientcmd.NewDefaultClientConfigLoadingRules()
 	if kubeconfig != "" {
@@ -165,3 +175,52 @@ func (k *kubernetesClient) FetchSnapshot(ctx context.Context, ref string) (*app.
 
 	return &snapshot, nil
 }
+
+// FetchNamespaceLabels gets the labels of the given namespace in a Kubernetes
+// cluster.
+func (k *kubernetesClient) FetchNamespaceLabels(ctx context.Context, namespace string) (map[string]string, error) {
+	if len(namespace) == 0 {
+		return nil, errors.New("namespace cannot be empty")
+	}
+
+	ns, err := k.client.Resource(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}).Get(ctx, namespace, v1.GetOptions{})
+	if err != nil {
+		log.Debugf("Failed to fetch the namespace from cluster: %s", err)
+		return nil, err
+	}
+
+	return ns.GetLabels(), nil
+}
+
+// FetchSecret gets the data of the Secret from the given reference in a
+// Kubernetes cluster.
+//
+// The reference is expected to be in the format [<namespace>/]<name>. If it does not contain
+// a namespace, the current namespace is used.
+func (k *kubernetesClient) FetchSecret(ctx context.Context, ref string) (map[string][]byte, error) {
+	if len(ref) == 0 {
+		return nil, errors.New("secret reference cannot be empty")
+	}
+
+	name, err := NamespacedName(ref)
+	if err != nil {
+		return nil, err
+	}
+	if name.Namespace == "" {
+		return nil, errors.New("unable to determine namespace for secret")
+	}
+
+	unstructuredSecret, err := k.client.Resource(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}).Namespace(name.Namespace).Get(ctx, name.Name, v1.GetOptions{})
+	if err != nil {
+		log.Debugf("Failed to fetch the secret from cluster: %s", err)
+		return nil, err
+	}
+
+	secret := corev1.Secret{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredSecret.UnstructuredContent(), &secret); err != nil {
+		log.Debugf("Failed to convert unstructured content to concrete secret structure: %s", err)
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
@@ -28,6 +28,7 @@ import (
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/fake"
@@ -76,6 +77,33 @@ var testSnapshot = app.Snapshot{
 	},
 }
 
+var testNamespace = unstructured.Unstructured{
+	Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata": map[string]any{
+			"name": "test",
+			"labels": map[string]any{
+				"team": "a",
+			},
+		},
+	},
+}
+
+var testSecret = unstructured.Unstructured{
+	Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]any{
+			"name":      "pull-secret",
+			"namespace": "test",
+		},
+		"data": map[string]any{
+			".dockerconfigjson": "eyJhdXRocyI6e319", // {"auths":{}}
+		},
+	},
+}
+
 var testKubeconfig = []byte(`
 apiVersion: v1
 kind: Config
@@ -100,7 +128,7 @@ func init() {
 		panic(err)
 	}
 
-	fakeClient = fake.NewSimpleDynamicClient(scheme, &testECP, &testSnapshot)
+	fakeClient = fake.NewSimpleDynamicClient(scheme, &testECP, &testSnapshot, &testNamespace, &testSecret)
 }
 
 func Test_FetchEnterpriseContractPolicy(t *testing.T) {
@@ -213,3 +241,35 @@ func Test_FetchSnapshot(t *testing.T) {
 		})
 	}
 }
+
+func Test_FetchNamespaceLabels(t *testing.T) {
+	k := kubernetesClient{
+		client: fakeClient,
+	}
+
+	labels, err := k.FetchNamespaceLabels(context.TODO(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "a"}, labels)
+
+	_, err = k.FetchNamespaceLabels(context.TODO(), "missing")
+	assert.ErrorContains(t, err, `namespaces "missing" not found`)
+
+	_, err = k.FetchNamespaceLabels(context.TODO(), "")
+	assert.EqualError(t, err, "namespace cannot be empty")
+}
+
+func Test_FetchSecret(t *testing.T) {
+	k := kubernetesClient{
+		client: fakeClient,
+	}
+
+	data, err := k.FetchSecret(context.TODO(), "test/pull-secret")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{".dockerconfigjson": []byte(`{"auths":{}}`)}, data)
+
+	_, err = k.FetchSecret(context.TODO(), "test/missing")
+	assert.ErrorContains(t, err, `secrets "missing" not found`)
+
+	_, err = k.FetchSecret(context.TODO(), "")
+	assert.EqualError(t, err, "secret reference cannot be empty")
+}
@@ -0,0 +1,138 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+// Bundle serves the content recorded in a bundle.
+type Bundle struct {
+	dir      string
+	manifest Manifest
+	sources  *cache.DiskCache
+
+	trustOnce sync.Once
+	trust     *TrustedRoot
+	trustErr  error
+}
+
+// Open extracts the bundle at the given path into a temporary directory,
+// removed by Close.
+func Open(path string) (*Bundle, error) {
+	dir, err := os.MkdirTemp("", "ec-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := open(path, dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("opening offline bundle %q: %w", path, err)
+	}
+
+	return b, nil
+}
+
+func open(path, dir string) (*Bundle, error) {
+	if err := readArchive(path, dir); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	if m.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d, expected %d", m.Version, bundleVersion)
+	}
+
+	return &Bundle{
+		dir:      dir,
+		manifest: m,
+		sources:  cache.NewArchiveCache(filepath.Join(dir, sourcesDir)),
+	}, nil
+}
+
+// Manifest returns the manifest of the bundle.
+func (b *Bundle) Manifest() Manifest {
+	return b.manifest
+}
+
+// Sources returns the cache holding the policy and data sources of the bundle.
+func (b *Bundle) Sources() *cache.DiskCache {
+	return b.sources
+}
+
+// TUFRoot returns the directory holding the sigstore TUF root of the bundle,
+// or an empty string if the bundle does not contain it.
+func (b *Bundle) TUFRoot() string {
+	if !b.manifest.TUFRoot {
+		return ""
+	}
+
+	return filepath.Join(b.dir, tufDir)
+}
+
+// Transport returns a HTTP transport responding with the recorded exchanges.
+// Requests not recorded fail with ErrNotInBundle, the network is never used.
+func (b *Bundle) Transport() http.RoundTripper {
+	return &replayingTransport{dir: filepath.Join(b.dir, httpDir)}
+}
+
+// Close removes the extracted bundle.
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.dir)
+}
+
+type replayingTransport struct {
+	dir string
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := readExchange(t.dir, key, req)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotInBundle, req.Method, req.URL.Redacted())
+	} else if err != nil {
+		return nil, err
+	}
+	log.Tracef("Replayed %s %s", req.Method, req.URL)
+
+	return resp, nil
+}
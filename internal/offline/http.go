@@ -0,0 +1,157 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package offline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// exchange is the recorded response to a HTTP request, the response body is
+// stored alongside in a separate file.
+type exchange struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+}
+
+// excludedHeaders are response headers not recorded, as those hold
+// credentials or are meaningless when replayed
+var excludedHeaders = []string{"Set-Cookie", "Www-Authenticate", "Date", "Connection"}
+
+// isPing returns true for the OCI distribution API version check requests.
+// The ping is used to find out the authentication method, when replayed it
+// always succeeds so no authentication is attempted.
+func isPing(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.URL.Path == "/v2/"
+}
+
+// isAuthentication returns true for requests obtaining registry tokens, those
+// are never recorded.
+func isAuthentication(req *http.Request) bool {
+	q := req.URL.Query()
+	if q.Has("service") || q.Has("scope") {
+		return true
+	}
+
+	return req.Method == http.MethodPost && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
+
+// requestKey returns the key the response to the request is stored under,
+// computed from the method, the URL and the request body. The request body
+// is read and replaced so that it can be sent again.
+func requestKey(req *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// writeExchange stores the exchange and the response body in the directory.
+func writeExchange(dir, key string, e exchange, body []byte) error {
+	for _, h := range excludedHeaders {
+		e.Header.Del(h)
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, key+".body"), body); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(dir, key+".json"), b)
+}
+
+// readExchange constructs the response to the request from the exchange
+// stored in the directory under the key.
+func readExchange(dir, key string, req *http.Request) (*http.Response, error) {
+	b, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var e exchange
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+
+	body, err := os.ReadFile(filepath.Join(dir, key+".body"))
+	if err != nil {
+		return nil, err
+	}
+
+	header := e.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// writeFileAtomic writes the file via a temporary file so that concurrent
+// writers never leave partially written files behind.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
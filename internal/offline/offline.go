@@ -0,0 +1,138 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package offline records everything fetched during image validation into a
+// bundle, and serves those lookups from the bundle to validate images without
+// network access. A bundle is a gzipped tarball holding the recorded HTTP
+// exchanges with OCI registries and Rekor, the policy and data sources and the
+// sigstore TUF root.
+package offline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+)
+
+// bundleVersion is the version of the bundle layout, bundles of other versions
+// are rejected
+const bundleVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	httpDir      = "http"
+	sourcesDir   = "sources"
+	tufDir       = "tuf"
+)
+
+// ErrNotInBundle is returned when a lookup is not present in the offline
+// bundle.
+var ErrNotInBundle = errors.New("not found in the offline bundle")
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	// Version is the version of the bundle layout
+	Version int `json:"version"`
+	// Created is the time the bundle was exported
+	Created time.Time `json:"created"`
+	// Policy is the resolved policy configuration used when exporting
+	Policy string `json:"policy,omitempty"`
+	// Snapshot holds the images validated when exporting
+	Snapshot *app.SnapshotSpec `json:"snapshot,omitempty"`
+	// TUFRoot is set when the bundle contains the sigstore TUF root
+	TUFRoot bool `json:"tufRoot"`
+}
+
+type contextKey string
+
+const (
+	recorderContextKey contextKey = "ec.offline.recorder"
+	bundleContextKey   contextKey = "ec.offline.bundle"
+)
+
+// WithRecorder returns a context under which all lookups are recorded by the
+// given Recorder.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderContextKey, r)
+}
+
+// RecorderFromContext returns the Recorder set with WithRecorder, or nil.
+func RecorderFromContext(ctx context.Context) *Recorder {
+	if ctx == nil {
+		return nil
+	}
+
+	r, _ := ctx.Value(recorderContextKey).(*Recorder)
+	return r
+}
+
+// WithBundle returns a context under which all lookups are served from the
+// given Bundle.
+func WithBundle(ctx context.Context, b *Bundle) context.Context {
+	return context.WithValue(ctx, bundleContextKey, b)
+}
+
+// BundleFromContext returns the Bundle set with WithBundle, or nil.
+func BundleFromContext(ctx context.Context) *Bundle {
+	if ctx == nil {
+		return nil
+	}
+
+	b, _ := ctx.Value(bundleContextKey).(*Bundle)
+	return b
+}
+
+// Enabled returns true if lookups are either recorded or served from a bundle
+// under the given context.
+func Enabled(ctx context.Context) bool {
+	return RecorderFromContext(ctx) != nil || BundleFromContext(ctx) != nil
+}
+
+// Transport returns the HTTP transport to use under the given context. When
+// serving from a bundle the returned transport never reaches the network,
+// when recording the exchanges made with the next transport are recorded,
+// otherwise the next transport is returned as is.
+func Transport(ctx context.Context, next http.RoundTripper) http.RoundTripper {
+	if b := BundleFromContext(ctx); b != nil {
+		return b.Transport()
+	}
+
+	if r := RecorderFromContext(ctx); r != nil {
+		return r.Transport(next)
+	}
+
+	return next
+}
+
+// tufRootDir returns the directory holding the sigstore TUF root, as used by
+// the sigstore TUF client.
+func tufRootDir() (string, error) {
+	if dir := os.Getenv("TUF_ROOT"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".sigstore", "root"), nil
+}
@@ -0,0 +1,362 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package offline
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	fileMetadata "github.com/enterprise-contract/go-gather/metadata/file"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func registry(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case r.URL.Path == "/v2/":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="/token",service="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/token":
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+		case r.URL.Path == "/v2/repo/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			_, _ = w.Write([]byte(`{"manifest": true}`))
+		case r.URL.Path == "/api/v1/index/retrieve" && r.Method == http.MethodPost:
+			b, _ := io.ReadAll(r.Body)
+			_, _ = w.Write([]byte(`["` + string(b) + `"]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &requests
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(b)
+}
+
+func post(t *testing.T, client *http.Client, url, body string) string {
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	tufRoot := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tufRoot, "root.json"), []byte(`{"signed": {}}`), 0600))
+	t.Setenv("TUF_ROOT", tufRoot)
+
+	srv, requests := registry(t)
+
+	recorder, err := NewRecorder()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = recorder.Close() })
+
+	ctx := WithRecorder(context.Background(), recorder)
+	client := &http.Client{Transport: Transport(ctx, http.DefaultTransport)}
+
+	resp, _ := get(t, client, srv.URL+"/v2/")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	_, token := get(t, client, srv.URL+"/token?scope=repository:repo:pull&service=registry")
+	assert.Equal(t, `{"token": "secret"}`, token)
+	_, manifest := get(t, client, srv.URL+"/v2/repo/manifests/latest")
+	assert.Equal(t, `{"manifest": true}`, manifest)
+	resp, _ = get(t, client, srv.URL+"/v2/repo/manifests/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, `["one"]`, post(t, client, srv.URL+"/api/v1/index/retrieve", "one"))
+	assert.Equal(t, `["two"]`, post(t, client, srv.URL+"/api/v1/index/retrieve", "two"))
+
+	policy := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(policy, "policy.rego"), []byte("package main"), 0600))
+	require.NoError(t, recorder.Sources().Put("/path/to/policy", policy, &fileMetadata.DirectoryMetadata{}))
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	snapshot := &app.SnapshotSpec{Components: []app.SnapshotComponent{{ContainerImage: "registry/repo:latest"}}}
+	require.NoError(t, recorder.Write(path, Manifest{Policy: `{"publicKey": "key"}`, Snapshot: snapshot}))
+
+	b, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	m := b.Manifest()
+	assert.Equal(t, bundleVersion, m.Version)
+	assert.Equal(t, `{"publicKey": "key"}`, m.Policy)
+	assert.Equal(t, snapshot, m.Snapshot)
+	assert.True(t, m.TUFRoot)
+	assert.FileExists(t, filepath.Join(b.TUFRoot(), "root.json"))
+
+	recorded := requests.Load()
+	ctx = WithBundle(context.Background(), b)
+	client = &http.Client{Transport: Transport(ctx, http.DefaultTransport)}
+
+	// the ping succeeds so that no authentication is attempted
+	resp, _ = get(t, client, srv.URL+"/v2/")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, manifest = get(t, client, srv.URL+"/v2/repo/manifests/latest")
+	assert.Equal(t, `{"manifest": true}`, manifest)
+	assert.Equal(t, "sha256:abc", resp.Header.Get("Docker-Content-Digest"))
+	assert.Equal(t, int64(len(manifest)), resp.ContentLength)
+
+	resp, _ = get(t, client, srv.URL+"/v2/repo/manifests/missing")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, `["two"]`, post(t, client, srv.URL+"/api/v1/index/retrieve", "two"))
+
+	// registry tokens are never recorded
+	_, err = client.Get(srv.URL + "/token?scope=repository:repo:pull&service=registry")
+	assert.ErrorIs(t, err, ErrNotInBundle)
+
+	_, err = client.Get(srv.URL + "/v2/repo/manifests/other")
+	assert.ErrorIs(t, err, ErrNotInBundle)
+
+	_, err = client.Post(srv.URL+"/api/v1/index/retrieve", "application/json", strings.NewReader("three"))
+	assert.ErrorIs(t, err, ErrNotInBundle)
+
+	// nothing was requested from the server when replaying
+	assert.Equal(t, recorded, requests.Load())
+
+	dest := filepath.Join(t.TempDir(), "policy")
+	_, ok := b.Sources().Get("/path/to/policy", dest)
+	require.True(t, ok)
+	assert.FileExists(t, filepath.Join(dest, "policy.rego"))
+
+	_, ok = b.Sources().Get("/path/to/other", filepath.Join(t.TempDir(), "other"))
+	assert.False(t, ok)
+}
+
+func TestWriteWithoutTUFRoot(t *testing.T) {
+	t.Setenv("TUF_ROOT", filepath.Join(t.TempDir(), "missing"))
+
+	recorder, err := NewRecorder()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = recorder.Close() })
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, recorder.Write(path, Manifest{}))
+
+	b, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	assert.False(t, b.Manifest().TUFRoot)
+	assert.Empty(t, b.TUFRoot())
+}
+
+func TestTrustedRoot(t *testing.T) {
+	rekorKey, err := cryptoutils.UnmarshalPEMToPublicKey([]byte(utils.TestRekorPublicKey))
+	require.NoError(t, err)
+	rekor, err := signature.LoadVerifier(rekorKey, crypto.SHA256)
+	require.NoError(t, err)
+	// despite the name, this is the certificate of the intermediate CA
+	intermediate, err := cryptoutils.UnmarshalCertificatesFromPEM([]byte(utils.TestFulcioRootCert))
+	require.NoError(t, err)
+
+	// initializes the sigstore TUF client cache in TUF_ROOT
+	tuf.NewSigstoreTufRepo(t, tuf.TestSigstoreRoot{Rekor: rekor, FulcioCertificate: intermediate[0]})
+
+	recorder, err := NewRecorder()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = recorder.Close() })
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, recorder.Write(path, Manifest{}))
+
+	// the TUF root is used only from the bundle
+	t.Setenv("TUF_ROOT", filepath.Join(t.TempDir(), "missing"))
+
+	b, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	trusted, err := b.TrustedRoot()
+	require.NoError(t, err)
+
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate[0])
+	assert.True(t, intermediates.Equal(trusted.FulcioIntermediates))
+	assert.True(t, x509.NewCertPool().Equal(trusted.FulcioRoots))
+	assert.Contains(t, trusted.RekorPubKeys.Keys, utils.TestRekorURLLogID)
+	assert.Empty(t, trusted.CTLogPubKeys.Keys)
+
+	// a tampered target is rejected
+	require.NoError(t, os.WriteFile(filepath.Join(b.TUFRoot(), "targets", "rekor.pub"), []byte(utils.TestCTLogPublicKey), 0600))
+	_, err = loadTrustedRoot(b.TUFRoot())
+	assert.ErrorContains(t, err, `verifying the TUF target "rekor.pub"`)
+}
+
+func TestTrustedRootWithoutTUFRoot(t *testing.T) {
+	t.Setenv("TUF_ROOT", filepath.Join(t.TempDir(), "missing"))
+
+	recorder, err := NewRecorder()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = recorder.Close() })
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, recorder.Write(path, Manifest{}))
+
+	b, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+
+	_, err = b.TrustedRoot()
+	assert.EqualError(t, err, "the offline bundle does not contain the sigstore TUF root")
+}
+
+func writeTarball(t *testing.T, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	return path
+}
+
+func TestOpenInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name:  "path traversal",
+			files: map[string]string{"../escape": "x"},
+			err:   `invalid path in bundle: "../escape"`,
+		},
+		{
+			name:  "absolute path",
+			files: map[string]string{"/etc/escape": "x"},
+			err:   `invalid path in bundle: "/etc/escape"`,
+		},
+		{
+			name:  "unsupported version",
+			files: map[string]string{manifestFile: `{"version": 42}`},
+			err:   "unsupported bundle version 42, expected 1",
+		},
+		{
+			name:  "no manifest",
+			files: map[string]string{"http/x.json": "{}"},
+			err:   "no such file or directory",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Open(writeTarball(t, c.files))
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
+
+func TestWriteArchiveSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "policy", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy", "lib", "lib.rego"), []byte("package lib"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join("lib", "lib.rego"), filepath.Join(dir, "policy", "link.rego")))
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	require.NoError(t, writeArchive(path, map[string]string{"sources": dir}))
+
+	out := t.TempDir()
+	require.NoError(t, readArchive(path, out))
+	b, err := os.ReadFile(filepath.Join(out, "sources", "policy", "link.rego"))
+	require.NoError(t, err)
+	assert.Equal(t, "package lib", string(b))
+
+	cases := []struct {
+		name   string
+		target func(t *testing.T) string
+		err    string
+	}{
+		{
+			name: "outside of the directory",
+			target: func(t *testing.T) string {
+				outside := filepath.Join(t.TempDir(), "outside.rego")
+				require.NoError(t, os.WriteFile(outside, []byte("package outside"), 0600))
+				return outside
+			},
+			err: "outside of",
+		},
+		{
+			name:   "directory",
+			target: func(*testing.T) string { return "lib" },
+			err:    "does not point to a regular file",
+		},
+		{
+			name:   "missing",
+			target: func(*testing.T) string { return "missing.rego" },
+			err:    "resolving the symbolic link",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+			require.NoError(t, os.Symlink(c.target(t), filepath.Join(dir, "link")))
+
+			err := writeArchive(filepath.Join(t.TempDir(), "bundle.tar.gz"), map[string]string{"sources": dir})
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package offline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+)
+
+// Recorder records the HTTP exchanges and the downloaded policy and data
+// sources so that those can be written to a bundle.
+type Recorder struct {
+	dir     string
+	sources *cache.DiskCache
+}
+
+// NewRecorder returns a Recorder storing the recorded content in a temporary
+// directory, removed by Close.
+func NewRecorder() (*Recorder, error) {
+	dir, err := os.MkdirTemp("", "ec-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, httpDir), 0700); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &Recorder{
+		dir:     dir,
+		sources: cache.NewArchiveCache(filepath.Join(dir, sourcesDir)),
+	}, nil
+}
+
+// Sources returns the cache recording the downloaded policy and data sources.
+func (r *Recorder) Sources() *cache.DiskCache {
+	return r.sources
+}
+
+// Transport returns a HTTP transport recording the exchanges made with the
+// next transport. Registry authentication is not recorded.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{dir: filepath.Join(r.dir, httpDir), next: next}
+}
+
+// Write writes the bundle with the recorded content, the given manifest and
+// the sigstore TUF root, if present, to the file at the given path.
+func (r *Recorder) Write(path string, m Manifest) error {
+	m.Version = bundleVersion
+	if m.Created.IsZero() {
+		m.Created = time.Now().UTC()
+	}
+
+	tufRoot, err := tufRootDir()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(tufRoot); err == nil {
+		m.TUFRoot = true
+	} else if errors.Is(err, fs.ErrNotExist) {
+		log.Warnf("The sigstore TUF root was not found in %q, it will not be included in the bundle", tufRoot)
+	} else {
+		return err
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(r.dir, manifestFile), b, 0600); err != nil {
+		return err
+	}
+
+	dirs := map[string]string{"": r.dir}
+	if m.TUFRoot {
+		dirs[tufDir] = tufRoot
+	}
+
+	return writeArchive(path, dirs)
+}
+
+// Close removes the recorded content.
+func (r *Recorder) Close() error {
+	return os.RemoveAll(r.dir)
+}
+
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isAuthentication(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	key, err := requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	e := exchange{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+
+	if isPing(req) {
+		e.StatusCode, e.Header = http.StatusOK, http.Header{"Content-Type": []string{"application/json"}}
+		if err := writeExchange(t.dir, key, e, []byte("{}")); err != nil {
+			return nil, fmt.Errorf("recording %s %s: %w", req.Method, req.URL, err)
+		}
+		return resp, nil
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := writeExchange(t.dir, key, e, body); err != nil {
+		return nil, fmt.Errorf("recording %s %s: %w", req.Method, req.URL, err)
+	}
+	log.Tracef("Recorded %s %s", req.Method, req.URL)
+
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package offline
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/tuf"
+	tufclient "github.com/theupdateframework/go-tuf/client"
+	tufleveldb "github.com/theupdateframework/go-tuf/client/leveldbstore"
+	"github.com/theupdateframework/go-tuf/util"
+)
+
+// TrustedRoot holds the sigstore trust material from the TUF root of a bundle,
+// the same material cosign otherwise fetches through its TUF client.
+type TrustedRoot struct {
+	FulcioRoots         *x509.CertPool
+	FulcioIntermediates *x509.CertPool
+	CTLogPubKeys        *cosign.TrustedTransparencyLogPubKeys
+	RekorPubKeys        *cosign.TrustedTransparencyLogPubKeys
+}
+
+// TrustedRoot returns the sigstore trust material from the TUF root of the
+// bundle. The TUF metadata is verified as the sigstore TUF client does, but
+// without ever updating it from the TUF mirror.
+func (b *Bundle) TrustedRoot() (*TrustedRoot, error) {
+	b.trustOnce.Do(func() {
+		dir := b.TUFRoot()
+		if dir == "" {
+			b.trustErr = errors.New("the offline bundle does not contain the sigstore TUF root")
+			return
+		}
+		b.trust, b.trustErr = loadTrustedRoot(dir)
+	})
+
+	return b.trust, b.trustErr
+}
+
+// loadTrustedRoot loads the trust material from the targets of the TUF root,
+// as cached by the sigstore TUF client in the given directory.
+func loadTrustedRoot(dir string) (*TrustedRoot, error) {
+	local, err := tufleveldb.FileLocalStore(filepath.Join(dir, "tuf.db"))
+	if err != nil {
+		return nil, fmt.Errorf("opening the TUF metadata: %w", err)
+	}
+	defer local.Close()
+
+	// the remote store is never used when only reading the local metadata
+	targets, err := tufclient.NewClient(local, nil).Targets()
+	if err != nil {
+		return nil, fmt.Errorf("verifying the TUF metadata: %w", err)
+	}
+
+	ctLogPubKeys := cosign.NewTrustedTransparencyLogPubKeys()
+	rekorPubKeys := cosign.NewTrustedTransparencyLogPubKeys()
+	trusted := TrustedRoot{
+		FulcioRoots:         x509.NewCertPool(),
+		FulcioIntermediates: x509.NewCertPool(),
+		CTLogPubKeys:        &ctLogPubKeys,
+		RekorPubKeys:        &rekorPubKeys,
+	}
+	for name, meta := range targets {
+		if meta.Custom == nil {
+			continue
+		}
+
+		var custom struct {
+			Sigstore struct {
+				Usage  tuf.UsageKind  `json:"usage"`
+				Status tuf.StatusKind `json:"status"`
+			} `json:"sigstore"`
+		}
+		if err := json.Unmarshal(*meta.Custom, &custom); err != nil {
+			continue
+		}
+
+		target, err := os.ReadFile(filepath.Join(dir, "targets", name))
+		if err != nil {
+			return nil, fmt.Errorf("reading the TUF target %q: %w", name, err)
+		}
+		if err := util.BytesMatchLenAndHashes(target, meta.Length, meta.Hashes); err != nil {
+			return nil, fmt.Errorf("verifying the TUF target %q: %w", name, err)
+		}
+
+		switch custom.Sigstore.Usage {
+		case tuf.Fulcio:
+			certs, err := cryptoutils.UnmarshalCertificatesFromPEM(target)
+			if err != nil {
+				return nil, fmt.Errorf("parsing the TUF target %q: %w", name, err)
+			}
+			for _, cert := range certs {
+				// self-signed certificates are the roots
+				if bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+					trusted.FulcioRoots.AddCert(cert)
+				} else {
+					trusted.FulcioIntermediates.AddCert(cert)
+				}
+			}
+		case tuf.CTFE:
+			if err := trusted.CTLogPubKeys.AddTransparencyLogPubKey(target, custom.Sigstore.Status); err != nil {
+				return nil, fmt.Errorf("parsing the TUF target %q: %w", name, err)
+			}
+		case tuf.Rekor:
+			if err := trusted.RekorPubKeys.AddTransparencyLogPubKey(target, custom.Sigstore.Status); err != nil {
+				return nil, fmt.Errorf("parsing the TUF target %q: %w", name, err)
+			}
+		}
+	}
+
+	return &trusted, nil
+}
@@ -219,7 +219,7 @@ func keepSomeMetadata(results []evaluator.Result) {
 
 func keepSomeMetadataSingle(result evaluator.Result) {
 	for key := range result.Metadata {
-		if key == "code" || key == "effective_on" {
+		if key == "code" || key == "effective_on" || key == "exception" || key == "image_scope" {
 			continue
 		}
 		delete(result.Metadata, key)
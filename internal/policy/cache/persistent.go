@@ -0,0 +1,492 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/enterprise-contract/go-gather/metadata"
+	fileMetadata "github.com/enterprise-contract/go-gather/metadata/file"
+	gitMetadata "github.com/enterprise-contract/go-gather/metadata/git"
+	httpMetadata "github.com/enterprise-contract/go-gather/metadata/http"
+	ociMetadata "github.com/enterprise-contract/go-gather/metadata/oci"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// SourceTypeGit is the type of cached sources fetched from git
+	SourceTypeGit = "git"
+	// SourceTypeOCI is the type of cached sources fetched from OCI registries
+	SourceTypeOCI = "oci"
+	// SourceTypeFile is the type of cached sources copied from local files
+	SourceTypeFile = "file"
+	// SourceTypeDirectory is the type of cached sources copied from local
+	// directories
+	SourceTypeDirectory = "directory"
+	// SourceTypeHTTP is the type of cached sources fetched over HTTP
+	SourceTypeHTTP = "http"
+
+	// DefaultTTL is the duration for which mutable references are reused
+	// unless the EC_POLICY_CACHE_TTL environment variable is set
+	DefaultTTL = time.Hour
+
+	refsDir    = "refs"
+	contentDir = "content"
+)
+
+// pinnedSource matches source URLs referencing immutable content, i.e. git
+// sources pinned to a commit or OCI sources pinned to a digest.
+var pinnedSource = regexp.MustCompile(`([?&]ref=[0-9a-f]{40}([^0-9a-f]|$)|@sha256:[0-9a-f]{64}$)`)
+
+// Entry describes a source stored in the persistent cache.
+type Entry struct {
+	// URL is the source URL as provided in the policy configuration
+	URL string `json:"url"`
+	// PinnedURL is the source URL pinned to the resolved commit or digest
+	PinnedURL string `json:"pinnedUrl"`
+	// Type is the type of the source, one of git, oci, file, directory or http
+	Type string `json:"type"`
+	// Resolved is the git commit, the OCI digest or the file checksum the URL
+	// resolved to
+	Resolved string `json:"resolved"`
+	// Fetched is the time the source was downloaded
+	Fetched time.Time `json:"fetched"`
+	// Used is the time the cached source was last used
+	Used time.Time `json:"used"`
+	// Key is the content address of the cached files
+	Key string `json:"key"`
+}
+
+// Pinned returns true if the entry's URL references immutable content.
+func (e Entry) Pinned() bool {
+	return pinnedSource.MatchString(e.URL)
+}
+
+// DiskCache is a persistent cache of downloaded policy and data sources. Each
+// source URL points to the content it resolved to, the content is addressed by
+// the URL pinned to the git commit or OCI digest so that URLs resolving to the
+// same commit or digest share it. URLs referencing mutable content, like git
+// branches or OCI tags, are reused only within the configured TTL. Pinned URLs
+// never expire.
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+	// archive is set when all sources are kept, regardless of their type,
+	// and never expire
+	archive bool
+}
+
+// NewDiskCache returns a DiskCache storing the sources in the given directory
+// and reusing mutable references within the given TTL.
+func NewDiskCache(dir string, ttl time.Duration) *DiskCache {
+	return &DiskCache{
+		dir: dir,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// NewArchiveCache returns a DiskCache storing the sources in the given
+// directory that keeps all sources, including local files and mutable
+// references, which never expire. It is used to archive the sources for later
+// use without network access.
+func NewArchiveCache(dir string) *DiskCache {
+	return &DiskCache{
+		dir:     dir,
+		now:     time.Now,
+		archive: true,
+	}
+}
+
+// DefaultDiskCacheDir returns the directory within the user's cache directory
+// used to store the downloaded sources.
+func DefaultDiskCacheDir() (string, error) {
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(userCache, "ec", "policies"), nil
+}
+
+// DefaultDiskCache returns the DiskCache in the user's cache directory or nil
+// if the cache is disabled or unavailable. Setting the EC_CACHE environment
+// variable to false disables the cache. The EC_POLICY_CACHE_TTL environment
+// variable sets the duration for which mutable references are reused, one hour
+// by default, setting it to 0 downloads those again on each use.
+func DefaultDiskCache() *DiskCache {
+	// if a value was set and it is parsed as false, turn the cache off
+	if v, err := strconv.ParseBool(os.Getenv("EC_CACHE")); err == nil && !v {
+		return nil
+	}
+
+	dir, err := DefaultDiskCacheDir()
+	if err != nil {
+		log.Debug("unable to find user cache directory")
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Debugf("unable to create directory for policy cache in %q: %v", dir, err)
+		return nil
+	}
+
+	ttl := DefaultTTL
+	if v := os.Getenv("EC_POLICY_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil {
+			log.Warnf("invalid EC_POLICY_CACHE_TTL value %q, using %s: %v", v, DefaultTTL, err)
+		} else {
+			ttl = d
+		}
+	}
+
+	log.Debugf("using %q directory to store policy cache", dir)
+	return NewDiskCache(dir, ttl)
+}
+
+// Dir returns the directory holding the cached sources.
+func (c *DiskCache) Dir() string {
+	return c.dir
+}
+
+// Get copies the cached content for the source URL into the destination
+// directory. It returns the metadata of the cached source and true if the
+// source was found in the cache and is not expired.
+func (c *DiskCache) Get(sourceUrl string, dest string) (metadata.Metadata, bool) {
+	entry, err := c.readEntry(c.refPath(sourceUrl))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Debugf("unable to read policy cache entry for %q: %v", sourceUrl, err)
+		}
+		return nil, false
+	}
+
+	if c.expired(entry) {
+		log.Debugf("Policy cache entry for %q expired", sourceUrl)
+		return nil, false
+	}
+
+	m, err := entry.metadata()
+	if err != nil {
+		log.Debugf("unable to use policy cache entry for %q: %v", sourceUrl, err)
+		return nil, false
+	}
+
+	if err := copyDir(c.contentPath(entry.Key), dest); err != nil {
+		log.Debugf("unable to copy cached policy for %q: %v", sourceUrl, err)
+		return nil, false
+	}
+
+	entry.Used = c.now()
+	if err := c.writeEntry(entry); err != nil {
+		log.Debugf("unable to update policy cache entry for %q: %v", sourceUrl, err)
+	}
+
+	return m, true
+}
+
+// Put stores the downloaded content of the source URL from the given
+// directory. Only git and OCI sources are stored, others are ignored. Mutable
+// references are not stored when the TTL is not positive. Archive caches
+// store all sources.
+func (c *DiskCache) Put(sourceUrl string, src string, m metadata.Metadata) error {
+	entry := Entry{URL: sourceUrl}
+	switch v := m.(type) {
+	case *gitMetadata.GitMetadata:
+		entry.Type, entry.Resolved = SourceTypeGit, v.LatestCommit
+	case *ociMetadata.OCIMetadata:
+		entry.Type, entry.Resolved = SourceTypeOCI, v.Digest
+	case *fileMetadata.FileMetadata:
+		entry.Type, entry.Resolved = SourceTypeFile, v.SHA
+	case *fileMetadata.DirectoryMetadata:
+		entry.Type = SourceTypeDirectory
+	case *httpMetadata.HTTPMetadata:
+		entry.Type = SourceTypeHTTP
+	default:
+		return nil
+	}
+
+	if !c.archive {
+		if entry.Type != SourceTypeGit && entry.Type != SourceTypeOCI {
+			return nil
+		}
+
+		if !entry.Pinned() && c.ttl <= 0 {
+			return nil
+		}
+	}
+
+	pinnedUrl, err := m.GetPinnedURL(sourceUrl)
+	if err != nil {
+		return err
+	}
+	entry.PinnedURL = pinnedUrl
+	entry.Key = key(pinnedUrl)
+	entry.Fetched = c.now()
+	entry.Used = entry.Fetched
+
+	content := c.contentPath(entry.Key)
+	if _, err := os.Stat(content); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(content), 0700); err != nil {
+			return err
+		}
+
+		// copy to a temporary directory first and rename, other processes
+		// should never observe partially copied content
+		tmp, err := os.MkdirTemp(filepath.Dir(content), entry.Key+".tmp-")
+		if err != nil {
+			return err
+		}
+
+		if err := copyDir(src, tmp); err != nil {
+			_ = os.RemoveAll(tmp)
+			return err
+		}
+
+		if err := os.Rename(tmp, content); err != nil {
+			// most likely stored concurrently by another process
+			_ = os.RemoveAll(tmp)
+			if _, statErr := os.Stat(content); statErr != nil {
+				return err
+			}
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return c.writeEntry(entry)
+}
+
+// expired returns true if the entry references mutable content fetched longer
+// than the TTL ago.
+func (c *DiskCache) expired(e Entry) bool {
+	return !c.archive && !e.Pinned() && c.now().Sub(e.Fetched) >= c.ttl
+}
+
+// List returns all cached entries ordered by URL.
+func (c *DiskCache) List() ([]Entry, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, refsDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(files))
+	for _, f := range files {
+		entry, err := c.readEntry(f)
+		if err != nil {
+			log.Debugf("ignoring unreadable policy cache entry %q: %v", f, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+
+	return entries, nil
+}
+
+// Prune removes the entries that have not been used within the given duration
+// along with expired mutable references and any content no longer referenced.
+// It returns the removed entries.
+func (c *DiskCache) Prune(unusedFor time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.now()
+	var pruned []Entry
+	referenced := map[string]bool{}
+	for _, e := range entries {
+		if now.Sub(e.Used) >= unusedFor || c.expired(e) {
+			if err := os.Remove(c.refPath(e.URL)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return pruned, err
+			}
+			pruned = append(pruned, e)
+			continue
+		}
+		referenced[e.Key] = true
+	}
+
+	contents, err := os.ReadDir(filepath.Join(c.dir, contentDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return pruned, err
+	}
+	for _, content := range contents {
+		if referenced[content.Name()] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(c.dir, contentDir, content.Name())); err != nil {
+			return pruned, err
+		}
+	}
+
+	return pruned, nil
+}
+
+// Clear removes all cached sources.
+func (c *DiskCache) Clear() error {
+	for _, d := range []string{refsDir, contentDir} {
+		if err := os.RemoveAll(filepath.Join(c.dir, d)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *DiskCache) refPath(sourceUrl string) string {
+	return filepath.Join(c.dir, refsDir, key(sourceUrl)+".json")
+}
+
+func (c *DiskCache) contentPath(key string) string {
+	return filepath.Join(c.dir, contentDir, key)
+}
+
+func (c *DiskCache) readEntry(path string) (Entry, error) {
+	var entry Entry
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+
+	err = json.Unmarshal(b, &entry)
+
+	return entry, err
+}
+
+func (c *DiskCache) writeEntry(entry Entry) error {
+	path := c.refPath(entry.URL)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (e Entry) metadata() (metadata.Metadata, error) {
+	switch e.Type {
+	case SourceTypeGit:
+		return &gitMetadata.GitMetadata{LatestCommit: e.Resolved}, nil
+	case SourceTypeOCI:
+		return &ociMetadata.OCIMetadata{Digest: e.Resolved}, nil
+	case SourceTypeFile:
+		return &fileMetadata.FileMetadata{SHA: e.Resolved}, nil
+	case SourceTypeDirectory:
+		return &fileMetadata.DirectoryMetadata{}, nil
+	case SourceTypeHTTP:
+		return &httpMetadata.HTTPMetadata{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported source type %q", e.Type)
+	}
+}
+
+// key returns the content address for the given value
+func key(value string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(value)))
+}
+
+// copyDir recursively copies the files from the src directory to the dest
+// directory, symbolic links are preserved.
+func copyDir(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dest string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,267 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enterprise-contract/go-gather/metadata"
+	fileMetadata "github.com/enterprise-contract/go-gather/metadata/file"
+	gitMetadata "github.com/enterprise-contract/go-gather/metadata/git"
+	httpMetadata "github.com/enterprise-contract/go-gather/metadata/http"
+	ociMetadata "github.com/enterprise-contract/go-gather/metadata/oci"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	commit = "6c3a1b4a1b7e8a3f0c9c2f5d6e8a9b0c1d2e3f4a"
+	digest = "sha256:0f5e0a0bce0fbe1b1a3d7e1e0c4d2c7f7c0c4d2b1d7f5e0a0bce0fbe1b1a3d7e"
+)
+
+func source(t *testing.T, content string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "policy.rego"), []byte(content), 0644))
+	return dir
+}
+
+func TestDiskCachePutGet(t *testing.T) {
+	cases := []struct {
+		name     string
+		url      string
+		metadata metadata.Metadata
+		ttl      time.Duration
+		age      time.Duration
+		cached   bool
+	}{
+		{
+			name:     "pinned git",
+			url:      "git::https://github.com/org/repo//policy?ref=" + commit,
+			metadata: &gitMetadata.GitMetadata{LatestCommit: commit},
+			age:      24 * time.Hour,
+			cached:   true,
+		},
+		{
+			name:     "pinned OCI",
+			url:      "oci::registry.io/org/policy@" + digest,
+			metadata: &ociMetadata.OCIMetadata{Digest: digest},
+			age:      24 * time.Hour,
+			cached:   true,
+		},
+		{
+			name:     "mutable git without TTL",
+			url:      "git::https://github.com/org/repo//policy?ref=main",
+			metadata: &gitMetadata.GitMetadata{LatestCommit: commit},
+		},
+		{
+			name:     "mutable OCI within TTL",
+			url:      "oci::registry.io/org/policy:latest",
+			metadata: &ociMetadata.OCIMetadata{Digest: digest},
+			ttl:      time.Hour,
+			age:      time.Minute,
+			cached:   true,
+		},
+		{
+			name:     "mutable OCI expired",
+			url:      "oci::registry.io/org/policy:latest",
+			metadata: &ociMetadata.OCIMetadata{Digest: digest},
+			ttl:      time.Hour,
+			age:      2 * time.Hour,
+		},
+		{
+			name:     "local file",
+			url:      "/some/path",
+			metadata: &fileMetadata.FileMetadata{Path: "/some/path"},
+			ttl:      time.Hour,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cache := NewDiskCache(t.TempDir(), c.ttl)
+			now := time.Now()
+			cache.now = func() time.Time { return now }
+
+			require.NoError(t, cache.Put(c.url, source(t, "package policy"), c.metadata))
+
+			now = now.Add(c.age)
+			dest := filepath.Join(t.TempDir(), "dest")
+			m, ok := cache.Get(c.url, dest)
+			assert.Equal(t, c.cached, ok)
+			if !c.cached {
+				assert.NoDirExists(t, dest)
+				return
+			}
+
+			assert.Equal(t, c.metadata, m)
+			b, err := os.ReadFile(filepath.Join(dest, "sub", "policy.rego"))
+			require.NoError(t, err)
+			assert.Equal(t, "package policy", string(b))
+
+			entries, err := cache.List()
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.True(t, now.Equal(entries[0].Used))
+		})
+	}
+}
+
+func TestArchiveCache(t *testing.T) {
+	cache := NewArchiveCache(t.TempDir())
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	sources := map[string]metadata.Metadata{
+		"git::https://github.com/org/repo//policy?ref=main": &gitMetadata.GitMetadata{LatestCommit: commit},
+		"oci::registry.io/org/policy:latest":                &ociMetadata.OCIMetadata{Digest: digest},
+		"/some/file.yaml":                                   &fileMetadata.FileMetadata{SHA: "abc"},
+		"/some/dir":                                         &fileMetadata.DirectoryMetadata{},
+		"https://example.com/data.json":                     &httpMetadata.HTTPMetadata{},
+	}
+	for url, m := range sources {
+		require.NoError(t, cache.Put(url, source(t, url), m))
+	}
+
+	// archived sources never expire
+	now = now.Add(365 * 24 * time.Hour)
+	for url, m := range sources {
+		dest := filepath.Join(t.TempDir(), "dest")
+		got, ok := cache.Get(url, dest)
+		require.True(t, ok, url)
+		assert.Equal(t, m, got)
+
+		b, err := os.ReadFile(filepath.Join(dest, "sub", "policy.rego"))
+		require.NoError(t, err)
+		assert.Equal(t, url, string(b))
+	}
+}
+
+func TestDiskCacheSharedContent(t *testing.T) {
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+
+	m := &gitMetadata.GitMetadata{LatestCommit: commit}
+	require.NoError(t, cache.Put("git::https://github.com/org/repo?ref=main", source(t, "first"), m))
+	require.NoError(t, cache.Put("git::https://github.com/org/repo?ref="+commit, source(t, "second"), m))
+
+	entries, err := cache.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, entries[0].Key, entries[1].Key)
+	assert.Equal(t, "git::github.com/org/repo?ref="+commit, entries[0].PinnedURL)
+
+	// the content is stored only once, by the first download
+	dest := t.TempDir()
+	_, ok := cache.Get("git::https://github.com/org/repo?ref="+commit, dest)
+	require.True(t, ok)
+	b, err := os.ReadFile(filepath.Join(dest, "sub", "policy.rego"))
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(b))
+}
+
+func TestDiskCachePruneAndClear(t *testing.T) {
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	pinned := "oci::registry.io/org/policy@" + digest
+	mutable := "git::https://github.com/org/repo?ref=main"
+	unused := "git::https://github.com/org/other?ref=" + strings.Repeat("a", 40)
+
+	require.NoError(t, cache.Put(unused, source(t, "unused"), &gitMetadata.GitMetadata{LatestCommit: strings.Repeat("a", 40)}))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, cache.Put(pinned, source(t, "pinned"), &ociMetadata.OCIMetadata{Digest: digest}))
+	require.NoError(t, cache.Put(mutable, source(t, "mutable"), &gitMetadata.GitMetadata{LatestCommit: commit}))
+
+	// the mutable reference expires, the unused reference was not used for
+	// longer than the given duration
+	now = now.Add(time.Hour)
+	pruned, err := cache.Prune(75 * time.Minute)
+	require.NoError(t, err)
+
+	urls := []string{}
+	for _, e := range pruned {
+		urls = append(urls, e.URL)
+	}
+	assert.ElementsMatch(t, []string{mutable, unused}, urls)
+
+	entries, err := cache.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, pinned, entries[0].URL)
+
+	contents, err := os.ReadDir(filepath.Join(cache.Dir(), contentDir))
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Equal(t, entries[0].Key, contents[0].Name())
+
+	require.NoError(t, cache.Clear())
+	entries, err = cache.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.NoDirExists(t, filepath.Join(cache.Dir(), contentDir))
+}
+
+func TestDefaultDiskCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	t.Setenv("EC_CACHE", "false")
+	assert.Nil(t, DefaultDiskCache())
+
+	t.Setenv("EC_CACHE", "true")
+	t.Setenv("EC_POLICY_CACHE_TTL", "")
+	c := DefaultDiskCache()
+	require.NotNil(t, c)
+	assert.Equal(t, DefaultTTL, c.ttl)
+	assert.DirExists(t, c.Dir())
+
+	t.Setenv("EC_POLICY_CACHE_TTL", "5m")
+	c = DefaultDiskCache()
+	require.NotNil(t, c)
+	assert.Equal(t, 5*time.Minute, c.ttl)
+
+	t.Setenv("EC_POLICY_CACHE_TTL", "0")
+	c = DefaultDiskCache()
+	require.NotNil(t, c)
+	assert.Equal(t, time.Duration(0), c.ttl)
+
+	t.Setenv("EC_POLICY_CACHE_TTL", "bogus")
+	c = DefaultDiskCache()
+	require.NotNil(t, c)
+	assert.Equal(t, DefaultTTL, c.ttl)
+}
+
+func TestDefaultDiskCacheStoresMutableReferences(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("EC_CACHE", "")
+	t.Setenv("EC_POLICY_CACHE_TTL", "")
+
+	c := DefaultDiskCache()
+	require.NotNil(t, c)
+
+	url := "git::https://github.com/org/repo//policy?ref=main"
+	require.NoError(t, c.Put(url, source(t, "package policy"), &gitMetadata.GitMetadata{LatestCommit: commit}))
+
+	_, ok := c.Get(url, filepath.Join(t.TempDir(), "dest"))
+	assert.True(t, ok)
+}
@@ -0,0 +1,118 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// exceptionsKey is the key of the exceptions within the policy configuration.
+// The exceptions are not part of the EnterpriseContractPolicySpec, and cannot
+// be provided via the EnterpriseContractPolicy Kubernetes resource.
+const exceptionsKey = "exceptions"
+
+// Exception waives the results of a rule, with the owner, ticket and reason
+// of the waiver recorded, until the exception expires.
+type Exception struct {
+	// Value matches the results to waive, in the same form as the items of
+	// the exclude list, e.g. "pkg.rule" or "pkg.rule:term"
+	Value string `json:"value"`
+	// ImageRef limits the exception to the image with the given digest
+	ImageRef string `json:"image_ref,omitempty"`
+	// ExpiresOn is the time, in RFC3339 format or as a date, at which the
+	// exception stops applying, the exception never expires if empty
+	ExpiresOn string `json:"expires_on,omitempty"`
+	Owner     string `json:"owner,omitempty"`
+	Ticket    string `json:"ticket,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Expiry returns the time at which the exception expires, or the zero time if
+// the exception never expires.
+func (e Exception) Expiry() time.Time {
+	// validated when the exceptions are parsed
+	t, _ := parseExpiry(e.ExpiresOn)
+	return t
+}
+
+// Expired returns true if the exception is expired at the given time.
+func (e Exception) Expired(at time.Time) bool {
+	expiry := e.Expiry()
+	return !expiry.IsZero() && !at.Before(expiry)
+}
+
+func parseExpiry(expiresOn string) (time.Time, error) {
+	if expiresOn == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, expiresOn); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(DateFormat, expiresOn)
+}
+
+// parseExceptions returns the exceptions from the policy configuration, given
+// as YAML or JSON of either the EnterpriseContractPolicy or its spec.
+func parseExceptions(policyConfig string) ([]Exception, error) {
+	var config map[string]json.RawMessage
+	if err := yaml.Unmarshal([]byte(policyConfig), &config); err != nil {
+		// not a map, nothing to parse
+		return nil, nil
+	}
+
+	if spec, ok := config["spec"]; ok {
+		config = nil
+		if err := json.Unmarshal(spec, &config); err != nil {
+			return nil, nil
+		}
+	}
+
+	raw, ok := config[exceptionsKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var exceptions []Exception
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&exceptions); err != nil {
+		return nil, fmt.Errorf("invalid exceptions: %w", err)
+	}
+
+	var allErrors error
+	for i, e := range exceptions {
+		if e.Value == "" {
+			allErrors = errors.Join(allErrors, fmt.Errorf("exception %d: value is required", i))
+		}
+		if _, err := parseExpiry(e.ExpiresOn); err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("exception %d: invalid expires_on %q, expected RFC3339 time or %s date", i, e.ExpiresOn, DateFormat))
+		}
+	}
+	if allErrors != nil {
+		return nil, allErrors
+	}
+
+	return exceptions, nil
+}
@@ -0,0 +1,148 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	hd "github.com/MakeNowJust/heredoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExceptionExpired(t *testing.T) {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		expiresOn string
+		expired   bool
+	}{
+		{"", false},
+		{"2024-06-02", false},
+		{"2024-06-01", true},
+		{"2024-06-01T12:00:01Z", false},
+		{"2024-06-01T12:00:00Z", true},
+		{"2024-05-01T00:00:00+02:00", true},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expired, Exception{Value: "pkg.rule", ExpiresOn: c.expiresOn}.Expired(at), c.expiresOn)
+	}
+}
+
+func TestPolicyExceptions(t *testing.T) {
+	cases := []struct {
+		name       string
+		policyRef  string
+		exceptions []Exception
+	}{
+		{
+			name: "spec",
+			policyRef: hd.Doc(`
+				sources:
+				  - policy:
+				      - oci::quay.io/policy
+				exceptions:
+				  - value: pkg.rule:term
+				    image_ref: sha256:abc
+				    expires_on: "2024-06-01"
+				    owner: team
+				    ticket: JIRA-1
+				    reason: waiting on upstream
+				  - value: other
+			`),
+			exceptions: []Exception{
+				{
+					Value:     "pkg.rule:term",
+					ImageRef:  "sha256:abc",
+					ExpiresOn: "2024-06-01",
+					Owner:     "team",
+					Ticket:    "JIRA-1",
+					Reason:    "waiting on upstream",
+				},
+				{Value: "other"},
+			},
+		},
+		{
+			name: "EnterpriseContractPolicy",
+			policyRef: hd.Doc(`
+				apiVersion: appstudio.redhat.com/v1alpha1
+				kind: EnterpriseContractPolicy
+				spec:
+				  sources:
+				    - policy:
+				        - oci::quay.io/policy
+				  exceptions:
+				    - value: pkg.rule
+			`),
+			exceptions: []Exception{{Value: "pkg.rule"}},
+		},
+		{
+			name:      "none",
+			policyRef: `{"sources": [{"policy": ["oci::quay.io/policy"]}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p, err := NewInertPolicy(context.Background(), c.policyRef)
+			require.NoError(t, err)
+			assert.Equal(t, c.exceptions, p.Exceptions())
+
+			// exceptions are kept when the spec is replaced
+			p = p.WithSpec(p.Spec())
+			assert.Equal(t, c.exceptions, p.Exceptions())
+		})
+	}
+}
+
+func TestPolicyExceptionsInvalid(t *testing.T) {
+	cases := []struct {
+		name      string
+		policyRef string
+		err       string
+	}{
+		{
+			name:      "unknown field",
+			policyRef: `{"exceptions": [{"value": "pkg.rule", "expires": "2024-06-01"}]}`,
+			err:       `invalid exceptions: json: unknown field "expires"`,
+		},
+		{
+			name:      "missing value",
+			policyRef: `{"exceptions": [{"owner": "team"}]}`,
+			err:       "exception 0: value is required",
+		},
+		{
+			name:      "invalid expiry",
+			policyRef: `{"exceptions": [{"value": "pkg.rule", "expires_on": "next week"}]}`,
+			err:       `exception 0: invalid expires_on "next week", expected RFC3339 time or 2006-01-02 date`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewInertPolicy(context.Background(), c.policyRef)
+			assert.EqualError(t, err, c.err)
+
+			assert.EqualError(t, ValidatePolicy(context.Background(), c.policyRef), c.err)
+		})
+	}
+}
@@ -19,15 +19,18 @@ package policy
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
 	app "github.com/konflux-ci/application-api/api/v1alpha1"
 )
 
 type FakeKubernetesClient struct {
-	Policy     ecc.EnterpriseContractPolicySpec
-	Snapshot   app.SnapshotSpec
-	FetchError bool
+	Policy          ecc.EnterpriseContractPolicySpec
+	Snapshot        app.SnapshotSpec
+	NamespaceLabels map[string]string
+	Secrets         map[string]map[string][]byte
+	FetchError      bool
 }
 
 func (c *FakeKubernetesClient) FetchEnterpriseContractPolicy(ctx context.Context, ref string) (*ecc.EnterpriseContractPolicy, error) {
@@ -43,3 +46,21 @@ func (c *FakeKubernetesClient) FetchSnapshot(ctx context.Context, ref string) (*
 	}
 	return &app.Snapshot{Spec: c.Snapshot}, nil
 }
+
+func (c *FakeKubernetesClient) FetchNamespaceLabels(ctx context.Context, namespace string) (map[string]string, error) {
+	if c.FetchError {
+		return nil, errors.New("no fetching for you")
+	}
+	return c.NamespaceLabels, nil
+}
+
+func (c *FakeKubernetesClient) FetchSecret(ctx context.Context, ref string) (map[string][]byte, error) {
+	if c.FetchError {
+		return nil, errors.New("no fetching for you")
+	}
+	data, ok := c.Secrets[ref]
+	if !ok {
+		return nil, fmt.Errorf("secret %q not found", ref)
+	}
+	return data, nil
+}
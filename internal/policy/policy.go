@@ -32,8 +32,8 @@ import (
 	schemaExporter "github.com/invopop/jsonschema"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
-	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/env"
 	cosignSig "github.com/sigstore/cosign/v2/pkg/signature"
 	"github.com/sigstore/sigstore/pkg/cryptoutils"
 	sigstoreSig "github.com/sigstore/sigstore/pkg/signature"
@@ -41,6 +41,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
 	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
 	"github.com/enterprise-contract/ec-cli/internal/policy/source"
 	"github.com/enterprise-contract/ec-cli/internal/utils"
@@ -62,6 +63,9 @@ var (
 )
 
 func ValidatePolicy(ctx context.Context, policyConfig string) error {
+	if _, err := parseExceptions(policyConfig); err != nil {
+		return err
+	}
 	return validatePolicyConfig(policyConfig)
 }
 
@@ -96,6 +100,7 @@ type Policy interface {
 	Identity() cosign.Identity
 	Keyless() bool
 	SigstoreOpts() (SigstoreOpts, error)
+	Exceptions() []Exception
 }
 
 type policy struct {
@@ -106,6 +111,7 @@ type policy struct {
 	attestationTime *time.Time
 	identity        cosign.Identity
 	ignoreRekor     bool
+	exceptions      []Exception
 }
 
 // PublicKeyPEM returns the PublicKey in PEM format.
@@ -327,6 +333,12 @@ func (p *policy) loadPolicy(ctx context.Context, policyRef string) error {
 				return fmt.Errorf("unable to parse EnterpriseContractPolicySpec: %w", err)
 			}
 		}
+		exceptions, err := parseExceptions(policyRef)
+		if err != nil {
+			return err
+		}
+		p.exceptions = exceptions
+
 		// Check if the policyRef is conformant to the schema
 		if policyRef != "" {
 			ok, err := p.isConformant(policyRef)
@@ -367,6 +379,11 @@ func (p *policy) isConformant(policyRef string) (bool, error) {
 	return true, nil
 }
 
+// Exceptions returns the exceptions waiving the results of the policy rules.
+func (p *policy) Exceptions() []Exception {
+	return p.exceptions
+}
+
 func (p *policy) WithSpec(spec ecc.EnterpriseContractPolicySpec) Policy {
 	p.EnterpriseContractPolicySpec = spec
 
@@ -440,19 +457,30 @@ func checkOpts(ctx context.Context, p *policy) (*cosign.CheckOpts, error) {
 		}
 	} else {
 		log.Debug("Using keyless workflow")
-		log.Debugf("TUF_ROOT=%s", os.Getenv("TUF_ROOT"))
 		opts.Identities = []cosign.Identity{p.identity}
 
 		// Get Fulcio certificates
-		if opts.RootCerts, err = fulcio.GetRoots(); err != nil {
-			return nil, err
-		}
-		if opts.IntermediateCerts, err = fulcio.GetIntermediates(); err != nil {
+		if trusted, err := bundleTrustedRoot(ctx, env.VariableSigstoreRootFile); err != nil {
 			return nil, err
+		} else if trusted != nil {
+			opts.RootCerts = trusted.FulcioRoots
+			opts.IntermediateCerts = trusted.FulcioIntermediates
+		} else {
+			log.Debugf("TUF_ROOT=%s", os.Getenv("TUF_ROOT"))
+			if opts.RootCerts, err = fulcio.GetRoots(); err != nil {
+				return nil, err
+			}
+			if opts.IntermediateCerts, err = fulcio.GetIntermediates(); err != nil {
+				return nil, err
+			}
 		}
 
 		// Get Certificate Transparency Log public keys
-		if opts.CTLogPubKeys, err = cosign.GetCTLogPubs(ctx); err != nil {
+		if trusted, err := bundleTrustedRoot(ctx, env.VariableSigstoreCTLogPublicKeyFile); err != nil {
+			return nil, err
+		} else if trusted != nil {
+			opts.CTLogPubKeys = trusted.CTLogPubKeys
+		} else if opts.CTLogPubKeys, err = cosign.GetCTLogPubs(ctx); err != nil {
 			return nil, err
 		}
 		log.Debug("Retrieved Rekor public keys")
@@ -473,14 +501,18 @@ func checkOpts(ctx context.Context, p *policy) (*cosign.CheckOpts, error) {
 		// NOTE: A Rekor client is only needed when a SignedEntryTimestamp is not available
 		// on the signature/attestation.
 		if rekorURL != "" {
-			if opts.RekorClient, err = rekor.NewClient(rekorURL); err != nil {
+			if opts.RekorClient, err = newRekorClient(ctx, rekorURL); err != nil {
 				log.Debugf("Problem creating a rekor client using url %q", rekorURL)
 				return nil, err
 			}
 			log.Debugf("Rekor client created, url %q", rekorURL)
 		}
 
-		if opts.RekorPubKeys, err = cosign.GetRekorPubs(ctx); err != nil {
+		if trusted, err := bundleTrustedRoot(ctx, env.VariableSigstoreRekorPublicKey); err != nil {
+			return nil, err
+		} else if trusted != nil {
+			opts.RekorPubKeys = trusted.RekorPubKeys
+		} else if opts.RekorPubKeys, err = cosign.GetRekorPubs(ctx); err != nil {
 			return nil, err
 		}
 		log.Debug("Retrieved Rekor public keys")
@@ -489,6 +521,20 @@ func checkOpts(ctx context.Context, p *policy) (*cosign.CheckOpts, error) {
 	return &opts, nil
 }
 
+// bundleTrustedRoot returns the sigstore trust material from the TUF root of
+// the offline bundle, when serving from one. Returns nil if the given cosign
+// environment variable provides alternative trust material, as cosign then
+// does not use the TUF root either.
+func bundleTrustedRoot(ctx context.Context, alternative env.Variable) (*offline.TrustedRoot, error) {
+	b := offline.BundleFromContext(ctx)
+	if b == nil || b.TUFRoot() == "" || env.Getenv(alternative) != "" {
+		return nil, nil
+	}
+
+	log.Debug("Using the sigstore TUF root of the offline bundle")
+	return b.TrustedRoot()
+}
+
 type signatureClient interface {
 	publicKeyFromKeyRef(context.Context, string) (sigstoreSig.Verifier, error)
 }
@@ -574,6 +620,9 @@ func validatePolicyConfig(policyConfig string) error {
 		}
 	}
 
+	// The exceptions are validated when parsed, they're not part of the schema
+	delete(v, exceptionsKey)
+
 	// Validate the policy against the schema.
 	if err := policySchema.Validate(v); err != nil {
 		log.Error(err)
@@ -0,0 +1,61 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-openapi/runtime"
+	httptransport "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/rekor/pkg/generated/client"
+	"github.com/sigstore/rekor/pkg/util"
+
+	"github.com/enterprise-contract/ec-cli/internal/offline"
+)
+
+// newRekorClient returns the Rekor client for the given URL. When recording
+// or serving from an offline bundle the client is constructed as the
+// cosign/Rekor client is, but with the offline HTTP transport.
+func newRekorClient(ctx context.Context, rekorURL string) (*client.Rekor, error) {
+	if !offline.Enabled(ctx) {
+		return rekor.NewClient(rekorURL)
+	}
+
+	u, err := url.Parse(rekorURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Path == "" {
+		u.Path = client.DefaultBasePath
+	}
+
+	httpClient := &http.Client{Transport: offline.Transport(ctx, http.DefaultTransport)}
+	rt := httptransport.NewWithClient(u.Host, u.Path, []string{u.Scheme}, httpClient)
+	rt.Consumers["application/json"] = runtime.JSONConsumer()
+	rt.Consumers["application/x-pem-file"] = runtime.TextConsumer()
+	rt.Producers["application/json"] = runtime.JSONProducer()
+
+	registry := strfmt.Default
+	registry.Add("signedCheckpoint", &util.SignedNote{}, util.SignedCheckpointValidator)
+
+	return client.New(rt, registry), nil
+}
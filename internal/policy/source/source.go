@@ -84,6 +84,16 @@ type cacheContent struct {
 	err       error
 }
 
+// ClearDownloadCache forgets all previously downloaded sources, subsequent
+// calls to GetPolicy will download the sources again. Long running processes
+// use this to pick up changes made to mutable source references.
+func ClearDownloadCache() {
+	downloadCache.Range(func(key, _ any) bool {
+		downloadCache.Delete(key)
+		return true
+	})
+}
+
 func getPolicyThroughCache(ctx context.Context, s PolicySource, workDir string, dl func(string, string) (metadata.Metadata, error)) (string, metadata.Metadata, error) {
 	sourceUrl := s.PolicyUrl()
 	dest := uniqueDestination(workDir, s.Subdir(), sourceUrl)
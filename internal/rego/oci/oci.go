@@ -43,6 +43,8 @@ const (
 	ociBlobName          = "ec.oci.blob"
 	ociImageManifestName = "ec.oci.image_manifest"
 	ociImageFilesName    = "ec.oci.image_files"
+	ociImageIndexName    = "ec.oci.image_index"
+	ociReferrersName     = "ec.oci.image_referrers"
 )
 
 func registerOCIBlob() {
@@ -73,50 +75,52 @@ func registerOCIBlob() {
 	})
 }
 
-func registerOCIImageManifest() {
-	platform := types.NewObject(
-		[]*types.StaticProperty{
-			{Key: "architecture", Value: types.S},
-			{Key: "os", Value: types.S},
-			{Key: "os.version", Value: types.S},
-			{Key: "os.features", Value: types.NewArray([]types.Type{types.S}, nil)},
-			{Key: "variant", Value: types.S},
-			{Key: "features", Value: types.NewArray([]types.Type{types.S}, nil)},
-		},
-		nil,
-	)
-
-	// annotations represents the map[string]string rego type
-	annotations := types.NewObject(nil, types.NewDynamicProperty(types.S, types.S))
+// platformType represents the v1.Platform rego type
+var platformType = types.NewObject(
+	[]*types.StaticProperty{
+		{Key: "architecture", Value: types.S},
+		{Key: "os", Value: types.S},
+		{Key: "os.version", Value: types.S},
+		{Key: "os.features", Value: types.NewArray([]types.Type{types.S}, nil)},
+		{Key: "variant", Value: types.S},
+		{Key: "features", Value: types.NewArray([]types.Type{types.S}, nil)},
+	},
+	nil,
+)
 
-	descriptor := types.NewObject(
-		[]*types.StaticProperty{
-			{Key: "mediaType", Value: types.S},
-			{Key: "size", Value: types.N},
-			{Key: "digest", Value: types.S},
-			{Key: "data", Value: types.S},
-			{Key: "urls", Value: types.NewArray(
-				[]types.Type{types.S}, nil,
-			)},
-			{Key: "annotations", Value: annotations},
-			{Key: "platform", Value: platform},
-			{Key: "artifactType", Value: types.S},
-		},
-		nil,
-	)
+// annotationsType represents the map[string]string rego type
+var annotationsType = types.NewObject(nil, types.NewDynamicProperty(types.S, types.S))
+
+// descriptorType represents the v1.Descriptor rego type
+var descriptorType = types.NewObject(
+	[]*types.StaticProperty{
+		{Key: "mediaType", Value: types.S},
+		{Key: "size", Value: types.N},
+		{Key: "digest", Value: types.S},
+		{Key: "data", Value: types.S},
+		{Key: "urls", Value: types.NewArray(
+			[]types.Type{types.S}, nil,
+		)},
+		{Key: "annotations", Value: annotationsType},
+		{Key: "platform", Value: platformType},
+		{Key: "artifactType", Value: types.S},
+	},
+	nil,
+)
 
+func registerOCIImageManifest() {
 	manifest := types.NewObject(
 		[]*types.StaticProperty{
 			// Specifying the properties like this ensure the compiler catches typos when
 			// evaluating rego functions.
 			{Key: "schemaVersion", Value: types.N},
 			{Key: "mediaType", Value: types.S},
-			{Key: "config", Value: descriptor},
+			{Key: "config", Value: descriptorType},
 			{Key: "layers", Value: types.NewArray(
-				[]types.Type{descriptor}, nil,
+				[]types.Type{descriptorType}, nil,
 			)},
-			{Key: "annotations", Value: annotations},
-			{Key: "subject", Value: descriptor},
+			{Key: "annotations", Value: annotationsType},
+			{Key: "subject", Value: descriptorType},
 		},
 		nil,
 	)
@@ -148,6 +152,76 @@ func registerOCIImageManifest() {
 	})
 }
 
+func registerOCIImageIndex() {
+	index := types.NewObject(
+		[]*types.StaticProperty{
+			{Key: "schemaVersion", Value: types.N},
+			{Key: "mediaType", Value: types.S},
+			{Key: "manifests", Value: types.NewArray(
+				[]types.Type{descriptorType}, nil,
+			)},
+			{Key: "annotations", Value: annotationsType},
+			{Key: "subject", Value: descriptorType},
+		},
+		nil,
+	)
+
+	decl := rego.Function{
+		Name: ociImageIndexName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("ref", types.S).Description("OCI image index reference"),
+			),
+			types.Named("object", index).Description("the Image Index object"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic. But also mark it as non-deterministic because it does rely on external
+		// entities, i.e. OCI registry. https://www.openpolicyagent.org/docs/latest/extensions/
+		Memoize:          true,
+		Nondeterministic: true,
+	}
+
+	rego.RegisterBuiltin1(&decl, ociImageIndex)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name:             decl.Name,
+		Description:      "Fetch an Image Index from an OCI registry, including the descriptors of the platform manifests.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
+func registerOCIReferrers() {
+	decl := rego.Function{
+		Name: ociReferrersName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("ref", types.S).Description("OCI image reference"),
+				types.Named("artifact_type", types.S).Description("the artifact type of the referrers to return, all referrers are returned if empty"),
+			),
+			types.Named("referrers", types.NewArray([]types.Type{descriptorType}, nil)).Description("the descriptors of the referrers"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic. But also mark it as non-deterministic because it does rely on external
+		// entities, i.e. OCI registry. https://www.openpolicyagent.org/docs/latest/extensions/
+		Memoize:          true,
+		Nondeterministic: true,
+	}
+
+	rego.RegisterBuiltin2(&decl, ociReferrers)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name:             decl.Name,
+		Description:      "Fetch the descriptors of the manifests referring to an image, e.g. signatures, attestations or SBOMs, from an OCI registry.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
 func registerOCIImageFiles() {
 	filesObject := types.NewObject(
 		nil,
@@ -275,6 +349,90 @@ func ociImageManifest(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error)
 	return ast.ObjectTerm(manifestTerms...), nil
 }
 
+func ociImageIndex(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
+	log := log.WithField("rego", ociImageIndexName)
+	uri, ok := a.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	ref, err := name.NewDigest(string(uri))
+	if err != nil {
+		log.Errorf("new digest: %s", err)
+		return nil, nil
+	}
+
+	index, err := oci.NewClient(bctx.Context).Index(ref)
+	if err != nil {
+		log.Errorf("fetch index: %s", err)
+		return nil, nil
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		log.Errorf("fetch index manifest: %s", err)
+		return nil, nil
+	}
+
+	if manifest == nil {
+		log.Error("index manifest is nil")
+		return nil, nil
+	}
+
+	manifests := []*ast.Term{}
+	for _, m := range manifest.Manifests {
+		manifests = append(manifests, newDescriptorTerm(m))
+	}
+
+	indexTerms := [][2]*ast.Term{
+		ast.Item(ast.StringTerm("schemaVersion"), ast.NumberTerm(json.Number(fmt.Sprintf("%d", manifest.SchemaVersion)))),
+		ast.Item(ast.StringTerm("mediaType"), ast.StringTerm(string(manifest.MediaType))),
+		ast.Item(ast.StringTerm("manifests"), ast.ArrayTerm(manifests...)),
+		ast.Item(ast.StringTerm("annotations"), newAnnotationsTerm(manifest.Annotations)),
+	}
+
+	if s := manifest.Subject; s != nil {
+		indexTerms = append(indexTerms, ast.Item(ast.StringTerm("subject"), newDescriptorTerm(*s)))
+	}
+
+	return ast.ObjectTerm(indexTerms...), nil
+}
+
+func ociReferrers(bctx rego.BuiltinContext, refTerm *ast.Term, artifactTypeTerm *ast.Term) (*ast.Term, error) {
+	log := log.WithField("rego", ociReferrersName)
+	uri, ok := refTerm.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	artifactType, ok := artifactTypeTerm.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	ref, err := name.NewDigest(string(uri))
+	if err != nil {
+		log.Errorf("new digest: %s", err)
+		return nil, nil
+	}
+
+	index, err := oci.NewClient(bctx.Context).Referrers(ref)
+	if err != nil {
+		log.Errorf("fetch referrers: %s", err)
+		return nil, nil
+	}
+
+	referrers := []*ast.Term{}
+	for _, d := range index.Manifests {
+		if artifactType != "" && d.ArtifactType != string(artifactType) {
+			continue
+		}
+		referrers = append(referrers, newDescriptorTerm(d))
+	}
+
+	return ast.ArrayTerm(referrers...), nil
+}
+
 func ociImageFiles(bctx rego.BuiltinContext, refTerm *ast.Term, pathsTerm *ast.Term) (*ast.Term, error) {
 	log := log.WithField("rego", ociImageFilesName)
 	uri, ok := refTerm.Value.(ast.String)
@@ -379,5 +537,7 @@ func newAnnotationsTerm(annotations map[string]string) *ast.Term {
 func init() {
 	registerOCIBlob()
 	registerOCIImageFiles()
+	registerOCIImageIndex()
 	registerOCIImageManifest()
+	registerOCIReferrers()
 }
@@ -357,11 +357,209 @@ func TestOCIImageFiles(t *testing.T) {
 	}
 }
 
+func TestOCIImageIndex(t *testing.T) {
+	cases := []struct {
+		name     string
+		ref      *ast.Term
+		index    *v1.IndexManifest
+		indexErr error
+		wantErr  bool
+	}{
+		{
+			name: "image index",
+			ref:  ast.StringTerm("registry.local/spam:latest@sha256:01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b"),
+			index: &v1.IndexManifest{
+				SchemaVersion: 2,
+				MediaType:     types.OCIImageIndex,
+				Manifests: []v1.Descriptor{
+					{
+						MediaType: types.OCIManifestSchema1,
+						Size:      123,
+						Digest: v1.Hash{
+							Algorithm: "sha256",
+							Hex:       "4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb",
+						},
+						Platform: &v1.Platform{
+							Architecture: "amd64",
+							OS:           "linux",
+						},
+					},
+					{
+						MediaType: types.OCIManifestSchema1,
+						Size:      456,
+						Digest: v1.Hash{
+							Algorithm: "sha256",
+							Hex:       "325392e8dd2826a53a9a35b7a7f8d71683cd27ebc2c73fee85dab673bc909b67",
+						},
+						Platform: &v1.Platform{
+							Architecture: "arm64",
+							OS:           "linux",
+							Variant:      "v8",
+						},
+					},
+				},
+				Annotations: map[string]string{
+					"index.annotation": "index.annotation.value",
+				},
+			},
+		},
+		{
+			name:    "missing digest",
+			ref:     ast.StringTerm("registry.local/spam:latest"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid ref type",
+			ref:     ast.IntNumberTerm(42),
+			wantErr: true,
+		},
+		{
+			name:     "index error",
+			ref:      ast.StringTerm("registry.local/spam:latest@sha256:01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b"),
+			indexErr: errors.New("kaboom!"),
+			wantErr:  true,
+		},
+		{
+			name:    "nil index manifest",
+			ref:     ast.StringTerm("registry.local/spam:latest@sha256:01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b"),
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.FakeClient{}
+			index := v1fake.FakeImageIndex{}
+			index.IndexManifestReturns(c.index, c.indexErr)
+			client.On("Index", mock.Anything).Return(&index, nil)
+			ctx := oci.WithClient(context.Background(), &client)
+			bctx := rego.BuiltinContext{Context: ctx}
+
+			got, err := ociImageIndex(bctx, c.ref)
+			require.NoError(t, err)
+			if c.wantErr {
+				require.Nil(t, got)
+			} else {
+				require.NotNil(t, got)
+				snaps.MatchJSON(t, got)
+			}
+		})
+	}
+}
+
+func TestOCIReferrers(t *testing.T) {
+	ref := "registry.local/spam@sha256:01ba4719c80b6fe911b091a7c05124b64eeece964e09c058ef8f9805daca546b"
+	referrers := &v1.IndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests: []v1.Descriptor{
+			{
+				MediaType:    types.OCIManifestSchema1,
+				Size:         123,
+				Digest:       v1.Hash{Algorithm: "sha256", Hex: "4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"},
+				ArtifactType: "application/spdx+json",
+			},
+			{
+				MediaType:    types.OCIManifestSchema1,
+				Size:         456,
+				Digest:       v1.Hash{Algorithm: "sha256", Hex: "325392e8dd2826a53a9a35b7a7f8d71683cd27ebc2c73fee85dab673bc909b67"},
+				ArtifactType: "application/vnd.dev.cosign.artifact.sig.v1+json",
+			},
+		},
+	}
+
+	cases := []struct {
+		name         string
+		ref          *ast.Term
+		artifactType *ast.Term
+		remoteErr    error
+		expected     []string
+		wantErr      bool
+	}{
+		{
+			name:         "all referrers",
+			ref:          ast.StringTerm(ref),
+			artifactType: ast.StringTerm(""),
+			expected: []string{
+				"sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb",
+				"sha256:325392e8dd2826a53a9a35b7a7f8d71683cd27ebc2c73fee85dab673bc909b67",
+			},
+		},
+		{
+			name:         "by artifact type",
+			ref:          ast.StringTerm(ref),
+			artifactType: ast.StringTerm("application/spdx+json"),
+			expected:     []string{"sha256:4e388ab32b10dc8dbc7e28144f552830adc74787c1e2c0824032078a79f227fb"},
+		},
+		{
+			name:         "no matching artifact type",
+			ref:          ast.StringTerm(ref),
+			artifactType: ast.StringTerm("application/vnd.cyclonedx+json"),
+			expected:     []string{},
+		},
+		{
+			name:         "unpinned",
+			ref:          ast.StringTerm("registry.local/spam:latest"),
+			artifactType: ast.StringTerm(""),
+			wantErr:      true,
+		},
+		{
+			name:         "invalid ref type",
+			ref:          ast.IntNumberTerm(42),
+			artifactType: ast.StringTerm(""),
+			wantErr:      true,
+		},
+		{
+			name:         "invalid artifact type",
+			ref:          ast.StringTerm(ref),
+			artifactType: ast.IntNumberTerm(42),
+			wantErr:      true,
+		},
+		{
+			name:         "remote error",
+			ref:          ast.StringTerm(ref),
+			artifactType: ast.StringTerm(""),
+			remoteErr:    errors.New("kaboom!"),
+			wantErr:      true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := fake.FakeClient{}
+			if c.remoteErr != nil {
+				client.On("Referrers", mock.Anything).Return(nil, c.remoteErr)
+			} else {
+				client.On("Referrers", mock.Anything).Return(referrers, nil)
+			}
+			ctx := oci.WithClient(context.Background(), &client)
+			bctx := rego.BuiltinContext{Context: ctx}
+
+			got, err := ociReferrers(bctx, c.ref, c.artifactType)
+			require.NoError(t, err)
+			if c.wantErr {
+				require.Nil(t, got)
+				return
+			}
+
+			require.NotNil(t, got)
+			digests := []string{}
+			require.NoError(t, got.Value.(*ast.Array).Iter(func(d *ast.Term) error {
+				digests = append(digests, string(d.Get(ast.StringTerm("digest")).Value.(ast.String)))
+				return nil
+			}))
+			require.Equal(t, c.expected, digests)
+		})
+	}
+}
+
 func TestFunctionsRegistered(t *testing.T) {
 	names := []string{
 		ociBlobName,
 		ociImageFilesName,
+		ociImageIndexName,
 		ociImageManifestName,
+		ociReferrersName,
 	}
 	for _, name := range names {
 		t.Run(name, func(t *testing.T) {
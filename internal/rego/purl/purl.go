@@ -21,6 +21,8 @@
 package rego
 
 import (
+	"strings"
+
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/types"
@@ -29,8 +31,11 @@ import (
 )
 
 const (
-	purlIsValidName = "ec.purl.is_valid"
-	purlParseName   = "ec.purl.parse"
+	purlIsValidName         = "ec.purl.is_valid"
+	purlParseName           = "ec.purl.parse"
+	purlCompareVersionsName = "ec.purl.compare_versions"
+	purlVersionInRangeName  = "ec.purl.version_in_range"
+	purlMatchesName         = "ec.purl.matches"
 )
 
 func registerPURLIsValid() {
@@ -107,6 +112,95 @@ func registerPURLParse() {
 	})
 }
 
+func registerPURLCompareVersions() {
+	decl := rego.Function{
+		Name: purlCompareVersionsName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("scheme", types.S).Description("the versioning scheme, i.e. the PURL type, e.g. rpm"),
+				types.Named("a", types.S).Description("the first version"),
+				types.Named("b", types.S).Description("the second version"),
+			),
+			types.Named("result", types.N).Description("-1, 0 or 1 if the first version is lower, equal or greater than the second version"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic.
+		Memoize:          true,
+		Nondeterministic: false,
+	}
+
+	rego.RegisterBuiltin3(&decl, purlCompareVersions)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "Compare two versions using the versioning scheme of the ecosystem. The rpm, deb, " +
+			"pypi (PEP 440), maven and semver (also npm, golang and cargo) schemes are supported, other " +
+			"schemes compare the numeric and alphabetic segments of the versions in turn.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
+func registerPURLVersionInRange() {
+	decl := rego.Function{
+		Name: purlVersionInRangeName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("purl", types.S).Description("the PURL, the epoch qualifier of rpm and deb PURLs is taken into account"),
+				types.Named("vers", types.S).Description("the version range specifier, e.g. vers:rpm/>=1.0|<2.0"),
+			),
+			types.Named("result", types.B).Description("true if the version of the PURL is within the range"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic.
+		Memoize:          true,
+		Nondeterministic: false,
+	}
+
+	rego.RegisterBuiltin2(&decl, purlVersionInRange)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "Determine whether or not the version of the PURL is within the vers range. The " +
+			"versions are compared using the versioning scheme of the range.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
+func registerPURLMatches() {
+	decl := rego.Function{
+		Name: purlMatchesName,
+		Decl: types.NewFunction(
+			types.Args(
+				types.Named("purl", types.S).Description("the PURL"),
+				types.Named("pattern", types.S).Description("the PURL to match against"),
+			),
+			types.Named("result", types.B).Description("true if the PURL matches"),
+		),
+		// As per the documentation, enable memoization to ensure function evaluation is
+		// deterministic.
+		Memoize:          true,
+		Nondeterministic: false,
+	}
+
+	rego.RegisterBuiltin2(&decl, purlMatches)
+	// Due to https://github.com/open-policy-agent/opa/issues/6449, we cannot set a description for
+	// the custom function through the call above. As a workaround we re-register the function with
+	// a declaration that does include the description.
+	ast.RegisterBuiltin(&ast.Builtin{
+		Name: decl.Name,
+		Description: "Determine whether or not the PURL matches another PURL, ignoring the qualifiers. " +
+			"The version and the subpath are only matched if present in the other PURL.",
+		Decl:             decl.Decl,
+		Nondeterministic: decl.Nondeterministic,
+	})
+}
+
 func purlIsValid(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
 	uri, ok := a.Value.(ast.String)
 	if !ok {
@@ -149,7 +243,114 @@ func purlParse(bctx rego.BuiltinContext, a *ast.Term) (*ast.Term, error) {
 	), nil
 }
 
+func purlCompareVersions(bctx rego.BuiltinContext, s, a, b *ast.Term) (*ast.Term, error) {
+	scheme, ok := s.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+	va, ok := a.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+	vb, ok := b.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	result, err := compareVersions(string(scheme), string(va), string(vb))
+	if err != nil {
+		log.Debugf("Comparing %s versions %s and %s failed: %s", scheme, va, vb, err)
+		return nil, nil
+	}
+
+	return ast.IntNumberTerm(result), nil
+}
+
+func purlVersionInRange(bctx rego.BuiltinContext, a, b *ast.Term) (*ast.Term, error) {
+	uri, ok := a.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+	spec, ok := b.Value.(ast.String)
+	if !ok {
+		return nil, nil
+	}
+
+	instance, err := packageurl.FromString(string(uri))
+	if err != nil {
+		log.Debugf("Parsing PURL %s failed: %s", uri, err)
+		return nil, nil
+	}
+	if instance.Version == "" {
+		log.Debugf("PURL %s has no version", uri)
+		return nil, nil
+	}
+
+	v, err := parseVers(string(spec))
+	if err != nil {
+		log.Debugf("Parsing vers %s failed: %s", spec, err)
+		return nil, nil
+	}
+
+	contains, err := v.contains(version(instance))
+	if err != nil {
+		log.Debugf("Matching PURL %s against %s failed: %s", uri, spec, err)
+		return nil, nil
+	}
+
+	return ast.BooleanTerm(contains), nil
+}
+
+// version returns the version of the PURL. The epoch of rpm and deb packages
+// is provided in the epoch qualifier, when set it is prepended to the version
+// as in epoch:version.
+func version(instance packageurl.PackageURL) string {
+	if instance.Type != packageurl.TypeRPM && instance.Type != packageurl.TypeDebian {
+		return instance.Version
+	}
+
+	epoch, ok := instance.Qualifiers.Map()["epoch"]
+	if !ok || epoch == "" || strings.Contains(instance.Version, ":") {
+		return instance.Version
+	}
+
+	return epoch + ":" + instance.Version
+}
+
+func purlMatches(bctx rego.BuiltinContext, a, b *ast.Term) (*ast.Term, error) {
+	uri, ok := a.Value.(ast.String)
+	if !ok {
+		return ast.BooleanTerm(false), nil
+	}
+	pattern, ok := b.Value.(ast.String)
+	if !ok {
+		return ast.BooleanTerm(false), nil
+	}
+
+	instance, err := packageurl.FromString(string(uri))
+	if err != nil {
+		log.Debugf("Parsing PURL %s failed: %s", uri, err)
+		return ast.BooleanTerm(false), nil
+	}
+	other, err := packageurl.FromString(string(pattern))
+	if err != nil {
+		log.Debugf("Parsing PURL %s failed: %s", pattern, err)
+		return ast.BooleanTerm(false), nil
+	}
+
+	matches := instance.Type == other.Type &&
+		instance.Namespace == other.Namespace &&
+		instance.Name == other.Name &&
+		(other.Version == "" || instance.Version == other.Version) &&
+		(other.Subpath == "" || instance.Subpath == other.Subpath)
+
+	return ast.BooleanTerm(matches), nil
+}
+
 func init() {
 	registerPURLIsValid()
 	registerPURLParse()
+	registerPURLCompareVersions()
+	registerPURLVersionInRange()
+	registerPURLMatches()
 }
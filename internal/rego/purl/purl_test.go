@@ -103,10 +103,210 @@ func TestPURLParse(t *testing.T) {
 	}
 }
 
+func TestPURLCompareVersions(t *testing.T) {
+	cases := []struct {
+		name     string
+		scheme   *ast.Term
+		a        *ast.Term
+		b        *ast.Term
+		expected *ast.Term
+	}{
+		{
+			name:     "lower",
+			scheme:   ast.StringTerm("rpm"),
+			a:        ast.StringTerm("3.0.7-16.el9_2"),
+			b:        ast.StringTerm("3.0.7-18.el9_2"),
+			expected: ast.IntNumberTerm(-1),
+		},
+		{
+			name:     "equal",
+			scheme:   ast.StringTerm("pypi"),
+			a:        ast.StringTerm("1.0"),
+			b:        ast.StringTerm("1.0.0"),
+			expected: ast.IntNumberTerm(0),
+		},
+		{
+			name:     "greater",
+			scheme:   ast.StringTerm("deb"),
+			a:        ast.StringTerm("1:1.0"),
+			b:        ast.StringTerm("2.0"),
+			expected: ast.IntNumberTerm(1),
+		},
+		{
+			name:   "unexpected scheme type",
+			scheme: ast.IntNumberTerm(42),
+			a:      ast.StringTerm("1.0"),
+			b:      ast.StringTerm("1.0"),
+		},
+		{
+			name:   "unexpected version type",
+			scheme: ast.StringTerm("rpm"),
+			a:      ast.StringTerm("1.0"),
+			b:      ast.IntNumberTerm(1),
+		},
+		{
+			name:   "invalid version",
+			scheme: ast.StringTerm("semver"),
+			a:      ast.StringTerm("1.0.0"),
+			b:      ast.StringTerm("latest"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bctx := rego.BuiltinContext{Context: context.Background()}
+
+			result, err := purlCompareVersions(bctx, c.scheme, c.a, c.b)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, result)
+		})
+	}
+}
+
+func TestPURLVersionInRange(t *testing.T) {
+	cases := []struct {
+		name     string
+		uri      *ast.Term
+		vers     *ast.Term
+		expected *ast.Term
+	}{
+		{
+			name:     "in range",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-16.el9_2?arch=x86_64"),
+			vers:     ast.StringTerm("vers:rpm/>=3.0.0|<3.0.7-18.el9_2"),
+			expected: ast.BooleanTerm(true),
+		},
+		{
+			name:     "not in range",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-18.el9_2?arch=x86_64"),
+			vers:     ast.StringTerm("vers:rpm/>=3.0.0|<3.0.7-18.el9_2"),
+			expected: ast.BooleanTerm(false),
+		},
+		{
+			name:     "rpm epoch qualifier",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-16.el9_2?arch=x86_64&epoch=1"),
+			vers:     ast.StringTerm("vers:rpm/>=3.0.0|<3.0.7-18.el9_2"),
+			expected: ast.BooleanTerm(false),
+		},
+		{
+			name:     "rpm epoch qualifier in range",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7-16.el9_2?arch=x86_64&epoch=1"),
+			vers:     ast.StringTerm("vers:rpm/>=1:3.0.0|<1:3.0.7-18.el9_2"),
+			expected: ast.BooleanTerm(true),
+		},
+		{
+			name:     "deb epoch qualifier",
+			uri:      ast.StringTerm("pkg:deb/debian/curl@7.50.3-1?arch=i386&epoch=2"),
+			vers:     ast.StringTerm("vers:deb/<8.0.0"),
+			expected: ast.BooleanTerm(false),
+		},
+		{
+			name: "unexpected uri type",
+			uri:  ast.IntNumberTerm(42),
+			vers: ast.StringTerm("vers:rpm/*"),
+		},
+		{
+			name: "malformed PURL string",
+			uri:  ast.StringTerm("pkg::rpm//fedora/curl7.50.3-1.fc25?arch=i386&distro=fedora-"),
+			vers: ast.StringTerm("vers:rpm/*"),
+		},
+		{
+			name: "PURL without version",
+			uri:  ast.StringTerm("pkg:rpm/redhat/openssl"),
+			vers: ast.StringTerm("vers:rpm/*"),
+		},
+		{
+			name: "malformed vers",
+			uri:  ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7"),
+			vers: ast.StringTerm(">=3.0.0"),
+		},
+		{
+			name: "invalid version",
+			uri:  ast.StringTerm("pkg:npm/left-pad@latest"),
+			vers: ast.StringTerm("vers:npm/>=1.0.0"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bctx := rego.BuiltinContext{Context: context.Background()}
+
+			result, err := purlVersionInRange(bctx, c.uri, c.vers)
+			require.NoError(t, err)
+			require.Equal(t, c.expected, result)
+		})
+	}
+}
+
+func TestPURLMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		uri      *ast.Term
+		pattern  *ast.Term
+		expected bool
+	}{
+		{
+			name:     "ignores qualifiers",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7?arch=x86_64&distro=rhel-9"),
+			pattern:  ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7?arch=aarch64"),
+			expected: true,
+		},
+		{
+			name:     "any version",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7?arch=x86_64"),
+			pattern:  ast.StringTerm("pkg:rpm/redhat/openssl"),
+			expected: true,
+		},
+		{
+			name:     "different version",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7"),
+			pattern:  ast.StringTerm("pkg:rpm/redhat/openssl@3.0.8"),
+			expected: false,
+		},
+		{
+			name:     "different namespace",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl@3.0.7"),
+			pattern:  ast.StringTerm("pkg:rpm/fedora/openssl"),
+			expected: false,
+		},
+		{
+			name:     "different subpath",
+			uri:      ast.StringTerm("pkg:golang/example.com/mod@v1.0.0#a"),
+			pattern:  ast.StringTerm("pkg:golang/example.com/mod#b"),
+			expected: false,
+		},
+		{
+			name:     "unexpected uri type",
+			uri:      ast.IntNumberTerm(42),
+			pattern:  ast.StringTerm("pkg:rpm/redhat/openssl"),
+			expected: false,
+		},
+		{
+			name:     "malformed PURL string",
+			uri:      ast.StringTerm("pkg:rpm/redhat/openssl"),
+			pattern:  ast.StringTerm("pkg::rpm//fedora/curl7.50.3-1.fc25?arch=i386&distro=fedora-"),
+			expected: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bctx := rego.BuiltinContext{Context: context.Background()}
+
+			result, err := purlMatches(bctx, c.uri, c.pattern)
+			require.NoError(t, err)
+			require.Equal(t, ast.BooleanTerm(c.expected), result)
+		})
+	}
+}
+
 func TestFunctionsRegistered(t *testing.T) {
 	names := []string{
 		purlIsValidName,
 		purlParseName,
+		purlCompareVersionsName,
+		purlVersionInRangeName,
+		purlMatchesName,
 	}
 	for _, name := range names {
 		t.Run(name, func(t *testing.T) {
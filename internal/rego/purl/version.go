@@ -0,0 +1,779 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package rego
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// versionComparators holds the version comparison for each versioning scheme,
+// named after the PURL type. Schemes not listed here use compareGeneric.
+var versionComparators = map[string]func(a, b string) (int, error){
+	"rpm":    compareRPM,
+	"deb":    compareDebian,
+	"pypi":   comparePEP440,
+	"maven":  compareMaven,
+	"semver": compareSemver,
+	"npm":    compareSemver,
+	"golang": compareSemver,
+	"cargo":  compareSemver,
+}
+
+// compareVersions compares the two versions using the versioning scheme, the
+// result is -1, 0 or 1 if a is lower, equal or greater than b.
+func compareVersions(scheme, a, b string) (int, error) {
+	compare, ok := versionComparators[strings.ToLower(scheme)]
+	if !ok {
+		compare = compareGeneric
+	}
+
+	return compare(a, b)
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// compareNumeric compares two strings of digits of arbitrary length
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return sign(len(a) - len(b))
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareGeneric compares the versions as RPM does for the version or the
+// release, i.e. segment by segment, numeric segments numerically and
+// alphabetic segments lexically.
+func compareGeneric(a, b string) (int, error) {
+	return rpmvercmp(a, b), nil
+}
+
+// rpmvercmp is a port of rpmvercmp from librpm
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	isSeparator := func(r rune) bool {
+		return r > 0x7f || !(isDigit(byte(r)) || isAlpha(byte(r)) || r == '~' || r == '^')
+	}
+
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		// tilde sorts before everything else, even the end of the version
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		// caret sorts after the end of the version, but before everything else
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		isNumeric := isDigit(a[0])
+		class := isAlpha
+		if isNumeric {
+			class = isDigit
+		}
+		segment := func(s string) (string, string) {
+			i := 0
+			for i < len(s) && class(s[i]) {
+				i++
+			}
+			return s[:i], s[i:]
+		}
+
+		var sa, sb string
+		sa, a = segment(a)
+		sb, b = segment(b)
+
+		// numeric segments are always newer than alphabetic segments
+		if sb == "" {
+			if isNumeric {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if isNumeric {
+			c = compareNumeric(sa, sb)
+		} else {
+			c = strings.Compare(sa, sb)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	if a == "" && b == "" {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+
+	return 1
+}
+
+// splitEpoch splits the optional numeric epoch, e.g. 1 in 1:2.3, from the
+// version
+func splitEpoch(v string) (string, string, error) {
+	epoch, rest, found := strings.Cut(v, ":")
+	if !found {
+		return "0", v, nil
+	}
+
+	if epoch == "" || strings.IndexFunc(epoch, func(r rune) bool { return r < '0' || r > '9' }) != -1 {
+		return "", "", fmt.Errorf("invalid epoch in version %q", v)
+	}
+
+	return epoch, rest, nil
+}
+
+// compareRPM compares RPM [epoch:]version[-release] versions. The releases
+// are compared only when both versions include it, e.g. 1.0 matches 1.0-1.
+func compareRPM(a, b string) (int, error) {
+	ea, va, err := splitEpoch(a)
+	if err != nil {
+		return 0, err
+	}
+	eb, vb, err := splitEpoch(b)
+	if err != nil {
+		return 0, err
+	}
+
+	if c := compareNumeric(ea, eb); c != 0 {
+		return c, nil
+	}
+
+	va, ra, _ := strings.Cut(va, "-")
+	vb, rb, _ := strings.Cut(vb, "-")
+
+	if c := rpmvercmp(va, vb); c != 0 || ra == "" || rb == "" {
+		return c, nil
+	}
+
+	return rpmvercmp(ra, rb), nil
+}
+
+// compareDebian compares Debian [epoch:]upstream_version[-debian_revision]
+// versions as dpkg does
+func compareDebian(a, b string) (int, error) {
+	ea, va, err := splitEpoch(a)
+	if err != nil {
+		return 0, err
+	}
+	eb, vb, err := splitEpoch(b)
+	if err != nil {
+		return 0, err
+	}
+
+	if c := compareNumeric(ea, eb); c != 0 {
+		return c, nil
+	}
+
+	split := func(v string) (string, string) {
+		if i := strings.LastIndex(v, "-"); i != -1 {
+			return v[:i], v[i+1:]
+		}
+		return v, ""
+	}
+
+	ua, ra := split(va)
+	ub, rb := split(vb)
+
+	if c := verrevcmp(ua, ub); c != 0 {
+		return c, nil
+	}
+
+	return verrevcmp(ra, rb), nil
+}
+
+// verrevcmp is a port of verrevcmp from dpkg
+func verrevcmp(a, b string) int {
+	order := func(s string) int {
+		switch {
+		case s == "" || isDigit(s[0]):
+			return 0
+		case isAlpha(s[0]):
+			return int(s[0])
+		case s[0] == '~':
+			return -1
+		default:
+			return int(s[0]) + 256
+		}
+	}
+
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			if c := order(a) - order(b); c != 0 {
+				return sign(c)
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+
+		firstDiff := 0
+		for a != "" && isDigit(a[0]) && b != "" && isDigit(b[0]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+
+	return 0
+}
+
+var pep440 = regexp.MustCompile(`^v?` +
+	`(?:(?P<epoch>[0-9]+)!)?` +
+	`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+	`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// pep440Version is a parsed PEP 440 version. The pre-release, post-release and
+// development release are -1 when absent.
+type pep440Version struct {
+	epoch   string
+	release []string
+	preKind int
+	pre     string
+	post    string
+	dev     string
+	local   []string
+}
+
+func parsePEP440(v string) (*pep440Version, error) {
+	m := pep440.FindStringSubmatch(strings.ToLower(strings.TrimSpace(v)))
+	if m == nil {
+		return nil, fmt.Errorf("invalid PEP 440 version %q", v)
+	}
+	group := func(name string) string {
+		return m[pep440.SubexpIndex(name)]
+	}
+	number := func(n string) string {
+		if n == "" {
+			return "0"
+		}
+		return n
+	}
+
+	p := pep440Version{
+		epoch:   number(group("epoch")),
+		release: strings.Split(group("release"), "."),
+		preKind: -1,
+		post:    "-1",
+		dev:     "-1",
+	}
+
+	// trailing zeros are not significant, i.e. 1.0 == 1.0.0
+	for len(p.release) > 1 && strings.Trim(p.release[len(p.release)-1], "0") == "" {
+		p.release = p.release[:len(p.release)-1]
+	}
+
+	switch group("pre_l") {
+	case "":
+	case "alpha", "a":
+		p.preKind = 0
+	case "beta", "b":
+		p.preKind = 1
+	default:
+		p.preKind = 2
+	}
+	if p.preKind != -1 {
+		p.pre = number(group("pre_n"))
+	}
+
+	if n := group("post_n1"); n != "" {
+		p.post = n
+	} else if group("post_l") != "" {
+		p.post = number(group("post_n2"))
+	}
+
+	if group("dev_l") != "" {
+		p.dev = number(group("dev_n"))
+	}
+
+	if l := group("local"); l != "" {
+		p.local = strings.FieldsFunc(l, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	}
+
+	return &p, nil
+}
+
+// compareOptional compares optional numbers, absent numbers, i.e. -1, sort
+// before any other number
+func compareOptional(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "-1":
+		return -1
+	case b == "-1":
+		return 1
+	default:
+		return compareNumeric(a, b)
+	}
+}
+
+// comparePEP440 compares Python package versions as defined by PEP 440
+func comparePEP440(a, b string) (int, error) {
+	pa, err := parsePEP440(a)
+	if err != nil {
+		return 0, err
+	}
+	pb, err := parsePEP440(b)
+	if err != nil {
+		return 0, err
+	}
+
+	if c := compareNumeric(pa.epoch, pb.epoch); c != 0 {
+		return c, nil
+	}
+
+	for i := 0; i < max(len(pa.release), len(pb.release)); i++ {
+		ra, rb := "0", "0"
+		if i < len(pa.release) {
+			ra = pa.release[i]
+		}
+		if i < len(pb.release) {
+			rb = pb.release[i]
+		}
+		if c := compareNumeric(ra, rb); c != 0 {
+			return c, nil
+		}
+	}
+
+	// a development release without a pre-release or a post-release sorts
+	// before the pre-releases, and a final release after them
+	preKind := func(p *pep440Version) int {
+		switch {
+		case p.preKind != -1:
+			return p.preKind
+		case p.post == "-1" && p.dev != "-1":
+			return -2
+		default:
+			return 3
+		}
+	}
+	if c := sign(preKind(pa) - preKind(pb)); c != 0 {
+		return c, nil
+	}
+	if pa.preKind != -1 {
+		if c := compareNumeric(pa.pre, pb.pre); c != 0 {
+			return c, nil
+		}
+	}
+
+	if c := compareOptional(pa.post, pb.post); c != 0 {
+		return c, nil
+	}
+
+	// no development release sorts after any development release
+	switch {
+	case pa.dev == pb.dev:
+	case pa.dev == "-1":
+		return 1, nil
+	case pb.dev == "-1":
+		return -1, nil
+	default:
+		return compareNumeric(pa.dev, pb.dev), nil
+	}
+
+	for i := 0; i < min(len(pa.local), len(pb.local)); i++ {
+		la, lb := pa.local[i], pb.local[i]
+		na, nb := isDigit(la[0]), isDigit(lb[0])
+		var c int
+		switch {
+		case na && nb:
+			c = compareNumeric(la, lb)
+		case na:
+			c = 1
+		case nb:
+			c = -1
+		default:
+			c = strings.Compare(la, lb)
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return sign(len(pa.local) - len(pb.local)), nil
+}
+
+// mavenQualifiers holds the order of the well known Maven qualifiers, unknown
+// qualifiers sort after these, lexically
+var mavenQualifiers = map[string]int{
+	"alpha":     0,
+	"a":         0,
+	"beta":      1,
+	"b":         1,
+	"milestone": 2,
+	"m":         2,
+	"rc":        3,
+	"cr":        3,
+	"snapshot":  4,
+	"":          5,
+	"ga":        5,
+	"final":     5,
+	"release":   5,
+	"sp":        6,
+}
+
+// mavenItems splits the Maven version into numeric and qualifier items, on
+// dots, dashes and transitions between digits and letters
+func mavenItems(v string) []string {
+	items := []string{}
+	current := strings.Builder{}
+	flush := func() {
+		items = append(items, current.String())
+		current.Reset()
+	}
+
+	v = strings.ToLower(v)
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '.' || c == '-':
+			flush()
+		case i > 0 && current.Len() > 0 && isDigit(c) != isDigit(v[i-1]):
+			flush()
+			current.WriteByte(c)
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+
+	// trailing zeros and release qualifiers are not significant, i.e.
+	// 1.0.0 == 1 == 1-final
+	for len(items) > 0 {
+		last := items[len(items)-1]
+		zero := last != "" && strings.Trim(last, "0") == ""
+		if q, ok := mavenQualifiers[last]; !zero && (!ok || q != 5) {
+			break
+		}
+		items = items[:len(items)-1]
+	}
+
+	return items
+}
+
+func compareMavenItem(a, b string) int {
+	na := a != "" && isDigit(a[0])
+	nb := b != "" && isDigit(b[0])
+
+	switch {
+	case na && nb:
+		return compareNumeric(a, b)
+	case na:
+		// a missing item counts as 0 against numbers
+		if b == "" {
+			return compareNumeric(a, "0")
+		}
+		return 1
+	case nb:
+		if a == "" {
+			return compareNumeric("0", b)
+		}
+		return -1
+	}
+
+	qa, ka := mavenQualifiers[a]
+	qb, kb := mavenQualifiers[b]
+	switch {
+	case ka && kb:
+		return sign(qa - qb)
+	case ka:
+		return -1
+	case kb:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compareMaven compares Maven versions, it is a simplified implementation of
+// Maven's ComparableVersion
+func compareMaven(a, b string) (int, error) {
+	ia, ib := mavenItems(a), mavenItems(b)
+	for i := 0; i < max(len(ia), len(ib)); i++ {
+		var xa, xb string
+		if i < len(ia) {
+			xa = ia[i]
+		}
+		if i < len(ib) {
+			xb = ib[i]
+		}
+		if c := compareMavenItem(xa, xb); c != 0 {
+			return c, nil
+		}
+	}
+
+	return 0, nil
+}
+
+var semverPattern = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?$`)
+
+// compareSemver compares semantic versions, a missing minor or patch version
+// counts as 0 and the build metadata is ignored
+func compareSemver(a, b string) (int, error) {
+	ma := semverPattern.FindStringSubmatch(a)
+	if ma == nil {
+		return 0, fmt.Errorf("invalid semantic version %q", a)
+	}
+	mb := semverPattern.FindStringSubmatch(b)
+	if mb == nil {
+		return 0, fmt.Errorf("invalid semantic version %q", b)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if c := compareNumeric(ma[i], mb[i]); c != 0 {
+			return c, nil
+		}
+	}
+
+	pa, pb := ma[4], mb[4]
+	switch {
+	case pa == pb:
+		return 0, nil
+	case pa == "":
+		return 1, nil
+	case pb == "":
+		return -1, nil
+	}
+
+	ia, ib := strings.Split(pa, "."), strings.Split(pb, ".")
+	for i := 0; i < min(len(ia), len(ib)); i++ {
+		numeric := func(s string) bool {
+			return strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) == -1
+		}
+		na, nb := numeric(ia[i]), numeric(ib[i])
+		var c int
+		switch {
+		case na && nb:
+			c = compareNumeric(ia[i], ib[i])
+		case na:
+			c = -1
+		case nb:
+			c = 1
+		default:
+			c = strings.Compare(ia[i], ib[i])
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return sign(len(ia) - len(ib)), nil
+}
+
+// versConstraint is a single constraint of a vers range, e.g. >=1.0
+type versConstraint struct {
+	comparator string
+	version    string
+}
+
+// vers is a parsed version range specifier, see
+// https://github.com/package-url/purl-spec/blob/master/VERSION-RANGE-SPEC.rst
+type vers struct {
+	scheme      string
+	any         bool
+	constraints []versConstraint
+}
+
+func parseVers(s string) (*vers, error) {
+	spec, found := strings.CutPrefix(strings.ReplaceAll(s, " ", ""), "vers:")
+	if !found {
+		return nil, fmt.Errorf("invalid vers %q, expecting the vers: prefix", s)
+	}
+
+	scheme, constraints, found := strings.Cut(spec, "/")
+	if !found || scheme == "" || constraints == "" {
+		return nil, fmt.Errorf("invalid vers %q, expecting vers:<scheme>/<constraints>", s)
+	}
+
+	v := vers{scheme: strings.ToLower(scheme)}
+	if constraints == "*" {
+		v.any = true
+		return &v, nil
+	}
+
+	for _, c := range strings.Split(constraints, "|") {
+		constraint := versConstraint{comparator: "="}
+		for _, comparator := range []string{">=", "<=", "!=", "<", ">", "="} {
+			if rest, ok := strings.CutPrefix(c, comparator); ok {
+				constraint.comparator = comparator
+				c = rest
+				break
+			}
+		}
+
+		version, err := url.PathUnescape(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version in vers %q: %w", s, err)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("invalid vers %q, empty version", s)
+		}
+		constraint.version = version
+
+		v.constraints = append(v.constraints, constraint)
+	}
+
+	return &v, nil
+}
+
+// contains returns true if the version is within the range, following the
+// algorithm of the version range specification
+func (v *vers) contains(version string) (bool, error) {
+	if v.any {
+		return true, nil
+	}
+
+	// the result of comparing the version with the version of each constraint
+	results := make(map[versConstraint]int, len(v.constraints))
+	for _, c := range v.constraints {
+		r, err := compareVersions(v.scheme, version, c.version)
+		if err != nil {
+			return false, err
+		}
+		results[c] = r
+	}
+
+	ranges := make([]versConstraint, 0, len(v.constraints))
+	for _, c := range v.constraints {
+		switch c.comparator {
+		case "=":
+			if results[c] == 0 {
+				return true, nil
+			}
+		case "!=":
+			if results[c] == 0 {
+				return false, nil
+			}
+		default:
+			ranges = append(ranges, c)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		// the versions of the constraints are known to be valid by now
+		c, _ := compareVersions(v.scheme, ranges[i].version, ranges[j].version)
+		return c < 0
+	})
+
+	satisfies := func(c versConstraint) bool {
+		r := results[c]
+		switch c.comparator {
+		case "<":
+			return r < 0
+		case "<=":
+			return r <= 0
+		case ">":
+			return r > 0
+		default:
+			return r >= 0
+		}
+	}
+	lower := func(c versConstraint) bool {
+		return c.comparator == ">" || c.comparator == ">="
+	}
+
+	for i, c := range ranges {
+		if !satisfies(c) {
+			continue
+		}
+
+		last := i == len(ranges)-1
+		switch {
+		case !lower(c) && i == 0:
+			return true, nil
+		case lower(c) && last:
+			return true, nil
+		case lower(c) && !last && !lower(ranges[i+1]) && satisfies(ranges[i+1]):
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
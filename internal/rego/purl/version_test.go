@@ -0,0 +1,184 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package rego
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		scheme   string
+		a        string
+		b        string
+		expected int
+	}{
+		// rpm
+		{"rpm", "1.0", "1.0", 0},
+		{"rpm", "1.0", "1.1", -1},
+		{"rpm", "1.10", "1.9", 1},
+		{"rpm", "1.0-1.el9", "1.0-2.el9", -1},
+		{"rpm", "1.0-10.el9", "1.0-9.el9", 1},
+		{"rpm", "1:1.0-1", "2.0-1", 1},
+		{"rpm", "0:1.0-1", "1.0-1", 0},
+		{"rpm", "1.0", "1.0-1", 0},
+		{"rpm", "1.0~rc1", "1.0", -1},
+		{"rpm", "1.0^git1", "1.0", 1},
+		{"rpm", "1.0^git1", "1.0.1", -1},
+		{"rpm", "1.0a", "1.0", 1},
+		{"rpm", "1.0a", "1.0.1", -1},
+		{"rpm", "3.0.7-18.el9_2", "3.0.7-16.el9_2", 1},
+		// deb
+		{"deb", "1.0", "1.0", 0},
+		{"deb", "1.0-1", "1.0-2", -1},
+		{"deb", "1:1.0", "2.0", 1},
+		{"deb", "1.0~rc1", "1.0", -1},
+		{"deb", "1.0+deb12u1", "1.0", 1},
+		{"deb", "1.0a", "1.0+", -1},
+		{"deb", "2.36-9+deb12u4", "2.36-9+deb12u10", -1},
+		// pypi
+		{"pypi", "1.0", "1.0.0", 0},
+		{"pypi", "1.0a1", "1.0", -1},
+		{"pypi", "1.0rc1", "1.0b2", 1},
+		{"pypi", "1.0.dev1", "1.0a1", -1},
+		{"pypi", "1.0.post1", "1.0", 1},
+		{"pypi", "1.0.post1.dev1", "1.0.post1", -1},
+		{"pypi", "1!0.1", "2.0", 1},
+		{"pypi", "1.0+local.1", "1.0", 1},
+		{"pypi", "1.0+local.2", "1.0+local.10", -1},
+		{"pypi", "V1.0-1", "1.0.post1", 0},
+		// maven
+		{"maven", "1.0", "1", 0},
+		{"maven", "1.0-final", "1", 0},
+		{"maven", "1.0-alpha-1", "1.0-beta-1", -1},
+		{"maven", "1.0-rc1", "1.0-SNAPSHOT", -1},
+		{"maven", "1.0-SNAPSHOT", "1.0", -1},
+		{"maven", "1.0-sp1", "1.0", 1},
+		{"maven", "1.0.1", "1.0-sp1", 1},
+		{"maven", "2.0.10", "2.0.9", 1},
+		{"maven", "1.0-foo", "1.0", 1},
+		// semver
+		{"semver", "1.2.3", "1.2.3", 0},
+		{"npm", "1.2.3", "1.10.0", -1},
+		{"golang", "v1.2.3", "1.2.3", 0},
+		{"golang", "v0.0.0-20240101000000-abcdef123456", "v0.0.1", -1},
+		{"cargo", "1.0.0-alpha", "1.0.0", -1},
+		{"semver", "1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"semver", "1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"semver", "1.0.0-rc.11", "1.0.0-rc.2", 1},
+		{"semver", "1.0.0+build.1", "1.0.0+build.2", 0},
+		{"semver", "1.2", "1.2.0", 0},
+		// generic
+		{"generic", "1.0", "1.1", -1},
+		{"unknown", "2.10", "2.9", 1},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %s %s", c.scheme, c.a, c.b), func(t *testing.T) {
+			result, err := compareVersions(c.scheme, c.a, c.b)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+
+			result, err = compareVersions(c.scheme, c.b, c.a)
+			require.NoError(t, err)
+			assert.Equal(t, -c.expected, result)
+		})
+	}
+}
+
+func TestCompareVersionsInvalid(t *testing.T) {
+	cases := []struct {
+		scheme string
+		a      string
+		b      string
+		err    string
+	}{
+		{"rpm", "x:1.0", "1.0", `invalid epoch in version "x:1.0"`},
+		{"deb", "1.0", ":1.0", `invalid epoch in version ":1.0"`},
+		{"pypi", "1.0", "latest", `invalid PEP 440 version "latest"`},
+		{"semver", "1.0.0.0", "1.0.0", `invalid semantic version "1.0.0.0"`},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %s %s", c.scheme, c.a, c.b), func(t *testing.T) {
+			_, err := compareVersions(c.scheme, c.a, c.b)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
+
+func TestVersContains(t *testing.T) {
+	cases := []struct {
+		vers     string
+		version  string
+		expected bool
+	}{
+		{"vers:rpm/*", "1.0", true},
+		{"vers:rpm/1.0-1", "1.0-1", true},
+		{"vers:rpm/1.0-1", "1.0-2", false},
+		{"vers:rpm/>=1.0|<2.0", "1.5", true},
+		{"vers:rpm/>=1.0|<2.0", "2.0", false},
+		{"vers:rpm/>=1.0|<2.0", "0.9", false},
+		{"vers:rpm/<1.0", "0.9", true},
+		{"vers:rpm/>2.0", "2.0.1", true},
+		{"vers:rpm/>=1.0|<2.0|!=1.5", "1.5", false},
+		{"vers:rpm/>=1.0|<2.0|>=3.0|<4.0", "3.5", true},
+		{"vers:rpm/>=1.0|<2.0|>=3.0|<4.0", "2.5", false},
+		{"vers:rpm/<3.0.7-18.el9_2", "3.0.7-16.el9_2", true},
+		{"vers:deb/>=2.36 | <2.36-9+deb12u4", "2.36-9+deb12u3", true},
+		{"vers:pypi/>=1.0a1|<1.0", "1.0rc1", true},
+		{"vers:maven/>=1.0|<=1.2|1.5", "1.5", true},
+		{"vers:npm/>=1.0.0|<1.2.0", "1.10.0", false},
+		{"vers:npm/1.0.0%2Bbuild", "1.0.0", true},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %s", c.vers, c.version), func(t *testing.T) {
+			v, err := parseVers(c.vers)
+			require.NoError(t, err)
+
+			contains, err := v.contains(c.version)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, contains)
+		})
+	}
+}
+
+func TestParseVersInvalid(t *testing.T) {
+	cases := []struct {
+		vers string
+		err  string
+	}{
+		{"rpm/>=1.0", `invalid vers "rpm/>=1.0", expecting the vers: prefix`},
+		{"vers:rpm", `invalid vers "vers:rpm", expecting vers:<scheme>/<constraints>`},
+		{"vers:/1.0", `invalid vers "vers:/1.0", expecting vers:<scheme>/<constraints>`},
+		{"vers:rpm/>=1.0|<", `invalid vers "vers:rpm/>=1.0|<", empty version`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.vers, func(t *testing.T) {
+			_, err := parseVers(c.vers)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
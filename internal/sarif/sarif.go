@@ -0,0 +1,237 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sarif converts validation results to the Static Analysis Results
+// Interchange Format (SARIF) version 2.1.0, as ingested by code scanning tools.
+package sarif
+
+import (
+	"fmt"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	toolName           = "ec"
+	toolInformationURI = "https://enterprisecontract.dev"
+)
+
+// Levels of SARIF results.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+)
+
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool      Tool       `json:"tool"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	Results   []Result   `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+	Version        string `json:"version,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+type Rule struct {
+	ID               string          `json:"id"`
+	ShortDescription *Message        `json:"shortDescription,omitempty"`
+	FullDescription  *Message        `json:"fullDescription,omitempty"`
+	Help             *Message        `json:"help,omitempty"`
+	Properties       *RuleProperties `json:"properties,omitempty"`
+}
+
+type RuleProperties struct {
+	Collections []string `json:"collections,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Artifact struct {
+	Location ArtifactLocation `json:"location"`
+}
+
+type ArtifactLocation struct {
+	URI   string `json:"uri"`
+	Index *int   `json:"index,omitempty"`
+}
+
+type Result struct {
+	RuleID    string     `json:"ruleId,omitempty"`
+	RuleIndex *int       `json:"ruleIndex,omitempty"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+}
+
+// Builder collects results, the rules and the artifacts they refer to, into
+// a SARIF log.
+type Builder struct {
+	run       Run
+	rules     map[string]int
+	artifacts map[string]int
+}
+
+// NewBuilder returns a Builder for a SARIF log produced by the given version
+// of ec.
+func NewBuilder(version string) *Builder {
+	return &Builder{
+		run: Run{
+			Tool: Tool{
+				Driver: Driver{
+					Name:           toolName,
+					InformationURI: toolInformationURI,
+					Version:        version,
+				},
+			},
+			Results: []Result{},
+		},
+		rules:     map[string]int{},
+		artifacts: map[string]int{},
+	}
+}
+
+// Add adds the results at the given level, found when validating the
+// artifact at the given location: an image reference or an input file path.
+func (b *Builder) Add(location string, level string, results []evaluator.Result) {
+	artifactIndex := b.artifact(location)
+
+	for _, r := range results {
+		result := Result{
+			Level:   level,
+			Message: Message{Text: r.Message},
+			Locations: []Location{
+				{
+					PhysicalLocation: PhysicalLocation{
+						ArtifactLocation: ArtifactLocation{URI: location, Index: &artifactIndex},
+					},
+				},
+			},
+		}
+
+		if code, ok := r.Metadata["code"].(string); ok && code != "" {
+			ruleIndex := b.rule(code, r.Metadata)
+			result.RuleID = code
+			result.RuleIndex = &ruleIndex
+		}
+
+		b.run.Results = append(b.run.Results, result)
+	}
+}
+
+// Log returns the SARIF log with all the results added.
+func (b *Builder) Log() Log {
+	return Log{
+		Version: Version,
+		Schema:  Schema,
+		Runs:    []Run{b.run},
+	}
+}
+
+func (b *Builder) artifact(location string) int {
+	if i, ok := b.artifacts[location]; ok {
+		return i
+	}
+
+	i := len(b.run.Artifacts)
+	b.run.Artifacts = append(b.run.Artifacts, Artifact{Location: ArtifactLocation{URI: location}})
+	b.artifacts[location] = i
+
+	return i
+}
+
+// rule returns the index of the rule descriptor with the given code, the
+// descriptor is created from the result metadata on first use
+func (b *Builder) rule(code string, metadata map[string]any) int {
+	if i, ok := b.rules[code]; ok {
+		return i
+	}
+
+	rule := Rule{ID: code}
+
+	if title := text(metadata["title"]); title != "" {
+		rule.ShortDescription = &Message{Text: title}
+	}
+
+	if description := text(metadata["description"]); description != "" {
+		rule.FullDescription = &Message{Text: description}
+	}
+
+	if solution := text(metadata["solution"]); solution != "" {
+		rule.Help = &Message{Text: solution}
+	}
+
+	if collections := stringSlice(metadata["collections"]); len(collections) > 0 {
+		rule.Properties = &RuleProperties{Collections: collections}
+	}
+
+	i := len(b.run.Tool.Driver.Rules)
+	b.run.Tool.Driver.Rules = append(b.run.Tool.Driver.Rules, rule)
+	b.rules[code] = i
+
+	return i
+}
+
+func text(v any) string {
+	if v == nil {
+		return ""
+	}
+
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(v)
+}
+
+func stringSlice(v any) []string {
+	var s []string
+	switch vals := v.(type) {
+	case []string:
+		s = append(s, vals...)
+	case []any:
+		for _, v := range vals {
+			s = append(s, fmt.Sprint(v))
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,156 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package sarif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder("v1.2.3")
+
+	b.Add("registry.io/repository/image@sha256:abc", LevelError, []evaluator.Result{
+		{
+			Message: "Violation 1",
+			Metadata: map[string]any{
+				"code":        "pkg.rule1",
+				"title":       "Rule 1",
+				"description": "Rule 1 checks things",
+				"solution":    "Do things right",
+				"collections": []string{"minimal", "redhat"},
+			},
+		},
+		{
+			Message: "Violation 2",
+			Metadata: map[string]any{
+				"code": "pkg.rule2",
+			},
+		},
+		{
+			Message: "Violation without code",
+		},
+	})
+	b.Add("registry.io/repository/image@sha256:abc", LevelWarning, []evaluator.Result{
+		{
+			Message: "Warning 1",
+			Metadata: map[string]any{
+				"code":        "pkg.rule3",
+				"collections": []any{"minimal"},
+			},
+		},
+	})
+	b.Add("registry.io/repository/other@sha256:def", LevelError, []evaluator.Result{
+		{
+			// same rule, the rule descriptor is reused
+			Message: "Violation 1 again",
+			Metadata: map[string]any{
+				"code": "pkg.rule1",
+			},
+		},
+	})
+	b.Add("registry.io/repository/passing@sha256:f00", LevelError, nil)
+
+	j, err := json.Marshal(b.Log())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [{
+			"tool": {
+				"driver": {
+					"name": "ec",
+					"informationUri": "https://enterprisecontract.dev",
+					"version": "v1.2.3",
+					"rules": [
+						{
+							"id": "pkg.rule1",
+							"shortDescription": {"text": "Rule 1"},
+							"fullDescription": {"text": "Rule 1 checks things"},
+							"help": {"text": "Do things right"},
+							"properties": {"collections": ["minimal", "redhat"]}
+						},
+						{"id": "pkg.rule2"},
+						{"id": "pkg.rule3", "properties": {"collections": ["minimal"]}}
+					]
+				}
+			},
+			"artifacts": [
+				{"location": {"uri": "registry.io/repository/image@sha256:abc"}},
+				{"location": {"uri": "registry.io/repository/other@sha256:def"}},
+				{"location": {"uri": "registry.io/repository/passing@sha256:f00"}}
+			],
+			"results": [
+				{
+					"ruleId": "pkg.rule1",
+					"ruleIndex": 0,
+					"level": "error",
+					"message": {"text": "Violation 1"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image@sha256:abc", "index": 0}}}]
+				},
+				{
+					"ruleId": "pkg.rule2",
+					"ruleIndex": 1,
+					"level": "error",
+					"message": {"text": "Violation 2"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image@sha256:abc", "index": 0}}}]
+				},
+				{
+					"level": "error",
+					"message": {"text": "Violation without code"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image@sha256:abc", "index": 0}}}]
+				},
+				{
+					"ruleId": "pkg.rule3",
+					"ruleIndex": 2,
+					"level": "warning",
+					"message": {"text": "Warning 1"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/image@sha256:abc", "index": 0}}}]
+				},
+				{
+					"ruleId": "pkg.rule1",
+					"ruleIndex": 0,
+					"level": "error",
+					"message": {"text": "Violation 1 again"},
+					"locations": [{"physicalLocation": {"artifactLocation": {"uri": "registry.io/repository/other@sha256:def", "index": 1}}}]
+				}
+			]
+		}]
+	}`, string(j))
+}
+
+func TestEmptyLog(t *testing.T) {
+	j, err := json.Marshal(NewBuilder("").Log())
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"version": "2.1.0",
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"runs": [{
+			"tool": {"driver": {"name": "ec", "informationUri": "https://enterprisecontract.dev"}},
+			"results": []
+		}]
+	}`, string(j))
+}
@@ -0,0 +1,139 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type cycloneDXDocument struct {
+	BOMFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Metadata     struct {
+		Component *cycloneDXComponent `json:"component"`
+	} `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+	Dependencies []struct {
+		Ref       string   `json:"ref"`
+		DependsOn []string `json:"dependsOn"`
+	} `json:"dependencies"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef   string `json:"bom-ref"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Purl     string `json:"purl"`
+	Licenses []struct {
+		License *struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	} `json:"licenses"`
+	Hashes []struct {
+		Alg     string `json:"alg"`
+		Content string `json:"content"`
+	} `json:"hashes"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+func parseCycloneDX(data []byte) (*Document, error) {
+	var cdx cycloneDXDocument
+	if err := json.Unmarshal(data, &cdx); err != nil {
+		return nil, fmt.Errorf("unable to parse the CycloneDX document: %w", err)
+	}
+
+	if cdx.BOMFormat != "CycloneDX" {
+		return nil, fmt.Errorf("unsupported BOM format %q, expecting CycloneDX", cdx.BOMFormat)
+	}
+
+	if cdx.SpecVersion == "" {
+		return nil, errors.New("invalid CycloneDX document, missing specVersion")
+	}
+
+	doc := Document{
+		Format:  FormatCycloneDX,
+		Version: cdx.SpecVersion,
+		Name:    cdx.SerialNumber,
+	}
+	if c := cdx.Metadata.Component; c != nil && c.Name != "" {
+		doc.Name = c.Name
+	}
+
+	var add func(parent string, components []cycloneDXComponent)
+	add = func(parent string, components []cycloneDXComponent) {
+		for _, c := range components {
+			pkg := Package{
+				ID:      c.BOMRef,
+				Name:    c.Name,
+				Version: c.Version,
+				Purl:    c.Purl,
+			}
+			if pkg.ID == "" {
+				// bom-ref is optional, the purl is the next best identifier
+				pkg.ID = c.Purl
+			}
+
+			for _, l := range c.Licenses {
+				switch {
+				case l.Expression != "":
+					pkg.Licenses = append(pkg.Licenses, l.Expression)
+				case l.License != nil && l.License.ID != "":
+					pkg.Licenses = append(pkg.Licenses, l.License.ID)
+				case l.License != nil && l.License.Name != "":
+					pkg.Licenses = append(pkg.Licenses, l.License.Name)
+				}
+			}
+
+			for _, h := range c.Hashes {
+				if pkg.Hashes == nil {
+					pkg.Hashes = make(map[string]string, len(c.Hashes))
+				}
+				pkg.Hashes[normalizeAlgorithm(h.Alg)] = h.Content
+			}
+
+			doc.Packages = append(doc.Packages, pkg)
+
+			if parent != "" && pkg.ID != "" {
+				doc.Relationships = append(doc.Relationships, Relationship{
+					From: parent,
+					To:   pkg.ID,
+					Type: "CONTAINS",
+				})
+			}
+
+			add(pkg.ID, c.Components)
+		}
+	}
+	add("", cdx.Components)
+
+	for _, d := range cdx.Dependencies {
+		for _, to := range d.DependsOn {
+			doc.Relationships = append(doc.Relationships, Relationship{
+				From: d.Ref,
+				To:   to,
+				Type: "DEPENDS_ON",
+			})
+		}
+	}
+
+	return &doc, nil
+}
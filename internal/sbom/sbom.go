@@ -0,0 +1,158 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sbom parses SPDX and CycloneDX documents into a common, normalized,
+// representation included in the policy input. Parsing large SBOMs is much
+// faster here than in Rego.
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The supported formats
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// The origins of the SBOMs
+const (
+	OriginAttestation = "attestation"
+	OriginBlob        = "blob"
+	OriginReferrer    = "referrer"
+)
+
+// SBOM is the normalized content of all the SBOMs of an image, the packages
+// and the relationships refer to the document they were found in using the
+// index of its source.
+type SBOM struct {
+	Sources       []Source       `json:"sources"`
+	Packages      []Package      `json:"packages"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// Source describes a SBOM document, when the document could not be parsed
+// the Error holds the reason and no packages or relationships are included
+// from it.
+type Source struct {
+	Format  string `json:"format,omitempty"`
+	Version string `json:"version,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Origin  string `json:"origin"`
+	Ref     string `json:"ref,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Package is a package, or a component in CycloneDX terms, listed in a SBOM.
+// Hashes are keyed by the lowercase algorithm name without dashes, e.g.
+// sha256.
+type Package struct {
+	Source   int               `json:"source"`
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Version  string            `json:"version,omitempty"`
+	Purl     string            `json:"purl,omitempty"`
+	Licenses []string          `json:"licenses,omitempty"`
+	Hashes   map[string]string `json:"hashes,omitempty"`
+}
+
+// Relationship relates two packages, using their IDs, e.g. a package
+// DEPENDS_ON another. CycloneDX dependencies are represented as DEPENDS_ON
+// and nested components as CONTAINS relationships.
+type Relationship struct {
+	Source int    `json:"source"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Type   string `json:"type"`
+}
+
+// Document is a parsed SBOM
+type Document struct {
+	Format        string
+	Version       string
+	Name          string
+	Packages      []Package
+	Relationships []Relationship
+}
+
+// Parse parses and validates the SBOM, in either the SPDX or the CycloneDX
+// JSON format.
+func Parse(data []byte) (*Document, error) {
+	var probe struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("unable to parse the SBOM: %w", err)
+	}
+
+	switch {
+	case probe.SPDXVersion != "":
+		return parseSPDX(data)
+	case probe.BOMFormat != "":
+		return parseCycloneDX(data)
+	default:
+		return nil, errors.New("unknown SBOM format, expecting SPDX or CycloneDX JSON")
+	}
+}
+
+// Add adds the document to the SBOM, the error, if not nil, is recorded
+// against the source of the document instead.
+func (s *SBOM) Add(source Source, doc *Document, err error) {
+	index := len(s.Sources)
+	if s.Packages == nil {
+		s.Packages = []Package{}
+	}
+	if s.Relationships == nil {
+		s.Relationships = []Relationship{}
+	}
+
+	if err != nil {
+		source.Error = err.Error()
+		s.Sources = append(s.Sources, source)
+		return
+	}
+
+	source.Format = doc.Format
+	source.Version = doc.Version
+	source.Name = doc.Name
+	s.Sources = append(s.Sources, source)
+
+	for _, p := range doc.Packages {
+		p.Source = index
+		s.Packages = append(s.Packages, p)
+	}
+
+	for _, r := range doc.Relationships {
+		r.Source = index
+		s.Relationships = append(s.Relationships, r)
+	}
+}
+
+// Empty returns true if no SBOMs were added
+func (s *SBOM) Empty() bool {
+	return s == nil || len(s.Sources) == 0
+}
+
+// normalizeAlgorithm returns the algorithm name in lowercase without the
+// dashes, e.g. sha256 for SHA-256 or SHA256.
+func normalizeAlgorithm(alg string) string {
+	return strings.ToLower(strings.ReplaceAll(alg, "-", ""))
+}
@@ -0,0 +1,198 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSPDX = `{
+  "spdxVersion": "SPDX-2.3",
+  "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "registry.io/repository/image",
+  "packages": [
+    {
+      "SPDXID": "SPDXRef-image",
+      "name": "image",
+      "licenseConcluded": "NOASSERTION"
+    },
+    {
+      "SPDXID": "SPDXRef-package-openssl",
+      "name": "openssl",
+      "versionInfo": "3.0.7",
+      "licenseConcluded": "Apache-2.0",
+      "licenseDeclared": "Apache-2.0",
+      "checksums": [{"algorithm": "SHA256", "checksumValue": "abc"}],
+      "externalRefs": [
+        {
+          "referenceCategory": "PACKAGE-MANAGER",
+          "referenceType": "purl",
+          "referenceLocator": "pkg:rpm/redhat/openssl@3.0.7"
+        }
+      ]
+    }
+  ],
+  "relationships": [
+    {
+      "spdxElementId": "SPDXRef-image",
+      "relatedSpdxElement": "SPDXRef-package-openssl",
+      "relationshipType": "CONTAINS"
+    }
+  ]
+}`
+
+const testCycloneDX = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "serialNumber": "urn:uuid:1",
+  "metadata": {"component": {"name": "registry.io/repository/image"}},
+  "components": [
+    {
+      "bom-ref": "app",
+      "name": "app",
+      "version": "1.0.0",
+      "purl": "pkg:golang/example.com/app@1.0.0",
+      "licenses": [{"license": {"id": "MIT"}}],
+      "hashes": [{"alg": "SHA-256", "content": "def"}],
+      "components": [
+        {
+          "name": "lib",
+          "purl": "pkg:golang/example.com/lib@0.1.0",
+          "licenses": [{"expression": "MIT OR Apache-2.0"}]
+        }
+      ]
+    }
+  ],
+  "dependencies": [
+    {"ref": "app", "dependsOn": ["pkg:golang/example.com/lib@0.1.0"]}
+  ]
+}`
+
+func TestParseSPDX(t *testing.T) {
+	doc, err := Parse([]byte(testSPDX))
+	require.NoError(t, err)
+
+	assert.Equal(t, &Document{
+		Format:  FormatSPDX,
+		Version: "SPDX-2.3",
+		Name:    "registry.io/repository/image",
+		Packages: []Package{
+			{ID: "SPDXRef-image", Name: "image"},
+			{
+				ID:       "SPDXRef-package-openssl",
+				Name:     "openssl",
+				Version:  "3.0.7",
+				Purl:     "pkg:rpm/redhat/openssl@3.0.7",
+				Licenses: []string{"Apache-2.0"},
+				Hashes:   map[string]string{"sha256": "abc"},
+			},
+		},
+		Relationships: []Relationship{
+			{From: "SPDXRef-image", To: "SPDXRef-package-openssl", Type: "CONTAINS"},
+		},
+	}, doc)
+}
+
+func TestParseCycloneDX(t *testing.T) {
+	doc, err := Parse([]byte(testCycloneDX))
+	require.NoError(t, err)
+
+	assert.Equal(t, &Document{
+		Format:  FormatCycloneDX,
+		Version: "1.5",
+		Name:    "registry.io/repository/image",
+		Packages: []Package{
+			{
+				ID:       "app",
+				Name:     "app",
+				Version:  "1.0.0",
+				Purl:     "pkg:golang/example.com/app@1.0.0",
+				Licenses: []string{"MIT"},
+				Hashes:   map[string]string{"sha256": "def"},
+			},
+			{
+				ID:       "pkg:golang/example.com/lib@0.1.0",
+				Name:     "lib",
+				Purl:     "pkg:golang/example.com/lib@0.1.0",
+				Licenses: []string{"MIT OR Apache-2.0"},
+			},
+		},
+		Relationships: []Relationship{
+			{From: "app", To: "pkg:golang/example.com/lib@0.1.0", Type: "CONTAINS"},
+			{From: "app", To: "pkg:golang/example.com/lib@0.1.0", Type: "DEPENDS_ON"},
+		},
+	}, doc)
+}
+
+func TestParseInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "not JSON", data: "spdx", err: "unable to parse the SBOM"},
+		{name: "unknown format", data: `{"kind": "sbom"}`, err: "unknown SBOM format"},
+		{name: "SPDX 3", data: `{"spdxVersion": "SPDX-3.0", "SPDXID": "x"}`, err: `unsupported SPDX version "SPDX-3.0"`},
+		{name: "SPDX without SPDXID", data: `{"spdxVersion": "SPDX-2.3"}`, err: "missing SPDXID"},
+		{name: "SPDX package without SPDXID", data: `{"spdxVersion": "SPDX-2.3", "SPDXID": "x", "packages": [{}]}`, err: "package 0 is missing SPDXID"},
+		{name: "not CycloneDX", data: `{"bomFormat": "Other"}`, err: `unsupported BOM format "Other"`},
+		{name: "CycloneDX without specVersion", data: `{"bomFormat": "CycloneDX"}`, err: "missing specVersion"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Parse([]byte(c.data))
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	s := &SBOM{}
+	assert.True(t, s.Empty())
+
+	spdx, err := Parse([]byte(testSPDX))
+	require.NoError(t, err)
+	s.Add(Source{Origin: OriginAttestation, Ref: "https://spdx.dev/Document"}, spdx, nil)
+
+	_, err = Parse([]byte("{}"))
+	s.Add(Source{Origin: OriginReferrer, Ref: "registry.io/repository/image@sha256:0"}, nil, err)
+
+	cdx, err := Parse([]byte(testCycloneDX))
+	require.NoError(t, err)
+	s.Add(Source{Origin: OriginReferrer, Ref: "registry.io/repository/image@sha256:1"}, cdx, nil)
+
+	assert.False(t, s.Empty())
+	assert.Equal(t, []Source{
+		{Format: FormatSPDX, Version: "SPDX-2.3", Name: "registry.io/repository/image", Origin: OriginAttestation, Ref: "https://spdx.dev/Document"},
+		{Origin: OriginReferrer, Ref: "registry.io/repository/image@sha256:0", Error: "unknown SBOM format, expecting SPDX or CycloneDX JSON"},
+		{Format: FormatCycloneDX, Version: "1.5", Name: "registry.io/repository/image", Origin: OriginReferrer, Ref: "registry.io/repository/image@sha256:1"},
+	}, s.Sources)
+
+	sources := make([]int, 0, len(s.Packages))
+	for _, p := range s.Packages {
+		sources = append(sources, p.Source)
+	}
+	assert.Equal(t, []int{0, 0, 2, 2}, sources)
+	assert.Len(t, s.Relationships, 3)
+	assert.Equal(t, 2, s.Relationships[2].Source)
+}
@@ -0,0 +1,131 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type spdxDocument struct {
+	SPDXVersion   string             `json:"spdxVersion"`
+	SPDXID        string             `json:"SPDXID"`
+	Name          string             `json:"name"`
+	Packages      []spdxPackage      `json:"packages"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+type spdxPackage struct {
+	SPDXID           string `json:"SPDXID"`
+	Name             string `json:"name"`
+	VersionInfo      string `json:"versionInfo"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+	Checksums        []struct {
+		Algorithm     string `json:"algorithm"`
+		ChecksumValue string `json:"checksumValue"`
+	} `json:"checksums"`
+	ExternalRefs []struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	} `json:"externalRefs"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+	RelationshipType   string `json:"relationshipType"`
+}
+
+// spdxLicense returns the license, or an empty string if the license is not
+// known, i.e. NOASSERTION or NONE
+func spdxLicense(l string) string {
+	switch l {
+	case "NOASSERTION", "NONE":
+		return ""
+	default:
+		return l
+	}
+}
+
+func parseSPDX(data []byte) (*Document, error) {
+	var spdx spdxDocument
+	if err := json.Unmarshal(data, &spdx); err != nil {
+		return nil, fmt.Errorf("unable to parse the SPDX document: %w", err)
+	}
+
+	if !strings.HasPrefix(spdx.SPDXVersion, "SPDX-2.") {
+		return nil, fmt.Errorf("unsupported SPDX version %q, expecting SPDX-2.x", spdx.SPDXVersion)
+	}
+
+	if spdx.SPDXID == "" {
+		return nil, errors.New("invalid SPDX document, missing SPDXID")
+	}
+
+	doc := Document{
+		Format:   FormatSPDX,
+		Version:  spdx.SPDXVersion,
+		Name:     spdx.Name,
+		Packages: make([]Package, 0, len(spdx.Packages)),
+	}
+
+	for i, p := range spdx.Packages {
+		if p.SPDXID == "" {
+			return nil, fmt.Errorf("invalid SPDX document, package %d is missing SPDXID", i)
+		}
+
+		pkg := Package{
+			ID:      p.SPDXID,
+			Name:    p.Name,
+			Version: p.VersionInfo,
+		}
+
+		for _, r := range p.ExternalRefs {
+			if r.ReferenceType == "purl" && pkg.Purl == "" {
+				pkg.Purl = r.ReferenceLocator
+			}
+		}
+
+		for _, l := range []string{spdxLicense(p.LicenseConcluded), spdxLicense(p.LicenseDeclared)} {
+			if l != "" && (len(pkg.Licenses) == 0 || pkg.Licenses[0] != l) {
+				pkg.Licenses = append(pkg.Licenses, l)
+			}
+		}
+
+		for _, c := range p.Checksums {
+			if pkg.Hashes == nil {
+				pkg.Hashes = make(map[string]string, len(p.Checksums))
+			}
+			pkg.Hashes[normalizeAlgorithm(c.Algorithm)] = c.ChecksumValue
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+	}
+
+	for _, r := range spdx.Relationships {
+		doc.Relationships = append(doc.Relationships, Relationship{
+			From: r.SPDXElementID,
+			To:   r.RelatedSPDXElement,
+			Type: r.RelationshipType,
+		})
+	}
+
+	return &doc, nil
+}
@@ -0,0 +1,229 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	validate_utils "github.com/enterprise-contract/ec-cli/internal/validate"
+)
+
+// prepared holds a pre-processed policy together with the evaluators created
+// for each of its source groups. Instances are shared between requests until
+// they expire.
+type prepared struct {
+	policy     policy.Policy
+	evaluators []evaluator.Evaluator
+	created    time.Time
+	// inflight tracks the requests currently using the evaluators, so that
+	// they are destroyed only after the last request has finished with them.
+	inflight sync.WaitGroup
+}
+
+func (p *prepared) destroy() {
+	p.inflight.Wait()
+	for _, e := range p.evaluators {
+		e.Destroy()
+	}
+}
+
+// preparer creates a prepared policy for the given options.
+type preparer func(context.Context, policy.Options) (*prepared, error)
+
+// policies is a cache of prepared policies keyed by the options used to create
+// them. Concurrent requests for the same options wait for a single preparation.
+type policies struct {
+	mu      sync.Mutex
+	entries map[policy.Options]*policyEntry
+	ttl     time.Duration
+	prepare preparer
+	now     func() time.Time
+}
+
+// policyEntry holds the outcome of preparing a policy. The prepared and err
+// fields are set once, before ready is closed, and must not be read until then.
+type policyEntry struct {
+	ready    chan struct{}
+	prepared *prepared
+	err      error
+}
+
+// result returns the prepared policy, or nil if the preparation has not
+// finished yet or has failed.
+func (e *policyEntry) result() *prepared {
+	select {
+	case <-e.ready:
+		return e.prepared
+	default:
+		return nil
+	}
+}
+
+func newPolicies(ttl time.Duration, prepare preparer) *policies {
+	return &policies{
+		entries: map[policy.Options]*policyEntry{},
+		ttl:     ttl,
+		prepare: prepare,
+		now:     time.Now,
+	}
+}
+
+// acquire returns the prepared policy for the given options, preparing it if
+// needed. The caller must invoke the returned release function once done with
+// the evaluators.
+func (c *policies) acquire(ctx context.Context, opts policy.Options) (*prepared, func(), error) {
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[opts]
+		if ok {
+			if p := entry.result(); p != nil && c.expired(p) {
+				log.Debugf("Prepared policy for %q expired, refreshing", opts.PolicyRef)
+				// Mutable policy references, e.g. git branches, are resolved
+				// through the download cache, forget those so that the refresh
+				// picks up any changes.
+				source.ClearDownloadCache()
+				go p.destroy()
+				ok = false
+			}
+		}
+		if !ok {
+			entry = &policyEntry{ready: make(chan struct{})}
+			c.entries[opts] = entry
+			c.evictExpired()
+			// The preparation is shared by all requests for the same options,
+			// so it must not be bound to the lifetime of this request.
+			go c.prepareEntry(context.WithoutCancel(ctx), opts, entry)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+
+		if entry.err != nil {
+			return nil, nil, entry.err
+		}
+
+		c.mu.Lock()
+		if c.entries[opts] != entry {
+			// The entry expired while we were waiting, try again
+			c.mu.Unlock()
+			continue
+		}
+		// Registering the request while holding the lock guarantees that an
+		// expired entry is not picked up after its destruction has started.
+		entry.prepared.inflight.Add(1)
+		c.mu.Unlock()
+
+		return entry.prepared, entry.prepared.inflight.Done, nil
+	}
+}
+
+// prepareEntry prepares the policy for the given entry and signals the
+// waiting requests once done.
+func (c *policies) prepareEntry(ctx context.Context, opts policy.Options, entry *policyEntry) {
+	prepared, err := c.prepare(ctx, opts)
+	if prepared != nil {
+		prepared.created = c.now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.prepared, entry.err = prepared, err
+	switch {
+	case c.entries[opts] != entry:
+		// The cache was closed while preparing, nothing can use the result
+		if prepared != nil {
+			go prepared.destroy()
+		}
+	case err != nil:
+		// Do not keep failures around, the next request will try again
+		delete(c.entries, opts)
+	}
+	close(entry.ready)
+}
+
+// expired reports whether the prepared policy has outlived the cache TTL.
+func (c *policies) expired(p *prepared) bool {
+	return c.ttl > 0 && c.now().Sub(p.created) > c.ttl
+}
+
+// evictExpired destroys the expired prepared policies, so that policies that
+// are not requested anymore do not accumulate. The caller must hold the lock.
+func (c *policies) evictExpired() {
+	if c.ttl <= 0 {
+		return
+	}
+
+	for opts, entry := range c.entries {
+		if p := entry.result(); p != nil && c.expired(p) {
+			delete(c.entries, opts)
+			go p.destroy()
+		}
+	}
+}
+
+// close destroys all the prepared policies.
+func (c *policies) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for opts, entry := range c.entries {
+		if p := entry.result(); p != nil {
+			p.destroy()
+		}
+		delete(c.entries, opts)
+	}
+}
+
+// preparePolicy fetches the policy configuration, pre-processes the policy and
+// creates an evaluator for each of its source groups.
+func preparePolicy(newEvaluator evaluatorFactory) preparer {
+	return func(ctx context.Context, opts policy.Options) (*prepared, error) {
+		policyConfiguration, err := validate_utils.GetPolicyConfig(ctx, opts.PolicyRef)
+		if err != nil {
+			return nil, err
+		}
+		opts.PolicyRef = policyConfiguration
+
+		p, _, err := policy.PreProcessPolicy(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		prep := prepared{policy: p}
+		for _, sourceGroup := range p.Spec().Sources {
+			log.Debugf("Preparing policy source group '%s'", sourceGroup.Name)
+			e, err := newEvaluator(ctx, source.PolicySourcesFrom(sourceGroup), p, sourceGroup)
+			if err != nil {
+				prep.destroy()
+				return nil, err
+			}
+			prep.evaluators = append(prep.evaluators, e)
+		}
+
+		return &prep, nil
+	}
+}
@@ -0,0 +1,404 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package server implements a long running HTTP service exposing the image
+// validation. Policies are prepared once and shared between requests, which
+// avoids paying for the policy download and the evaluator setup on every
+// validation.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/image"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+const (
+	ValidateImagePath = "/v1/validate/image"
+	HealthPath        = "/healthz"
+	ReadyPath         = "/readyz"
+)
+
+// maxRequestSize limits the size of the request body to 10MiB
+const maxRequestSize = 10 << 20
+
+type imageValidationFunc func(context.Context, app.SnapshotComponent, *app.SnapshotSpec, policy.Policy, []evaluator.Evaluator, bool) (*output.Output, error)
+
+type evaluatorFactory func(context.Context, []source.PolicySource, evaluator.ConfigProvider, ecc.Source) (evaluator.Evaluator, error)
+
+// Options configure the Server.
+type Options struct {
+	// Address to listen on, e.g. ":8080"
+	Address string
+	// Policy is the policy configuration used when the request doesn't specify
+	// one. When set, the policy is prepared before the server reports ready.
+	Policy string
+	// PolicyTTL is the duration a prepared policy is reused before it's
+	// prepared again. Zero means prepared policies never expire.
+	PolicyTTL time.Duration
+	// RequestTimeout limits the duration of a single validation request.
+	RequestTimeout time.Duration
+	// Workers is the number of components validated concurrently per request.
+	Workers int
+}
+
+// ValidateImageRequest is the payload accepted by the image validation
+// endpoint. The policy related attributes have the same meaning as the flags
+// of the same name on the `ec validate image` command.
+type ValidateImageRequest struct {
+	Snapshot                    app.SnapshotSpec `json:"snapshot"`
+	Policy                      string           `json:"policy,omitempty"`
+	PublicKey                   string           `json:"publicKey,omitempty"`
+	RekorURL                    string           `json:"rekorURL,omitempty"`
+	IgnoreRekor                 bool             `json:"ignoreRekor,omitempty"`
+	CertificateIdentity         string           `json:"certificateIdentity,omitempty"`
+	CertificateIdentityRegExp   string           `json:"certificateIdentityRegExp,omitempty"`
+	CertificateOIDCIssuer       string           `json:"certificateOIDCIssuer,omitempty"`
+	CertificateOIDCIssuerRegExp string           `json:"certificateOIDCIssuerRegExp,omitempty"`
+	EffectiveTime               string           `json:"effectiveTime,omitempty"`
+	Info                        bool             `json:"info,omitempty"`
+	ShowSuccesses               bool             `json:"showSuccesses,omitempty"`
+}
+
+func (r ValidateImageRequest) policyOptions(defaultPolicy string) policy.Options {
+	opts := policy.Options{
+		EffectiveTime: r.EffectiveTime,
+		Identity: cosign.Identity{
+			Issuer:        r.CertificateOIDCIssuer,
+			IssuerRegExp:  r.CertificateOIDCIssuerRegExp,
+			Subject:       r.CertificateIdentity,
+			SubjectRegExp: r.CertificateIdentityRegExp,
+		},
+		IgnoreRekor: r.IgnoreRekor,
+		PolicyRef:   r.Policy,
+		PublicKey:   r.PublicKey,
+		RekorURL:    r.RekorURL,
+	}
+
+	if opts.PolicyRef == "" {
+		opts.PolicyRef = defaultPolicy
+	}
+
+	if opts.EffectiveTime == "" {
+		opts.EffectiveTime = policy.Now
+	}
+
+	return opts
+}
+
+// errorResponse is returned as JSON body on failed requests.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the image validation over HTTP.
+type Server struct {
+	opts     Options
+	validate imageValidationFunc
+	policies *policies
+	ready    atomic.Bool
+	// readyErr holds the error of the last readiness attempt
+	readyErr atomic.Value
+}
+
+// New creates a Server that validates images with the given function and
+// evaluators created by the given factory.
+func New(opts Options, validate imageValidationFunc, newEvaluator evaluatorFactory) *Server {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	return &Server{
+		opts:     opts,
+		validate: validate,
+		policies: newPolicies(opts.PolicyTTL, preparePolicy(newEvaluator)),
+	}
+}
+
+// Handler returns the http.Handler serving all the endpoints.
+func (s *Server) Handler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+HealthPath, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET "+ReadyPath, s.handleReady)
+	mux.HandleFunc("POST "+ValidateImagePath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleValidateImage(ctx, w, r)
+	})
+
+	return mux
+}
+
+// Run serves the endpoints until the given context is done. The context is
+// also the base context of all validations, so it should carry any values,
+// e.g. the filesystem, needed by the validation.
+func (s *Server) Run(ctx context.Context) error {
+	// Share the downloaded policies between all requests
+	policyCache, err := cache.NewPolicyCache(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = cache.WithPolicyCache(ctx, policyCache)
+
+	srv := &http.Server{
+		Addr:              s.opts.Address,
+		Handler:           s.Handler(ctx),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go s.warmUp(ctx)
+
+	defer s.policies.close()
+
+	return serve(ctx, srv, srv.ListenAndServe)
+}
+
+// serve runs the listen function until it fails or the given context is done,
+// in which case the HTTP server is shut down gracefully.
+func serve(ctx context.Context, srv *http.Server, listen func() error) error {
+	errs := make(chan error, 1)
+	go func() {
+		log.Infof("Listening on %s", srv.Addr)
+		errs <- listen()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		log.Info("Shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
+
+// warmUp prepares the default policy, if any, and marks the server as ready.
+func (s *Server) warmUp(ctx context.Context) {
+	if s.opts.Policy == "" {
+		s.ready.Store(true)
+		return
+	}
+
+	for {
+		opts := ValidateImageRequest{}.policyOptions(s.opts.Policy)
+		_, release, err := s.policies.acquire(ctx, opts)
+		if err == nil {
+			release()
+			log.Info("Default policy prepared")
+			s.ready.Store(true)
+			return
+		}
+
+		log.Warnf("Unable to prepare the default policy: %v", err)
+		s.readyErr.Store(err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if s.ready.Load() {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+		return
+	}
+
+	msg := "preparing the default policy"
+	if e, ok := s.readyErr.Load().(string); ok {
+		msg = e
+	}
+	writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: msg})
+}
+
+func (s *Server) handleValidateImage(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req ValidateImageRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	if len(req.Snapshot.Components) == 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request: no components provided in the snapshot"})
+		return
+	}
+
+	ctx, cancel := requestContext(ctx, r, s.opts.RequestTimeout)
+	defer cancel()
+
+	report, err := s.Validate(ctx, req)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// Validate validates the images of the snapshot from the request against the
+// policy from the request, or the default policy, and returns the report.
+func (s *Server) Validate(ctx context.Context, req ValidateImageRequest) (applicationsnapshot.Report, error) {
+	prep, release, err := s.policies.acquire(ctx, req.policyOptions(s.opts.Policy))
+	if err != nil {
+		return applicationsnapshot.Report{}, fmt.Errorf("unable to prepare policy: %w", err)
+	}
+	defer release()
+
+	return s.validateSnapshot(ctx, req, prep)
+}
+
+// Close destroys all the prepared policies, it is meant to be used when the
+// Server is used without running it, e.g. via Validate.
+func (s *Server) Close() {
+	s.policies.close()
+}
+
+// requestContext returns the context in which the request is handled. The
+// request's context is canceled once the client disconnects, the validation
+// however relies on values present on the server's context. The returned
+// context is canceled in either case, or once the timeout, if any, expires.
+func requestContext(ctx context.Context, r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(r.Context(), cancel)
+	cancelAll := func() {
+		stop()
+		cancel()
+	}
+
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		return ctx, func() {
+			cancelTimeout()
+			cancelAll()
+		}
+	}
+
+	return ctx, cancelAll
+}
+
+// validateSnapshot validates all components of the snapshot from the request
+// and returns the report, the same one `ec validate image` produces.
+func (s *Server) validateSnapshot(ctx context.Context, req ValidateImageRequest, prep *prepared) (applicationsnapshot.Report, error) {
+	type result struct {
+		err         error
+		component   applicationsnapshot.Component
+		data        []evaluator.Data
+		policyInput []byte
+	}
+
+	// the policy sources are evaluated concurrently within the same bounds
+	ctx = image.WithEvaluationPool(ctx, s.opts.Workers)
+	// the registry lookups are shared by the components of the request
+	ctx = oci.WithMemo(ctx)
+
+	components := req.Snapshot.Components
+	jobs := make(chan app.SnapshotComponent, len(components))
+	results := make(chan result, len(components))
+
+	for i := 0; i < s.opts.Workers; i++ {
+		go func() {
+			for comp := range jobs {
+				out, err := s.validate(ctx, comp, &req.Snapshot, prep.policy, prep.evaluators, req.Info)
+				res := result{
+					err: err,
+					component: applicationsnapshot.Component{
+						SnapshotComponent: comp,
+					},
+				}
+				if err == nil {
+					res.component.Violations = out.Violations()
+					res.component.Warnings = out.Warnings()
+					successes := out.Successes()
+					res.component.SuccessCount = len(successes)
+					if req.ShowSuccesses {
+						res.component.Successes = successes
+					}
+					res.component.Signatures = out.Signatures
+					res.component.Attestations = out.Attestations
+					res.component.ContainerImage = out.ImageURL
+					res.data = out.Data
+					res.policyInput = out.PolicyInput
+				}
+				res.component.Success = err == nil && len(res.component.Violations) == 0
+				results <- res
+			}
+		}()
+	}
+
+	for _, c := range components {
+		jobs <- c
+	}
+	close(jobs)
+
+	var validated []applicationsnapshot.Component
+	var manyData [][]evaluator.Data
+	var manyPolicyInput [][]byte
+	var allErrors error
+	for range components {
+		r := <-results
+		if r.err != nil {
+			allErrors = errors.Join(allErrors, fmt.Errorf("error validating image %s of component %s: %w", r.component.ContainerImage, r.component.Name, r.err))
+			continue
+		}
+		validated = append(validated, r.component)
+		manyData = append(manyData, r.data)
+		manyPolicyInput = append(manyPolicyInput, r.policyInput)
+	}
+
+	if allErrors != nil {
+		return applicationsnapshot.Report{}, allErrors
+	}
+
+	// Ensure some consistency in output, same as `ec validate image`
+	sort.Slice(validated, func(i, j int) bool {
+		return validated[i].ContainerImage > validated[j].ContainerImage
+	})
+
+	return applicationsnapshot.NewReport("", validated, prep.policy, manyData, manyPolicyInput, req.ShowSuccesses)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Debugf("Unable to write response: %v", err)
+	}
+}
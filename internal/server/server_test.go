@@ -0,0 +1,295 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/policy/source"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+)
+
+func happyValidator(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+	return &output.Output{
+		ImageSignatureCheck:       output.VerificationStatus{Passed: true},
+		ImageAccessibleCheck:      output.VerificationStatus{Passed: true},
+		AttestationSignatureCheck: output.VerificationStatus{Passed: true},
+		AttestationSyntaxCheck:    output.VerificationStatus{Passed: true},
+		ImageURL:                  component.ContainerImage,
+	}, nil
+}
+
+func noEvaluator(context.Context, []source.PolicySource, evaluator.ConfigProvider, ecc.Source) (evaluator.Evaluator, error) {
+	return nil, errors.New("unexpected")
+}
+
+func TestHealthAndReadiness(t *testing.T) {
+	s := New(Options{}, happyValidator, noEvaluator)
+	h := s.Handler(context.Background())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"error": "preparing the default policy"}`, rec.Body.String())
+
+	s.warmUp(context.Background())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestValidateImage(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+
+	effectiveTime := time.Now().UTC().Format(time.RFC3339)
+
+	cases := []struct {
+		name     string
+		body     string
+		status   int
+		expected string
+	}{
+		{
+			name:     "invalid JSON",
+			body:     `{`,
+			status:   http.StatusBadRequest,
+			expected: `{"error": "invalid request: unexpected EOF"}`,
+		},
+		{
+			name:     "no components",
+			body:     `{"snapshot": {}}`,
+			status:   http.StatusBadRequest,
+			expected: `{"error": "invalid request: no components provided in the snapshot"}`,
+		},
+		{
+			name:   "invalid policy",
+			body:   `{"snapshot": {"components": [{"containerImage": "registry/image:tag"}]}, "policy": "{\"publicKey\": 1}"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "success",
+			body: fmt.Sprintf(`{
+				"snapshot": {"components": [{"containerImage": "registry/image:tag"}]},
+				"policy": %q,
+				"effectiveTime": %q
+			}`, fmt.Sprintf(`{"publicKey": %s}`, utils.TestPublicKeyJSON), effectiveTime),
+			status: http.StatusOK,
+			expected: fmt.Sprintf(`{
+				"success": true,
+				"ec-version": "development",
+				"effective-time": %q,
+				"key": %s,
+				"components": [
+				  {
+					"name": "",
+					"containerImage": "registry/image:tag",
+					"source": {},
+					"success": true
+				  }
+				],
+				"policy": {
+					"publicKey": %s
+				}
+			}`, effectiveTime, utils.TestPublicKeyJSON, utils.TestPublicKeyJSON),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := New(Options{Workers: 2}, happyValidator, noEvaluator)
+			h := s.Handler(ctx)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ValidateImagePath, strings.NewReader(c.body)))
+
+			assert.Equal(t, c.status, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			if c.expected != "" {
+				assert.JSONEq(t, c.expected, rec.Body.String())
+			}
+		})
+	}
+}
+
+type fakeEvaluator struct {
+	destroyed atomic.Bool
+}
+
+func (*fakeEvaluator) Evaluate(context.Context, evaluator.EvaluationTarget) ([]evaluator.Outcome, evaluator.Data, error) {
+	return nil, nil, nil
+}
+
+func (f *fakeEvaluator) Destroy() {
+	f.destroyed.Store(true)
+}
+
+func (*fakeEvaluator) CapabilitiesPath() string {
+	return ""
+}
+
+func TestPoliciesCache(t *testing.T) {
+	ctx := context.Background()
+
+	count := 0
+	var last *fakeEvaluator
+	c := newPolicies(time.Minute, func(_ context.Context, opts policy.Options) (*prepared, error) {
+		if opts.PolicyRef == "bad" {
+			return nil, errors.New("bad policy")
+		}
+		count++
+		last = &fakeEvaluator{}
+		return &prepared{evaluators: []evaluator.Evaluator{last}}, nil
+	})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	opts := policy.Options{PolicyRef: "good"}
+
+	p1, release1, err := c.acquire(ctx, opts)
+	require.NoError(t, err)
+	release1()
+
+	p2, release2, err := c.acquire(ctx, opts)
+	require.NoError(t, err)
+	release2()
+
+	assert.Same(t, p1, p2)
+	assert.Equal(t, 1, count)
+
+	_, _, err = c.acquire(ctx, policy.Options{PolicyRef: "bad"})
+	assert.EqualError(t, err, "bad policy")
+	assert.NotContains(t, c.entries, policy.Options{PolicyRef: "bad"})
+
+	// expire the prepared policy
+	first := last
+	now = now.Add(2 * time.Minute)
+	p3, release3, err := c.acquire(ctx, opts)
+	require.NoError(t, err)
+	release3()
+	assert.NotSame(t, p1, p3)
+	assert.Equal(t, 2, count)
+	assert.Eventually(t, func() bool { return p1.evaluators[0].(*fakeEvaluator).destroyed.Load() }, time.Second, 10*time.Millisecond)
+	assert.Same(t, first, p1.evaluators[0])
+
+	// expired policies are evicted when preparing other policies
+	now = now.Add(2 * time.Minute)
+	p4, release4, err := c.acquire(ctx, policy.Options{PolicyRef: "other"})
+	require.NoError(t, err)
+	release4()
+	assert.NotContains(t, c.entries, opts)
+	assert.Eventually(t, func() bool { return p3.evaluators[0].(*fakeEvaluator).destroyed.Load() }, time.Second, 10*time.Millisecond)
+	assert.Same(t, last, p4.evaluators[0])
+
+	c.close()
+	assert.True(t, last.destroyed.Load())
+	assert.Empty(t, c.entries)
+}
+
+func TestPoliciesCacheConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	c := newPolicies(time.Minute, func(_ context.Context, _ policy.Options) (*prepared, error) {
+		return &prepared{evaluators: []evaluator.Evaluator{&fakeEvaluator{}}}, nil
+	})
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	c.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// every few requests move the clock past the TTL so that
+			// acquiring races with expiring and evicting entries
+			if i%5 == 0 {
+				now.Add(int64(2 * time.Minute))
+			}
+			p, release, err := c.acquire(ctx, policy.Options{PolicyRef: fmt.Sprintf("policy-%d", i%3)})
+			if assert.NoError(t, err) {
+				assert.False(t, p.evaluators[0].(*fakeEvaluator).destroyed.Load())
+				release()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	c.close()
+	assert.Empty(t, c.entries)
+}
+
+func TestPoliciesCacheDetachedFromRequest(t *testing.T) {
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+	c := newPolicies(time.Minute, func(ctx context.Context, _ policy.Options) (*prepared, error) {
+		close(started)
+		<-proceed
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &prepared{evaluators: []evaluator.Evaluator{&fakeEvaluator{}}}, nil
+	})
+
+	opts := policy.Options{PolicyRef: "good"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, _, err := c.acquire(ctx, opts)
+		errs <- err
+	}()
+
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	close(proceed)
+
+	// the preparation started by the cancelled request is not affected by
+	// the cancellation and is reused by subsequent requests
+	p, release, err := c.acquire(context.Background(), opts)
+	require.NoError(t, err)
+	release()
+	assert.NotNil(t, p)
+
+	c.close()
+}
@@ -0,0 +1,485 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+	"github.com/enterprise-contract/ec-cli/internal/policy/cache"
+	"github.com/enterprise-contract/ec-cli/internal/report/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+const (
+	AdmissionPath = "/v1/admission"
+
+	// DefaultPolicyLabel is the namespace label holding the name of the
+	// EnterpriseContractPolicy the workloads of the namespace are validated
+	// against.
+	DefaultPolicyLabel = "enterprisecontract.dev/policy"
+)
+
+// WebhookOptions configure the Webhook.
+type WebhookOptions struct {
+	// Options.Policy is the policy used for namespaces without the policy
+	// label. Workloads in such namespaces are denied when it is not set,
+	// unless FailOpen is set.
+	Options
+	// CertFile and KeyFile are the TLS certificate and key to serve with. The
+	// webhook is served over plain HTTP when they're not set, e.g. behind a
+	// proxy terminating TLS.
+	CertFile string
+	KeyFile  string
+	// PolicyLabel is the namespace label holding the name of the
+	// EnterpriseContractPolicy, from the same namespace, to validate the
+	// workloads against.
+	PolicyLabel string
+	// DryRun admits all workloads, the violations are reported as warnings.
+	DryRun bool
+	// FailOpen admits, without validation, the workloads in namespaces with
+	// neither the policy label nor a default policy.
+	FailOpen bool
+	// VerdictTTL is the duration the verdict on an image digest is reused.
+	// Zero disables caching of verdicts.
+	VerdictTTL time.Duration
+}
+
+// Webhook is a Kubernetes validating admission webhook denying workloads with
+// images that do not comply with the policy.
+type Webhook struct {
+	opts     WebhookOptions
+	server   *Server
+	verdicts *verdicts
+	client   kubernetes.Client
+}
+
+// NewWebhook creates a Webhook that validates images with the given function
+// and evaluators created by the given factory. The Kubernetes client used to
+// look up the policy labels of namespaces is created from the given context.
+func NewWebhook(ctx context.Context, opts WebhookOptions, validate imageValidationFunc, newEvaluator evaluatorFactory) (*Webhook, error) {
+	if opts.PolicyLabel == "" {
+		opts.PolicyLabel = DefaultPolicyLabel
+	}
+
+	client, err := kubernetes.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the Kubernetes client: %w", err)
+	}
+
+	return &Webhook{
+		opts:     opts,
+		server:   New(opts.Options, validate, newEvaluator),
+		verdicts: newVerdicts(opts.VerdictTTL),
+		client:   client,
+	}, nil
+}
+
+// Handler returns the http.Handler serving all the endpoints.
+func (w *Webhook) Handler(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+HealthPath, func(rw http.ResponseWriter, _ *http.Request) {
+		writeJSON(rw, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET "+ReadyPath, w.server.handleReady)
+	mux.HandleFunc("POST "+AdmissionPath, func(rw http.ResponseWriter, r *http.Request) {
+		w.handleAdmission(ctx, rw, r)
+	})
+
+	return mux
+}
+
+// Run serves the endpoints until the given context is done, see Server.Run.
+func (w *Webhook) Run(ctx context.Context) error {
+	policyCache, err := cache.NewPolicyCache(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = cache.WithPolicyCache(ctx, policyCache)
+
+	srv := &http.Server{
+		Addr:              w.opts.Address,
+		Handler:           w.Handler(ctx),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go w.server.warmUp(ctx)
+
+	defer w.server.policies.close()
+
+	listen := srv.ListenAndServe
+	if w.opts.CertFile != "" || w.opts.KeyFile != "" {
+		listen = func() error {
+			return srv.ListenAndServeTLS(w.opts.CertFile, w.opts.KeyFile)
+		}
+	}
+
+	return serve(ctx, srv, listen)
+}
+
+func (w *Webhook) handleAdmission(ctx context.Context, rw http.ResponseWriter, r *http.Request) {
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxRequestSize)).Decode(&review); err != nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	if review.Request == nil {
+		writeJSON(rw, http.StatusBadRequest, errorResponse{Error: "invalid request: no admission request provided"})
+		return
+	}
+
+	ctx, cancel := requestContext(ctx, r, w.opts.RequestTimeout)
+	defer cancel()
+
+	response := w.admit(ctx, review.Request)
+	response.UID = review.Request.UID
+
+	writeJSON(rw, http.StatusOK, admissionv1.AdmissionReview{
+		TypeMeta: review.TypeMeta,
+		Response: response,
+	})
+}
+
+// admit decides on the admission of the workload from the request. Workloads
+// are denied if any of their images violate the policy, or if the images could
+// not be validated.
+func (w *Webhook) admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	spec, err := podSpec(req.Kind.Kind, req.Object.Raw)
+	if err != nil {
+		return w.deny(http.StatusBadRequest, fmt.Sprintf("unable to decode %s %s: %v", req.Kind.Kind, req.Name, err))
+	}
+
+	components := containerImages(spec)
+	if len(components) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	policyRef, err := w.policyRef(ctx, req.Namespace)
+	if err != nil {
+		return w.deny(http.StatusInternalServerError, fmt.Sprintf("unable to determine the policy of namespace %s: %v", req.Namespace, err))
+	}
+
+	if policyRef == "" {
+		if w.opts.FailOpen {
+			log.Debugf("No policy configured for namespace %s, admitting %s %s", req.Namespace, req.Kind.Kind, req.Name)
+			return &admissionv1.AdmissionResponse{Allowed: true}
+		}
+		return w.deny(http.StatusForbidden, fmt.Sprintf("no policy configured for namespace %s", req.Namespace))
+	}
+
+	violations, err := w.validate(ctx, components, policyRef)
+	if err != nil {
+		return w.deny(http.StatusInternalServerError, err.Error())
+	}
+
+	if len(violations) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	log.Infof("%s %s/%s violates the policy %s", req.Kind.Kind, req.Namespace, req.Name, policyRef)
+
+	return w.deny(http.StatusForbidden, violations...)
+}
+
+// deny returns the response denying the admission with the given messages.
+// In dry-run mode the admission is allowed and the messages are returned as
+// warnings instead.
+func (w *Webhook) deny(code int32, messages ...string) *admissionv1.AdmissionResponse {
+	if w.opts.DryRun {
+		return &admissionv1.AdmissionResponse{
+			Allowed:  true,
+			Warnings: messages,
+		}
+	}
+
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Message: strings.Join(messages, "\n"),
+		},
+	}
+}
+
+// policyRef returns the reference to the policy configured, via the policy
+// label, for the namespace. The default policy is returned if the namespace
+// is not labeled.
+func (w *Webhook) policyRef(ctx context.Context, namespace string) (string, error) {
+	if namespace == "" {
+		return w.opts.Policy, nil
+	}
+
+	labels, err := w.client.FetchNamespaceLabels(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	if name := labels[w.opts.PolicyLabel]; name != "" {
+		return fmt.Sprintf("%s/%s", namespace, name), nil
+	}
+
+	return w.opts.Policy, nil
+}
+
+// validate validates the images of the components against the policy and
+// returns the violations found. Images with a cached verdict are not
+// validated again.
+func (w *Webhook) validate(ctx context.Context, components []app.SnapshotComponent, policyRef string) ([]string, error) {
+	snapshot, err := json.Marshal(app.SnapshotSpec{Components: components})
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := applicationsnapshot.DetermineInputSpec(ctx, applicationsnapshot.Input{Images: string(snapshot)})
+	if err != nil {
+		return nil, err
+	}
+
+	client := oci.NewClient(ctx)
+
+	var violations []string
+	var pending []app.SnapshotComponent
+	for _, c := range spec.Components {
+		if digest := resolveDigest(client, c.ContainerImage); digest != "" {
+			if v, ok := w.verdicts.get(policyRef, digest); ok {
+				violations = append(violations, v...)
+				continue
+			}
+		}
+		pending = append(pending, c)
+	}
+
+	if len(pending) > 0 {
+		prep, release, err := w.server.policies.acquire(ctx, ValidateImageRequest{}.policyOptions(policyRef))
+		if err != nil {
+			return nil, fmt.Errorf("unable to prepare policy %s: %w", policyRef, err)
+		}
+		defer release()
+
+		report, err := w.server.validateSnapshot(ctx, ValidateImageRequest{Snapshot: app.SnapshotSpec{Components: pending}}, prep)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range report.Components {
+			v := condensedViolations(c)
+			// the image reference is resolved to its digest when the image
+			// is accessible, images that are not are not cached
+			if digest, err := name.NewDigest(c.ContainerImage); err == nil {
+				w.verdicts.put(policyRef, digest.String(), v)
+			}
+			violations = append(violations, v...)
+		}
+	}
+
+	sort.Strings(violations)
+
+	return violations, nil
+}
+
+// resolveDigest returns the image reference pinned to its digest, or an empty
+// string if the digest can not be resolved.
+func resolveDigest(client oci.Client, image string) string {
+	if digest, err := name.NewDigest(image); err == nil {
+		return digest.String()
+	}
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return ""
+	}
+
+	digest, err := client.ResolveDigest(ref)
+	if err != nil {
+		log.Debugf("Unable to resolve the digest of image %s: %v", image, err)
+		return ""
+	}
+
+	return fmt.Sprintf("%s@%s", ref.Context().Name(), digest)
+}
+
+// condensedViolations returns the violations of the component, repetitive
+// violations of the same rule are condensed into one.
+func condensedViolations(c applicationsnapshot.Component) []string {
+	var violations []string
+	for code, messages := range utils.CondensedMsg(c.Violations) {
+		for _, msg := range messages {
+			violations = append(violations, fmt.Sprintf("%s: [%s] %s", c.ContainerImage, code, msg))
+		}
+	}
+
+	for _, v := range c.Violations {
+		if _, ok := v.Metadata["code"]; !ok {
+			violations = append(violations, fmt.Sprintf("%s: %s", c.ContainerImage, v.Message))
+		}
+	}
+
+	sort.Strings(violations)
+
+	return violations
+}
+
+// podSpec returns the spec of the pods created by the workload of the given
+// kind, or nil if the kind is not a known workload.
+func podSpec(kind string, raw []byte) (*corev1.PodSpec, error) {
+	switch kind {
+	case "Pod":
+		return decode(raw, func(o *corev1.Pod) *corev1.PodSpec { return &o.Spec })
+	case "ReplicationController":
+		return decode(raw, func(o *corev1.ReplicationController) *corev1.PodSpec {
+			if o.Spec.Template == nil {
+				return nil
+			}
+			return &o.Spec.Template.Spec
+		})
+	case "Deployment":
+		return decode(raw, func(o *appsv1.Deployment) *corev1.PodSpec { return &o.Spec.Template.Spec })
+	case "ReplicaSet":
+		return decode(raw, func(o *appsv1.ReplicaSet) *corev1.PodSpec { return &o.Spec.Template.Spec })
+	case "StatefulSet":
+		return decode(raw, func(o *appsv1.StatefulSet) *corev1.PodSpec { return &o.Spec.Template.Spec })
+	case "DaemonSet":
+		return decode(raw, func(o *appsv1.DaemonSet) *corev1.PodSpec { return &o.Spec.Template.Spec })
+	case "Job":
+		return decode(raw, func(o *batchv1.Job) *corev1.PodSpec { return &o.Spec.Template.Spec })
+	case "CronJob":
+		return decode(raw, func(o *batchv1.CronJob) *corev1.PodSpec { return &o.Spec.JobTemplate.Spec.Template.Spec })
+	}
+
+	return nil, nil
+}
+
+func decode[T any](raw []byte, spec func(*T) *corev1.PodSpec) (*corev1.PodSpec, error) {
+	var o T
+	if err := json.Unmarshal(raw, &o); err != nil {
+		return nil, err
+	}
+
+	return spec(&o), nil
+}
+
+// containerImages returns a component for each distinct image of the
+// containers in the pod spec, named after the first container using it.
+func containerImages(spec *corev1.PodSpec) []app.SnapshotComponent {
+	if spec == nil {
+		return nil
+	}
+
+	var components []app.SnapshotComponent
+	seen := map[string]bool{}
+	add := func(name, image string) {
+		if image == "" || seen[image] {
+			return
+		}
+		seen[image] = true
+		components = append(components, app.SnapshotComponent{Name: name, ContainerImage: image})
+	}
+
+	for _, c := range spec.InitContainers {
+		add(c.Name, c.Image)
+	}
+	for _, c := range spec.Containers {
+		add(c.Name, c.Image)
+	}
+	for _, c := range spec.EphemeralContainers {
+		add(c.Name, c.Image)
+	}
+
+	return components
+}
+
+// verdicts caches the violations found in images, by their digest, so that
+// workloads with the same images, e.g. the pods of a Deployment, are not
+// validated again against the same policy.
+type verdicts struct {
+	mu      sync.Mutex
+	entries map[verdictKey]verdict
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+type verdictKey struct {
+	policy string
+	digest string
+}
+
+type verdict struct {
+	violations []string
+	created    time.Time
+}
+
+func newVerdicts(ttl time.Duration) *verdicts {
+	return &verdicts{
+		entries: map[verdictKey]verdict{},
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+func (v *verdicts) get(policy, digest string) ([]string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	e, ok := v.entries[verdictKey{policy, digest}]
+	if !ok || v.now().Sub(e.created) > v.ttl {
+		return nil, false
+	}
+
+	return e.violations, true
+}
+
+func (v *verdicts) put(policy, digest string, violations []string) {
+	if v.ttl <= 0 {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	// drop the expired verdicts so that the cache doesn't grow unbounded
+	for k, e := range v.entries {
+		if now.Sub(e.created) > v.ttl {
+			delete(v.entries, k)
+		}
+	}
+
+	v.entries[verdictKey{policy, digest}] = verdict{violations: violations, created: now}
+}
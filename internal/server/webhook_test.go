@@ -0,0 +1,351 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ecc "github.com/enterprise-contract/enterprise-contract-controller/api/v1alpha1"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	app "github.com/konflux-ci/application-api/api/v1alpha1"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/enterprise-contract/ec-cli/internal/evaluator"
+	"github.com/enterprise-contract/ec-cli/internal/kubernetes"
+	"github.com/enterprise-contract/ec-cli/internal/output"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const (
+	goodImage = "registry.io/good@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+	badImage  = "registry.io/bad@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+)
+
+func TestPodSpec(t *testing.T) {
+	template := `{"spec": {"template": {"spec": {"containers": [{"name": "c", "image": "img"}]}}}}`
+
+	cases := []struct {
+		kind   string
+		object string
+	}{
+		{kind: "Pod", object: `{"spec": {"containers": [{"name": "c", "image": "img"}]}}`},
+		{kind: "ReplicationController", object: template},
+		{kind: "Deployment", object: template},
+		{kind: "ReplicaSet", object: template},
+		{kind: "StatefulSet", object: template},
+		{kind: "DaemonSet", object: template},
+		{kind: "Job", object: template},
+		{kind: "CronJob", object: `{"spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"name": "c", "image": "img"}]}}}}}}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.kind, func(t *testing.T) {
+			spec, err := podSpec(c.kind, []byte(c.object))
+			require.NoError(t, err)
+			assert.Equal(t, []app.SnapshotComponent{{Name: "c", ContainerImage: "img"}}, containerImages(spec))
+		})
+	}
+
+	spec, err := podSpec("ConfigMap", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Nil(t, spec)
+
+	_, err = podSpec("Pod", []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestContainerImages(t *testing.T) {
+	spec, err := podSpec("Pod", []byte(`{"spec": {
+		"initContainers": [{"name": "init", "image": "a"}],
+		"containers": [{"name": "one", "image": "b"}, {"name": "two", "image": "a"}],
+		"ephemeralContainers": [{"name": "debug", "image": "c"}]
+	}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []app.SnapshotComponent{
+		{Name: "init", ContainerImage: "a"},
+		{Name: "one", ContainerImage: "b"},
+		{Name: "debug", ContainerImage: "c"},
+	}, containerImages(spec))
+}
+
+func TestAdmission(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	client := fake.FakeClient{}
+	client.On("Head", mock.Anything).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
+	ctx = oci.WithClient(ctx, &client)
+
+	pod := func(images ...string) string {
+		containers := []string{}
+		for _, i := range images {
+			containers = append(containers, `{"name": "c", "image": "`+i+`"}`)
+		}
+		return `{"spec": {"containers": [` + strings.Join(containers, ",") + `]}}`
+	}
+
+	cases := []struct {
+		name        string
+		operation   admissionv1.Operation
+		kind        string
+		object      string
+		dryRun      bool
+		unlabeled   bool
+		failOpen    bool
+		allowed     bool
+		message     string
+		warnings    []string
+		validations int32
+	}{
+		{
+			name:        "compliant",
+			operation:   admissionv1.Create,
+			kind:        "Pod",
+			object:      pod(goodImage),
+			allowed:     true,
+			validations: 1,
+		},
+		{
+			name:        "violation",
+			operation:   admissionv1.Update,
+			kind:        "Deployment",
+			object:      `{"spec": {"template": ` + pod(goodImage, badImage) + `}}`,
+			message:     badImage + ": [test.bad] Image is bad",
+			validations: 2,
+		},
+		{
+			name:        "dry-run",
+			operation:   admissionv1.Create,
+			kind:        "Pod",
+			object:      pod(badImage),
+			dryRun:      true,
+			allowed:     true,
+			warnings:    []string{badImage + ": [test.bad] Image is bad"},
+			validations: 1,
+		},
+		{
+			name:      "delete",
+			operation: admissionv1.Delete,
+			kind:      "Pod",
+			object:    pod(badImage),
+			allowed:   true,
+		},
+		{
+			name:      "not a workload",
+			operation: admissionv1.Create,
+			kind:      "ConfigMap",
+			object:    `{"data": {}}`,
+			allowed:   true,
+		},
+		{
+			name:      "invalid object",
+			operation: admissionv1.Create,
+			kind:      "Pod",
+			object:    `"pod"`,
+			message:   "unable to decode Pod name: json: cannot unmarshal string into Go value of type v1.Pod",
+		},
+		{
+			name:      "no policy",
+			operation: admissionv1.Create,
+			kind:      "Pod",
+			object:    pod(badImage),
+			unlabeled: true,
+			message:   "no policy configured for namespace namespace",
+		},
+		{
+			name:      "no policy dry-run",
+			operation: admissionv1.Create,
+			kind:      "Pod",
+			object:    pod(badImage),
+			unlabeled: true,
+			dryRun:    true,
+			allowed:   true,
+			warnings:  []string{"no policy configured for namespace namespace"},
+		},
+		{
+			name:      "no policy fail-open",
+			operation: admissionv1.Create,
+			kind:      "Pod",
+			object:    pod(badImage),
+			unlabeled: true,
+			failOpen:  true,
+			allowed:   true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			validations := atomic.Int32{}
+			validate := func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+				validations.Add(1)
+				out := output.Output{ImageURL: component.ContainerImage}
+				if component.ContainerImage == badImage {
+					out.SetPolicyCheck([]evaluator.Outcome{{
+						Failures: []evaluator.Result{{Message: "Image is bad", Metadata: map[string]any{"code": "test.bad"}}},
+					}})
+				}
+				return &out, nil
+			}
+
+			labels := map[string]string{DefaultPolicyLabel: "ecp"}
+			if c.unlabeled {
+				labels = nil
+			}
+			ctx := kubernetes.WithClient(ctx, &policy.FakeKubernetesClient{
+				Policy:          ecc.EnterpriseContractPolicySpec{PublicKey: utils.TestPublicKey},
+				NamespaceLabels: labels,
+			})
+
+			w, err := NewWebhook(ctx, WebhookOptions{DryRun: c.dryRun, FailOpen: c.failOpen, Options: Options{Workers: 2}}, validate, noEvaluator)
+			require.NoError(t, err)
+			h := w.Handler(ctx)
+
+			request := admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "uid",
+					Kind:      metav1.GroupVersionKind{Kind: c.kind},
+					Name:      "name",
+					Namespace: "namespace",
+					Operation: c.operation,
+				},
+			}
+			request.Request.Object.Raw = []byte(c.object)
+			body, err := json.Marshal(request)
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, AdmissionPath, strings.NewReader(string(body))))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var review admissionv1.AdmissionReview
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &review))
+			assert.Equal(t, request.TypeMeta, review.TypeMeta)
+			require.NotNil(t, review.Response)
+			assert.Equal(t, request.Request.UID, review.Response.UID)
+			assert.Equal(t, c.allowed, review.Response.Allowed)
+			assert.Equal(t, c.warnings, review.Response.Warnings)
+			if c.message != "" {
+				require.NotNil(t, review.Response.Result)
+				assert.Equal(t, c.message, review.Response.Result.Message)
+			} else {
+				assert.Nil(t, review.Response.Result)
+			}
+			assert.Equal(t, c.validations, validations.Load())
+		})
+	}
+}
+
+func TestAdmissionPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name          string
+		labels        map[string]string
+		defaultPolicy string
+		expected      string
+	}{
+		{name: "labeled", labels: map[string]string{DefaultPolicyLabel: "ecp"}, expected: "namespace/ecp"},
+		{name: "labeled with default", labels: map[string]string{DefaultPolicyLabel: "ecp"}, defaultPolicy: "default", expected: "namespace/ecp"},
+		{name: "default", defaultPolicy: "default", expected: "default"},
+		{name: "none"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := kubernetes.WithClient(ctx, &policy.FakeKubernetesClient{NamespaceLabels: c.labels})
+			w, err := NewWebhook(ctx, WebhookOptions{Options: Options{Policy: c.defaultPolicy}}, happyValidator, noEvaluator)
+			require.NoError(t, err)
+
+			ref, err := w.policyRef(ctx, "namespace")
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, ref)
+		})
+	}
+}
+
+func TestVerdictsCache(t *testing.T) {
+	utils.SetTestRekorPublicKey(t)
+
+	ctx := utils.WithFS(context.Background(), afero.NewMemMapFs())
+	ctx = kubernetes.WithClient(ctx, &policy.FakeKubernetesClient{
+		Policy: ecc.EnterpriseContractPolicySpec{PublicKey: utils.TestPublicKey},
+	})
+	client := fake.FakeClient{}
+	client.On("Head", mock.Anything).Return(&v1.Descriptor{MediaType: types.OCIManifestSchema1}, nil)
+	ctx = oci.WithClient(ctx, &client)
+
+	validations := atomic.Int32{}
+	validate := func(_ context.Context, component app.SnapshotComponent, _ *app.SnapshotSpec, _ policy.Policy, _ []evaluator.Evaluator, _ bool) (*output.Output, error) {
+		validations.Add(1)
+		out := output.Output{ImageURL: component.ContainerImage}
+		out.SetPolicyCheck([]evaluator.Outcome{{
+			Failures: []evaluator.Result{{Message: "Image is bad", Metadata: map[string]any{"code": "test.bad"}}},
+		}})
+		return &out, nil
+	}
+
+	w, err := NewWebhook(ctx, WebhookOptions{VerdictTTL: time.Minute}, validate, noEvaluator)
+	require.NoError(t, err)
+	now := time.Now()
+	w.verdicts.now = func() time.Time { return now }
+
+	components := []app.SnapshotComponent{{Name: "c", ContainerImage: badImage}}
+	expected := []string{badImage + ": [test.bad] Image is bad"}
+
+	violations, err := w.validate(ctx, components, "namespace/ecp")
+	require.NoError(t, err)
+	assert.Equal(t, expected, violations)
+	assert.Equal(t, int32(1), validations.Load())
+
+	// cached verdict
+	violations, err = w.validate(ctx, components, "namespace/ecp")
+	require.NoError(t, err)
+	assert.Equal(t, expected, violations)
+	assert.Equal(t, int32(1), validations.Load())
+
+	// verdicts are cached per policy
+	_, err = w.validate(ctx, components, "namespace/other")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), validations.Load())
+
+	// expired verdict
+	now = now.Add(2 * time.Minute)
+	violations, err = w.validate(ctx, components, "namespace/ecp")
+	require.NoError(t, err)
+	assert.Equal(t, expected, violations)
+	assert.Equal(t, int32(3), validations.Load())
+}
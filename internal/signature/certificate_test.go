@@ -30,6 +30,8 @@ import (
 	cosignTypes "github.com/sigstore/cosign/v2/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
 )
 
 func TestAddCertificateMetadata(t *testing.T) {
@@ -68,7 +70,7 @@ func TestNewEntitySignature(t *testing.T) {
 	)
 	require.NoError(t, err)
 
-	es, err := NewEntitySignature(signature)
+	es, err := NewEntitySignature(oci.WithDiscovery(signature, oci.DiscoveryTag))
 	require.NoError(t, err)
 
 	snaps.MatchSnapshot(t, es)
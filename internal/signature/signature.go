@@ -29,6 +29,14 @@ type EntitySignature struct {
 	Certificate string            `json:"certificate,omitempty"`
 	Chain       []string          `json:"chain,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	// Discovery is the method used to find the signature, "referrers" for the
+	// OCI referrers API, "tag" for the cosign tag schema
+	Discovery string `json:"discovery,omitempty"`
+}
+
+// discovered is implemented by the signatures reporting how they were found
+type discovered interface {
+	Discovery() string
 }
 
 // NewEntitySignature creates a new EntitySignature from the given Signature.
@@ -37,6 +45,10 @@ func NewEntitySignature(sig oci.Signature) (EntitySignature, error) {
 		Metadata: map[string]string{},
 	}
 
+	if d, ok := sig.(discovered); ok {
+		es.Discovery = d.Discovery()
+	}
+
 	var err error
 	es.Signature, err = sig.Base64Signature()
 	if err != nil {
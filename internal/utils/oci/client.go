@@ -22,26 +22,40 @@ import (
 	"os"
 	"path"
 	"runtime/trace"
+	"slices"
 	"strconv"
 	"sync"
 
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/cache"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignremote "github.com/sigstore/cosign/v2/pkg/cosign/remote"
 	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
 	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/enterprise-contract/ec-cli/internal/auth"
 	"github.com/enterprise-contract/ec-cli/internal/http"
+	"github.com/enterprise-contract/ec-cli/internal/offline"
 )
 
+// defaultRegistryConcurrency is the number of concurrent requests sent to
+// each registry, unless set via the EC_REGISTRY_CONCURRENCY environment
+// variable
+const defaultRegistryConcurrency = 10
+
+// registryLimiter limits the concurrent requests to each registry and pauses
+// the requests to registries that signal rate limiting, it is shared by all
+// clients. By default, remote.DefaultTransport is equivalent to
+// http.DefaultTransport, with a reduced timeout and keep-alive
+var registryLimiter = http.NewRateLimitingRoundTripper(remote.DefaultTransport, registryConcurrency())
+
 // imageRefTransport is used to inject the type of transport to use with the
-// remote.WithTransport function. By default, remote.DefaultTransport is
-// equivalent to http.DefaultTransport, with a reduced timeout and keep-alive
-var imageRefTransport = remote.WithTransport(remote.DefaultTransport)
+// remote.WithTransport function.
+var imageRefTransport = remote.WithTransport(registryLimiter)
 
 type contextKey string
 
@@ -51,7 +65,26 @@ var imgCache = sync.OnceValue(initCache)
 
 func init() {
 	if log.IsLevelEnabled(log.TraceLevel) {
-		imageRefTransport = remote.WithTransport(http.NewTracingRoundTripper(remote.DefaultTransport))
+		imageRefTransport = remote.WithTransport(http.NewTracingRoundTripper(registryLimiter))
+	}
+}
+
+func registryConcurrency() int {
+	if v, err := strconv.Atoi(os.Getenv("EC_REGISTRY_CONCURRENCY")); err == nil && v > 0 {
+		return v
+	}
+
+	return defaultRegistryConcurrency
+}
+
+// LogRequestCounts logs the number of requests sent to each registry.
+func LogRequestCounts() {
+	if !log.IsLevelEnabled(log.DebugLevel) {
+		return
+	}
+
+	for _, c := range registryLimiter.RequestCounts() {
+		log.Debugf("Sent %d requests to %s, %d throttled", c.Requests, c.Host, c.Throttled)
 	}
 }
 
@@ -83,12 +116,28 @@ func createRemoteOptions(ctx context.Context) []remote.Option {
 		Steps:    http.DefaultRetry.MaxRetry,
 	}
 
-	return []remote.Option{
+	opts := []remote.Option{
 		imageRefTransport,
 		remote.WithContext(ctx),
-		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithAuthFromKeychain(auth.KeychainFromContext(ctx)),
 		remote.WithRetryBackoff(backoff),
 	}
+
+	if l := localImagesFrom(ctx); offline.Enabled(ctx) || l != nil {
+		// record the registry exchanges into, or serve those from, the offline
+		// bundle, the latter option takes precedence
+		transport := offline.Transport(ctx, registryLimiter)
+		if l != nil {
+			// the local images are served from memory
+			transport = l.transport(transport)
+		}
+		if log.IsLevelEnabled(log.TraceLevel) {
+			transport = http.NewTracingRoundTripper(transport)
+		}
+		opts = append(opts, remote.WithTransport(transport))
+	}
+
+	return opts
 }
 
 type Client interface {
@@ -99,6 +148,8 @@ type Client interface {
 	Image(name.Reference) (v1.Image, error)
 	Layer(name.Digest) (v1.Layer, error)
 	Index(name.Reference) (v1.ImageIndex, error)
+	Referrers(name.Digest) (*v1.IndexManifest, error)
+	AttachAttestation(name.Digest, oci.Signature, string) error
 }
 
 func WithClient(ctx context.Context, client Client) context.Context {
@@ -112,17 +163,49 @@ func NewClient(ctx context.Context, opts ...remote.Option) Client {
 		return client
 	}
 
-	o := opts
-	if len(opts) == 0 {
-		o = createRemoteOptions(ctx)
+	if len(opts) > 0 {
+		// the results of lookups with arbitrary options cannot be shared
+		return &defaultClient{ctx: ctx, opts: opts}
+	}
+
+	return &defaultClient{ctx: ctx, opts: createRemoteOptions(ctx), memo: memoFrom(ctx), key: optionsKey(ctx)}
+}
+
+// optionsKey identifies the options created by createRemoteOptions for the
+// given context, clients with different options, e.g. credentials, do not share
+// the results of their lookups
+func optionsKey(ctx context.Context) string {
+	keychain := "default"
+	if k, ok := auth.KeychainFromContext(ctx).(*auth.Keychain); ok {
+		keychain = fmt.Sprintf("%p", k)
 	}
 
-	return &defaultClient{ctx, o}
+	return fmt.Sprintf("%s|%t|%p", keychain, offline.Enabled(ctx), localImagesFrom(ctx))
 }
 
 type defaultClient struct {
 	ctx  context.Context
 	opts []remote.Option
+	memo *memo
+	key  string
+}
+
+// memoKey returns the key of the lookup of the given kind in the memo
+func (c *defaultClient) memoKey(kind string, ref name.Reference) string {
+	return kind + ":" + c.key + ":" + ref.String()
+}
+
+// detachedOpts returns the options for lookups whose results are remembered.
+// The results, e.g. a v1.Image, fetch the content lazily and are shared with
+// other clients, so they must not be bound to the context of the client that
+// made the lookup, which can be cancelled long before. The requests are still
+// bound by the timeouts of the transport.
+func (c *defaultClient) detachedOpts() []remote.Option {
+	if c.memo == nil {
+		return c.opts
+	}
+
+	return append(slices.Clone(c.opts), remote.WithContext(context.WithoutCancel(c.ctx)))
 }
 
 func (c *defaultClient) VerifyImageSignatures(ref name.Reference, opts *cosign.CheckOpts) ([]oci.Signature, bool, error) {
@@ -133,7 +216,7 @@ func (c *defaultClient) VerifyImageSignatures(ref name.Reference, opts *cosign.C
 	}
 
 	opts.RegistryClientOpts = append(opts.RegistryClientOpts, ociremote.WithRemoteOptions(c.opts...))
-	return cosign.VerifyImageSignatures(c.ctx, ref, opts)
+	return c.discover(ref, opts, referrerSignature, cosign.VerifyImageSignatures)
 }
 
 func (c *defaultClient) VerifyImageAttestations(ref name.Reference, opts *cosign.CheckOpts) ([]oci.Signature, bool, error) {
@@ -144,7 +227,7 @@ func (c *defaultClient) VerifyImageAttestations(ref name.Reference, opts *cosign
 	}
 
 	opts.RegistryClientOpts = append(opts.RegistryClientOpts, ociremote.WithRemoteOptions(c.opts...))
-	return cosign.VerifyImageAttestations(c.ctx, ref, opts)
+	return c.discover(ref, opts, referrerAttestation, cosign.VerifyImageAttestations)
 }
 
 func (c *defaultClient) Head(ref name.Reference) (*v1.Descriptor, error) {
@@ -154,7 +237,9 @@ func (c *defaultClient) Head(ref name.Reference) (*v1.Descriptor, error) {
 		trace.Logf(c.ctx, "", "image=%q", ref)
 	}
 
-	return remote.Head(ref, c.opts...)
+	return memoize(c.memo, c.memoKey("head", ref), func() (*v1.Descriptor, error) {
+		return remote.Head(ref, c.detachedOpts()...)
+	})
 }
 
 // gather all attestation uris and digests associated with an image
@@ -190,15 +275,17 @@ func (c *defaultClient) ResolveDigest(ref name.Reference) (string, error) {
 		trace.Logf(c.ctx, "", "image=%q", ref)
 	}
 
-	digest, err := ociremote.ResolveDigest(ref, ociremote.WithRemoteOptions(c.opts...))
-	if err != nil {
-		return "", err
-	}
-	h, err := v1.NewHash(digest.Identifier())
-	if err != nil {
-		return "", err
-	}
-	return h.String(), nil
+	return memoize(c.memo, c.memoKey("digest", ref), func() (string, error) {
+		digest, err := ociremote.ResolveDigest(ref, ociremote.WithRemoteOptions(c.detachedOpts()...))
+		if err != nil {
+			return "", err
+		}
+		h, err := v1.NewHash(digest.Identifier())
+		if err != nil {
+			return "", err
+		}
+		return h.String(), nil
+	})
 }
 
 func (c *defaultClient) Image(ref name.Reference) (v1.Image, error) {
@@ -208,16 +295,20 @@ func (c *defaultClient) Image(ref name.Reference) (v1.Image, error) {
 		trace.Logf(c.ctx, "", "image=%q", ref)
 	}
 
-	img, err := remote.Image(ref, c.opts...)
-	if err != nil {
-		return nil, err
-	}
+	return memoize(c.memo, c.memoKey("image", ref), func() (v1.Image, error) {
+		img, err := remote.Image(ref, c.detachedOpts()...)
+		if err != nil {
+			return nil, err
+		}
 
-	if c := imgCache(); c != nil {
-		img = cache.Image(img, c)
-	}
+		// images are fetched from the registry when recording or from the
+		// bundle when offline, never from the cache
+		if ic := imgCache(); ic != nil && !offline.Enabled(c.ctx) {
+			img = cache.Image(img, ic)
+		}
 
-	return img, nil
+		return img, nil
+	})
 }
 
 func (c *defaultClient) Layer(ref name.Digest) (v1.Layer, error) {
@@ -243,10 +334,54 @@ func (c *defaultClient) Index(ref name.Reference) (v1.ImageIndex, error) {
 		trace.Logf(c.ctx, "", "image=%q", ref)
 	}
 
-	index, err := remote.Index(ref, c.opts...)
+	return memoize(c.memo, c.memoKey("index", ref), func() (v1.ImageIndex, error) {
+		index, err := remote.Index(ref, c.detachedOpts()...)
+		if err != nil {
+			return nil, fmt.Errorf("fetching index: %w", err)
+		}
+
+		return index, nil
+	})
+}
+
+// Referrers returns the descriptors of the manifests referring to the given
+// digest, using the OCI referrers API or its fallback tag schema.
+func (c *defaultClient) Referrers(ref name.Digest) (*v1.IndexManifest, error) {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:oci-fetch-referrers")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	return memoize(c.memo, c.memoKey("referrers", ref), func() (*v1.IndexManifest, error) {
+		index, err := remote.Referrers(ref, c.detachedOpts()...)
+		if err != nil {
+			return nil, fmt.Errorf("fetching referrers: %w", err)
+		}
+
+		return index.IndexManifest()
+	})
+}
+
+// AttachAttestation attaches the given attestation to the image with the
+// provided digest, replacing any existing attestations of the same predicate
+// type.
+func (c *defaultClient) AttachAttestation(ref name.Digest, att oci.Signature, predicateType string) error {
+	if trace.IsEnabled() {
+		region := trace.StartRegion(c.ctx, "ec:oci-attach-attestation")
+		defer region.End()
+		trace.Logf(c.ctx, "", "image=%q", ref)
+	}
+
+	se, err := ociremote.SignedEntity(ref, ociremote.WithRemoteOptions(c.opts...))
+	if err != nil {
+		return fmt.Errorf("fetching signed entity: %w", err)
+	}
+
+	se, err = mutate.AttachAttestationToEntity(se, att, mutate.WithReplaceOp(cosignremote.NewReplaceOp(predicateType)))
 	if err != nil {
-		return nil, fmt.Errorf("fetching index: %w", err)
+		return fmt.Errorf("attaching attestation: %w", err)
 	}
 
-	return index, nil
+	return ociremote.WriteAttestations(ref.Repository, se, ociremote.WithRemoteOptions(c.opts...))
 }
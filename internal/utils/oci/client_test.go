@@ -27,6 +27,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -38,6 +39,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/enterprise-contract/ec-cli/internal/auth"
 	"github.com/enterprise-contract/ec-cli/internal/mocks"
 )
 
@@ -175,6 +177,61 @@ func TestLayer(t *testing.T) {
 	assert.Equal(t, fetchCount, blobDownloadCount)
 }
 
+func TestMemo(t *testing.T) {
+	transport := imageRefTransport
+	t.Cleanup(func() { imageRefTransport = transport })
+	imageRefTransport = remote.WithTransport(registryLimiter)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	l := &bytes.Buffer{}
+	registry := httptest.NewServer(registry.New(registry.Logger(log.New(l, "", 0))))
+	t.Cleanup(registry.Close)
+
+	u, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Push(ref, img))
+
+	lookup := func(ctx context.Context) {
+		wg := sync.WaitGroup{}
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client := NewClient(ctx)
+				_, err := client.Head(ref)
+				assert.NoError(t, err)
+				_, err = client.ResolveDigest(ref)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+	}
+
+	countManifestRequests := func() int {
+		count := strings.Count(l.String(), " /v2/repository/image/manifests/tag")
+		l.Reset()
+		return count
+	}
+	countManifestRequests()
+
+	lookup(context.Background())
+	assert.Equal(t, 6, countManifestRequests())
+
+	ctx := WithMemo(context.Background())
+	lookup(ctx)
+	// one for Head and one for ResolveDigest
+	assert.Equal(t, 2, countManifestRequests())
+
+	lookup(ctx)
+	assert.Equal(t, 0, countManifestRequests())
+}
+
 func TestScopedAuth(t *testing.T) {
 	cases := []struct {
 		repository string
@@ -211,3 +268,60 @@ func TestScopedAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestMemoDetachedFromContext(t *testing.T) {
+	transport := imageRefTransport
+	t.Cleanup(func() { imageRefTransport = transport })
+	imageRefTransport = remote.WithTransport(registryLimiter)
+	t.Setenv("EC_CACHE", "false")
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	registry := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(registry.Close)
+
+	u, err := url.Parse(registry.URL)
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(fmt.Sprintf("localhost:%s/repository/image:tag", u.Port()))
+	require.NoError(t, err)
+
+	require.NoError(t, remote.Push(ref, img))
+
+	memoCtx := WithMemo(context.Background())
+
+	// the first client fetches the image and its context is cancelled, as
+	// when the evaluation of a component finishes
+	first, cancel := context.WithCancel(memoCtx)
+	_, err = NewClient(first).Image(ref)
+	require.NoError(t, err)
+	cancel()
+
+	// the remembered image is still usable by other clients
+	fetched, err := NewClient(memoCtx).Image(ref)
+	require.NoError(t, err)
+	layers, err := fetched.Layers()
+	require.NoError(t, err)
+	for _, l := range layers {
+		r, err := l.Uncompressed()
+		require.NoError(t, err)
+		_, err = io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+	}
+}
+
+func TestMemoKeyIncludesOptions(t *testing.T) {
+	ref := name.MustParseReference("registry.io/repository/image:tag")
+	ctx := WithMemo(context.Background())
+
+	c1 := NewClient(ctx).(*defaultClient)
+	c2 := NewClient(auth.WithKeychain(ctx, &auth.Keychain{})).(*defaultClient)
+	assert.NotEqual(t, c1.memoKey("image", ref), c2.memoKey("image", ref))
+	assert.Equal(t, c1.memoKey("image", ref), NewClient(ctx).(*defaultClient).memoKey("image", ref))
+
+	// clients with explicit options do not share the results
+	c3 := NewClient(ctx, remote.WithContext(ctx)).(*defaultClient)
+	assert.Nil(t, c3.memo)
+}
@@ -21,4 +21,13 @@ const (
 	// https://github.com/opencontainers/image-spec/blob/main/annotations.md#pre-defined-annotation-keys
 	BaseImageNameAnnotation   = "org.opencontainers.image.base.name"
 	BaseImageDigestAnnotation = "org.opencontainers.image.base.digest"
+
+	// SBOMAnnotation references the blob holding the SBOM of the image, either
+	// by its digest, within the image repository, or by its full reference
+	SBOMAnnotation = "dev.enterprisecontract.sbom"
+
+	// The media types of the SPDX and CycloneDX SBOMs, also used as the
+	// artifact types of the SBOMs attached to images as OCI referrers
+	SPDXMediaType      = "application/spdx+json"
+	CycloneDXMediaType = "application/vnd.cyclonedx+json"
 )
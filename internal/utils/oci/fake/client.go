@@ -73,6 +73,7 @@ func WithTestImageConfig(ctx context.Context, url string) context.Context {
 	client := &FakeClient{}
 	client.On("Image", ref, mock.Anything).Return(image, nil)
 	client.On("Image", parentRef, mock.Anything).Return(parentImage, nil)
+	client.On("Referrers", ref).Return(&v1.IndexManifest{}, nil)
 
 	return oci.WithClient(ctx, client)
 }
@@ -143,3 +144,17 @@ func (m *FakeClient) Index(ref name.Reference) (v1.ImageIndex, error) {
 	}
 	return index, args.Error(1)
 }
+
+func (m *FakeClient) Referrers(ref name.Digest) (*v1.IndexManifest, error) {
+	args := m.Called(ref)
+	var index *v1.IndexManifest
+	if maybeIndex, ok := args.Get(0).(*v1.IndexManifest); ok {
+		index = maybeIndex
+	}
+	return index, args.Error(1)
+}
+
+func (m *FakeClient) AttachAttestation(ref name.Digest, att cosignoci.Signature, predicateType string) error {
+	args := m.Called(ref, att, predicateType)
+	return args.Error(0)
+}
@@ -0,0 +1,469 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	log "github.com/sirupsen/logrus"
+)
+
+// LocalRegistry is the host of the in-memory registry serving the local
+// images, it is never accessed over the network.
+const LocalRegistry = "ec.local"
+
+const (
+	ociLayoutPrefix     = "oci:"
+	dockerArchivePrefix = "docker-archive:"
+	localContextKey     = contextKey("ec.oci.local")
+	// refNameAnnotation holds the tag of the manifests in the OCI layout
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+	// kindAnnotation marks the manifests in the OCI layouts created by
+	// `cosign save`
+	kindAnnotation = "kind"
+)
+
+// The kinds of manifests in the OCI layouts created by `cosign save`
+const (
+	cosignImage      = "dev.cosignproject.cosign/image"
+	cosignImageIndex = "dev.cosignproject.cosign/imageIndex"
+	cosignSignatures = "dev.cosignproject.cosign/sigs"
+	cosignAtts       = "dev.cosignproject.cosign/atts"
+)
+
+var invalidRepositoryChars = regexp.MustCompile("[^a-z0-9]+")
+
+// localImages holds the in-memory registry the local images are loaded into
+type localImages struct {
+	handler http.Handler
+	mu      sync.Mutex
+	loaded  map[string]string
+	// origins holds the local paths, with the oci: or docker-archive:
+	// prefix, by the name of the repository they are loaded into
+	origins map[string]string
+}
+
+// WithLocalImages returns a context in which images from OCI layout
+// directories, "oci:<path>[@<digest>|:<tag>]", and from archives created by
+// `docker save`, "docker-archive:<path>[:<tag>]", can be validated. Without it
+// local images are rejected, e.g. when the image references come from
+// untrusted sources.
+func WithLocalImages(ctx context.Context) context.Context {
+	return context.WithValue(ctx, localContextKey, &localImages{
+		handler: registry.New(registry.Logger(stdlog.New(io.Discard, "", 0)), registry.WithReferrersSupport(true)),
+		loaded:  map[string]string{},
+		origins: map[string]string{},
+	})
+}
+
+func localImagesFrom(ctx context.Context) *localImages {
+	if l, ok := ctx.Value(localContextKey).(*localImages); ok {
+		return l
+	}
+
+	return nil
+}
+
+// IsLocal returns true if the url refers to a local image.
+func IsLocal(url string) bool {
+	return strings.HasPrefix(url, ociLayoutPrefix) || strings.HasPrefix(url, dockerArchivePrefix)
+}
+
+// ResolveLocal loads the local image, along with its signatures and
+// attestations, into the in-memory registry and returns the reference of the
+// image in it. The reference can be used as any other image reference. The
+// signatures and attestations are expected to be tagged using the cosign
+// conventions, e.g. `sha256-<digest>.sig`, or to be in an OCI layout created
+// by `cosign save`.
+func ResolveLocal(ctx context.Context, url string) (string, error) {
+	l := localImagesFrom(ctx)
+	if l == nil {
+		return "", fmt.Errorf("local images are not supported, unable to use %s", url)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ref, ok := l.loaded[url]; ok {
+		return ref, nil
+	}
+
+	var ref string
+	var err error
+	if p, ok := strings.CutPrefix(url, ociLayoutPrefix); ok {
+		ref, err = l.loadLayout(p)
+	} else if p, ok := strings.CutPrefix(url, dockerArchivePrefix); ok {
+		ref, err = l.loadArchive(p)
+	} else {
+		err = errors.New("not a local image")
+	}
+	if err != nil {
+		return "", fmt.Errorf("loading the local image %s: %w", url, err)
+	}
+
+	log.Debugf("Loaded the local image %s as %s", url, ref)
+	l.loaded[url] = ref
+
+	return ref, nil
+}
+
+// LocalReference returns the reference of the local image, as the user would
+// reference it, for the reference of the image in the in-memory registry. As
+// with other images the digest of the image is included, e.g.
+// oci:<path>@<digest>. Other references are returned unchanged.
+func LocalReference(ctx context.Context, ref string) string {
+	l := localImagesFrom(ctx)
+	if l == nil {
+		return ref
+	}
+
+	d, err := name.NewDigest(ref)
+	if err != nil || d.RegistryStr() != LocalRegistry {
+		return ref
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if origin, ok := l.origins[d.Context().Name()]; ok {
+		return origin + "@" + d.DigestStr()
+	}
+
+	return ref
+}
+
+// transport serves the requests to the in-memory registry, other requests
+// are sent using the base transport
+func (l *localImages) transport(base http.RoundTripper) http.RoundTripper {
+	return localTransport{handler: l.handler, base: base}
+}
+
+type localTransport struct {
+	handler http.Handler
+	base    http.RoundTripper
+}
+
+func (t localTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != LocalRegistry {
+		return t.base.RoundTrip(req)
+	}
+
+	// the handler expects the requests as received by a server, i.e. always
+	// with a body
+	in := req
+	if in.Body == nil {
+		in = req.Clone(req.Context())
+		in.Body = http.NoBody
+	}
+
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, in)
+	resp := rec.Result()
+	resp.Request = req
+
+	return resp, nil
+}
+
+func (l *localImages) options() []remote.Option {
+	return []remote.Option{remote.WithTransport(l.transport(http.DefaultTransport))}
+}
+
+// repository returns the repository in the in-memory registry for the local
+// path, derived from the path to be recognizable and unique
+func repository(kind, path string) (name.Repository, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return name.Repository{}, err
+	}
+
+	base := strings.Trim(invalidRepositoryChars.ReplaceAllString(strings.ToLower(filepath.Base(abs)), "-"), "-")
+	if base == "" {
+		base = "image"
+	}
+
+	sum := sha256.Sum256([]byte(abs))
+
+	return name.NewRepository(fmt.Sprintf("%s/%s/%s-%x", LocalRegistry, kind, base, sum[:6]))
+}
+
+// splitLocal splits the path from the digest or the tag selecting the image.
+func splitLocal(s string) (path, digest, tag string) {
+	if i := strings.LastIndex(s, "@"); i != -1 {
+		return s[:i], s[i+1:], ""
+	}
+
+	if i := strings.LastIndex(s, ":"); i != -1 && !strings.ContainsAny(s[i+1:], `/\`) {
+		return s[:i], "", s[i+1:]
+	}
+
+	return s, "", ""
+}
+
+// tagOf returns the tag from the value of the ref.name annotation, which is
+// either a tag or a full image reference.
+func tagOf(repo name.Repository, refName string) string {
+	if refName == "" {
+		return ""
+	}
+
+	if r, err := name.ParseReference(refName); err == nil && strings.ContainsAny(refName, "/:") {
+		if t, ok := r.(name.Tag); ok {
+			return t.TagStr()
+		}
+		return ""
+	}
+
+	if _, err := name.NewTag(repo.Name() + ":" + refName); err != nil {
+		return ""
+	}
+
+	return refName
+}
+
+func isAuxiliaryTag(tag string) bool {
+	return strings.HasSuffix(tag, ".sig") || strings.HasSuffix(tag, ".att") || strings.HasSuffix(tag, ".sbom")
+}
+
+type localManifest struct {
+	digest    v1.Hash
+	tag       string
+	auxiliary bool
+}
+
+// selectImage returns the digest of the manifest with the given digest or
+// tag, or of the only manifest which is not a signature or an attestation.
+func selectImage(manifests []localManifest, digest, tag string) (v1.Hash, error) {
+	if digest != "" {
+		return v1.NewHash(digest)
+	}
+
+	var candidates []localManifest
+	for _, m := range manifests {
+		if tag != "" && m.tag == tag {
+			return m.digest, nil
+		}
+		if tag == "" && !m.auxiliary {
+			candidates = append(candidates, m)
+		}
+	}
+
+	if tag != "" {
+		return v1.Hash{}, fmt.Errorf("no image tagged %q", tag)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return v1.Hash{}, errors.New("no images found")
+	case 1:
+		return candidates[0].digest, nil
+	default:
+		return v1.Hash{}, fmt.Errorf("found %d images, specify the digest or the tag of the image", len(candidates))
+	}
+}
+
+func (l *localImages) loadLayout(s string) (string, error) {
+	path, digest, tag := splitLocal(s)
+
+	repo, err := repository("oci", path)
+	if err != nil {
+		return "", err
+	}
+	l.origins[repo.Name()] = ociLayoutPrefix + path
+
+	p, err := layout.FromPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	index, err := p.ImageIndex()
+	if err != nil {
+		return "", err
+	}
+
+	im, err := index.IndexManifest()
+	if err != nil {
+		return "", err
+	}
+
+	var subject *v1.Hash
+	for _, d := range im.Manifests {
+		if k := d.Annotations[kindAnnotation]; k == cosignImage || k == cosignImageIndex {
+			subject = &d.Digest
+		}
+	}
+
+	manifests := make([]localManifest, 0, len(im.Manifests))
+	for _, d := range im.Manifests {
+		m := localManifest{digest: d.Digest, tag: tagOf(repo, d.Annotations[refNameAnnotation])}
+
+		// signatures and attestations from `cosign save`
+		switch d.Annotations[kindAnnotation] {
+		case cosignSignatures, cosignAtts:
+			if subject == nil {
+				continue
+			}
+			suffix := "sig"
+			if d.Annotations[kindAnnotation] == cosignAtts {
+				suffix = "att"
+			}
+			m.tag = fmt.Sprintf("%s-%s.%s", subject.Algorithm, subject.Hex, suffix)
+		}
+		m.auxiliary = isAuxiliaryTag(m.tag)
+
+		if err := l.write(repo, index, d, m.tag); err != nil {
+			return "", err
+		}
+
+		manifests = append(manifests, m)
+	}
+
+	h, err := selectImage(manifests, digest, tag)
+	if err != nil {
+		return "", err
+	}
+
+	// the digest can be of a manifest within an index, those are found in the
+	// in-memory registry once the layout is loaded
+	ref := repo.Digest(h.String())
+	if digest != "" {
+		if _, err := remote.Head(ref, l.options()...); err != nil {
+			return "", fmt.Errorf("no image with the digest %s in %s", digest, path)
+		}
+	}
+
+	return ref.String(), nil
+}
+
+// write copies the manifest, and its content, from the OCI layout to the
+// in-memory registry
+func (l *localImages) write(repo name.Repository, index v1.ImageIndex, d v1.Descriptor, tag string) error {
+	var ref name.Reference = repo.Digest(d.Digest.String())
+	if tag != "" {
+		ref = repo.Tag(tag)
+	}
+
+	switch {
+	case d.MediaType.IsIndex():
+		ii, err := index.ImageIndex(d.Digest)
+		if err != nil {
+			return err
+		}
+		return remote.WriteIndex(ref, ii, l.options()...)
+	case d.MediaType.IsImage():
+		img, err := index.Image(d.Digest)
+		if err != nil {
+			return err
+		}
+		return remote.Write(ref, img, l.options()...)
+	default:
+		log.Debugf("Skipping %s with unsupported media type %s", d.Digest, d.MediaType)
+		return nil
+	}
+}
+
+func (l *localImages) loadArchive(s string) (string, error) {
+	path, _, tag := splitLocal(s)
+	if strings.Contains(s, "@") {
+		return "", errors.New("docker archives do not preserve the image digest, use the tag to select the image")
+	}
+
+	repo, err := repository("docker-archive", path)
+	if err != nil {
+		return "", err
+	}
+	l.origins[repo.Name()] = dockerArchivePrefix + path
+
+	opener := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+
+	manifest, err := tarball.LoadManifest(opener)
+	if err != nil {
+		return "", err
+	}
+
+	manifests := make([]localManifest, 0, len(manifest))
+	for i, d := range manifest {
+		var img v1.Image
+		var tags []string
+		if len(d.RepoTags) == 0 {
+			if len(manifest) > 1 {
+				log.Debugf("Skipping the untagged image %d in the archive %s", i, path)
+				continue
+			}
+			img, err = tarball.Image(opener, nil)
+		} else {
+			var t name.Tag
+			t, err = name.NewTag(d.RepoTags[0])
+			if err != nil {
+				return "", err
+			}
+			img, err = tarball.Image(opener, &t)
+			for _, rt := range d.RepoTags {
+				if t, err := name.NewTag(rt); err == nil {
+					tags = append(tags, t.TagStr())
+				}
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+
+		digest, err := img.Digest()
+		if err != nil {
+			return "", err
+		}
+
+		if len(tags) == 0 {
+			if err := remote.Write(repo.Digest(digest.String()), img, l.options()...); err != nil {
+				return "", err
+			}
+			manifests = append(manifests, localManifest{digest: digest})
+		}
+		for _, t := range tags {
+			if err := remote.Write(repo.Tag(t), img, l.options()...); err != nil {
+				return "", err
+			}
+			manifests = append(manifests, localManifest{digest: digest, tag: t, auxiliary: isAuxiliaryTag(t)})
+		}
+	}
+
+	h, err := selectImage(manifests, "", tag)
+	if err != nil {
+		return "", err
+	}
+
+	return repo.Digest(h.String()).String(), nil
+}
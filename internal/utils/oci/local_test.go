@@ -0,0 +1,185 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLocal(t *testing.T) {
+	assert.True(t, IsLocal("oci:./dir"))
+	assert.True(t, IsLocal("docker-archive:/tmp/image.tar"))
+	assert.False(t, IsLocal("registry.io/repository/image:tag"))
+	assert.False(t, IsLocal("oci.io/repository/image:tag"))
+}
+
+func TestSplitLocal(t *testing.T) {
+	cases := []struct {
+		value  string
+		path   string
+		digest string
+		tag    string
+	}{
+		{value: "./dir", path: "./dir"},
+		{value: "./dir:v1", path: "./dir", tag: "v1"},
+		{value: "./dir@sha256:abc", path: "./dir", digest: "sha256:abc"},
+		{value: "/tmp/a:b/dir", path: "/tmp/a:b/dir"},
+		{value: "image.tar:latest", path: "image.tar", tag: "latest"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			path, digest, tag := splitLocal(c.value)
+			assert.Equal(t, c.path, path)
+			assert.Equal(t, c.digest, digest)
+			assert.Equal(t, c.tag, tag)
+		})
+	}
+}
+
+func TestResolveLocalNotEnabled(t *testing.T) {
+	_, err := ResolveLocal(context.Background(), "oci:./dir")
+	assert.ErrorContains(t, err, "local images are not supported")
+}
+
+func TestResolveLocalLayout(t *testing.T) {
+	img, err := random.Image(512, 2)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	sig, err := random.Image(128, 1)
+	require.NoError(t, err)
+	sigDigest, err := sig.Digest()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{
+		kindAnnotation:    cosignImage,
+		refNameAnnotation: "registry.io/repository/image:v1",
+	})))
+	require.NoError(t, p.AppendImage(sig, layout.WithAnnotations(map[string]string{
+		kindAnnotation: cosignSignatures,
+	})))
+
+	ctx := WithLocalImages(context.Background())
+
+	cases := []struct {
+		name string
+		url  string
+		err  string
+	}{
+		{name: "single image", url: "oci:" + dir},
+		{name: "by tag", url: "oci:" + dir + ":v1"},
+		{name: "by digest", url: fmt.Sprintf("oci:%s@%s", dir, digest)},
+		{name: "unknown tag", url: "oci:" + dir + ":v2", err: `no image tagged "v2"`},
+		{name: "unknown digest", url: "oci:" + dir + "@sha256:" + strings.Repeat("0", 64), err: "no image with the digest sha256:" + strings.Repeat("0", 64)},
+		{name: "missing layout", url: "oci:" + filepath.Join(dir, "missing"), err: "loading the local image"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ref, err := ResolveLocal(ctx, c.url)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			d, err := name.NewDigest(ref)
+			require.NoError(t, err)
+			assert.Equal(t, LocalRegistry, d.RegistryStr())
+			assert.Equal(t, digest.String(), d.DigestStr())
+			assert.Equal(t, fmt.Sprintf("oci:%s@%s", dir, digest), LocalReference(ctx, ref))
+
+			client := NewClient(ctx)
+			got, err := client.Image(d)
+			require.NoError(t, err)
+			gotDigest, err := got.Digest()
+			require.NoError(t, err)
+			assert.Equal(t, digest, gotDigest)
+
+			// the signature is tagged following the cosign convention
+			sigRef := d.Context().Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+			desc, err := client.Head(sigRef)
+			require.NoError(t, err)
+			assert.Equal(t, sigDigest, desc.Digest)
+		})
+	}
+}
+
+func TestResolveLocalArchive(t *testing.T) {
+	img, err := random.Image(512, 2)
+	require.NoError(t, err)
+	config, err := img.ConfigName()
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	tag, err := name.NewTag("registry.io/repository/image:v1")
+	require.NoError(t, err)
+	require.NoError(t, tarball.WriteToFile(path, tag, img))
+
+	ctx := WithLocalImages(context.Background())
+
+	_, err = ResolveLocal(ctx, "docker-archive:"+path+"@sha256:abc")
+	assert.ErrorContains(t, err, "do not preserve the image digest")
+
+	for _, url := range []string{"docker-archive:" + path, "docker-archive:" + path + ":v1"} {
+		t.Run(url, func(t *testing.T) {
+			ref, err := ResolveLocal(ctx, url)
+			require.NoError(t, err)
+
+			d, err := name.NewDigest(ref)
+			require.NoError(t, err)
+			assert.Equal(t, "docker-archive:"+path+"@"+d.DigestStr(), LocalReference(ctx, ref))
+
+			got, err := NewClient(ctx).Image(d)
+			require.NoError(t, err)
+
+			var gotConfig v1.Hash
+			gotConfig, err = got.ConfigName()
+			require.NoError(t, err)
+			assert.Equal(t, config, gotConfig)
+		})
+	}
+}
+
+func TestLocalReference(t *testing.T) {
+	ref := "registry.io/repository/image@sha256:" + strings.Repeat("0", 64)
+	assert.Equal(t, ref, LocalReference(context.Background(), ref))
+	assert.Equal(t, ref, LocalReference(WithLocalImages(context.Background()), ref))
+
+	unknown := LocalRegistry + "/oci/unknown@sha256:" + strings.Repeat("0", 64)
+	assert.Equal(t, unknown, LocalReference(WithLocalImages(context.Background()), unknown))
+}
@@ -0,0 +1,84 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const memoContextKey contextKey = "ec.oci.memo"
+
+// memo holds the results of the registry lookups, concurrent lookups of the
+// same kind for the same reference are made only once. Failed lookups are not
+// remembered.
+type memo struct {
+	group  singleflight.Group
+	mu     sync.Mutex
+	values map[string]any
+}
+
+// WithMemo returns a context in which the clients share the results of the
+// registry lookups, e.g. for the same base image used by many components.
+// Tags are resolved once, so the context should not outlive a single run.
+func WithMemo(ctx context.Context) context.Context {
+	return context.WithValue(ctx, memoContextKey, &memo{values: map[string]any{}})
+}
+
+func memoFrom(ctx context.Context) *memo {
+	if m, ok := ctx.Value(memoContextKey).(*memo); ok {
+		return m
+	}
+
+	return nil
+}
+
+// memoize returns the remembered result of the lookup with the given key, or
+// performs the lookup using fn. Without a memo the lookup is always performed.
+func memoize[T any](m *memo, key string, fn func() (T, error)) (T, error) {
+	if m == nil {
+		return fn()
+	}
+
+	m.mu.Lock()
+	v, ok := m.values[key]
+	m.mu.Unlock()
+	if ok {
+		return v.(T), nil
+	}
+
+	v, err, _ := m.group.Do(key, func() (any, error) {
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		m.mu.Lock()
+		m.values[key] = value
+		m.mu.Unlock()
+
+		return value, nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return v.(T), nil
+}
@@ -0,0 +1,351 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	ctypes "github.com/sigstore/cosign/v2/pkg/types"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// The discovery methods of the signatures and attestations
+const (
+	// DiscoveryReferrers is used for the signatures and attestations found
+	// using the OCI referrers API, or its fallback tag schema
+	DiscoveryReferrers = "referrers"
+	// DiscoveryTag is used for the signatures and attestations found using the
+	// cosign tag schema, i.e. the `.sig` and `.att` tags
+	DiscoveryTag = "tag"
+)
+
+// The artifact types of the referrers holding signatures and attestations
+const (
+	cosignSignatureArtifactType   = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	cosignAttestationArtifactType = "application/vnd.dev.cosign.artifact.att.v1+json"
+	// sigstoreBundlePrefix matches both the current media type of the
+	// sigstore bundle, e.g. application/vnd.dev.sigstore.bundle.v0.3+json,
+	// and the older one, e.g. application/vnd.dev.sigstore.bundle+json;version=0.2
+	sigstoreBundlePrefix = "application/vnd.dev.sigstore.bundle"
+)
+
+// errNoReferrers is returned when the image has no referrers holding
+// signatures or attestations
+var errNoReferrers = errors.New("no referrers found")
+
+// DiscoveredSignature is a signature, or an attestation, along with the method
+// used to discover it.
+type DiscoveredSignature struct {
+	ociSignature
+	method string
+}
+
+// ociSignature allows embedding oci.Signature, which has a Signature method
+type ociSignature = oci.Signature
+
+// Discovery returns the method used to discover the signature.
+func (s DiscoveredSignature) Discovery() string {
+	return s.method
+}
+
+// WithDiscovery returns the signature along with the method used to discover
+// it.
+func WithDiscovery(sig oci.Signature, method string) DiscoveredSignature {
+	return DiscoveredSignature{ociSignature: sig, method: method}
+}
+
+func discovered(sigs []oci.Signature, method string) []oci.Signature {
+	out := make([]oci.Signature, 0, len(sigs))
+	for _, s := range sigs {
+		out = append(out, WithDiscovery(s, method))
+	}
+
+	return out
+}
+
+// signatureList provides the signatures collected from the referrers to the
+// cosign verification
+type signatureList struct {
+	oci.Signatures
+	signatures []oci.Signature
+}
+
+func (s signatureList) Get() ([]oci.Signature, error) {
+	return s.signatures, nil
+}
+
+type referrerKind int
+
+const (
+	referrerSignature referrerKind = iota
+	referrerAttestation
+)
+
+// referrers returns the digest of the image and the signatures, or the
+// attestations, found using the OCI referrers API. The referrers can be cosign
+// artifacts, holding the same layers as the images tagged `.sig` and `.att`,
+// or sigstore bundles. Only the bundles holding DSSE envelopes, i.e.
+// attestations, are supported.
+func (c *defaultClient) referrers(ref name.Reference, opts *cosign.CheckOpts, kind referrerKind) (v1.Hash, []oci.Signature, error) {
+	digest, err := c.ResolveDigest(ref)
+	if err != nil {
+		return v1.Hash{}, nil, err
+	}
+
+	h, err := v1.NewHash(digest)
+	if err != nil {
+		return v1.Hash{}, nil, err
+	}
+
+	subject := ref.Context().Digest(digest)
+	index, err := c.Referrers(subject)
+	if err != nil {
+		return h, nil, err
+	}
+
+	var sigs []oci.Signature
+	for _, d := range index.Manifests {
+		artifact := subject.Context().Digest(d.Digest.String())
+		artifactType := d.ArtifactType
+
+		switch {
+		case kind == referrerSignature && artifactType == cosignSignatureArtifactType,
+			kind == referrerAttestation && artifactType == cosignAttestationArtifactType:
+			s, err := ociremote.Signatures(artifact, opts.RegistryClientOpts...)
+			if err != nil {
+				return h, nil, err
+			}
+			l, err := s.Get()
+			if err != nil {
+				return h, nil, err
+			}
+			sigs = append(sigs, l...)
+		case strings.HasPrefix(artifactType, sigstoreBundlePrefix):
+			l, err := c.bundleSignatures(artifact, kind)
+			if err != nil {
+				return h, nil, err
+			}
+			sigs = append(sigs, l...)
+		}
+	}
+
+	if len(sigs) == 0 {
+		return h, nil, errNoReferrers
+	}
+
+	return h, sigs, nil
+}
+
+// bundleSignatures returns the attestations from the sigstore bundles in the
+// layers of the artifact
+func (c *defaultClient) bundleSignatures(artifact name.Digest, kind referrerKind) ([]oci.Signature, error) {
+	img, err := c.Image(artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var sigs []oci.Signature
+	for _, layer := range layers {
+		mt, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(string(mt), sigstoreBundlePrefix) {
+			continue
+		}
+
+		content, err := layer.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var b protobundle.Bundle
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &b); err != nil {
+			return nil, fmt.Errorf("unable to parse the sigstore bundle %s: %w", artifact, err)
+		}
+
+		if b.GetDsseEnvelope() == nil {
+			// message signatures sign the digest of a payload which is not
+			// part of the bundle, so they cannot be verified as cosign
+			// signatures
+			log.Debugf("Skipping the sigstore bundle %s without a DSSE envelope", artifact)
+			continue
+		}
+
+		if kind != referrerAttestation {
+			continue
+		}
+
+		sig, err := attestationFromBundle(&b)
+		if err != nil {
+			return nil, fmt.Errorf("unable to use the sigstore bundle %s: %w", artifact, err)
+		}
+		sigs = append(sigs, sig)
+	}
+
+	return sigs, nil
+}
+
+// attestationFromBundle converts the sigstore bundle holding a DSSE envelope
+// to the equivalent cosign attestation, so that it is verified the same way
+func attestationFromBundle(b *protobundle.Bundle) (oci.Signature, error) {
+	envelope := dsse.Envelope{
+		PayloadType: b.GetDsseEnvelope().GetPayloadType(),
+		Payload:     base64.StdEncoding.EncodeToString(b.GetDsseEnvelope().GetPayload()),
+	}
+	for _, s := range b.GetDsseEnvelope().GetSignatures() {
+		envelope.Signatures = append(envelope.Signatures, dsse.Signature{
+			KeyID: s.GetKeyid(),
+			Sig:   base64.StdEncoding.EncodeToString(s.GetSig()),
+		})
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []static.Option{static.WithLayerMediaType(ctypes.DssePayloadType)}
+
+	vm := b.GetVerificationMaterial()
+	certs := vm.GetX509CertificateChain().GetCertificates()
+	if c := vm.GetCertificate(); c != nil {
+		certs = append(certs, c)
+	}
+	if len(certs) > 0 {
+		var chain bytes.Buffer
+		for _, c := range certs[1:] {
+			if err := pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: c.GetRawBytes()}); err != nil {
+				return nil, err
+			}
+		}
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[0].GetRawBytes()})
+		opts = append(opts, static.WithCertChain(cert, chain.Bytes()))
+	}
+
+	for _, e := range vm.GetTlogEntries() {
+		if e.GetInclusionPromise() == nil {
+			continue
+		}
+		opts = append(opts, static.WithBundle(&cbundle.RekorBundle{
+			SignedEntryTimestamp: e.GetInclusionPromise().GetSignedEntryTimestamp(),
+			Payload: cbundle.RekorPayload{
+				Body:           base64.StdEncoding.EncodeToString(e.GetCanonicalizedBody()),
+				IntegratedTime: e.GetIntegratedTime(),
+				LogIndex:       e.GetLogIndex(),
+				LogID:          hex.EncodeToString(e.GetLogId().GetKeyId()),
+			},
+		}))
+		break
+	}
+
+	if ts := vm.GetTimestampVerificationData().GetRfc3161Timestamps(); len(ts) > 0 {
+		opts = append(opts, static.WithRFC3161Timestamp(&cbundle.RFC3161Timestamp{
+			SignedRFC3161Timestamp: ts[0].GetSignedTimestamp(),
+		}))
+	}
+
+	return static.NewAttestation(payload, opts...)
+}
+
+// verifySignatures verifies the signatures found using the referrers
+func verifySignatures(ctx context.Context, sigs []oci.Signature, h v1.Hash, opts *cosign.CheckOpts) ([]oci.Signature, bool, error) {
+	var checked []oci.Signature
+	var bundleVerified bool
+	var errs error
+	for _, sig := range sigs {
+		verified, err := cosign.VerifyImageSignature(ctx, sig, h, opts)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		bundleVerified = bundleVerified || verified
+		checked = append(checked, sig)
+	}
+
+	if len(checked) == 0 {
+		return nil, false, fmt.Errorf("no matching signatures: %w", errs)
+	}
+
+	return checked, bundleVerified, nil
+}
+
+// discover verifies the signatures, or the attestations, found using the OCI
+// referrers API, falling back to the cosign tag schema when there are none.
+// The tag schema is also used when none of the referrers could be verified, in
+// which case the error of the referrers is returned if the tag schema fails
+// as well.
+func (c *defaultClient) discover(ref name.Reference, opts *cosign.CheckOpts, kind referrerKind,
+	byTag func(context.Context, name.Reference, *cosign.CheckOpts) ([]oci.Signature, bool, error)) ([]oci.Signature, bool, error) {
+	h, sigs, err := c.referrers(ref, opts, kind)
+	if err == nil {
+		var checked []oci.Signature
+		var bundleVerified bool
+		if kind == referrerAttestation {
+			checked, bundleVerified, err = cosign.VerifyImageAttestation(c.ctx, signatureList{signatures: sigs}, h, opts)
+		} else {
+			checked, bundleVerified, err = verifySignatures(c.ctx, sigs, h, opts)
+		}
+		if err == nil {
+			return discovered(checked, DiscoveryReferrers), bundleVerified, nil
+		}
+	}
+
+	if !errors.Is(err, errNoReferrers) {
+		log.Debugf("Unable to use the referrers of %s, falling back to the tag schema: %v", ref, err)
+	}
+
+	checked, bundleVerified, tagErr := byTag(c.ctx, ref, opts)
+	if tagErr != nil {
+		if sigs != nil {
+			// the referrers were found but not verified
+			return nil, false, err
+		}
+		return nil, false, tagErr
+	}
+
+	return discovered(checked, DiscoveryTag), bundleVerified, nil
+}
@@ -0,0 +1,241 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	gcrstatic "github.com/google/go-containerregistry/pkg/v1/static"
+	gcrtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	protodsse "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	ecsignature "github.com/enterprise-contract/ec-cli/internal/signature"
+)
+
+type referrersFixture struct {
+	ref    name.Digest
+	digest v1.Hash
+	signer signature.SignerVerifier
+}
+
+func newReferrersFixture(t *testing.T) referrersFixture {
+	transport := imageRefTransport
+	t.Cleanup(func() { imageRefTransport = transport })
+	imageRefTransport = remote.WithTransport(registryLimiter)
+
+	srv := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(512, 1)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref, err := name.NewDigest(fmt.Sprintf("localhost:%s/repository/image@%s", u.Port(), digest))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := signature.LoadECDSASignerVerifier(key, crypto.SHA256)
+	require.NoError(t, err)
+
+	return referrersFixture{ref: ref, digest: digest, signer: signer}
+}
+
+// attach writes an artifact with the given layer referring to the image
+func (f referrersFixture) attach(t *testing.T, artifactType string, add mutate.Addendum) {
+	artifact, err := mutate.Append(mutate.MediaType(empty.Image, gcrtypes.OCIManifestSchema1), add)
+	require.NoError(t, err)
+	artifact = mutate.ConfigMediaType(artifact, gcrtypes.MediaType(artifactType))
+	artifact = mutate.Subject(artifact, v1.Descriptor{
+		MediaType: gcrtypes.DockerManifestSchema2,
+		Digest:    f.digest,
+	}).(v1.Image)
+
+	digest, err := artifact.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(f.ref.Context().Digest(digest.String()), artifact))
+}
+
+func (f referrersFixture) signature(t *testing.T) mutate.Addendum {
+	p, err := (&payload.Cosign{Image: f.ref}).MarshalJSON()
+	require.NoError(t, err)
+	sig, err := f.signer.SignMessage(bytes.NewReader(p))
+	require.NoError(t, err)
+	b64sig := base64.StdEncoding.EncodeToString(sig)
+
+	layer, err := static.NewSignature(p, b64sig)
+	require.NoError(t, err)
+
+	return mutate.Addendum{
+		Layer:       layer,
+		MediaType:   "application/vnd.dev.cosign.simplesigning.v1+json",
+		Annotations: map[string]string{static.SignatureAnnotationKey: b64sig},
+	}
+}
+
+func (f referrersFixture) bundle(t *testing.T) mutate.Addendum {
+	statement, err := json.Marshal(in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV01,
+			PredicateType: "https://example.com/predicate",
+			Subject: []in_toto.Subject{
+				{Name: f.ref.Context().Name(), Digest: map[string]string{f.digest.Algorithm: f.digest.Hex}},
+			},
+		},
+		Predicate: map[string]string{"hello": "world"},
+	})
+	require.NoError(t, err)
+
+	sig, err := f.signer.SignMessage(bytes.NewReader(ssldsse.PAE(in_toto.PayloadType, statement)))
+	require.NoError(t, err)
+
+	b, err := protojson.Marshal(&protobundle.Bundle{
+		MediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+		VerificationMaterial: &protobundle.VerificationMaterial{
+			Content: &protobundle.VerificationMaterial_PublicKey{
+				PublicKey: &protocommon.PublicKeyIdentifier{Hint: "key"},
+			},
+		},
+		Content: &protobundle.Bundle_DsseEnvelope{
+			DsseEnvelope: &protodsse.Envelope{
+				Payload:     statement,
+				PayloadType: in_toto.PayloadType,
+				Signatures:  []*protodsse.Signature{{Sig: sig}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return mutate.Addendum{
+		Layer:     gcrstatic.NewLayer(b, "application/vnd.dev.sigstore.bundle.v0.3+json"),
+		MediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+	}
+}
+
+func (f referrersFixture) checkOpts(claims func(oci.Signature, v1.Hash, map[string]any) error) *cosign.CheckOpts {
+	return &cosign.CheckOpts{
+		SigVerifier:   f.signer,
+		IgnoreTlog:    true,
+		IgnoreSCT:     true,
+		ClaimVerifier: claims,
+	}
+}
+
+func TestReferrersSignatures(t *testing.T) {
+	f := newReferrersFixture(t)
+	f.attach(t, cosignSignatureArtifactType, f.signature(t))
+
+	sigs, _, err := NewClient(context.Background()).VerifyImageSignatures(f.ref, f.checkOpts(cosign.SimpleClaimVerifier))
+	require.NoError(t, err)
+	require.Len(t, sigs, 1)
+
+	d, ok := sigs[0].(DiscoveredSignature)
+	require.True(t, ok)
+	assert.Equal(t, DiscoveryReferrers, d.Discovery())
+
+	es, err := ecsignature.NewEntitySignature(sigs[0])
+	require.NoError(t, err)
+	assert.Equal(t, DiscoveryReferrers, es.Discovery)
+}
+
+func TestReferrersBundleAttestations(t *testing.T) {
+	f := newReferrersFixture(t)
+	f.attach(t, "application/vnd.dev.sigstore.bundle.v0.3+json", f.bundle(t))
+
+	atts, _, err := NewClient(context.Background()).VerifyImageAttestations(f.ref, f.checkOpts(cosign.IntotoSubjectClaimVerifier))
+	require.NoError(t, err)
+	require.Len(t, atts, 1)
+
+	d, ok := atts[0].(DiscoveredSignature)
+	require.True(t, ok)
+	assert.Equal(t, DiscoveryReferrers, d.Discovery())
+
+	mt, err := atts[0].MediaType()
+	require.NoError(t, err)
+	assert.Equal(t, gcrtypes.MediaType("application/vnd.dsse.envelope.v1+json"), mt)
+
+	// the bundle holds no signatures
+	_, _, err = NewClient(context.Background()).VerifyImageSignatures(f.ref, f.checkOpts(cosign.SimpleClaimVerifier))
+	assert.Error(t, err)
+}
+
+func TestReferrersFallbackToTag(t *testing.T) {
+	f := newReferrersFixture(t)
+
+	// the signature is tagged following the cosign convention
+	sig, err := mutate.Append(empty.Image, f.signature(t))
+	require.NoError(t, err)
+	tag := f.ref.Context().Tag(fmt.Sprintf("%s-%s.sig", f.digest.Algorithm, f.digest.Hex))
+	require.NoError(t, remote.Write(tag, sig))
+
+	sigs, _, err := NewClient(context.Background()).VerifyImageSignatures(f.ref, f.checkOpts(cosign.SimpleClaimVerifier))
+	require.NoError(t, err)
+	require.Len(t, sigs, 1)
+
+	d, ok := sigs[0].(DiscoveredSignature)
+	require.True(t, ok)
+	assert.Equal(t, DiscoveryTag, d.Discovery())
+}
+
+func TestReferrersNotVerified(t *testing.T) {
+	f := newReferrersFixture(t)
+	f.attach(t, cosignSignatureArtifactType, f.signature(t))
+
+	other := newReferrersFixture(t)
+	opts := f.checkOpts(cosign.SimpleClaimVerifier)
+	opts.SigVerifier = other.signer
+
+	_, _, err := NewClient(context.Background()).VerifyImageSignatures(f.ref, opts)
+	assert.ErrorContains(t, err, "no matching signatures")
+}
@@ -0,0 +1,207 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package vsa
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignoci "github.com/sigstore/cosign/v2/pkg/oci"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/applicationsnapshot"
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+var now = time.Now
+
+// VerifyOptions configure the checks performed on the VSA predicate.
+type VerifyOptions struct {
+	// PolicySourceDigests when set, the digests of the policy sources recorded
+	// in the VSA must match this set
+	PolicySourceDigests []string
+	// MaxAge when set, the VSA must have been produced within this duration
+	MaxAge time.Duration
+}
+
+// Verified describes the VSA that passed the verification.
+type Verified struct {
+	Image         string     `json:"image"`
+	Success       bool       `json:"success"`
+	VerifiedTime  *time.Time `json:"verified-time,omitempty"`
+	PolicySources []string   `json:"policy-sources,omitempty"`
+}
+
+// Verify fetches the VSAs attached to the image, verifies their signatures
+// using the check options of the provided policy, and checks their predicate
+// against the options. Attestations that are not VSAs, or cannot be read, are
+// skipped. The first VSA that passes all checks is returned, if none do the
+// returned error explains why each VSA was rejected.
+func Verify(ctx context.Context, image string, p policy.Policy, opts VerifyOptions) (*Verified, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	checkOpts, err := p.CheckOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the ClaimVerifier on a shallow *copy* of CheckOpts to avoid
+	// unexpected side-effects
+	co := *checkOpts
+	co.ClaimVerifier = cosign.IntotoSubjectClaimVerifier
+
+	atts, _, err := oci.NewClient(ctx).VerifyImageAttestations(ref, &co)
+	if err != nil {
+		return nil, fmt.Errorf("unable to verify the attestations of image %s: %w", image, err)
+	}
+
+	var allErrors error
+	found := false
+	for _, att := range atts {
+		report, err := predicate(att)
+		if err != nil {
+			// other attestations of the image are of no concern here
+			log.Debugf("Skipping unreadable attestation of image %s: %v", image, err)
+			continue
+		}
+
+		if report == nil {
+			// not a VSA
+			continue
+		}
+		found = true
+
+		if err := check(report, opts); err != nil {
+			allErrors = errors.Join(allErrors, err)
+			continue
+		}
+
+		return &Verified{
+			Image:         image,
+			Success:       report.Success,
+			VerifiedTime:  report.VerifiedTime,
+			PolicySources: report.PolicySources,
+		}, nil
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no signed VSA found for image %s", image)
+	}
+
+	return nil, fmt.Errorf("no VSA of image %s passed the verification: %w", image, allErrors)
+}
+
+// predicate returns the VSA predicate of the attestation, or nil if the
+// attestation is not a VSA.
+func predicate(att cosignoci.Signature) (*applicationsnapshot.Report, error) {
+	payload, err := att.Payload()
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope dsse.Envelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("malformed attestation envelope: %w", err)
+	}
+
+	statement, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("malformed attestation payload: %w", err)
+	}
+
+	var vsa applicationsnapshot.ProvenanceStatementVSA
+	if err := json.Unmarshal(statement, &vsa); err != nil {
+		log.Debugf("Unable to parse the attestation statement: %v", err)
+		return nil, nil
+	}
+
+	if vsa.PredicateType != applicationsnapshot.PredicateVSAProvenance {
+		return nil, nil
+	}
+
+	return &vsa.Predicate, nil
+}
+
+// check performs the checks on the VSA predicate.
+func check(report *applicationsnapshot.Report, opts VerifyOptions) error {
+	if !report.Success {
+		return errors.New("the VSA records a failed validation")
+	}
+
+	if opts.MaxAge > 0 {
+		if report.VerifiedTime == nil {
+			return errors.New("the VSA does not record the time of the validation")
+		}
+
+		if age := now().Sub(*report.VerifiedTime); age > opts.MaxAge {
+			return fmt.Errorf("the VSA is %s old, older than the maximum age of %s", age.Round(time.Second), opts.MaxAge)
+		}
+	}
+
+	if len(opts.PolicySourceDigests) > 0 {
+		digests := make([]string, 0, len(report.PolicySources))
+		for _, s := range report.PolicySources {
+			digests = append(digests, sourceDigest(s))
+		}
+
+		if !sameSet(digests, opts.PolicySourceDigests) {
+			return fmt.Errorf("the policy source digests %v do not match the expected %v", digests, opts.PolicySourceDigests)
+		}
+	}
+
+	return nil
+}
+
+// sourceDigest returns the digest from a pinned policy source URL, or the URL
+// itself if it is not pinned to a digest, i.e. the image digest for OCI and
+// the commit for git sources.
+func sourceDigest(url string) string {
+	if strings.HasPrefix(url, "oci::") {
+		if i := strings.LastIndex(url, "@"); i != -1 {
+			return url[i+1:]
+		}
+	}
+
+	if strings.HasPrefix(url, "git::") {
+		if i := strings.LastIndex(url, "?ref="); i != -1 {
+			return url[i+len("?ref="):]
+		}
+	}
+
+	return url
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
@@ -0,0 +1,206 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package vsa
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	cosignoci "github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/policy"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+const image = "registry.io/repository/image@sha256:1ad4c9b3dc6f5a2c1b1ba7a5a1b2d0bd5ea4a5d9b06b65dd3e93bcf0d1b44c9a"
+
+var verifiedTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func attestation(t *testing.T, statement string) cosignoci.Signature {
+	envelope, err := json.Marshal(map[string]any{
+		"payloadType": "application/vnd.in-toto+json",
+		"payload":     base64.StdEncoding.EncodeToString([]byte(statement)),
+		"signatures":  []any{},
+	})
+	require.NoError(t, err)
+
+	att, err := static.NewAttestation(envelope)
+	require.NoError(t, err)
+
+	return att
+}
+
+func vsaStatement(predicate string) string {
+	return `{
+		"_type": "https://in-toto.io/Statement/v1",
+		"predicateType": "https://enterprisecontract.dev/verification_summary/v1",
+		"predicate": ` + predicate + `
+	}`
+}
+
+func TestVerify(t *testing.T) {
+	n := now
+	t.Cleanup(func() { now = n })
+	now = func() time.Time { return verifiedTime.Add(time.Hour) }
+
+	successful := vsaStatement(`{
+		"success": true,
+		"components": [],
+		"verified-time": "2024-01-02T03:04:05Z",
+		"policy-sources": [
+			"oci::registry.io/policy:latest@sha256:abc",
+			"git::github.com/org/data?ref=f00"
+		]
+	}`)
+
+	provenance := `{
+		"_type": "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"predicate": {}
+	}`
+
+	malformed, err := static.NewAttestation([]byte(`{"payloadType": "application/vnd.in-toto+json", "payload": "not base64!"}`))
+	require.NoError(t, err)
+	notJSON, err := static.NewAttestation([]byte(`not JSON`))
+	require.NoError(t, err)
+
+	cases := []struct {
+		name        string
+		unreadable  []cosignoci.Signature
+		statements  []string
+		verifyErr   error
+		options     VerifyOptions
+		expected    *Verified
+		expectedErr string
+	}{
+		{
+			name:       "successful",
+			statements: []string{provenance, successful},
+			options: VerifyOptions{
+				PolicySourceDigests: []string{"f00", "sha256:abc"},
+				MaxAge:              2 * time.Hour,
+			},
+			expected: &Verified{
+				Image:        image,
+				Success:      true,
+				VerifiedTime: &verifiedTime,
+				PolicySources: []string{
+					"oci::registry.io/policy:latest@sha256:abc",
+					"git::github.com/org/data?ref=f00",
+				},
+			},
+		},
+		{
+			name:       "unreadable attestations are skipped",
+			unreadable: []cosignoci.Signature{malformed, notJSON},
+			statements: []string{successful},
+			expected: &Verified{
+				Image:        image,
+				Success:      true,
+				VerifiedTime: &verifiedTime,
+				PolicySources: []string{
+					"oci::registry.io/policy:latest@sha256:abc",
+					"git::github.com/org/data?ref=f00",
+				},
+			},
+		},
+		{
+			name:        "only unreadable attestations",
+			unreadable:  []cosignoci.Signature{malformed},
+			statements:  []string{provenance},
+			expectedErr: "no signed VSA found for image " + image,
+		},
+		{
+			name:        "signature verification failure",
+			verifyErr:   errors.New("no matching attestations"),
+			expectedErr: "unable to verify the attestations of image " + image + ": no matching attestations",
+		},
+		{
+			name:        "no VSA",
+			statements:  []string{provenance},
+			expectedErr: "no signed VSA found for image " + image,
+		},
+		{
+			name:        "failed validation",
+			statements:  []string{vsaStatement(`{"success": false, "components": []}`)},
+			expectedErr: "no VSA of image " + image + " passed the verification: the VSA records a failed validation",
+		},
+		{
+			name:        "too old",
+			statements:  []string{successful},
+			options:     VerifyOptions{MaxAge: 30 * time.Minute},
+			expectedErr: "no VSA of image " + image + " passed the verification: the VSA is 1h0m0s old, older than the maximum age of 30m0s",
+		},
+		{
+			name:        "no verified time",
+			statements:  []string{vsaStatement(`{"success": true, "components": []}`)},
+			options:     VerifyOptions{MaxAge: 30 * time.Minute},
+			expectedErr: "no VSA of image " + image + " passed the verification: the VSA does not record the time of the validation",
+		},
+		{
+			name:        "policy source digest mismatch",
+			statements:  []string{successful},
+			options:     VerifyOptions{PolicySourceDigests: []string{"sha256:abc"}},
+			expectedErr: "no VSA of image " + image + " passed the verification: the policy source digests [sha256:abc f00] do not match the expected [sha256:abc]",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			atts := append([]cosignoci.Signature{}, c.unreadable...)
+			for _, s := range c.statements {
+				atts = append(atts, attestation(t, s))
+			}
+
+			client := fake.FakeClient{}
+			client.On("VerifyImageAttestations", name.MustParseReference(image), mock.Anything).Return(atts, true, c.verifyErr)
+			ctx := oci.WithClient(context.Background(), &client)
+
+			p, err := policy.NewOfflinePolicy(ctx, policy.Now)
+			require.NoError(t, err)
+
+			verified, err := Verify(ctx, image, p, c.options)
+			if c.expectedErr != "" {
+				assert.EqualError(t, err, c.expectedErr)
+				assert.Nil(t, verified)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, verified)
+		})
+	}
+}
+
+func TestSourceDigest(t *testing.T) {
+	assert.Equal(t, "sha256:abc", sourceDigest("oci::registry.io/policy:latest@sha256:abc"))
+	assert.Equal(t, "f00", sourceDigest("git::github.com/org/data?ref=f00"))
+	assert.Equal(t, "file::/policy", sourceDigest("file::/policy"))
+	assert.Equal(t, "oci::registry.io/policy:latest", sourceDigest("oci::registry.io/policy:latest"))
+}
@@ -0,0 +1,244 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vsa signs the Verification Summary Attestation (VSA) produced by
+// the validation, attaches it to the validated images and records it in the
+// Rekor transparency log. This allows others to trust the outcome of the
+// validation without performing it again.
+package vsa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/fulcio"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
+	"github.com/sigstore/cosign/v2/cmd/cosign/cli/rekor"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	sigs "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/cosign/v2/pkg/types"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	signatureoptions "github.com/sigstore/sigstore/pkg/signature/options"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+)
+
+const (
+	DefaultFulcioURL  = "https://fulcio.sigstore.dev"
+	DefaultOIDCIssuer = "https://oauth2.sigstore.dev/auth"
+	DefaultRekorURL   = "https://rekor.sigstore.dev"
+)
+
+// Options configure how the VSA is signed.
+type Options struct {
+	// Key is a reference to the private key used for signing, the same
+	// references as with cosign are supported: a file path, a KMS URI or a
+	// Kubernetes secret (k8s://<namespace>/<name>). The password of the key is
+	// read from the COSIGN_PASSWORD environment variable.
+	Key string
+	// Keyless enables signing with a short-lived certificate issued by Fulcio
+	Keyless bool
+	// IdentityToken is the OIDC identity token, or path to a file holding it,
+	// presented to Fulcio when signing keyless
+	IdentityToken string
+	// FulcioURL is the URL of Fulcio used when signing keyless
+	FulcioURL string
+	// OIDCIssuer is the URL of the OIDC issuer used when signing keyless
+	OIDCIssuer string
+	// RekorURL when set, the signed VSA is uploaded to this Rekor instance
+	RekorURL string
+}
+
+// Enabled returns true if signing has been configured.
+func (o Options) Enabled() bool {
+	return o.Key != "" || o.Keyless
+}
+
+// Signed holds the signed VSA.
+type Signed struct {
+	// PredicateType is the predicate type of the signed statement
+	PredicateType string
+	// Envelope is the DSSE envelope with the signed VSA statement
+	Envelope []byte
+	// Cert is the PEM encoded signing certificate, set when signing keyless
+	Cert []byte
+	// Chain is the PEM encoded certificate chain, set when signing keyless
+	Chain []byte
+	// Bundle is the Rekor bundle, set when the VSA was uploaded to Rekor
+	Bundle *cbundle.RekorBundle
+}
+
+// Signer signs VSA statements.
+type Signer struct {
+	signer   signature.SignerVerifier
+	cert     []byte
+	chain    []byte
+	rekorURL string
+}
+
+// passFunc provides the password of the signing key from the COSIGN_PASSWORD
+// environment variable, as cosign does.
+func passFunc(bool) ([]byte, error) {
+	return []byte(os.Getenv("COSIGN_PASSWORD")), nil
+}
+
+// NewSigner creates a Signer from the provided Options, when keyless signing
+// is configured the signing certificate is requested from Fulcio.
+func NewSigner(ctx context.Context, opts Options) (*Signer, error) {
+	if opts.Key != "" && opts.Keyless {
+		return nil, errors.New("signing with a key and keyless signing are mutually exclusive")
+	}
+
+	if opts.Key != "" {
+		sv, err := sigs.SignerVerifierFromKeyRef(ctx, opts.Key, passFunc)
+		if err != nil {
+			return nil, fmt.Errorf("loading the signing key: %w", err)
+		}
+
+		return &Signer{signer: sv, rekorURL: opts.RekorURL}, nil
+	}
+
+	if !opts.Keyless {
+		return nil, errors.New("either a signing key or keyless signing needs to be configured")
+	}
+
+	sv, _, err := signature.NewDefaultECDSASignerVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("creating an ephemeral signing key: %w", err)
+	}
+
+	ko := options.KeyOpts{
+		FulcioURL:        opts.FulcioURL,
+		OIDCIssuer:       opts.OIDCIssuer,
+		OIDCClientID:     "sigstore",
+		IDToken:          opts.IdentityToken,
+		SkipConfirmation: true,
+	}
+	if ko.FulcioURL == "" {
+		ko.FulcioURL = DefaultFulcioURL
+	}
+	if ko.OIDCIssuer == "" {
+		ko.OIDCIssuer = DefaultOIDCIssuer
+	}
+
+	fs, err := fulcio.NewSigner(ctx, ko, sv)
+	if err != nil {
+		return nil, fmt.Errorf("requesting the signing certificate: %w", err)
+	}
+
+	return &Signer{signer: fs, cert: fs.Cert, chain: fs.Chain, rekorURL: opts.RekorURL}, nil
+}
+
+// Sign signs the given in-toto statement with DSSE, and uploads the signed
+// statement to Rekor if configured.
+func (s *Signer) Sign(ctx context.Context, predicateType string, statement any) (*Signed, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := dsse.WrapSigner(s.signer, types.IntotoPayloadType)
+	envelope, err := wrapped.SignMessage(bytes.NewReader(payload), signatureoptions.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("signing the VSA: %w", err)
+	}
+
+	signed := Signed{
+		PredicateType: predicateType,
+		Envelope:      envelope,
+		Cert:          s.cert,
+		Chain:         s.chain,
+	}
+
+	if s.rekorURL == "" {
+		return &signed, nil
+	}
+
+	pem := s.cert
+	if len(pem) == 0 {
+		pub, err := s.signer.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		if pem, err = cryptoutils.MarshalPublicKeyToPEM(pub); err != nil {
+			return nil, err
+		}
+	}
+
+	rekorClient, err := rekor.NewClient(s.rekorURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := cosign.TLogUploadInTotoAttestation(ctx, rekorClient, envelope, pem)
+	if err != nil {
+		return nil, fmt.Errorf("uploading the VSA to Rekor: %w", err)
+	}
+	log.Debugf("Uploaded the VSA to Rekor with log index %d", *entry.LogIndex)
+
+	signed.Bundle = cbundle.EntryToBundle(entry)
+
+	return &signed, nil
+}
+
+// Attach attaches the signed VSA to the image as a cosign attestation,
+// replacing any previously attached VSA.
+func Attach(ctx context.Context, image string, signed *Signed) error {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return err
+	}
+
+	client := oci.NewClient(ctx)
+
+	digest, ok := ref.(name.Digest)
+	if !ok {
+		d, err := client.ResolveDigest(ref)
+		if err != nil {
+			return err
+		}
+
+		if digest, err = name.NewDigest(fmt.Sprintf("%s@%s", ref.Context().Name(), d)); err != nil {
+			return err
+		}
+	}
+
+	opts := []static.Option{}
+	if len(signed.Cert) > 0 {
+		opts = append(opts, static.WithCertChain(signed.Cert, signed.Chain))
+	}
+	if signed.Bundle != nil {
+		opts = append(opts, static.WithBundle(signed.Bundle))
+	}
+
+	att, err := static.NewAttestation(signed.Envelope, opts...)
+	if err != nil {
+		return err
+	}
+
+	return client.AttachAttestation(digest, att, signed.PredicateType)
+}
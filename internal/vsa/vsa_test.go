@@ -0,0 +1,155 @@
+// Copyright The Enterprise Contract Contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unit
+
+package vsa
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	cosignoci "github.com/sigstore/cosign/v2/pkg/oci"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci"
+	"github.com/enterprise-contract/ec-cli/internal/utils/oci/fake"
+)
+
+// signingKey generates a password protected cosign key pair, returning the
+// path to the private key and the public key verifier
+func signingKey(t *testing.T) (string, signature.Verifier) {
+	t.Setenv("COSIGN_PASSWORD", "s3cr3t")
+
+	keys, err := cosign.GenerateKeyPair(passFunc)
+	require.NoError(t, err)
+
+	keyPath := path.Join(t.TempDir(), "cosign.key")
+	require.NoError(t, os.WriteFile(keyPath, keys.PrivateBytes, 0600))
+
+	pub, err := cosign.PemToECDSAKey(keys.PublicBytes)
+	require.NoError(t, err)
+
+	verifier, err := signature.LoadVerifier(pub, crypto.SHA256)
+	require.NoError(t, err)
+
+	return keyPath, verifier
+}
+
+func TestNewSignerOptions(t *testing.T) {
+	_, err := NewSigner(context.Background(), Options{})
+	assert.EqualError(t, err, "either a signing key or keyless signing needs to be configured")
+
+	_, err = NewSigner(context.Background(), Options{Key: "cosign.key", Keyless: true})
+	assert.EqualError(t, err, "signing with a key and keyless signing are mutually exclusive")
+
+	_, err = NewSigner(context.Background(), Options{Key: path.Join(t.TempDir(), "nope.key")})
+	assert.ErrorContains(t, err, "loading the signing key: ")
+}
+
+func TestOptionsEnabled(t *testing.T) {
+	assert.False(t, Options{}.Enabled())
+	assert.False(t, Options{RekorURL: DefaultRekorURL}.Enabled())
+	assert.True(t, Options{Key: "cosign.key"}.Enabled())
+	assert.True(t, Options{Keyless: true}.Enabled())
+}
+
+func TestSignWithKey(t *testing.T) {
+	ctx := context.Background()
+	keyPath, verifier := signingKey(t)
+
+	signer, err := NewSigner(ctx, Options{Key: keyPath})
+	require.NoError(t, err)
+
+	statement := map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": "https://enterprisecontract.dev/verification_summary/v1",
+		"predicate":     map[string]any{"success": true},
+	}
+
+	signed, err := signer.Sign(ctx, "https://enterprisecontract.dev/verification_summary/v1", statement)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://enterprisecontract.dev/verification_summary/v1", signed.PredicateType)
+	assert.Nil(t, signed.Cert)
+	assert.Nil(t, signed.Chain)
+	assert.Nil(t, signed.Bundle)
+
+	// the envelope is verifiable with the public key
+	require.NoError(t, dsse.WrapVerifier(verifier).VerifySignature(bytes.NewReader(signed.Envelope), nil))
+
+	var envelope struct {
+		PayloadType string `json:"payloadType"`
+		Payload     string `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(signed.Envelope, &envelope))
+	assert.Equal(t, "application/vnd.in-toto+json", envelope.PayloadType)
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(statement)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(payload))
+}
+
+func TestAttach(t *testing.T) {
+	signed := &Signed{
+		PredicateType: "https://enterprisecontract.dev/verification_summary/v1",
+		Envelope:      []byte(`{"payloadType": "application/vnd.in-toto+json", "payload": "", "signatures": []}`),
+	}
+
+	const digest = "sha256:1ad4c9b3dc6f5a2c1b1ba7a5a1b2d0bd5ea4a5d9b06b65dd3e93bcf0d1b44c9a"
+
+	isAttestation := mock.MatchedBy(func(att cosignoci.Signature) bool {
+		payload, err := att.Payload()
+		return err == nil && bytes.Equal(signed.Envelope, payload)
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		client := fake.FakeClient{}
+		ref := name.MustParseReference("registry.io/repository/image@" + digest).(name.Digest)
+		client.On("AttachAttestation", ref, isAttestation, signed.PredicateType).Return(nil)
+
+		ctx := oci.WithClient(context.Background(), &client)
+		require.NoError(t, Attach(ctx, ref.String(), signed))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		client := fake.FakeClient{}
+		tag := name.MustParseReference("registry.io/repository/image:latest")
+		client.On("ResolveDigest", tag).Return(digest, nil)
+		ref := name.MustParseReference("registry.io/repository/image@" + digest).(name.Digest)
+		client.On("AttachAttestation", ref, isAttestation, signed.PredicateType).Return(nil)
+
+		ctx := oci.WithClient(context.Background(), &client)
+		require.NoError(t, Attach(ctx, tag.String(), signed))
+		client.AssertExpectations(t)
+	})
+}
@@ -30,6 +30,25 @@ var SLSA_Provenance_v0_2 *jsonschema.Schema
 
 var SLSA_Provenance_v0_2_URI = "https://slsa.dev/provenance/v0.2"
 
+//go:embed slsa_provenance_v1.json
+var slsa_provenance_v1_json string
+
+var SLSA_Provenance_v1 *jsonschema.Schema
+
+var SLSA_Provenance_v1_URI = "https://slsa.dev/provenance/v1"
+
+//go:generate go run ../../internal/input_schema policy_input_v1.json
+
+// Policy_Input_v1_JSON is the JSON schema of the policy input, generated from
+// the Go types
+//
+//go:embed policy_input_v1.json
+var Policy_Input_v1_JSON string
+
+var Policy_Input_v1 *jsonschema.Schema
+
+var Policy_Input_v1_URI = "https://enterprisecontract.dev/schema/policy_input/v1.json"
+
 func init() {
 	compiler := jsonschema.NewCompiler()
 	compiler.AssertFormat = true
@@ -38,4 +57,14 @@ func init() {
 		panic(err)
 	}
 	SLSA_Provenance_v0_2 = compiler.MustCompile(SLSA_Provenance_v0_2_URI)
+
+	if err := compiler.AddResource(SLSA_Provenance_v1_URI, strings.NewReader(slsa_provenance_v1_json)); err != nil {
+		panic(err)
+	}
+	SLSA_Provenance_v1 = compiler.MustCompile(SLSA_Provenance_v1_URI)
+
+	if err := compiler.AddResource(Policy_Input_v1_URI, strings.NewReader(Policy_Input_v1_JSON)); err != nil {
+		panic(err)
+	}
+	Policy_Input_v1 = compiler.MustCompile(Policy_Input_v1_URI)
 }